@@ -87,3 +87,10 @@ type DBMigrator interface {
 	// Close closes the database if initialized and deletes any temporary columns created
 	Close() error
 }
+
+// DBInitializer can be implemented by a DBMigrator that sets up the configured gochan database itself when it isn't
+// migrating in place, instead of having gochan's tables and default staff account, section, and board created for it
+type DBInitializer interface {
+	// InitializeDB is called before Init to prepare the destination database, which uses the given database type
+	InitializeDB(dbType string) error
+}
@@ -0,0 +1,253 @@
+// Package dbcopy copies the contents of one gochan database to another, for example when moving a site from SQLite to
+// MySQL or PostgreSQL
+package dbcopy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gochan-org/gochan/cmd/gochan-migration/internal/common"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+)
+
+const (
+	// ChanType is the -oldchan value for copying from another gochan database
+	ChanType = "gochan"
+)
+
+var (
+	ErrSameDatabase      = common.NewMigrationError(ChanType, "source and destination databases are the same")
+	ErrSourceNotUpToDate = common.NewMigrationError(ChanType,
+		"source database version doesn't match this version of gochan, run gochan with the source configuration first to update it")
+
+	// the tables copied by each step of the migration, in the same order as gcsql.TransferTables
+	boardTables        = []string{"DBPREFIXsections", "DBPREFIXboards"}
+	staffTables        = []string{"DBPREFIXstaff", "DBPREFIXsessions", "DBPREFIXboard_staff"}
+	announcementTables = []string{"DBPREFIXannouncements"}
+	postTables         = []string{
+		"DBPREFIXthreads", "DBPREFIXposts", "DBPREFIXfiles", "DBPREFIXfile_metadata", "DBPREFIXfile_fingerprints",
+	}
+	banTables = []string{
+		"DBPREFIXip_ban", "DBPREFIXip_ban_audit", "DBPREFIXip_ban_appeals", "DBPREFIXip_ban_appeals_audit",
+		"DBPREFIXip_ban_appeals_messages", "DBPREFIXreports", "DBPREFIXreports_audit", "DBPREFIXfilters",
+		"DBPREFIXfilter_boards", "DBPREFIXfilter_conditions", "DBPREFIXfilter_hits", "DBPREFIXreport_bans",
+		"DBPREFIXwebhooks",
+	}
+)
+
+// DBCopyMigrator copies every table from a gochan database (the source, set by the database settings in the file passed
+// to -oldconfig) to the configured gochan database (the destination), keeping the IDs of the rows. The destination
+// database may use a different database type than the source
+type DBCopyMigrator struct {
+	db      *gcsql.GCDB
+	options *common.MigrationOptions
+	config  config.SQLConfig
+}
+
+// readConfig reads the source database settings from the old configuration file, which is expected to be a gochan.json
+// file
+func (m *DBCopyMigrator) readConfig() error {
+	ba, err := os.ReadFile(m.options.OldChanConfig)
+	if err != nil {
+		return err
+	}
+	m.config = config.GetSQLConfig()
+	return json.Unmarshal(ba, &m.config)
+}
+
+// InitializeDB implements common.DBInitializer. It creates gochan's tables in the destination database if it is empty,
+// without the default staff account, section, and board, since they would conflict with the copied rows
+func (*DBCopyMigrator) InitializeDB(dbType string) error {
+	dbVersion, versionFlag, err := gcsql.GetCompleteDatabaseVersion()
+	if err != nil {
+		return err
+	}
+	switch versionFlag {
+	case gcsql.DBClean:
+		return gcsql.InitializeEmptyDatabase(dbType)
+	case gcsql.DBUpToDate:
+		// tables were already created, IsMigrated checks if they have any rows
+		return nil
+	}
+	return common.NewMigrationError(ChanType, fmt.Sprintf(
+		"destination database must be empty or up to date (database version %d, expected %d)", dbVersion, gcsql.DatabaseVersion))
+}
+
+// Init implements common.DBMigrator.
+func (m *DBCopyMigrator) Init(options *common.MigrationOptions) error {
+	m.options = options
+	var err error
+	if err = m.readConfig(); err != nil {
+		return err
+	}
+	if m.IsMigratingInPlace() {
+		return ErrSameDatabase
+	}
+	if m.db, err = gcsql.Open(&m.config); err != nil {
+		return err
+	}
+
+	var sourceVersion int
+	if err = m.db.QueryRow(nil, "SELECT version FROM DBPREFIXdatabase_version WHERE component = 'gochan'",
+		nil, []any{&sourceVersion}); err != nil {
+		return fmt.Errorf("unable to get source database version: %w", err)
+	}
+	if sourceVersion != gcsql.DatabaseVersion {
+		return ErrSourceNotUpToDate
+	}
+	return nil
+}
+
+// IsMigrated implements common.DBMigrator. It returns true if the destination database already has boards or staff
+func (*DBCopyMigrator) IsMigrated() (bool, error) {
+	var count int
+	err := gcsql.QueryRow(nil,
+		"SELECT (SELECT COUNT(*) FROM DBPREFIXboards) + (SELECT COUNT(*) FROM DBPREFIXstaff)", nil, []any{&count})
+	return count > 0, err
+}
+
+// IsMigratingInPlace implements common.DBMigrator. Copying a database to itself isn't supported, so Init returns an
+// error if this is true
+func (m *DBCopyMigrator) IsMigratingInPlace() bool {
+	sqlConfig := config.GetSQLConfig()
+	return m.config.DBtype == sqlConfig.DBtype && m.config.DBhost == sqlConfig.DBhost &&
+		m.config.DBname == sqlConfig.DBname && m.config.DBprefix == sqlConfig.DBprefix
+}
+
+// copyTables copies the rows of the given tables from the source database to the destination database in a single
+// transaction
+func (m *DBCopyMigrator) copyTables(tables ...string) error {
+	errEv := common.LogError()
+	defer errEv.Discard()
+	ctx := context.Background()
+	db, err := gcsql.GetDatabase()
+	if err != nil {
+		errEv.Err(err).Caller().Send()
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		errEv.Err(err).Caller().Msg("Unable to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+	opts := &gcsql.RequestOptions{Context: ctx, Tx: tx}
+
+	for _, table := range tables {
+		errEv.Str("table", table)
+		columns, err := m.db.TableColumns(ctx, table)
+		if err != nil {
+			errEv.Err(err).Caller().Msg("Unable to get table columns")
+			return err
+		}
+		stmt, err := db.PrepareTableInsert(ctx, tx, table, columns)
+		if err != nil {
+			errEv.Err(err).Caller().Msg("Unable to prepare insert statement")
+			return err
+		}
+		var numRows int
+		err = m.db.ReadTableRows(ctx, table, columns, func(row []any) error {
+			numRows++
+			_, err := stmt.ExecContext(ctx, row...)
+			return err
+		})
+		stmt.Close()
+		if err != nil {
+			errEv.Err(err).Caller().Int("row", numRows).Msg("Unable to copy rows")
+			return err
+		}
+		if err = db.ResetTableSequence(opts, table, columns); err != nil {
+			errEv.Err(err).Caller().Msg("Unable to reset table ID sequence")
+			return err
+		}
+		common.LogInfo().Str("table", table).Int("rows", numRows).Msg("Copied table")
+	}
+	if err = tx.Commit(); err != nil {
+		errEv.Err(err).Caller().Msg("Unable to commit transaction")
+	}
+	return err
+}
+
+// MigrateDB implements common.DBMigrator.
+func (m *DBCopyMigrator) MigrateDB() (bool, error) {
+	errEv := common.LogError()
+	defer errEv.Discard()
+	migrated, err := m.IsMigrated()
+	if err != nil {
+		errEv.Err(err).Caller().Msg("Error checking if database is migrated")
+		return false, err
+	}
+	if migrated {
+		return true, nil
+	}
+
+	if err = m.MigrateBoards(); err != nil {
+		return false, err
+	}
+	common.LogInfo().Msg("Copied sections and boards successfully")
+
+	if err = m.MigrateStaff(); err != nil {
+		return false, err
+	}
+	common.LogInfo().Msg("Copied staff successfully")
+
+	if err = m.MigrateAnnouncements(); err != nil {
+		return false, err
+	}
+	common.LogInfo().Msg("Copied announcements successfully")
+
+	if err = m.MigratePosts(); err != nil {
+		return false, err
+	}
+	common.LogInfo().Msg("Copied threads, posts, and uploads successfully")
+
+	if err = m.MigrateBans(); err != nil {
+		return false, err
+	}
+	common.LogInfo().Msg("Copied bans, appeals, reports, filters, and webhooks successfully")
+
+	if err = gcsql.ResetViews(); err != nil {
+		errEv.Err(err).Caller().Msg("Error resetting views")
+		return false, err
+	}
+	common.LogInfo().Msg("Views set up for destination database successfully")
+	return false, nil
+}
+
+// MigrateBoards implements common.DBMigrator.
+func (m *DBCopyMigrator) MigrateBoards() error {
+	return m.copyTables(boardTables...)
+}
+
+// MigrateStaff implements common.DBMigrator. Staff passwords and sessions are copied as they are, so staff can log in
+// with their existing passwords
+func (m *DBCopyMigrator) MigrateStaff() error {
+	return m.copyTables(staffTables...)
+}
+
+// MigrateAnnouncements implements common.DBMigrator.
+func (m *DBCopyMigrator) MigrateAnnouncements() error {
+	return m.copyTables(announcementTables...)
+}
+
+// MigratePosts implements common.DBMigrator.
+func (m *DBCopyMigrator) MigratePosts() error {
+	return m.copyTables(postTables...)
+}
+
+// MigrateBans implements common.DBMigrator. Reports and filters are also copied here since they depend on the posts
+// and staff, followed by the webhooks
+func (m *DBCopyMigrator) MigrateBans() error {
+	return m.copyTables(banTables...)
+}
+
+// Close implements common.DBMigrator.
+func (m *DBCopyMigrator) Close() error {
+	if m.db != nil {
+		return m.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package dbcopy
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+
+	_ "github.com/gochan-org/gochan/pkg/gcsql/initsql"
+
+	"github.com/gochan-org/gochan/cmd/gochan-migration/internal/common"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopiedTables(t *testing.T) {
+	assert.Equal(t, gcsql.TransferTables,
+		slices.Concat(boardTables, staffTables, announcementTables, postTables, banTables),
+		"tables should be copied in the same order as gcsql.TransferTables")
+}
+
+// createSourceDB creates a SQLite gochan database in dir with a thread whose ID doesn't start at 1 and a reply with an
+// IPv6 address, and returns the path to a gochan.json file pointing to it
+func createSourceDB(t *testing.T, dir string) string {
+	t.Helper()
+	dbHost := path.Join(dir, "source.db")
+	config.SetTestDBConfig("sqlite3", dbHost, "source.db", "gochan", "password", "gc_")
+	sqlConfig := config.GetSQLConfig()
+	if !assert.NoError(t, gcsql.ConnectToDB(&sqlConfig)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, gcsql.CheckAndInitializeDatabase("sqlite3", true)) {
+		t.FailNow()
+	}
+	board, err := gcsql.GetBoardFromDir("test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO DBPREFIXthreads(board_id) VALUES(?)`, []any{board.ID}},
+		{`INSERT INTO DBPREFIXthreads(board_id, locked) VALUES(?, TRUE)`, []any{board.ID}},
+		{`DELETE FROM DBPREFIXthreads WHERE id = 1`, nil},
+		{`INSERT INTO DBPREFIXposts(thread_id, is_top_post, ip, message, message_raw, password)
+			VALUES(2, TRUE, INET6_ATON(?), 'OP', 'OP', '')`, []any{"192.168.56.1"}},
+		{`INSERT INTO DBPREFIXposts(thread_id, is_top_post, ip, message, message_raw, password)
+			VALUES(2, FALSE, INET6_ATON(?), 'reply', 'reply', '')`, []any{"2601::1"}},
+	}
+	for _, stmt := range statements {
+		if _, err = gcsql.Exec(nil, stmt.query, stmt.args...); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	if !assert.NoError(t, gcsql.Close()) {
+		t.FailNow()
+	}
+
+	configPath := path.Join(dir, "gochan.json")
+	if !assert.NoError(t, os.WriteFile(configPath,
+		[]byte(`{"DBtype": "sqlite3", "DBhost": "`+dbHost+`", "DBname": "source.db", "DBprefix": "gc_"}`),
+		config.NormalFileMode)) {
+		t.FailNow()
+	}
+	return configPath
+}
+
+func TestCopyDatabase(t *testing.T) {
+	_, err := testutil.GoToGochanRoot(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, common.InitTestMigrationLog(t)) {
+		t.FailNow()
+	}
+	dir := t.TempDir()
+	options := &common.MigrationOptions{ChanType: ChanType, OldChanConfig: createSourceDB(t, dir)}
+
+	config.SetTestDBConfig("sqlite3", path.Join(dir, "destination.db"), "destination.db", "gochan", "password", "gc_")
+	sqlConfig := config.GetSQLConfig()
+	if !assert.NoError(t, gcsql.ConnectToDB(&sqlConfig)) {
+		t.FailNow()
+	}
+	defer gcsql.Close()
+
+	migrator := &DBCopyMigrator{}
+	defer migrator.Close()
+	if !assert.NoError(t, migrator.InitializeDB(sqlConfig.DBtype)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, migrator.Init(options)) {
+		t.FailNow()
+	}
+	migrated, err := migrator.MigrateDB()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.False(t, migrated)
+
+	var numStaff, numBoards int
+	assert.NoError(t, gcsql.QueryRow(nil, "SELECT COUNT(*) FROM DBPREFIXstaff", nil, []any{&numStaff}))
+	assert.Equal(t, 1, numStaff, "the default staff account should be copied, not created")
+	assert.NoError(t, gcsql.QueryRow(nil, "SELECT COUNT(*) FROM DBPREFIXboards", nil, []any{&numBoards}))
+	assert.Equal(t, 1, numBoards, "the default board should be copied, not created")
+
+	var ip string
+	var locked bool
+	assert.NoError(t, gcsql.QueryRow(nil, `SELECT IP_NTOA, locked FROM DBPREFIXposts
+		JOIN DBPREFIXthreads ON DBPREFIXthreads.id = thread_id WHERE DBPREFIXposts.id = 2 AND thread_id = 2`,
+		nil, []any{&ip, &locked}))
+	assert.Equal(t, "2601::1", ip)
+	assert.True(t, locked)
+
+	// the next thread should get the next unused ID
+	result, err := gcsql.Exec(nil, `INSERT INTO DBPREFIXthreads(board_id) VALUES(1)`)
+	if assert.NoError(t, err) {
+		id, err := result.LastInsertId()
+		assert.NoError(t, err)
+		assert.EqualValues(t, 3, id)
+	}
+
+	// the views should be usable in the destination database
+	_, err = gcsql.GetPostFromID(2, true)
+	assert.NoError(t, err)
+
+	migrated, err = migrator.MigrateDB()
+	assert.NoError(t, err)
+	assert.True(t, migrated)
+}
+
+func TestCopyDatabaseToItself(t *testing.T) {
+	_, err := testutil.GoToGochanRoot(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	dir := t.TempDir()
+	options := &common.MigrationOptions{ChanType: ChanType, OldChanConfig: createSourceDB(t, dir)}
+	migrator := &DBCopyMigrator{}
+	assert.ErrorIs(t, migrator.Init(options), ErrSameDatabase)
+}
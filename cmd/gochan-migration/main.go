@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/gochan-org/gochan/cmd/gochan-migration/internal/common"
+	"github.com/gochan-org/gochan/cmd/gochan-migration/internal/dbcopy"
 	"github.com/gochan-org/gochan/cmd/gochan-migration/internal/pre2021"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql/migrationutil"
@@ -37,8 +38,8 @@ func cleanup() {
 func main() {
 	var options common.MigrationOptions
 
-	flag.StringVar(&options.ChanType, "oldchan", "", "The imageboard we are migrating from (currently only pre2021 is supported, but more are coming")
-	flag.StringVar(&options.OldChanConfig, "oldconfig", "", "The path to the old chan's configuration file")
+	flag.StringVar(&options.ChanType, "oldchan", "", "The imageboard we are migrating from (currently pre2021, or gochan to copy another gochan database to the configured one)")
+	flag.StringVar(&options.OldChanConfig, "oldconfig", "", "The path to the old chan's configuration file (for gochan, a gochan.json file with the source database settings)")
 	flag.Parse()
 
 	err := config.InitConfig()
@@ -67,12 +68,14 @@ func main() {
 	switch options.ChanType {
 	case "pre2021":
 		migrator = &pre2021.Pre2021Migrator{}
+	case dbcopy.ChanType:
+		migrator = &dbcopy.DBCopyMigrator{}
 	case "kusabax":
 		fallthrough
 	case "tinyboard":
 		fallthrough
 	default:
-		fatalEv.Msg("Unsupported chan type, Currently only pre2021 database migration is supported")
+		fatalEv.Msg("Unsupported chan type, currently only pre2021 and gochan database migration are supported")
 	}
 	migratingInPlace := migrator.IsMigratingInPlace()
 	common.LogInfo().
@@ -96,7 +99,12 @@ func main() {
 		if err != nil {
 			fatalEv.Err(err).Caller().Msg("Failed to connect to the database")
 		}
-		if err = gcsql.CheckAndInitializeDatabase(sqlCfg.DBtype, true); err != nil {
+		if initializer, ok := migrator.(common.DBInitializer); ok {
+			err = initializer.InitializeDB(sqlCfg.DBtype)
+		} else {
+			err = gcsql.CheckAndInitializeDatabase(sqlCfg.DBtype, true)
+		}
+		if err != nil {
 			fatalEv.Err(err).Caller().Msg("Unable to initialize the database")
 		}
 	}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/backup"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+// backupOutput is the command line output of the backup and restore commands
+type backupOutput struct {
+	Path            string           `json:"path"`
+	GochanVersion   string           `json:"gochanVersion"`
+	DatabaseVersion int              `json:"databaseVersion"`
+	DBType          string           `json:"dbType"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	Tables          int              `json:"tables"`
+	Rows            int              `json:"rows"`
+	Files           int              `json:"files"`
+	Problems        []backup.Problem `json:"problems"`
+}
+
+func newBackupOutput(archivePath string, manifest *backup.Manifest, problems []backup.Problem) backupOutput {
+	output := backupOutput{
+		Path:            archivePath,
+		GochanVersion:   manifest.GochanVersion,
+		DatabaseVersion: manifest.DatabaseVersion,
+		DBType:          manifest.DBType,
+		CreatedAt:       manifest.CreatedAt,
+		Tables:          len(manifest.Tables),
+		Files:           len(manifest.Files) - len(manifest.Tables),
+		Problems:        problems,
+	}
+	for _, table := range manifest.Tables {
+		output.Rows += table.Rows
+	}
+	if output.Problems == nil {
+		output.Problems = []backup.Problem{}
+	}
+	return output
+}
+
+// printBackupOutput writes the output as JSON if asJSON is true, or msg followed by any problems otherwise
+func printBackupOutput(asJSON bool, output backupOutput, msg string) {
+	if asJSON {
+		printJSON(output)
+		return
+	}
+	if msg != "" {
+		fmt.Println(msg)
+	}
+	for _, problem := range output.Problems {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", problem.Path, problem.Problem)
+	}
+}
+
+// runBackupCommand handles the commands for backing up the site and verifying and restoring backups
+func runBackupCommand(cmd string, args []string) {
+	var asJSON bool
+	flagSet := newCommandFlagSet(cmd, &asJSON)
+	var archivePath string
+	var verifyOnly, overwrite, force, rebuild bool
+
+	switch cmd {
+	case "backup":
+		flagSet.StringVar(&archivePath, "out", "", "Path of the backup archive to create (gochan-backup-<date>-<time>.tar.gz in the working directory if not set)")
+	case "restore":
+		flagSet.StringVar(&archivePath, "file", "", "Path of the backup archive to restore")
+		flagSet.BoolVar(&verifyOnly, "verify", false, "Verify the backup without restoring it")
+		flagSet.BoolVar(&overwrite, "overwrite", false, "Delete the rows in an existing gochan database before restoring the backup")
+		flagSet.BoolVar(&force, "force", false, "Overwrite the database without confirmation")
+		flagSet.BoolVar(&rebuild, "rebuild", true, "Build the boards and front page after restoring the backup")
+	}
+	flagSet.Parse(args)
+
+	switch cmd {
+	case "backup":
+		if archivePath == "" {
+			archivePath = fmt.Sprintf("gochan-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+	case "restore":
+		requireFlags(flagSet, "-file is required", archivePath != "")
+	}
+
+	if verifyOnly {
+		// verifying doesn't load the configuration or connect to the database
+		manifest, problems, err := backup.Verify(archivePath)
+		if manifest == nil {
+			fmt.Fprintln(os.Stderr, "Unable to verify backup:", err)
+			os.Exit(1)
+		}
+		output := newBackupOutput(archivePath, manifest, problems)
+		msg := fmt.Sprintf("%s contains %d rows from %d tables and %d files", archivePath, output.Rows, output.Tables, output.Files)
+		printBackupOutput(asJSON, output, msg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		if err != nil || len(problems) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if overwrite && !force && !confirmAction("Are you sure you want to delete everything in the database and replace it with the backup?") {
+		fmt.Println("Not restoring.")
+		return
+	}
+
+	fatalEv := initCommandLineConfig().Str("source", "commandLine").Str("command", cmd).Str("path", archivePath)
+	infoEv := gcutil.LogInfo().Str("source", "commandLine").Str("command", cmd).Str("path", archivePath)
+	ctx := context.Background()
+
+	switch cmd {
+	case "backup":
+		initDB(fatalEv)
+		file, err := os.OpenFile(archivePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			fatalAndLog("Unable to create backup file:", err, fatalEv)
+		}
+		manifest, problems, err := backup.Create(ctx, file)
+		if err == nil {
+			err = file.Close()
+		}
+		if err != nil {
+			file.Close()
+			os.Remove(archivePath)
+			fatalAndLog("Unable to create backup:", err, fatalEv)
+		}
+		output := newBackupOutput(archivePath, manifest, problems)
+		msg := fmt.Sprintf("Backed up %d rows from %d tables and %d files to %s", output.Rows, output.Tables, output.Files, archivePath)
+		infoEv.Int("rows", output.Rows).Int("files", output.Files).Int("problems", len(problems)).Msg(msg)
+		printBackupOutput(asJSON, output, msg)
+	case "restore":
+		systemCritical := config.GetSystemCriticalConfig()
+		if err := gcsql.ConnectToDB(&systemCritical.SQLConfig); err != nil {
+			fatalAndLog("Unable to connect to the database:", err, fatalEv)
+		}
+		manifest, problems, err := backup.Restore(ctx, archivePath, backup.RestoreOptions{Overwrite: overwrite})
+		if err != nil {
+			if manifest != nil {
+				printBackupOutput(false, newBackupOutput(archivePath, manifest, problems), "")
+			}
+			if errors.Is(err, gcsql.ErrDatabaseNotEmpty) {
+				err = fmt.Errorf("%w, run with -overwrite to replace its contents", err)
+			}
+			fatalAndLog("Unable to restore backup:", err, fatalEv)
+		}
+		if rebuild {
+			boards, err := gcsql.GetAllBoards(false)
+			if err != nil {
+				fatalAndLog("Unable to get boards:", err, fatalEv)
+			}
+			boardIDs := make([]int, len(boards))
+			for b, board := range boards {
+				boardIDs[b] = board.ID
+			}
+			rebuildAfterChange(fatalEv, true, boardIDs...)
+		}
+		output := newBackupOutput(archivePath, manifest, problems)
+		msg := fmt.Sprintf("Restored %d rows from %d tables and %d files from %s", output.Rows, output.Tables, output.Files, archivePath)
+		infoEv.Int("rows", output.Rows).Int("files", output.Files).Int("problems", len(problems)).Msg(msg)
+		printBackupOutput(asJSON, output, msg)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+// boardOutput is the command line output of a board
+type boardOutput struct {
+	ID             int    `json:"id"`
+	Dir            string `json:"dir"`
+	Title          string `json:"title"`
+	Subtitle       string `json:"subtitle"`
+	Description    string `json:"description"`
+	SectionID      int    `json:"section"`
+	NavbarPosition int    `json:"navbarPosition"`
+}
+
+func newBoardOutput(board *gcsql.Board) boardOutput {
+	return boardOutput{
+		ID:             board.ID,
+		Dir:            board.Dir,
+		Title:          board.Title,
+		Subtitle:       board.Subtitle,
+		Description:    board.Description,
+		SectionID:      board.SectionID,
+		NavbarPosition: board.NavbarPosition,
+	}
+}
+
+// sectionOutput is the command line output of a board section
+type sectionOutput struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	Position     int    `json:"position"`
+	Hidden       bool   `json:"hidden"`
+}
+
+func newSectionOutput(section *gcsql.Section) sectionOutput {
+	return sectionOutput{
+		ID:           section.ID,
+		Name:         section.Name,
+		Abbreviation: section.Abbreviation,
+		Position:     section.Position,
+		Hidden:       section.Hidden,
+	}
+}
+
+// runBoardCommand handles the commands for listing, creating, modifying, and deleting boards and sections
+func runBoardCommand(cmd string, args []string) {
+	var asJSON bool
+	flagSet := newCommandFlagSet(cmd, &asJSON)
+	var board gcsql.Board
+	var section gcsql.Section
+	var rebuild bool
+	var force bool
+
+	switch cmd {
+	case "newboard", "editboard":
+		flagSet.StringVar(&board.Dir, "dir", "", "Directory of the board")
+		flagSet.StringVar(&board.Title, "title", "", "Title of the board")
+		flagSet.StringVar(&board.Subtitle, "subtitle", "", "Subtitle of the board")
+		flagSet.StringVar(&board.Description, "description", "", "Description of the board")
+		flagSet.IntVar(&board.SectionID, "section", 0, "ID of the board's section (defaults to the first section for new boards)")
+		flagSet.IntVar(&board.NavbarPosition, "navbar", 3, "Position of the board in the top navigation bar")
+		flagSet.BoolVar(&rebuild, "rebuild", true, "Build the board's pages, the board list, and the front page")
+	case "delboard":
+		flagSet.StringVar(&board.Dir, "dir", "", "Directory of the board to delete")
+		flagSet.BoolVar(&force, "force", false, "Force deletion without confirmation")
+		flagSet.BoolVar(&rebuild, "rebuild", true, "Build the board list and the front page")
+	case "newsection", "editsection":
+		if cmd == "editsection" {
+			flagSet.IntVar(&section.ID, "id", 0, "ID of the section to change")
+		}
+		flagSet.StringVar(&section.Name, "name", "", "Name of the section")
+		flagSet.StringVar(&section.Abbreviation, "abbr", "", "Abbreviation of the section")
+		flagSet.IntVar(&section.Position, "position", -1, "Position of the section on the front page (the new section is placed last if not set)")
+		flagSet.BoolVar(&section.Hidden, "hidden", false, "Hide the section and its boards from the front page and board lists")
+		flagSet.BoolVar(&rebuild, "rebuild", true, "Build the board list and the front page")
+	case "delsection":
+		flagSet.IntVar(&section.ID, "id", 0, "ID of the section to delete")
+		flagSet.BoolVar(&force, "force", false, "Force deletion without confirmation")
+		flagSet.BoolVar(&rebuild, "rebuild", true, "Build the board list and the front page")
+	}
+	flagSet.Parse(args)
+	setFlags := flagsSet(flagSet)
+
+	switch cmd {
+	case "newboard":
+		requireFlags(flagSet, "-dir and -title are required", board.Dir != "", board.Title != "")
+		requireFlags(flagSet, "-dir must not contain slashes", !strings.ContainsAny(board.Dir, `/\`))
+	case "editboard", "delboard":
+		requireFlags(flagSet, "-dir is required", board.Dir != "")
+	case "newsection":
+		requireFlags(flagSet, "-name and -abbr are required", section.Name != "", section.Abbreviation != "")
+	case "editsection", "delsection":
+		requireFlags(flagSet, "-id is required", section.ID > 0)
+	}
+	if (cmd == "delboard" || cmd == "delsection") && !force {
+		question := fmt.Sprintf("Are you sure you want to delete the board /%s/?", board.Dir)
+		if cmd == "delsection" {
+			question = fmt.Sprintf("Are you sure you want to delete the section with ID %d?", section.ID)
+		}
+		if !confirmAction(question) {
+			fmt.Println("Not deleting.")
+			return
+		}
+	}
+
+	fatalEv := initCommandLine().Str("source", "commandLine").Str("command", cmd)
+	infoEv := gcutil.LogInfo().Str("source", "commandLine").Str("command", cmd)
+
+	switch cmd {
+	case "listboards":
+		boards, err := gcsql.GetAllBoards(false)
+		if err != nil {
+			fatalAndLog("Unable to get boards:", err, fatalEv)
+		}
+		results := make([]boardOutput, len(boards))
+		for b := range boards {
+			results[b] = newBoardOutput(&boards[b])
+		}
+		printResults(asJSON, results,
+			[]string{"ID", "Dir", "Title", "Subtitle", "Section", "Navbar position"},
+			func(b boardOutput) []string {
+				return []string{strconv.Itoa(b.ID), "/" + b.Dir + "/", b.Title, b.Subtitle,
+					strconv.Itoa(b.SectionID), strconv.Itoa(b.NavbarPosition)}
+			})
+	case "newboard":
+		if board.SectionID == 0 {
+			sections, err := gcsql.GetAllSections(false)
+			if err != nil {
+				fatalAndLog("Unable to get sections:", err, fatalEv)
+			}
+			if len(sections) == 0 {
+				fatalAndLog("Unable to create board:", errors.New("no sections exist, create one with newsection"), fatalEv)
+			}
+			board.SectionID = sections[0].ID
+		}
+		fatalEv.Str("dir", board.Dir)
+		if err := gcsql.CreateBoard(&board, true); err != nil {
+			fatalAndLog("Unable to create board:", err, fatalEv)
+		}
+		if rebuild {
+			rebuildAfterChange(fatalEv, true, board.ID)
+		}
+		printResult(asJSON, newBoardOutput(&board), fmt.Sprintf("Created board /%s/", board.Dir), infoEv.Str("dir", board.Dir))
+	case "editboard":
+		fatalEv.Str("dir", board.Dir)
+		existing, err := gcsql.GetBoardFromDir(board.Dir)
+		if err != nil {
+			fatalAndLog("Unable to get board:", err, fatalEv)
+		}
+		if setFlags["title"] {
+			existing.Title = board.Title
+		}
+		if setFlags["subtitle"] {
+			existing.Subtitle = board.Subtitle
+		}
+		if setFlags["description"] {
+			existing.Description = board.Description
+		}
+		if setFlags["section"] {
+			existing.SectionID = board.SectionID
+		}
+		if setFlags["navbar"] {
+			existing.NavbarPosition = board.NavbarPosition
+		}
+		if err = existing.ModifyInDB(); err != nil {
+			fatalAndLog("Unable to modify board:", err, fatalEv)
+		}
+		if rebuild {
+			rebuildAfterChange(fatalEv, true, existing.ID)
+		}
+		printResult(asJSON, newBoardOutput(existing), fmt.Sprintf("Modified board /%s/", existing.Dir), infoEv.Str("dir", existing.Dir))
+	case "delboard":
+		fatalEv.Str("dir", board.Dir)
+		existing, err := gcsql.GetBoardFromDir(board.Dir)
+		if err != nil {
+			fatalAndLog("Unable to get board:", err, fatalEv)
+		}
+		if err = existing.Delete(); err != nil {
+			fatalAndLog("Unable to delete board:", err, fatalEv)
+		}
+		if rebuild {
+			rebuildAfterChange(fatalEv, true)
+		}
+		printResult(asJSON, commandMessage{Message: "Deleted board /" + existing.Dir + "/"},
+			fmt.Sprintf("Deleted board /%s/ (its directory at %s was not removed)", existing.Dir, existing.AbsolutePath()),
+			infoEv.Str("dir", existing.Dir))
+	case "listsections":
+		sections, err := gcsql.GetAllSections(false)
+		if err != nil {
+			fatalAndLog("Unable to get sections:", err, fatalEv)
+		}
+		results := make([]sectionOutput, len(sections))
+		for s := range sections {
+			results[s] = newSectionOutput(&sections[s])
+		}
+		printResults(asJSON, results,
+			[]string{"ID", "Name", "Abbreviation", "Position", "Hidden"},
+			func(s sectionOutput) []string {
+				return []string{strconv.Itoa(s.ID), s.Name, s.Abbreviation, strconv.Itoa(s.Position), strconv.FormatBool(s.Hidden)}
+			})
+	case "newsection":
+		newSection, err := gcsql.NewSection(section.Name, section.Abbreviation, section.Hidden, section.Position)
+		if err != nil {
+			fatalAndLog("Unable to create section:", err, fatalEv.Str("name", section.Name))
+		}
+		if rebuild {
+			rebuildAfterChange(fatalEv, true)
+		}
+		printResult(asJSON, newSectionOutput(newSection), fmt.Sprintf("Created section %q with ID %d", newSection.Name, newSection.ID),
+			infoEv.Int("sectionID", newSection.ID))
+	case "editsection":
+		fatalEv.Int("sectionID", section.ID)
+		existing, err := gcsql.GetSectionFromID(section.ID)
+		if err != nil {
+			fatalAndLog("Unable to get section:", err, fatalEv)
+		}
+		if setFlags["name"] {
+			existing.Name = section.Name
+		}
+		if setFlags["abbr"] {
+			existing.Abbreviation = section.Abbreviation
+		}
+		if setFlags["position"] {
+			existing.Position = section.Position
+		}
+		if setFlags["hidden"] {
+			existing.Hidden = section.Hidden
+		}
+		if err = existing.UpdateValues(); err != nil {
+			fatalAndLog("Unable to modify section:", err, fatalEv)
+		}
+		if rebuild {
+			rebuildAfterChange(fatalEv, true)
+		}
+		printResult(asJSON, newSectionOutput(existing), fmt.Sprintf("Modified section %q", existing.Name),
+			infoEv.Int("sectionID", existing.ID))
+	case "delsection":
+		fatalEv.Int("sectionID", section.ID)
+		if _, err := gcsql.GetSectionFromID(section.ID); err != nil {
+			fatalAndLog("Unable to get section:", err, fatalEv)
+		}
+		if err := gcsql.DeleteSection(section.ID); err != nil {
+			fatalAndLog("Unable to delete section:", err, fatalEv)
+		}
+		if rebuild {
+			rebuildAfterChange(fatalEv, true)
+		}
+		printResult(asJSON, commandMessage{Message: "Deleted section " + strconv.Itoa(section.ID)},
+			fmt.Sprintf("Deleted section %d", section.ID), infoEv.Int("sectionID", section.ID))
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown board command:", cmd)
+		os.Exit(1)
+	}
+}
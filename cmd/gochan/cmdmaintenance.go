@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/posting/uploads"
+)
+
+// checkConfigOutput is the command line output of the checkconfig command
+type checkConfigOutput struct {
+	Path  string `json:"path"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// thumbnailOutput is the command line output of an upload with a regenerated thumbnail
+type thumbnailOutput struct {
+	uploads.RegeneratedThumbnail
+	Error string `json:"error,omitempty"`
+}
+
+// runMaintenanceCommand handles the commands for resetting staff passwords, cleaning up the database, regenerating
+// thumbnails, validating the configuration, and checking gochan's dependencies
+func runMaintenanceCommand(cmd string, args []string) {
+	var asJSON bool
+	flagSet := newCommandFlagSet(cmd, &asJSON)
+	var username, password string
+	var boardDir string
+	var postID int
+	var cfgPath string
+
+	switch cmd {
+	case "resetpassword":
+		flagSet.StringVar(&username, "username", "", "Username of the staff account")
+		flagSet.StringVar(&password, "password", "", "New password for the staff account (prompted for if not set)")
+	case "fixthumbnails":
+		flagSet.StringVar(&boardDir, "board", "", "Directory of the board to regenerate thumbnails for")
+		flagSet.IntVar(&postID, "post", 0, "ID of a single post to regenerate the thumbnail of")
+	case "checkconfig":
+		flagSet.StringVar(&cfgPath, "config", "", "Path to the configuration file to check (gochan.json in the standard locations if not set)")
+	}
+	flagSet.Parse(args)
+
+	switch cmd {
+	case "resetpassword":
+		requireFlags(flagSet, "-username is required", username != "")
+		if password == "" {
+			password = readNewPassword(fmt.Sprintf("Enter new password for %s: ", username))
+		}
+	case "fixthumbnails":
+		requireFlags(flagSet, "-board or -post is required", boardDir != "" || postID > 0)
+	case "checkconfig":
+		// checkconfig doesn't load the configuration or connect to the database
+		output := checkConfigOutput{Valid: true}
+		var err error
+		if output.Path, err = config.CheckConfigFile(cfgPath); err != nil {
+			output.Valid = false
+			output.Error = err.Error()
+		}
+		if asJSON {
+			printJSON(output)
+		} else if output.Valid {
+			fmt.Println(output.Path, "is valid")
+		} else if output.Path != "" {
+			fmt.Fprintf(os.Stderr, "%s is invalid: %s\n", output.Path, output.Error)
+		} else {
+			fmt.Fprintln(os.Stderr, "Error:", output.Error)
+		}
+		if !output.Valid {
+			os.Exit(1)
+		}
+		return
+	case "doctor":
+		// doctor reports database connection errors instead of exiting
+		runDoctor(asJSON)
+		return
+	}
+
+	fatalEv := initCommandLine().Str("source", "commandLine").Str("command", cmd)
+	infoEv := gcutil.LogInfo().Str("source", "commandLine").Str("command", cmd)
+
+	switch cmd {
+	case "resetpassword":
+		fatalEv.Str("username", username)
+		staff, err := gcsql.GetStaffByUsername(username, true)
+		if err != nil {
+			fatalAndLog("Unable to get staff account:", err, fatalEv)
+		}
+		if err = staff.UpdatePassword(password); err != nil {
+			fatalAndLog("Unable to update password:", err, fatalEv)
+		}
+		// log out any existing sessions, in case the password was reset because the account was compromised
+		if err = staff.ClearSessions(); err != nil {
+			fatalAndLog("Unable to clear staff sessions:", err, fatalEv)
+		}
+		printResult(asJSON, commandMessage{Message: "Password updated for " + username},
+			fmt.Sprintf("Password updated for %s", username), infoEv.Str("username", username))
+	case "cleanup":
+		if err := gcsql.PermanentlyRemoveDeletedPosts(); err != nil {
+			fatalAndLog("Unable to remove deleted posts from the database:", err, fatalEv.Str("cleanup", "removeDeletedPosts"))
+		}
+		if !asJSON {
+			printInfoAndLog("Removed deleted posts from the database", gcutil.LogInfo().Str("source", "commandLine"))
+		}
+		if err := gcsql.OptimizeDatabase(); err != nil {
+			fatalAndLog("Failed optimizing SQL tables:", err, fatalEv.Str("sql", "optimization"))
+		}
+		printResult(asJSON, commandMessage{Message: "Cleanup finished"}, "Cleanup finished", infoEv)
+	case "fixthumbnails":
+		if boardDir != "" && postID == 0 {
+			if _, err := gcsql.GetBoardFromDir(boardDir); err != nil {
+				fatalAndLog("Unable to get board:", err, fatalEv.Str("board", boardDir))
+			}
+		}
+		regenerated, err := uploads.RegenerateThumbnails(boardDir, postID)
+		if err != nil {
+			fatalAndLog("Unable to regenerate thumbnails:", err, fatalEv.Str("board", boardDir).Int("postID", postID))
+		}
+		if postID > 0 && len(regenerated) == 0 {
+			fatalAndLog("Unable to regenerate thumbnail:", errors.New("post does not exist or has no upload"), fatalEv.Int("postID", postID))
+		}
+		var numErrors int
+		results := make([]thumbnailOutput, len(regenerated))
+		for r, thumb := range regenerated {
+			results[r].RegeneratedThumbnail = thumb
+			if thumb.Error != nil {
+				results[r].Error = thumb.Error.Error()
+				numErrors++
+				gcutil.LogWarning().Err(thumb.Error).
+					Str("source", "commandLine").
+					Int("postID", thumb.PostID).
+					Str("filename", thumb.Filename).
+					Msg("Unable to regenerate thumbnail")
+			}
+		}
+		infoEv.Int("regenerated", len(results)-numErrors).Int("errors", numErrors).Msg("Regenerated thumbnails")
+		printResults(asJSON, results, []string{"Post", "Board", "Filename", "Thumbnail size", "Error"}, func(t thumbnailOutput) []string {
+			var size string
+			if t.Error == "" {
+				size = strconv.Itoa(t.ThumbWidth) + "x" + strconv.Itoa(t.ThumbHeight)
+			}
+			return []string{strconv.Itoa(t.PostID), "/" + t.Board + "/", t.Filename, size, t.Error}
+		})
+		// rebuild the boards with regenerated thumbnails, since the pages include the thumbnail sizes
+		var boardIDs []int
+		boardsFound := make(map[string]bool)
+		for _, result := range results {
+			if result.Error != "" || boardsFound[result.Board] {
+				continue
+			}
+			boardsFound[result.Board] = true
+			boardID, err := gcsql.GetBoardIDFromDir(result.Board)
+			if err != nil {
+				fatalAndLog("Unable to get board:", err, fatalEv.Str("board", result.Board))
+			}
+			boardIDs = append(boardIDs, boardID)
+		}
+		if len(boardIDs) > 0 {
+			rebuildAfterChange(fatalEv, false, boardIDs...)
+		}
+		if numErrors > 0 {
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown maintenance command:", cmd)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcsql/dbupdate"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+// migrationOutput is the command line output of a migration that was (or would be) applied or rolled back
+type migrationOutput struct {
+	Version    int      `json:"version"`
+	Name       string   `json:"name"`
+	Statements []string `json:"statements,omitempty"`
+}
+
+func newMigrationOutputs(migrations []dbupdate.Migration, dbType string, down bool, withStatements bool) []migrationOutput {
+	outputs := make([]migrationOutput, len(migrations))
+	prefix := config.GetSQLConfig().DBprefix
+	for m, migration := range migrations {
+		outputs[m] = migrationOutput{Version: migration.Version, Name: migration.Name}
+		if !withStatements {
+			continue
+		}
+		// the migrations have already been checked for statements for the database type
+		statements, _ := migration.Statements(dbType, down)
+		for _, statement := range statements {
+			outputs[m].Statements = append(outputs[m].Statements, strings.ReplaceAll(statement, "DBPREFIX", prefix))
+		}
+	}
+	return outputs
+}
+
+func printMigrateUsage() {
+	fmt.Println("Usage: gochan migrate <status|up|down|dry-run> [options]")
+	fmt.Println("  status   Show the database version and which migrations have been applied")
+	fmt.Println("  up       Apply pending migrations")
+	fmt.Println("  down     Roll back the most recently applied migrations")
+	fmt.Println("  dry-run  Show the SQL statements that up (or down with -down) would run without running them")
+	fmt.Println("Run 'gochan migrate <subcommand> --help' for more information on a subcommand.")
+}
+
+// runMigrateCommand handles the subcommands for showing the status of the database migrations and applying or rolling
+// them back. Unlike other commands, it doesn't update the database automatically when connecting to it
+func runMigrateCommand(args []string) {
+	if len(args) < 1 {
+		printMigrateUsage()
+		os.Exit(1)
+	}
+	subcommand := args[0]
+	var asJSON bool
+	flagSet := newCommandFlagSet("migrate "+subcommand, &asJSON)
+	var target, steps int
+	var down, force bool
+
+	switch subcommand {
+	case "status":
+	case "up":
+		flagSet.IntVar(&target, "to", 0, "Version to migrate the database to (the latest version if not set)")
+	case "down":
+		flagSet.IntVar(&steps, "steps", 1, "Number of migrations to roll back")
+		flagSet.BoolVar(&force, "force", false, "Roll back the migrations without confirmation")
+	case "dry-run":
+		flagSet.BoolVar(&down, "down", false, "Show the statements that would roll back migrations instead of applying them")
+		flagSet.IntVar(&target, "to", 0, "Version to migrate the database to (the latest version if not set)")
+		flagSet.IntVar(&steps, "steps", 1, "Number of migrations to roll back, used with -down")
+	case "help", "-h", "-help", "--help":
+		printMigrateUsage()
+		return
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown migrate subcommand:", subcommand)
+		printMigrateUsage()
+		os.Exit(1)
+	}
+	flagSet.Parse(args[1:])
+
+	if subcommand == "down" && !force &&
+		!confirmAction(fmt.Sprintf("Rolling back migrations may delete data. Are you sure you want to roll back %d migration(s)?", steps)) {
+		fmt.Println("Not rolling back.")
+		return
+	}
+
+	fatalEv := initCommandLineConfig().Str("source", "commandLine").Str("command", "migrate").Str("subcommand", subcommand)
+	infoEv := gcutil.LogInfo().Str("source", "commandLine").Str("command", "migrate").Str("subcommand", subcommand)
+	sqlConfig := config.GetSQLConfig()
+	if err := gcsql.ConnectToDB(&sqlConfig); err != nil {
+		fatalAndLog("Unable to connect to the database:", err, fatalEv)
+	}
+	ctx := context.Background()
+
+	switch subcommand {
+	case "status":
+		report, err := dbupdate.GetMigrationStatus(ctx)
+		if err != nil {
+			fatalAndLog("Unable to get migration status:", err, fatalEv)
+		}
+		if asJSON {
+			printJSON(report)
+			return
+		}
+		fmt.Printf("Database version: %d (latest version: %d)\n", report.DatabaseVersion, report.LatestVersion)
+		if report.LegacyUpdateRequired {
+			fmt.Println("The database is older than the first migration and will be updated before migrations are applied")
+		}
+		if len(report.Migrations) == 0 {
+			fmt.Println("No migrations have been added since the database was versioned")
+			return
+		}
+		printResults(false, report.Migrations, []string{"Version", "Name", "Status", "Applied at"}, func(status dbupdate.MigrationStatus) []string {
+			applied := "pending"
+			var appliedAt string
+			if status.Applied {
+				applied = "applied"
+			}
+			if status.AppliedAt != nil {
+				appliedAt = formatTime(*status.AppliedAt)
+			}
+			return []string{strconv.Itoa(status.Version), status.Name, applied, appliedAt}
+		})
+	case "up":
+		applied, err := dbupdate.MigrateUp(ctx, target, false)
+		if !asJSON {
+			for _, migration := range applied {
+				fmt.Printf("Applied migration %d (%s)\n", migration.Version, migration.Name)
+			}
+		}
+		if err != nil {
+			fatalAndLog("Unable to apply migrations:", err, fatalEv)
+		}
+		if err = gcsql.ResetViews(); err != nil {
+			fatalAndLog("Unable to reset SQL views:", err, fatalEv)
+		}
+		msg := fmt.Sprintf("Applied %d migration(s)", len(applied))
+		if len(applied) == 0 {
+			msg = "No migrations to apply, the database is up to date"
+		}
+		printResult(asJSON, newMigrationOutputs(applied, sqlConfig.DBtype, false, false), msg, infoEv.Int("migrations", len(applied)))
+	case "down":
+		rolledBack, err := dbupdate.MigrateDown(ctx, steps, false)
+		if !asJSON {
+			for _, migration := range rolledBack {
+				fmt.Printf("Rolled back migration %d (%s)\n", migration.Version, migration.Name)
+			}
+		}
+		if err != nil {
+			fatalAndLog("Unable to roll back migrations:", err, fatalEv)
+		}
+		if err = gcsql.ResetViews(); err != nil {
+			fatalAndLog("Unable to reset SQL views:", err, fatalEv)
+		}
+		msg := fmt.Sprintf("Rolled back %d migration(s). gochan will apply them again when it starts unless it is downgraded",
+			len(rolledBack))
+		printResult(asJSON, newMigrationOutputs(rolledBack, sqlConfig.DBtype, true, false), msg, infoEv.Int("migrations", len(rolledBack)))
+	case "dry-run":
+		var planned []dbupdate.Migration
+		var err error
+		var report *dbupdate.MigrationReport
+		if down {
+			planned, err = dbupdate.MigrateDown(ctx, steps, true)
+		} else if report, err = dbupdate.GetMigrationStatus(ctx); err == nil {
+			planned, err = dbupdate.MigrateUp(ctx, target, true)
+		}
+		if err != nil {
+			fatalAndLog("Unable to plan migrations:", err, fatalEv)
+		}
+		if report != nil && report.LegacyUpdateRequired && !asJSON {
+			fmt.Printf("-- Before any migrations, the database will be updated from version %d by the legacy update functions\n",
+				report.DatabaseVersion)
+		}
+		outputs := newMigrationOutputs(planned, sqlConfig.DBtype, down, true)
+		if asJSON {
+			printJSON(outputs)
+			return
+		}
+		if len(outputs) == 0 {
+			fmt.Println("No migrations would be run")
+		}
+		for _, output := range outputs {
+			fmt.Printf("-- Migration %d (%s)\n", output.Version, output.Name)
+			for _, statement := range output.Statements {
+				fmt.Println(statement + ";")
+			}
+		}
+	}
+}
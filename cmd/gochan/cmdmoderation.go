@@ -0,0 +1,352 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Eggbertx/durationutil"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/rs/zerolog"
+)
+
+// banOutput is the command line output of an IP ban
+type banOutput struct {
+	ID          int        `json:"id"`
+	IP          string     `json:"ip"`
+	Board       string     `json:"board,omitempty"`
+	Staff       string     `json:"staff"`
+	IssuedAt    time.Time  `json:"issuedAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	Active      bool       `json:"active"`
+	ThreadBan   bool       `json:"threadBan"`
+	CanAppeal   bool       `json:"canAppeal"`
+	AppealAt    time.Time  `json:"appealAt"`
+	Reason      string     `json:"reason"`
+	StaffNote   string     `json:"staffNote"`
+	BannedForID *int       `json:"bannedForPost,omitempty"`
+}
+
+// newBanOutput returns the command line output of the ban, using staffNames and boardDirs to look up (and cache)
+// the staff username and board directory
+func newBanOutput(ban *gcsql.IPBan, staffNames map[int]string, boardDirs map[int]string) (banOutput, error) {
+	output := banOutput{
+		ID:          ban.ID,
+		IP:          ban.RangeStart,
+		IssuedAt:    ban.IssuedAt,
+		Active:      ban.IsActive,
+		ThreadBan:   ban.IsThreadBan,
+		CanAppeal:   ban.CanAppeal,
+		AppealAt:    ban.AppealAt,
+		Reason:      html.UnescapeString(ban.Message),
+		StaffNote:   html.UnescapeString(ban.StaffNote),
+		BannedForID: ban.BannedForPostID,
+	}
+	if ban.RangeStart != ban.RangeEnd {
+		output.IP += "-" + ban.RangeEnd
+	}
+	if !ban.Permanent {
+		output.ExpiresAt = &ban.ExpiresAt
+	}
+	var err error
+	var ok bool
+	if output.Staff, ok = staffNames[ban.StaffID]; !ok {
+		if output.Staff, err = gcsql.GetStaffUsernameFromID(ban.StaffID); err != nil {
+			return output, err
+		}
+		staffNames[ban.StaffID] = output.Staff
+	}
+	if ban.BoardID != nil {
+		if output.Board, ok = boardDirs[*ban.BoardID]; !ok {
+			if output.Board, err = gcsql.GetBoardDir(*ban.BoardID); err != nil {
+				return output, err
+			}
+			boardDirs[*ban.BoardID] = output.Board
+		}
+	}
+	return output, nil
+}
+
+// deletedPostOutput is the command line output of a post (or its file) deleted by the delpost command
+type deletedPostOutput struct {
+	PostID   int    `json:"post"`
+	ThreadOP int    `json:"op"`
+	Board    string `json:"board"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// getStaffIDOrExit returns the ID of the staff account with the given username, exiting if it doesn't exist
+func getStaffIDOrExit(username string, fatalEv *zerolog.Event) int {
+	staffID, err := gcsql.GetStaffID(username)
+	if err != nil {
+		fatalAndLog("Unable to get staff account:", err, fatalEv.Str("staff", username))
+	}
+	return staffID
+}
+
+// parseIDList parses a comma separated list of IDs
+func parseIDList(list string) ([]int, error) {
+	var ids []int
+	for idStr := range strings.SplitSeq(list, ",") {
+		idStr = strings.TrimSpace(idStr)
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid ID %q", idStr)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// runModerationCommand handles the commands for managing bans, deleting posts, and handling reports
+func runModerationCommand(cmd string, args []string) {
+	var asJSON bool
+	flagSet := newCommandFlagSet(cmd, &asJSON)
+	var staffUsername string
+	var boardDir string
+	var id int
+	var limit int
+	var all bool
+
+	// ban flags
+	var ip, duration, reason, staffNote, appealWait string
+	var threadBan, noAppeals bool
+
+	// delpost flags
+	var postIDsStr string
+	var fileOnly bool
+
+	// clearreport flags
+	var block bool
+
+	switch cmd {
+	case "listbans":
+		flagSet.StringVar(&boardDir, "board", "", "Only list bans on the board with this directory")
+		flagSet.IntVar(&limit, "limit", 50, "Maximum number of bans to list")
+		flagSet.BoolVar(&all, "all", false, "Include inactive bans")
+	case "ban":
+		flagSet.StringVar(&ip, "ip", "", "IP address or CIDR range to ban")
+		flagSet.StringVar(&boardDir, "board", "", "Directory of the board to ban the IP from (all boards if not set)")
+		flagSet.StringVar(&duration, "duration", "", "Ban duration, e.g. 3d or 1mo (permanent if not set)")
+		flagSet.StringVar(&reason, "reason", "", "Reason shown to the banned user")
+		flagSet.StringVar(&staffNote, "note", "", "Note only visible to staff")
+		flagSet.StringVar(&staffUsername, "staff", "", "Username of the staff account issuing the ban")
+		flagSet.BoolVar(&threadBan, "threadban", false, "Only ban the IP from creating new threads")
+		flagSet.BoolVar(&noAppeals, "noappeals", false, "Don't allow the ban to be appealed")
+		flagSet.StringVar(&appealWait, "appealwait", "", "How long the banned user must wait before appealing, e.g. 1w")
+	case "unban":
+		flagSet.IntVar(&id, "id", 0, "ID of the ban to deactivate")
+		flagSet.StringVar(&staffUsername, "staff", "", "Username of the staff account deactivating the ban")
+	case "delpost":
+		flagSet.StringVar(&postIDsStr, "id", "", "Comma separated list of post IDs to delete (deleting a thread's OP deletes the thread)")
+		flagSet.BoolVar(&fileOnly, "fileonly", false, "Only delete the posts' files")
+	case "listreports":
+		flagSet.BoolVar(&all, "all", false, "Include reports that have already been cleared")
+	case "clearreport":
+		flagSet.IntVar(&id, "id", 0, "ID of the report to clear")
+		flagSet.StringVar(&staffUsername, "staff", "", "Username of the staff account clearing the report")
+		flagSet.BoolVar(&block, "block", false, "Ignore future reports of the post")
+	}
+	flagSet.Parse(args)
+
+	var postIDs []int
+	switch cmd {
+	case "listbans":
+		requireFlags(flagSet, "-limit must be greater than 0", limit > 0)
+	case "ban":
+		requireFlags(flagSet, "-ip and -staff are required", ip != "", staffUsername != "")
+	case "unban", "clearreport":
+		requireFlags(flagSet, "-id and -staff are required", id > 0, staffUsername != "")
+	case "delpost":
+		var err error
+		postIDs, err = parseIDList(postIDsStr)
+		requireFlags(flagSet, "-id must be a comma separated list of post IDs", err == nil, len(postIDs) > 0)
+	}
+
+	fatalEv := initCommandLine().Str("source", "commandLine").Str("command", cmd)
+	infoEv := gcutil.LogInfo().Str("source", "commandLine").Str("command", cmd)
+
+	switch cmd {
+	case "listbans":
+		var boardID int
+		var err error
+		if boardDir != "" {
+			if boardID, err = gcsql.GetBoardIDFromDir(boardDir); err != nil {
+				fatalAndLog("Unable to get board:", err, fatalEv.Str("board", boardDir))
+			}
+		}
+		bans, err := gcsql.GetIPBans(boardID, limit, !all)
+		if err != nil {
+			fatalAndLog("Unable to get bans:", err, fatalEv)
+		}
+		staffNames := make(map[int]string)
+		boardDirs := make(map[int]string)
+		results := make([]banOutput, len(bans))
+		for b := range bans {
+			if results[b], err = newBanOutput(&bans[b], staffNames, boardDirs); err != nil {
+				fatalAndLog("Unable to get ban info:", err, fatalEv.Int("banID", bans[b].ID))
+			}
+		}
+		printResults(asJSON, results,
+			[]string{"ID", "IP", "Board", "Staff", "Issued", "Expires", "Active", "Appealable", "Reason"},
+			func(b banOutput) []string {
+				board := "all"
+				if b.Board != "" {
+					board = "/" + b.Board + "/"
+				}
+				expires := "never"
+				if b.ExpiresAt != nil {
+					expires = formatTime(*b.ExpiresAt)
+				}
+				return []string{strconv.Itoa(b.ID), b.IP, board, b.Staff, formatTime(b.IssuedAt), expires,
+					strconv.FormatBool(b.Active), strconv.FormatBool(b.CanAppeal), b.Reason}
+			})
+	case "ban":
+		var ban gcsql.IPBan
+		var err error
+		ban.StaffID = getStaffIDOrExit(staffUsername, fatalEv)
+		if ban.RangeStart, ban.RangeEnd, err = gcutil.ParseIPRange(ip); err != nil {
+			fatalAndLog("Unable to parse IP range:", err, fatalEv.Str("ip", ip))
+		}
+		if boardDir != "" {
+			boardID, err := gcsql.GetBoardIDFromDir(boardDir)
+			if err != nil {
+				fatalAndLog("Unable to get board:", err, fatalEv.Str("board", boardDir))
+			}
+			ban.BoardID = &boardID
+		}
+		ban.Permanent = duration == ""
+		if !ban.Permanent {
+			banDuration, err := durationutil.ParseLongerDuration(duration)
+			if err != nil {
+				fatalAndLog("Invalid duration:", err, fatalEv.Str("duration", duration))
+			}
+			ban.ExpiresAt = time.Now().Add(banDuration)
+		}
+		ban.CanAppeal = !noAppeals
+		ban.AppealAt = time.Now()
+		if ban.CanAppeal && appealWait != "" {
+			waitDuration, err := durationutil.ParseLongerDuration(appealWait)
+			if err != nil {
+				fatalAndLog("Invalid appeal wait duration:", err, fatalEv.Str("appealwait", appealWait))
+			}
+			ban.AppealAt = ban.AppealAt.Add(waitDuration)
+		}
+		ban.IsThreadBan = threadBan
+		ban.Message = html.EscapeString(reason)
+		ban.StaffNote = html.EscapeString(staffNote)
+		ban.IsActive = true
+		if err = gcsql.NewIPBan(&ban); err != nil {
+			fatalAndLog("Unable to create ban:", err, fatalEv.Str("ip", ip))
+		}
+		newBan, err := gcsql.GetIPBanByID(nil, ban.ID)
+		if err != nil {
+			fatalAndLog("Unable to get new ban:", err, fatalEv.Int("banID", ban.ID))
+		}
+		output, err := newBanOutput(newBan, make(map[int]string), make(map[int]string))
+		if err != nil {
+			fatalAndLog("Unable to get ban info:", err, fatalEv.Int("banID", ban.ID))
+		}
+		printResult(asJSON, output, fmt.Sprintf("Banned %s with ban ID %d", ip, ban.ID),
+			infoEv.Str("ip", ip).Int("banID", ban.ID).Str("staff", staffUsername))
+	case "unban":
+		staffID := getStaffIDOrExit(staffUsername, fatalEv)
+		if err := gcsql.DeactivateBan(id, staffID); err != nil {
+			fatalAndLog("Unable to deactivate ban:", err, fatalEv.Int("banID", id))
+		}
+		printResult(asJSON, commandMessage{Message: "Deactivated ban " + strconv.Itoa(id)},
+			fmt.Sprintf("Deactivated ban %d", id), infoEv.Int("banID", id).Str("staff", staffUsername))
+	case "delpost":
+		postIDsAny := make([]any, len(postIDs))
+		for p, postID := range postIDs {
+			postIDsAny[p] = postID
+		}
+		delPosts, affectedPostIDs, err := getAllPostsToDelete(postIDsAny, fileOnly)
+		if err != nil {
+			fatalAndLog("Unable to get post info:", err, fatalEv)
+		}
+		if len(delPosts) == 0 {
+			fatalAndLog("Unable to delete posts:", gcsql.ErrPostDoesNotExist, fatalEv)
+		}
+		errEv := gcutil.LogError(nil).Str("source", "commandLine").Str("command", cmd)
+		if err = deletePostFiles(delPosts, affectedPostIDs, !fileOnly, errEv); err != nil {
+			fatalAndLog("Unable to delete files:", err, fatalEv)
+		}
+		if !fileOnly {
+			if err = markPostsAsDeleted(delPosts, nil, errEv); err != nil {
+				fatalAndLog("Unable to delete posts:", err, fatalEv)
+			}
+		}
+		errEv.Discard()
+
+		var boardIDs []int
+		boardIDsFound := make(map[string]bool)
+		results := make([]deletedPostOutput, len(delPosts))
+		for p, post := range delPosts {
+			results[p] = deletedPostOutput{
+				PostID:   post.postID,
+				ThreadOP: post.opID,
+				Board:    post.boardDir,
+			}
+			if post.filename != "deleted" && !strings.HasPrefix(post.filename, "embed:") {
+				results[p].Filename = post.filename
+			}
+			if boardIDsFound[post.boardDir] {
+				continue
+			}
+			boardIDsFound[post.boardDir] = true
+			boardID, err := gcsql.GetBoardIDFromDir(post.boardDir)
+			if err != nil {
+				fatalAndLog("Unable to get board:", err, fatalEv.Str("board", post.boardDir))
+			}
+			boardIDs = append(boardIDs, boardID)
+		}
+		rebuildAfterChange(fatalEv, false, boardIDs...)
+		gcutil.LogInt("affectedPosts", len(delPosts), infoEv)
+		gcutil.LogBool("fileOnly", fileOnly, infoEv)
+		if fileOnly {
+			infoEv.Msg("file(s) deleted")
+		} else {
+			infoEv.Msg("post(s) deleted")
+		}
+		printResults(asJSON, results, []string{"Post", "Thread", "Board", "File"}, func(p deletedPostOutput) []string {
+			return []string{strconv.Itoa(p.PostID), strconv.Itoa(p.ThreadOP), "/" + p.Board + "/", p.Filename}
+		})
+	case "listreports":
+		reports, err := gcsql.GetReports(all)
+		if err != nil {
+			fatalAndLog("Unable to get reports:", err, fatalEv)
+		}
+		printResults(asJSON, reports,
+			[]string{"ID", "Board", "Post", "Thread", "Category", "Reason", "Reporter IP", "Poster IP", "Cleared by"},
+			func(r gcsql.PostReport) []string {
+				var staff string
+				if r.StaffUser != nil {
+					staff = *r.StaffUser
+				}
+				return []string{strconv.Itoa(r.ID), "/" + r.Board + "/", strconv.Itoa(r.PostID), strconv.Itoa(r.ThreadOP),
+					r.Category, r.Reason, r.ReporterIP, r.PosterIP, staff}
+			})
+	case "clearreport":
+		staffID := getStaffIDOrExit(staffUsername, fatalEv)
+		found, err := gcsql.ClearReport(id, staffID, block)
+		if err != nil {
+			fatalAndLog("Unable to clear report:", err, fatalEv.Int("reportID", id))
+		}
+		if !found {
+			fatalAndLog("Unable to clear report:", fmt.Errorf("report %d does not exist", id), fatalEv.Int("reportID", id))
+		}
+		printResult(asJSON, commandMessage{Message: "Cleared report " + strconv.Itoa(id)},
+			fmt.Sprintf("Cleared report %d", id), infoEv.Int("reportID", id).Str("staff", staffUsername).Bool("block", block))
+	default:
+		fmt.Fprintln(os.Stderr, "Unknown moderation command:", cmd)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	cmdTimeFormat = "2006-01-02 15:04:05"
+)
+
+var (
+	tableCellReplacer = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+)
+
+// commandMessage is used as the JSON output of commands that only report whether they succeeded
+type commandMessage struct {
+	Message string `json:"message"`
+}
+
+// newCommandFlagSet returns a flag set for the given command with a -json flag for selecting JSON output
+func newCommandFlagSet(cmd string, asJSON *bool) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(cmd, flag.ExitOnError)
+	flagSet.BoolVar(asJSON, "json", false, "Output JSON instead of text")
+	return flagSet
+}
+
+// requireFlags exits with the flag set's usage information if any of the given conditions are false
+func requireFlags(flagSet *flag.FlagSet, msg string, conditions ...bool) {
+	for _, condition := range conditions {
+		if !condition {
+			fmt.Fprintln(os.Stderr, "Error:", msg)
+			flagSet.Usage()
+			os.Exit(1)
+		}
+	}
+}
+
+// flagsSet returns a set of the names of the flags that were passed on the command line
+func flagsSet(flagSet *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// printJSON writes data to stdout as indented JSON
+func printJSON(data any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		fmt.Fprintln(os.Stderr, "Error encoding JSON output:", err)
+		os.Exit(1)
+	}
+}
+
+// printResults writes results to stdout as a JSON array if asJSON is true, or as a table with the given column
+// headers and a row (returned by row) for each result otherwise
+func printResults[T any](asJSON bool, results []T, headers []string, row func(T) []string) {
+	if asJSON {
+		if results == nil {
+			results = []T{}
+		}
+		printJSON(results)
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, result := range results {
+		cells := row(result)
+		for c, cell := range cells {
+			cells[c] = tableCellReplacer.Replace(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+// printResult logs msg and writes result to stdout as JSON if asJSON is true, or msg otherwise
+func printResult(asJSON bool, result any, msg string, infoEv *zerolog.Event) {
+	if !asJSON {
+		printInfoAndLog(msg, infoEv)
+		return
+	}
+	infoEv.Msg(msg)
+	printJSON(result)
+}
+
+// formatTime returns t formatted for table output, or an empty string if it is the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(cmdTimeFormat)
+}
@@ -29,6 +29,13 @@ func printInfoAndLog(msg string, infoEv ...*zerolog.Event) {
 }
 
 func initCommandLine() *zerolog.Event {
+	fatalEv := initCommandLineConfig()
+	initDB(fatalEv)
+	return fatalEv
+}
+
+// initCommandLineConfig loads the configuration and initializes the log files without connecting to the database
+func initCommandLineConfig() *zerolog.Event {
 	var err error
 	if err = config.InitConfig(); err != nil {
 		fmt.Fprintln(os.Stderr, "Error initializing config:", err)
@@ -38,10 +45,7 @@ func initCommandLine() *zerolog.Event {
 	if err = gcutil.InitLogs(systemCritical.LogDir, &gcutil.LogOptions{FileOnly: true}); err != nil {
 		os.Exit(1)
 	}
-	fatalEv := gcutil.LogFatal()
-
-	initDB(fatalEv)
-	return fatalEv
+	return gcutil.LogFatal()
 }
 
 func fatalAndLog(msg string, err error, fatalEv *zerolog.Event) {
@@ -49,6 +53,50 @@ func fatalAndLog(msg string, err error, fatalEv *zerolog.Event) {
 	fatalEv.Err(err).Caller(1).Msg(msg)
 }
 
+// readNewPassword prompts for a password and a confirmation without echoing them to the terminal, exiting if the
+// password is empty or doesn't match the confirmation
+func readNewPassword(prompt string) string {
+	fmt.Print(prompt)
+	passwordBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		if errors.Is(err, errAborted) {
+			fmt.Println("Aborted.")
+		} else {
+			fmt.Fprintln(os.Stderr, "Error getting password:", err)
+		}
+		os.Exit(1)
+	}
+	if len(passwordBytes) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: Password cannot be empty")
+		os.Exit(1)
+	}
+	fmt.Print("\nConfirm password: ")
+	confirmBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		if errors.Is(err, errAborted) {
+			fmt.Println("Aborted.")
+		} else {
+			fmt.Fprintln(os.Stderr, "Error getting password confirmation:", err)
+		}
+		os.Exit(1)
+	}
+	fmt.Println()
+	if string(passwordBytes) != string(confirmBytes) {
+		fmt.Fprintln(os.Stderr, "Error: Passwords do not match")
+		os.Exit(1)
+	}
+	return string(passwordBytes)
+}
+
+// confirmAction asks the user to confirm an action, returning true if they answer yes
+func confirmAction(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
 func parseCommandLine() {
 	var newstaff string
 	var delstaff string
@@ -81,12 +129,35 @@ func parseCommandLine() {
 	case "help", "-h", "-help":
 		fmt.Println("Usage: gochan [command] [options]")
 		fmt.Println("Commands:")
-		fmt.Println("  version       Show the version of gochan")
-		fmt.Println("  buildinfo     Show build information")
-		fmt.Println("  help          Show this help message")
-		fmt.Println("  newstaff      Create a new staff account")
-		fmt.Println("  delstaff      Delete a staff account")
-		fmt.Println("  rebuild       Rebuild the specified components")
+		fmt.Println("  version        Show the version of gochan")
+		fmt.Println("  buildinfo      Show build information")
+		fmt.Println("  help           Show this help message")
+		fmt.Println("  newstaff       Create a new staff account")
+		fmt.Println("  delstaff       Delete a staff account")
+		fmt.Println("  rebuild        Rebuild the specified components")
+		fmt.Println("  resetpassword  Set a new password for a staff account")
+		fmt.Println("  listboards     List boards")
+		fmt.Println("  newboard       Create a new board")
+		fmt.Println("  editboard      Change a board's title, subtitle, description, section, or navbar position")
+		fmt.Println("  delboard       Delete a board")
+		fmt.Println("  listsections   List board sections")
+		fmt.Println("  newsection     Create a new board section")
+		fmt.Println("  editsection    Change a board section")
+		fmt.Println("  delsection     Delete a board section")
+		fmt.Println("  listbans       List IP bans")
+		fmt.Println("  ban            Ban an IP address or range")
+		fmt.Println("  unban          Deactivate an IP ban")
+		fmt.Println("  delpost        Delete posts, threads, or their files")
+		fmt.Println("  listreports    List reported posts")
+		fmt.Println("  clearreport    Dismiss a post report")
+		fmt.Println("  cleanup        Remove deleted posts from the database and optimize it")
+		fmt.Println("  fixthumbnails  Regenerate the thumbnails of a board or post")
+		fmt.Println("  checkconfig    Validate gochan.json and board configuration files without applying them")
+		fmt.Println("  doctor         Check the database, DocumentRoot, templates, and required tools, and suggest fixes")
+		fmt.Println("  migrate        Show, apply, roll back, or preview database schema migrations")
+		fmt.Println("  backup         Back up the database, uploads, board configuration files, and template overrides")
+		fmt.Println("  restore        Verify a backup and restore it, optionally to a different database type")
+		fmt.Println("Commands that output data accept -json to output JSON instead of a table.")
 		fmt.Println("Run 'gochan [command] --help' for more information on a command.")
 	case "newstaff":
 		flagSet := flag.NewFlagSet("newstaff", flag.ExitOnError)
@@ -100,39 +171,8 @@ func parseCommandLine() {
 			os.Exit(1)
 		}
 
-		var passwordBytes, confirmBytes []byte
-
 		if password == "" {
-			fmt.Print("Enter password for new staff account: ")
-			passwordBytes, err = term.ReadPassword(int(os.Stdin.Fd()))
-			if err != nil {
-				if errors.Is(err, errAborted) {
-					fmt.Println("Aborted.")
-				} else {
-					fmt.Fprintln(os.Stderr, "Error getting password:", err)
-				}
-				os.Exit(1)
-			}
-			if len(passwordBytes) == 0 {
-				fmt.Fprintln(os.Stderr, "Error: Password cannot be empty")
-				os.Exit(1)
-			}
-			fmt.Print("\nConfirm password: ")
-			confirmBytes, err = term.ReadPassword(int(os.Stdin.Fd()))
-			if err != nil {
-				if errors.Is(err, errAborted) {
-					fmt.Println("Aborted.")
-				} else {
-					fmt.Fprintln(os.Stderr, "Error getting password confirmation:", err)
-				}
-				os.Exit(1)
-			}
-			password = string(passwordBytes)
-			confirm := string(confirmBytes)
-			if password != confirm {
-				fmt.Fprintln(os.Stderr, "Error: Passwords do not match")
-				os.Exit(1)
-			}
+			password = readNewPassword("Enter password for new staff account: ")
 		}
 		fatalEv = initCommandLine()
 
@@ -156,15 +196,9 @@ func parseCommandLine() {
 			flagSet.Usage()
 			os.Exit(1)
 		}
-		if !force {
-			fmt.Printf("Are you sure you want to delete the staff account %q? [y/N]: ", delstaff)
-			var answer string
-			fmt.Scanln(&answer)
-			answer = strings.ToLower(answer)
-			if answer != "y" && answer != "yes" {
-				fmt.Println("Not deleting.")
-				return
-			}
+		if !force && !confirmAction(fmt.Sprintf("Are you sure you want to delete the staff account %q?", delstaff)) {
+			fmt.Println("Not deleting.")
+			return
 		}
 		fatalEv = initCommandLine()
 		if err = gcsql.DeactivateStaff(delstaff); err != nil {
@@ -202,6 +236,16 @@ func parseCommandLine() {
 		}
 		fatalEv = initCommandLine()
 		startupRebuild(rebuildFlag, fatalEv)
+	case "listboards", "newboard", "editboard", "delboard", "listsections", "newsection", "editsection", "delsection":
+		runBoardCommand(cmd, os.Args[2:])
+	case "listbans", "ban", "unban", "delpost", "listreports", "clearreport":
+		runModerationCommand(cmd, os.Args[2:])
+	case "resetpassword", "cleanup", "fixthumbnails", "checkconfig", "doctor":
+		runMaintenanceCommand(cmd, os.Args[2:])
+	case "backup", "restore":
+		runBackupCommand(cmd, os.Args[2:])
+	case "migrate":
+		runMigrateCommand(os.Args[2:])
 	default:
 		fmt.Fprintln(os.Stderr, "Unknown command:", cmd)
 		fmt.Println("Run 'gochan help' for a list of commands.")
@@ -156,7 +156,6 @@ func deletePosts(checkedPosts []int, writer http.ResponseWriter, request *http.R
 	defer gcutil.LogDiscard(infoEv, warnEv, errEv)
 
 	password := request.FormValue("password")
-	passwordMD5 := gcutil.Md5Sum(password)
 	wantsJSON := serverutil.IsRequestingJSON(request)
 	contentType := "text/plain"
 	if wantsJSON {
@@ -189,15 +188,15 @@ func deletePosts(checkedPosts []int, writer http.ResponseWriter, request *http.R
 	if staff.Rank > 0 {
 		gcutil.LogStr("staff", staff.Username, infoEv, errEv)
 	} else {
-		sumsMatch, err := validatePostPasswords(posts, passwordMD5)
+		passwordsMatch, err := gcsql.CheckPostPasswords(password, posts)
 		if err != nil {
-			errEv.Err(err).Caller().Msg("Unable to validate post password checksums")
+			errEv.Err(err).Caller().Msg("Unable to validate post passwords")
 			server.ServeError(writer,
-				server.NewServerError("Unable to validate post password checksums", http.StatusInternalServerError),
+				server.NewServerError("Unable to validate post passwords", http.StatusInternalServerError),
 				wantsJSON, nil)
 			return
 		}
-		if !sumsMatch {
+		if !passwordsMatch {
 			warnEv.Msg("One or more post passwords do not match")
 			server.ServeError(writer,
 				server.NewServerError("One or more post passwords do not match", http.StatusUnauthorized),
@@ -232,12 +231,21 @@ func deletePosts(checkedPosts []int, writer http.ResponseWriter, request *http.R
 	}
 
 	// delete files, leaving the filename in the db as 'deleted' if the post should remain
-	if !deletePostFiles(delPosts, affectedPostIDs, !fileOnly, request, writer, errEv) {
+	if err = deletePostFiles(delPosts, affectedPostIDs, !fileOnly, errEv); err != nil {
+		// deletePostFiles logs any errors
+		server.ServeError(writer, server.NewServerError(err, http.StatusInternalServerError), wantsJSON, nil)
 		return
 	}
-	if !fileOnly && !markPostsAsDeleted(affectedPostIDs, request, writer, errEv) {
-		// markPostsAsDeleted logs any errors
-		return
+	if !fileOnly {
+		var deletedBy *gcsql.Staff
+		if staff.Rank > 0 {
+			deletedBy = staff
+		}
+		if err = markPostsAsDeleted(delPosts, deletedBy, errEv); err != nil {
+			// markPostsAsDeleted logs any errors
+			server.ServeError(writer, server.NewServerError(err, http.StatusInternalServerError), wantsJSON, nil)
+			return
+		}
 	}
 
 	if err = building.BuildBoards(false, boardid); err != nil {
@@ -261,26 +269,15 @@ func deletePosts(checkedPosts []int, writer http.ResponseWriter, request *http.R
 	}
 }
 
-// should return true if all posts have the same password checksum
-func validatePostPasswords(posts []any, passwordMD5 string) (bool, error) {
-	var count int
-	queryPosts := `SELECT COUNT(*) FROM DBPREFIXposts WHERE password = ? AND id in (`
-	numPosts := len(posts)
-	params := []any{passwordMD5}
-	for p := range posts {
-		if p < numPosts-1 {
-			queryPosts += "?,"
-		} else {
-			queryPosts += "?)"
-		}
-		params = append(params, posts[p])
+// markPostsAsDeleted marks the given posts, and the threads of any OPs in them, as deleted and triggers
+// gcsql.PostDeletedEvent for each post. deletedBy is the staff member deleting the posts, or nil if they are being
+// deleted by their poster or from the command line. Any errors are logged to errEv, and the returned error can be
+// shown to the user
+func markPostsAsDeleted(delPosts []delPost, deletedBy *gcsql.Staff, errEv *zerolog.Event) error {
+	posts := make([]any, len(delPosts))
+	for p, post := range delPosts {
+		posts[p] = post.postID
 	}
-
-	err := gcsql.QueryRow(nil, queryPosts, params, []any{&count})
-	return count == len(posts), err
-}
-
-func markPostsAsDeleted(posts []any, request *http.Request, writer http.ResponseWriter, errEv *zerolog.Event) bool {
 	deletePostsSQL := `UPDATE DBPREFIXposts SET is_deleted = TRUE WHERE id IN (`
 	deleteThreadSQL := `UPDATE DBPREFIXthreads SET is_deleted = TRUE WHERE id in (
 		SELECT thread_id FROM DBPREFIXposts WHERE is_top_post AND id in (`
@@ -308,37 +305,41 @@ func markPostsAsDeleted(posts []any, request *http.Request, writer http.Response
 	tx, err := gcsql.BeginContextTx(ctx)
 	opts := &gcsql.RequestOptions{Context: ctx, Tx: tx, Cancel: cancel}
 
-	wantsJSON := serverutil.IsRequestingJSON(request)
 	if err != nil {
 		errEv.Err(err).Caller().Msg("Unable to start deletion transaction")
-		server.ServeError(writer, server.NewServerError("Unable to start deletion transaction", http.StatusInternalServerError), wantsJSON, nil)
-		return false
+		return errors.New("Unable to start deletion transaction")
 	}
 	defer tx.Rollback()
-	const postsError = "Unable to delete post(s)"
-	const threadsError = "Unable to delete thread(s)"
 	if _, err = gcsql.Exec(opts, deletePostsSQL, posts...); err != nil {
 		errEv.Err(err).Caller().Msg("Unable to mark post(s) as deleted")
-		server.ServeError(writer, server.NewServerError(postsError, http.StatusInternalServerError), wantsJSON, nil)
-		return false
+		return errors.New("Unable to delete post(s)")
 	}
 
 	if _, err = gcsql.Exec(opts, deleteThreadSQL, posts...); err != nil {
 		errEv.Err(err).Caller().Msg("Unable to mark thread(s) as deleted")
-		server.ServeError(writer, server.NewServerError(threadsError, http.StatusInternalServerError), wantsJSON, nil)
-		return false
+		return errors.New("Unable to delete thread(s)")
 	}
 
 	if err = tx.Commit(); err != nil {
 		errEv.Err(err).Caller().Msg("Unable to commit deletion transaction")
-		server.ServeError(writer, server.NewServerError("Unable to finalize deletion", http.StatusInternalServerError), wantsJSON, nil)
-		return false
+		return errors.New("Unable to finalize deletion")
 	}
-
-	return true
+	for _, post := range delPosts {
+		gcsql.PostDeletedEvent.Notify(&gcsql.PostDeletedPayload{
+			PostID:    post.postID,
+			ThreadID:  post.threadID,
+			IsTopPost: post.isOP,
+			BoardDir:  post.boardDir,
+			Staff:     deletedBy,
+		})
+	}
+	return nil
 }
 
-func deletePostFiles(posts []delPost, deleteIDs []any, permDelete bool, request *http.Request, writer http.ResponseWriter, errEv *zerolog.Event) bool {
+// deletePostFiles deletes the files of the given posts (and the thread pages of OPs if permDelete is true) and
+// removes their entries from the database, or sets them to 'deleted' if the posts should remain. Any errors are
+// logged to errEv, and the returned error can be shown to the user
+func deletePostFiles(posts []delPost, deleteIDs []any, permDelete bool, errEv *zerolog.Event) error {
 	params := "("
 	for i := range posts {
 		if i < len(posts)-1 {
@@ -347,7 +348,6 @@ func deletePostFiles(posts []delPost, deleteIDs []any, permDelete bool, request
 			params += "?)"
 		}
 	}
-	wantsJSON := serverutil.IsRequestingJSON(request)
 
 	errArr := zerolog.Arr()
 	var err error
@@ -372,10 +372,7 @@ func deletePostFiles(posts []delPost, deleteIDs []any, permDelete bool, request
 	}
 	if err != nil {
 		errEv.Array("errors", errArr).Caller().Msg("Received 1 or more errors while trying to delete post files")
-		server.ServeError(writer,
-			server.NewServerError("Received 1 or more errors while trying to delete post files", http.StatusInternalServerError),
-			wantsJSON, nil)
-		return false
+		return errors.New("Received 1 or more errors while trying to delete post files")
 	}
 
 	if permDelete {
@@ -388,10 +385,7 @@ func deletePostFiles(posts []delPost, deleteIDs []any, permDelete bool, request
 	}
 	if err != nil {
 		errEv.Err(err).Caller().Msg("Unable to delete file entries from database")
-		server.ServeError(writer,
-			server.NewServerError("Unable to delete file entries from database", http.StatusInternalServerError),
-			wantsJSON, nil)
-		return false
+		return errors.New("Unable to delete file entries from database")
 	}
-	return true
+	return nil
 }
@@ -45,8 +45,6 @@ func editPost(checkedPosts []int, editBtn string, doEdit string, writer http.Res
 			server.ServeError(writer, server.NewServerError("Password required for post editing", http.StatusUnauthorized), wantsJSON, nil)
 			return
 		}
-		passwordMD5 := gcutil.Md5Sum(password)
-
 		post, err := gcsql.GetPostFromID(checkedPosts[0], true)
 		if err != nil {
 			errEv.Err(err).Caller().
@@ -56,10 +54,18 @@ func editPost(checkedPosts []int, editBtn string, doEdit string, writer http.Res
 		}
 		errEv.Int("postID", post.ID)
 
-		if post.Password != passwordMD5 && rank == 0 {
-			errEv.Msg("Wrong password")
-			server.ServeError(writer, server.NewServerError("Wrong password", http.StatusUnauthorized), wantsJSON, nil)
-			return
+		if rank == 0 {
+			passwordMatches, err := post.CheckPassword(password)
+			if err != nil {
+				errEv.Err(err).Caller().Msg("Unable to check post password")
+				server.ServeError(writer, server.NewServerError("Unable to check post password", http.StatusInternalServerError), wantsJSON, nil)
+				return
+			}
+			if !passwordMatches {
+				errEv.Msg("Wrong password")
+				server.ServeError(writer, server.NewServerError("Wrong password", http.StatusUnauthorized), wantsJSON, nil)
+				return
+			}
 		}
 
 		board, err := post.GetBoard()
@@ -139,10 +145,18 @@ func editPost(checkedPosts []int, editBtn string, doEdit string, writer http.Res
 	gcutil.LogInt("boardID", boardid, infoEv, errEv)
 
 	rank := manage.GetStaffRank(request)
-	passwordMD5 := gcutil.Md5Sum(password)
-	if post.Password != passwordMD5 && rank == 0 {
-		server.ServeError(writer, server.NewServerError("Wrong password", http.StatusUnauthorized), wantsJSON, nil)
-		return
+	if rank == 0 {
+		passwordMatches, err := post.CheckPassword(password)
+		if err != nil {
+			errEv.Err(err).Caller().Msg("Unable to check post password")
+			server.ServeError(writer, server.NewServerError("Unable to check post password", http.StatusInternalServerError), wantsJSON, nil)
+			return
+		}
+		if !passwordMatches {
+			warnEv.Msg("Wrong password")
+			server.ServeError(writer, server.NewServerError("Wrong password", http.StatusUnauthorized), wantsJSON, nil)
+			return
+		}
 	}
 
 	board, err := gcsql.GetBoardFromID(boardid)
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/health"
+)
+
+// serveHealthReport runs the given checks and responds with a 503 status if any of them failed. The report is only
+// served as JSON if the request is allowed by HealthAccess, since it includes details about the server's setup
+func serveHealthReport(writer http.ResponseWriter, request *http.Request, checks []health.Check) {
+	ctx, cancel := context.WithTimeout(request.Context(), health.DefaultTimeout)
+	defer cancel()
+	report := health.RunChecks(ctx, checks)
+	writer.Header().Set("Cache-Control", "no-store")
+	status := http.StatusOK
+	if !report.OK() {
+		gcutil.LogWarning().
+			Str("path", request.URL.Path).
+			Interface("checks", report.Checks).
+			Msg("Health check failed")
+		status = http.StatusServiceUnavailable
+	}
+	if !hasAccess(request, config.GetSystemCriticalConfig().HealthAccess) {
+		writer.WriteHeader(status)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(report); err != nil {
+		gcutil.LogError(err).Caller().Str("path", request.URL.Path).Msg("Unable to write health report")
+	}
+}
+
+// serveHealthz reports whether gochan is alive, without checking external services like the database so that
+// a database outage doesn't cause it to be restarted
+func serveHealthz(writer http.ResponseWriter, request *http.Request) {
+	serveHealthReport(writer, request, health.LivenessChecks)
+}
+
+// serveReadyz reports whether gochan and all of its dependencies are ready to serve requests
+func serveReadyz(writer http.ResponseWriter, request *http.Request) {
+	serveHealthReport(writer, request, health.ReadinessChecks)
+}
+
+// runDoctor runs all of the health checks and prints the results with suggested fixes, exiting with status 1 if
+// any of them failed
+func runDoctor(asJSON bool) {
+	initCommandLineConfig()
+	// connection errors are reported by the database check instead of exiting
+	systemCritical := config.GetSystemCriticalConfig()
+	if err := gcsql.ConnectToDB(&systemCritical.SQLConfig); err == nil {
+		// the boards are needed for checking board configurations
+		gcsql.ResetBoardSectionArrays()
+	}
+	report := health.RunChecks(context.Background(), health.DoctorChecks)
+	gcutil.LogInfo().
+		Str("source", "commandLine").
+		Str("command", "doctor").
+		Str("status", string(report.Status)).
+		Msg("Ran health checks")
+	if asJSON {
+		printJSON(report)
+	} else {
+		for _, result := range report.Checks {
+			fmt.Printf("[%s] %s: %s\n", result.Status, result.Name, result.Message)
+			if result.Suggestion != "" {
+				fmt.Println("    Suggestion:", result.Suggestion)
+			}
+		}
+		switch report.Status {
+		case health.StatusOK:
+			fmt.Println("No problems found")
+		case health.StatusWarning:
+			fmt.Println("Some features may not work until the warnings above are fixed")
+		default:
+			fmt.Println("gochan will not work correctly until the errors above are fixed")
+		}
+	}
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+var (
+	errHandoffUnsupported = errors.New("listener handoff is not supported on this platform")
+)
+
+// inheritingListener always returns false, since socket activation and listener handoff are only supported on
+// Unix-like systems
+func inheritingListener() bool {
+	return false
+}
+
+// getListener returns a new listener on the given network ("tcp" or "unix") and address. Socket activation and
+// listener handoff are only supported on Unix-like systems
+func getListener(network, address string) (net.Listener, bool, error) {
+	listener, err := net.Listen(network, address)
+	return listener, false, err
+}
+
+// notifyHandoff does nothing, since there is no signal for requesting a listener handoff on this platform
+func notifyHandoff(_ chan<- os.Signal) {}
+
+func handOffListener(_ net.Listener) error {
+	return errHandoffUnsupported
+}
@@ -0,0 +1,105 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// inheritedListenerEnvVar is set by a running gochan process when it starts a new process and hands off its
+	// listener to it
+	inheritedListenerEnvVar = "GOCHAN_INHERITED_LISTENER"
+
+	// listenFDsStart is the first file descriptor passed by systemd socket activation (SD_LISTEN_FDS_START), and the
+	// file descriptor that the listener is passed as during a handoff
+	listenFDsStart = 3
+)
+
+var (
+	errNoListenerFile = errors.New("listener does not support handoff")
+)
+
+// usingSocketActivation returns true if gochan was started by systemd with socket activation
+func usingSocketActivation() bool {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return false
+	}
+	numFDs, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	return err == nil && numFDs > 0
+}
+
+// inheritingListener returns true if gochan was passed a listener by systemd socket activation or by a previous
+// gochan process during a handoff
+func inheritingListener() bool {
+	return os.Getenv(inheritedListenerEnvVar) != "" || usingSocketActivation()
+}
+
+// getListener returns the listener passed to gochan by systemd socket activation or by a previous gochan process
+// during a handoff if there is one, or a new listener on the given network ("tcp" or "unix") and address otherwise
+func getListener(network, address string) (listener net.Listener, inherited bool, err error) {
+	if !inheritingListener() {
+		listener, err = net.Listen(network, address)
+		return listener, false, err
+	}
+	// make sure that processes started by gochan don't try to use the listener
+	os.Unsetenv(inheritedListenerEnvVar)
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	file := os.NewFile(listenFDsStart, "gochan-listener")
+	defer file.Close()
+	listener, err = net.FileListener(file)
+	return listener, true, err
+}
+
+// notifyHandoff relays SIGUSR2 to ch, which tells gochan to hand off its listener to a new process
+func notifyHandoff(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR2)
+}
+
+// handOffListener starts a new gochan process (using the current executable, which may have been upgraded) with
+// the same arguments and passes the listener to it so that it can start accepting connections while this process
+// finishes handling in-flight requests and shuts down
+func handOffListener(listener net.Listener) error {
+	if unixListener, ok := listener.(*net.UnixListener); ok {
+		// the new process will be using the socket, so don't remove it when this process closes its listener
+		unixListener.SetUnlinkOnClose(false)
+	}
+	filer, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return errNoListenerFile
+	}
+	file, err := filer.File()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, os.Args[1:]...) // skipcq: GSC-G204
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "LISTEN_") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	cmd.Env = append(cmd.Env, inheritedListenerEnvVar+"=1")
+	cmd.ExtraFiles = []*os.File{file} // ExtraFiles[0] becomes file descriptor 3 in the new process
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
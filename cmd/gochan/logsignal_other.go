@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyReopenLogs does nothing, since there is no signal for reopening the log files on this platform
+func notifyReopenLogs(_ chan<- os.Signal) {}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReopenLogs relays SIGUSR1 to ch, which tells gochan to reopen its log files after they were moved by an
+// external tool like logrotate
+func notifyReopenLogs(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gochan-org/gochan/pkg/building"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/events"
+	"github.com/gochan-org/gochan/pkg/jobs"
 	"github.com/gochan-org/gochan/pkg/manage"
 	"github.com/rs/zerolog"
 
@@ -23,8 +27,10 @@ import (
 	"github.com/gochan-org/gochan/pkg/gctemplates"
 	"github.com/gochan-org/gochan/pkg/posting"
 	"github.com/gochan-org/gochan/pkg/posting/geoip"
+	"github.com/gochan-org/gochan/pkg/posting/uploads"
 	_ "github.com/gochan-org/gochan/pkg/posting/uploads/inituploads"
 	"github.com/gochan-org/gochan/pkg/server/serverutil"
+	"github.com/gochan-org/gochan/pkg/webhooks"
 )
 
 func cleanup() {
@@ -43,6 +49,7 @@ func main() {
 	defer func() {
 		fatalEv.Discard()
 		cleanup()
+		gcutil.CloseLogs()
 	}()
 	err := config.InitConfig()
 	if errors.Is(err, fs.ErrNotExist) {
@@ -56,9 +63,11 @@ func main() {
 	uid, gid := config.GetUser()
 	systemCritical := config.GetSystemCriticalConfig()
 	if err = gcutil.InitLogs(systemCritical.LogDir, &gcutil.LogOptions{
-		LogLevel: systemCritical.LogLevel(),
-		UID:      uid,
-		GID:      gid,
+		LogLevel:     systemCritical.LogLevel(),
+		UID:          uid,
+		GID:          gid,
+		Rotate:       systemCritical.LogRotateOptions(),
+		SystemLogger: systemCritical.SystemLogger,
 	}); err != nil {
 		fatalEv.Err(err).Caller().
 			Str("LogDir", systemCritical.LogDir).
@@ -76,12 +85,23 @@ func main() {
 	}
 
 	if err = gcplugin.LoadPlugins(systemCritical.Plugins); err != nil {
-		fatalEv.Err(err).Msg("Failed loading plugins")
+		// plugins that failed to load are logged individually and shown at /manage/plugins, and don't keep the other
+		// plugins or gochan from running
+		gcutil.LogWarning().Msg("One or more plugins failed to load")
 	}
 
 	events.TriggerEvent("startup")
 
 	initDB(fatalEv)
+	// thumbnails of PDF and audio uploads made before they had built-in handlers keep the upload's extension
+	if err = uploads.RenameLegacyThumbnailsOnce(); err != nil {
+		gcutil.LogError(err).Caller().Msg("Unable to rename legacy thumbnails")
+	}
+	webhooks.Init()
+	// retries of failed webhook deliveries are cancelled when gochan shuts down
+	defer webhooks.Stop()
+	// asynchronous event handlers (including the webhooks handler) are waited for before the webhooks are stopped
+	defer events.Wait()
 
 	serverutil.InitMinifier()
 	siteCfg := config.GetSiteConfig()
@@ -113,16 +133,79 @@ func main() {
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	reopenLogs := make(chan os.Signal, 1)
+	notifyReopenLogs(reopenLogs)
+	handoff := make(chan os.Signal, 1)
+	notifyHandoff(handoff)
 	posting.InitPosting()
 	defer events.TriggerEvent("shutdown")
 	manage.InitManagePages()
-	go initServer()
+	gcplugin.RegisterManagePage()
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go jobs.Start(jobsCtx)
+	defer func() {
+		// jobs are cancelled and waited for before the database connection is closed
+		stopJobs()
+		jobs.Wait()
+	}()
+	gs := initServer()
+	scheme := "http"
+	if systemCritical.UseTLS() {
+		scheme = "https"
+	}
 	gcutil.LogInfo().
 		Str("ListenAddress", systemCritical.ListenAddress).
 		Int("Port", systemCritical.Port).
-		Str("siteURL", (&url.URL{Scheme: "http", Host: systemCritical.SiteHost, Path: systemCritical.WebRoot}).String()).
+		Str("listener", gs.listener.Addr().String()).
+		Bool("inheritedListener", gs.inherited).
+		Str("siteURL", (&url.URL{Scheme: scheme, Host: systemCritical.SiteHost, Path: systemCritical.WebRoot}).String()).
 		Msg("Gochan server started")
-	<-sc
+	for {
+		select {
+		case <-hup:
+			gcutil.LogInfo().Msg("Received SIGHUP, reloading configuration")
+			// errors are logged by ReloadConfig, and the previous configuration is kept if the new one is invalid
+			manage.ReloadConfig(gcutil.Logger())
+		case <-reopenLogs:
+			if err = gcutil.ReopenLogs(); err != nil {
+				// the log file may not be usable, so this is also written to stderr
+				fmt.Fprintln(os.Stderr, "Unable to reopen log files:", err)
+				gcutil.LogError(err).Caller().Msg("Unable to reopen log files")
+				continue
+			}
+			gcutil.LogInfo().Msg("Received SIGUSR1, reopened log files")
+		case <-handoff:
+			if err = handOffListener(gs.listener); err != nil {
+				gcutil.LogError(err).Caller().Msg("Unable to hand off listener to a new process")
+				continue
+			}
+			gcutil.LogInfo().Msg("Handed off listener to a new process, shutting down")
+			shutdownServer(gs)
+			return
+		case sig := <-sc:
+			gcutil.LogInfo().Str("signal", sig.String()).Msg("Shutting down")
+			shutdownServer(gs)
+			return
+		}
+	}
+}
+
+// shutdownServer stops accepting new connections and waits for in-flight requests to finish, up to
+// ShutdownTimeoutSeconds
+func shutdownServer(gs *gochanServer) {
+	ctx := context.Background()
+	if timeout := config.GetSystemCriticalConfig().ShutdownTimeoutSeconds; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+	if err := gs.shutdown(ctx); err != nil {
+		gcutil.LogWarning().Err(err).
+			Int64("inFlightRequests", gs.inFlight.Load()).
+			Msg("Unable to finish handling in-flight requests before shutting down")
+	}
 }
 
 func initDB(fatalEv *zerolog.Event) {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/manage"
+	"github.com/gochan-org/gochan/pkg/metrics"
+	"github.com/gochan-org/gochan/pkg/server"
+)
+
+// hasAccess returns true if the request is allowed by an access setting like MetricsAccess or HealthAccess
+func hasAccess(request *http.Request, access string) bool {
+	switch access {
+	case "public":
+		return true
+	case "admin":
+		staff, err := gcsql.GetStaffFromRequest(request)
+		return err == nil && staff.Rank >= manage.AdminPerms
+	default:
+		return gcutil.IsLocalRequest(request)
+	}
+}
+
+// serveMetrics serves gochan's metrics in the Prometheus text format if EnableMetrics is true
+func serveMetrics(writer http.ResponseWriter, request *http.Request) {
+	systemCritical := config.GetSystemCriticalConfig()
+	if !systemCritical.EnableMetrics {
+		server.ServeNotFound(writer, request)
+		return
+	}
+	if !hasAccess(request, systemCritical.MetricsAccess) {
+		gcutil.LogAccess(request).Int("status", http.StatusForbidden).Msg("Rejected request for metrics")
+		server.ServeError(writer, server.NewServerError("You do not have permission to view metrics", http.StatusForbidden), false, nil)
+		return
+	}
+	writer.Header().Set("Content-Type", metrics.ContentType)
+	if err := metrics.WriteText(writer); err != nil {
+		// the metrics that could be read were still written
+		gcutil.LogWarning().Err(err).Msg("Unable to read some metrics")
+	}
+}
@@ -21,10 +21,6 @@ import (
 
 func moveThread(checkedPosts []int, moveBtn string, doMove string, writer http.ResponseWriter, request *http.Request) {
 	password := request.PostFormValue("password")
-	var passwordMD5 string
-	if password != "" {
-		passwordMD5 = gcutil.Md5Sum(password)
-	}
 	wantsJSON := serverutil.IsRequestingJSON(request)
 	infoEv, warnEv, errEv := gcutil.LogRequest(request)
 	defer gcutil.LogDiscard(infoEv, warnEv, errEv)
@@ -171,10 +167,18 @@ func moveThread(checkedPosts []int, moveBtn string, doMove string, writer http.R
 			return
 		}
 
-		if passwordMD5 != post.Password && rank == 0 {
-			warnEv.Msg("Wrong password")
-			server.ServeError(writer, server.NewServerError("Wrong password", http.StatusUnauthorized), wantsJSON, nil)
-			return
+		if rank == 0 {
+			passwordMatches, err := post.CheckPassword(password)
+			if err != nil {
+				errEv.Err(err).Caller().Msg("Unable to check post password")
+				server.ServeError(writer, server.NewServerError("Unable to check post password", http.StatusInternalServerError), wantsJSON, nil)
+				return
+			}
+			if !passwordMatches {
+				warnEv.Msg("Wrong password")
+				server.ServeError(writer, server.NewServerError("Wrong password", http.StatusUnauthorized), wantsJSON, nil)
+				return
+			}
 		}
 
 		if err = post.ChangeBoardID(destBoardID); err != nil {
@@ -298,6 +302,7 @@ func moveThread(checkedPosts []int, moveBtn string, doMove string, writer http.R
 			})
 			return
 		}
+		gcsql.ThreadMovedEvent.Notify(&gcsql.ThreadMovedPayload{Post: post, FromBoard: srcBoard, ToBoard: destBoard})
 		if wantsJSON {
 			server.ServeJSON(writer, map[string]any{
 				"status":    "success",
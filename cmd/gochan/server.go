@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"math/rand"
 	"net"
 	"net/http"
 	"net/http/fcgi"
+	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/uptrace/bunrouter"
@@ -19,8 +24,74 @@ import (
 	"github.com/gochan-org/gochan/pkg/server/serverutil"
 )
 
-func initServer() {
-	var listener net.Listener
+// gochanServer serves requests over HTTP or FastCGI and keeps track of requests that are being handled so that
+// they can finish before gochan shuts down
+type gochanServer struct {
+	listener   net.Listener
+	handler    http.Handler
+	httpServer *http.Server
+	inherited  bool
+	inFlight   atomic.Int64
+}
+
+func (gs *gochanServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	gs.inFlight.Add(1)
+	defer gs.inFlight.Add(-1)
+	// resolve the client's IP address once so that handlers don't need to parse the forwarding headers again
+	gs.handler.ServeHTTP(writer, gcutil.WithRealIP(request))
+}
+
+// shutdown stops accepting new connections and waits for in-flight requests to finish or for ctx to be done
+func (gs *gochanServer) shutdown(ctx context.Context) error {
+	if gs.httpServer != nil {
+		return gs.httpServer.Shutdown(ctx)
+	}
+	// fcgi.Serve doesn't support graceful shutdown, so close the listener and wait for the requests being handled
+	if err := gs.listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for gs.inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// listenUnixSocket listens on the Unix domain socket in ListenSocket, replacing the socket file if it was left
+// behind by a previous process, and sets its mode and owner so that the web server can connect to it
+func listenUnixSocket(systemCritical *config.SystemCriticalConfig) (net.Listener, bool, error) {
+	mode, err := systemCritical.ListenSocketFileMode()
+	if err != nil {
+		return nil, false, err
+	}
+	fi, err := os.Stat(systemCritical.ListenSocket)
+	if err == nil && fi.Mode().Type() == fs.ModeSocket && !inheritingListener() {
+		// remove the socket left behind if the previous process didn't shut down cleanly
+		if err = os.Remove(systemCritical.ListenSocket); err != nil {
+			return nil, false, err
+		}
+	}
+	listener, inherited, err := getListener("unix", systemCritical.ListenSocket)
+	if err != nil || inherited {
+		return listener, inherited, err
+	}
+	if err = os.Chmod(systemCritical.ListenSocket, mode); err != nil {
+		listener.Close()
+		return nil, false, err
+	}
+	if err = config.TakeOwnership(systemCritical.ListenSocket); err != nil {
+		listener.Close()
+		return nil, false, err
+	}
+	return listener, false, nil
+}
+
+func initServer() *gochanServer {
 	var err error
 	systemCritical := config.GetSystemCriticalConfig()
 	listenAddr := net.JoinHostPort(systemCritical.ListenAddress, strconv.Itoa(systemCritical.Port))
@@ -40,27 +111,61 @@ func initServer() {
 	router.GET(config.WebPath("/util"), bunrouter.HTTPHandlerFunc(utilHandler))
 	router.POST(config.WebPath("/util"), bunrouter.HTTPHandlerFunc(utilHandler))
 	router.GET(config.WebPath("/util/banner"), bunrouter.HTTPHandlerFunc(randomBanner))
+	router.GET(config.WebPath("/metrics"), bunrouter.HTTPHandlerFunc(serveMetrics))
+	router.GET(config.WebPath("/healthz"), bunrouter.HTTPHandlerFunc(serveHealthz))
+	router.GET(config.WebPath("/readyz"), bunrouter.HTTPHandlerFunc(serveReadyz))
 	// Eventually plugins might be able to register new namespaces or they might be restricted to something
 	// like /plugin
 
-	if systemCritical.UseFastCGI {
-		listener, err = net.Listen("tcp", listenAddr)
-		if err != nil {
-			fatalEv.Err(err).Caller().Msg("Failed listening on address/port")
-		}
-		err = fcgi.Serve(listener, router)
+	gs := &gochanServer{handler: router}
+	if systemCritical.ListenSocket != "" {
+		fatalEv.Str("listenSocket", systemCritical.ListenSocket)
+		gs.listener, gs.inherited, err = listenUnixSocket(systemCritical)
 	} else {
-		httpServer := &http.Server{
-			Addr:              listenAddr,
-			Handler:           router,
+		gs.listener, gs.inherited, err = getListener("tcp", listenAddr)
+	}
+	if err != nil {
+		fatalEv.Err(err).Caller().Msg("Failed listening on address/port")
+	}
+	if !systemCritical.UseFastCGI {
+		gs.httpServer = &http.Server{
+			Handler:           gs,
 			ReadHeaderTimeout: 5 * time.Second,
 		}
-		err = httpServer.ListenAndServe()
 	}
-
-	if err != nil {
-		fatalEv.Err(err).Caller().Msg("Error initializing server")
+	if systemCritical.UseTLS() {
+		certReloader, err := newCertificateReloader(systemCritical.TLSCertFile, systemCritical.TLSKeyFile)
+		if err != nil {
+			fatalEv.Err(err).Caller().
+				Str("certFile", systemCritical.TLSCertFile).
+				Str("keyFile", systemCritical.TLSKeyFile).
+				Msg("Unable to load TLS certificate")
+		}
+		gs.httpServer.TLSConfig = certReloader.tlsConfig(systemCritical)
+		gs.httpServer.Protocols = new(http.Protocols)
+		gs.httpServer.Protocols.SetHTTP1(true)
+		gs.httpServer.Protocols.SetHTTP2(!systemCritical.DisableHTTP2)
 	}
+
+	go func() {
+		var err error
+		if gs.httpServer != nil && gs.httpServer.TLSConfig != nil {
+			// the certificate is provided by TLSConfig.GetCertificate
+			err = gs.httpServer.ServeTLS(gs.listener, "", "")
+		} else if gs.httpServer != nil {
+			err = gs.httpServer.Serve(gs.listener)
+		} else {
+			err = fcgi.Serve(gs.listener, gs)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			gcutil.LogFatal().Err(err).Caller().
+				Str("listenAddress", systemCritical.ListenAddress).
+				Bool("useFastCGI", systemCritical.UseFastCGI).
+				Int("port", systemCritical.Port).
+				Msg("Error initializing server")
+		}
+	}()
+	return gs
 }
 
 func randomBanner(writer http.ResponseWriter, request *http.Request) {
@@ -54,3 +54,28 @@ func startupRebuild(buildFlag int, fatalEv *zerolog.Event) {
 	}
 	printInfoAndLog("Finished building without errors, exiting.")
 }
+
+// rebuildAfterChange builds the pages of the given boards after they were changed from the command line, as well as
+// the board list and front page if buildFront is true
+func rebuildAfterChange(fatalEv *zerolog.Event, buildFront bool, boardIDs ...int) {
+	serverutil.InitMinifier()
+	if err := gctemplates.InitTemplates(); err != nil {
+		fatalAndLog("Unable to initialize templates:", err, fatalEv.Str("building", "initialization"))
+	}
+	if err := gcsql.ResetBoardSectionArrays(); err != nil {
+		fatalAndLog("Unable to reset board section arrays:", err, fatalEv.Str("building", "reset"))
+	}
+	if len(boardIDs) > 0 {
+		if err := building.BuildBoards(false, boardIDs...); err != nil {
+			fatalAndLog("Unable to build boards:", err, fatalEv.Str("building", "boards"))
+		}
+	}
+	if buildFront {
+		if err := building.BuildBoardListJSON(); err != nil {
+			fatalAndLog("Unable to build board list JSON:", err, fatalEv.Str("building", "boardListJSON"))
+		}
+		if err := building.BuildFrontPage(); err != nil {
+			fatalAndLog("Unable to build front page:", err, fatalEv.Str("building", "front"))
+		}
+	}
+}
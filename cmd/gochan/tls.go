@@ -0,0 +1,97 @@
+package main
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+const (
+	// certificateCheckInterval is the minimum time between checks for modified certificate and key files
+	certificateCheckInterval = 30 * time.Second
+)
+
+// certificateReloader provides the TLS certificate for new connections, and reloads the certificate and key when
+// either file is modified (e.g. by a certificate renewal client) so that they can be used without restarting gochan
+type certificateReloader struct {
+	certFile    string
+	keyFile     string
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastChecked time.Time
+	mutex       sync.Mutex
+}
+
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	cr := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := cr.reloadIfModified(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// reloadIfModified loads the certificate and key if either file was modified since they were last loaded. If the
+// files can't be loaded, the current certificate is kept
+func (cr *certificateReloader) reloadIfModified() (reloaded bool, err error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return false, err
+	}
+	if cr.cert != nil && certInfo.ModTime().Equal(cr.certModTime) && keyInfo.ModTime().Equal(cr.keyModTime) {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		// the certificate may have been updated without the key being updated yet (or vice versa), so the
+		// modification times aren't updated, and loading will be tried again after the next interval
+		return false, err
+	}
+	cr.cert = &cert
+	cr.certModTime = certInfo.ModTime()
+	cr.keyModTime = keyInfo.ModTime()
+	return true, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (cr *certificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	if time.Since(cr.lastChecked) >= certificateCheckInterval {
+		cr.lastChecked = time.Now()
+		reloaded, err := cr.reloadIfModified()
+		if err != nil {
+			gcutil.LogWarning().Err(err).
+				Str("certFile", cr.certFile).
+				Str("keyFile", cr.keyFile).
+				Msg("Unable to reload TLS certificate, using the previously loaded certificate")
+		} else if reloaded {
+			gcutil.LogInfo().Str("certFile", cr.certFile).Msg("Reloaded TLS certificate")
+		}
+	}
+	return cr.cert, nil
+}
+
+// tlsConfig returns the TLS configuration for serving HTTPS using the certificate reloader
+func (cr *certificateReloader) tlsConfig(systemCritical *config.SystemCriticalConfig) *tls.Config {
+	tlsCfg := &tls.Config{
+		GetCertificate: cr.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+	if systemCritical.TLSMinVersion == "1.3" {
+		tlsCfg.MinVersion = tls.VersionTLS13
+	}
+	return tlsCfg
+}
@@ -1,4 +1,5 @@
 -- requires ghostscript to be installed
+-- gochan has a built-in PDF handler that uses pdftoppm (see AllowPDFUploads in config.md), this plugin replaces it
 
 local config = require("config")
 local os = require("os")
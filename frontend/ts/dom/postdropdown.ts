@@ -31,12 +31,14 @@ function moveThread(id: number, _board: string) {
 }
 
 function reportPost(id: number, board: string) {
-	promptLightbox("", false, async (_$lb, reason) => {
-		if(reason === "" || reason === null) return;
+	const $lb = promptLightbox("", false, async ($el, reason) => {
+		const category = ($el.find("select[name=category]").val() as string) ?? "";
+		if((reason === "" || reason === null) && category === "") return;
 		const searchParams = new URLSearchParams();
 		searchParams.append("board", board);
 		searchParams.append("report_btn", "Report");
-		searchParams.append("reason", reason as string);
+		searchParams.append("category", category);
+		searchParams.append("reason", (reason ?? "") as string);
 		searchParams.append(`check${id}`, "on");
 		searchParams.append("json", "1");
 
@@ -64,6 +66,11 @@ function reportPost(id: number, board: string) {
 				alertLightbox(`Report failed: ${reason}`, "Error");
 		});
 	}, "Report post");
+	// let the reporter choose from the board's report categories, if it has any
+	const $categories = $("select#report-category").first();
+	if($categories.length > 0) {
+		$categories.clone().removeAttr("id").insertBefore($lb.find("input#promptinput"));
+	}
 }
 
 function deletePostFile(id: number) {
@@ -189,12 +196,21 @@ function handleActions(action: string, postIDStr: string) {
 			alertLightbox(`Failed getting post IP: ${reason.statusText}`, "Error");
 		});
 		break;
+	case "Posts with this ID":
+		window.open(`${webroot}manage/posterid?postid=${postID}`);
+		break;
 	case "Ban IP address":
 		window.open(`${webroot}manage/bans?dir=${board}&postid=${postID}`);
 		break;
 	case "Filter similar posts":
 		window.open(`${webroot}manage/filters?srcpost=${postID}`);
 		break;
+	case "Find similar images":
+		window.open(`${webroot}manage/similarimages?postid=${postID}`);
+		break;
+	case "Ban image":
+		window.open(`${webroot}manage/similarimages?postid=${postID}#banimage`);
+		break;
 	default:
 		// this shouldn't happen under normal circumstances
 		alertLightbox("Unrecognized post dropdown option");
@@ -52,7 +52,10 @@ export function createPostElement(post: ThreadPost, boardDir: string, elementCla
 		}).text(post.name));
 	}
 	$postInfo.prepend($postName);
-	if(post.trip !== "") {
+	if(post.capcode) {
+		const capcodeTitle = post.capcode.charAt(0).toUpperCase() + post.capcode.slice(1);
+		$postInfo.prepend($postName, $("<span/>").prop({class: `capcode capcode-${post.capcode}`}).text("## " + capcodeTitle), " ");
+	} else if(post.trip !== "") {
 		$postInfo.prepend($postName, $("<span/>").prop({class: "tripcode"}).text("!" + post.trip), " ");
 	} else {
 		$postInfo.prepend($postName, " ");
@@ -21,7 +21,7 @@ let threadCooldown = 0;
 let replyCooldown = 0;
 
 const qrButtonHTML =
-	'<input type="file" id="imagefile" name="imagefile" accept="image/jpeg,image/png,image/gif,video/webm,video/mp4"/>' +
+	'<input type="file" id="imagefile" name="imagefile" accept="image/jpeg,image/png,image/gif,video/webm,video/mp4,audio/mpeg,audio/ogg,audio/flac,audio/opus"/>' +
 	'<input type="submit" value="Post" style="float:right;min-width:50px"/>';
 
 const qrTitleBar =
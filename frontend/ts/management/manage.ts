@@ -3,7 +3,6 @@ import $ from "jquery";
 import { alertLightbox } from "../dom/lightbox";
 import { $topbar, TopBarButton, menuItem } from "../dom/topbar";
 import "./sections";
-import "./viewlog";
 import { isThreadLocked } from "../api/management";
 import { getNumberStorageVal, setStorageVal } from "../storage";
 
@@ -23,6 +22,7 @@ let $staffBtn: TopBarButton|null = null;
 let staffNotificationsInterval: number;
 let latestReportID: number = getNumberStorageVal("latestreport", -1);
 let latestAppealID: number = getNumberStorageVal("latestappeal", -1);
+let unreadAppeals: number = getNumberStorageVal("unreadappeals", 0);
 $(document).on("gotStaffRank", (_e, rank:number) => {
 	if(rank >= 2 && staffNotificationsInterval === null) {
 		const intervalSeconds = getNumberStorageVal("reportinterval", 30);
@@ -63,10 +63,21 @@ function addManageEvents(_i: number, el: HTMLSelectElement) {
 		if(!dropdownHasItem(el, "Ban IP address")) {
 			$el.append("<option>Ban IP address</option>");
 		}
+		if($post.find("span.poster-id").length > 0 && !dropdownHasItem(el, "Posts with this ID")) {
+			$el.append("<option>Posts with this ID</option>");
+		}
 	}
 	if(!dropdownHasItem(el, "Filter similar posts")) {
 		$el.append("<option>Filter similar posts</option>");
 	}
+	if($post.find("div.file-info").length > 0 && $post.find("a.embed-orig").length === 0) {
+		if(!dropdownHasItem(el, "Find similar images")) {
+			$el.append("<option>Find similar images</option>");
+		}
+		if(!dropdownHasItem(el, "Ban image")) {
+			$el.append("<option>Ban image</option>");
+		}
+	}
 }
 
 function setupManagementEvents() {
@@ -192,7 +203,8 @@ export function createStaffMenu(staff = staffInfo) {
 			if((text === "Reports" && staffInfo?.reports) ||
 				(text === "Ban Appeals" && staffInfo?.appeals)) {
 				item
-					.find("a").text(`${text} (${(text === "Reports" ? (staffInfo?.reports ?? []).length : (staffInfo?.appeals ?? []).length)} open)`)
+					.find("a").text(`${text} (${(text === "Reports" ? (staffInfo?.reports ?? []).length : (staffInfo?.appeals ?? []).length)} open` +
+						((text === "Ban Appeals" && (staffInfo?.unread_appeals ?? 0) > 0) ? `, ${staffInfo?.unread_appeals} unread)` : ")"))
 					.addClass("text-bold")
 					.css("color", "red");
 			}
@@ -261,6 +273,7 @@ function updateLatestReportAppeal(info: StaffInfo) {
 			$staffBtn.button.append(...elements);
 			staffInfo.reports = info.reports;
 			staffInfo.appeals = info.appeals;
+			staffInfo.unread_appeals = info.unread_appeals;
 		} else {
 			$staffBtn.button.text("Staff ▼");
 		}
@@ -271,7 +284,7 @@ function updateLatestReportAppeal(info: StaffInfo) {
 		if(latestReport && latestReport.id > latestReportID) {
 			latestReportID = latestReport.id;
 			setStorageVal("latestreport", latestReportID);
-			Notification.requestPermission().then(permission => (permission === "granted")?
+			if(latestReport.notify) Notification.requestPermission().then(permission => (permission === "granted")?
 				new Notification("New report", {
 					body: `New report for post ${latestReport.post_link} from ${latestReport.reporter_ip}\nReason: ${latestReport.reason}`,
 				}):null
@@ -290,6 +303,18 @@ function updateLatestReportAppeal(info: StaffInfo) {
 			);
 		}
 	}
+	const newUnreadAppeals = info.unread_appeals ?? 0;
+	if(newUnreadAppeals > unreadAppeals) {
+		Notification.requestPermission().then(permission => (permission === "granted")?
+			new Notification("New ban appeal messages", {
+				body: `${newUnreadAppeals} appeal${newUnreadAppeals === 1 ? " has" : "s have"} unread messages`,
+			}):null
+		);
+	}
+	if(newUnreadAppeals !== unreadAppeals) {
+		unreadAppeals = newUnreadAppeals;
+		setStorageVal("unreadappeals", unreadAppeals);
+	}
 }
 
 async function updateStaffNotifications() {
@@ -14,6 +14,7 @@ let $hoverPreview: JQuery<HTMLElement>|null = null;
 
 const videoTestRE = /\.(mp4)|(webm)$/;
 const imageTestRE = /\.(gif)|(jfif)|(jpe?g)|(png)|(webp)$/;
+const audioTestRE = /\.(mp3|ogg|flac|opus)$/;
 const postrefRE = /\/([^\s/]+)\/res\/(\d+)\.html(#(\d+))?/;
 
 // data retrieved from /<board>/res/<thread>.json
@@ -168,6 +169,23 @@ export function prepareThumbnails($parent: JQuery<HTMLElement>) {
 	$container.on("click", function(e) {
 		const $a = $(this);
 		const uploadHref = $a.siblings("div.file-info").children("a.file-orig").attr("href") ?? "";
+		if(audioTestRE.test(uploadHref)) {
+			// Upload is an audio file, keep the cover art/waveform thumbnail and toggle the player
+			e.preventDefault();
+			const $fileInfo = $a.prevAll(".file-info:first");
+			const $audio = $fileInfo.nextAll("audio.upload:first");
+			if($audio.length > 0) {
+				$audio.remove();
+			} else {
+				$("<audio />").prop({
+					src: uploadHref,
+					autoplay: true,
+					controls: true,
+					class: "upload"
+				}).insertAfter($fileInfo);
+			}
+			return false;
+		}
 		if(imageTestRE.exec(uploadHref) === null && videoTestRE.exec(uploadHref) === null)
 			return true; // not an image or a video
 
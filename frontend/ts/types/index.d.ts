@@ -37,6 +37,11 @@ declare global {
 		ban_id: number;
 		appeal_text: string;
 		is_denied: boolean;
+		/**
+		 * open, awaiting_user, denied, or approved
+		 */
+		status: string;
+		staff_unread: boolean;
 	}
 
 	// /util/banner
@@ -110,7 +115,7 @@ declare global {
 		h: number;
 		tn_w: number;
 		tn_h: number;
-		capcode: string;
+		capcode?: string;
 		time: string;
 		last_modified: string;
 	}
@@ -163,7 +168,15 @@ declare global {
 		reporter_ip: string;
 		poster_ip: string;
 		reason: string;
+		category?: string;
+		category_name?: string;
+		severity: number;
+		report_count: number;
+		notify: boolean;
 		is_cleared: boolean;
+		timestamp: string;
+		post_message: string;
+		post_hidden: boolean;
 		post_link: string;
 	}
 
@@ -189,6 +202,10 @@ declare global {
 		actions?: StaffAction[]
 		reports?: PostReport[];
 		appeals?: Appeal[];
+		/**
+		 * The number of open appeals with messages from banned users that staff haven't read yet
+		 */
+		unread_appeals?: number;
 	}
 
 	/**
@@ -0,0 +1,350 @@
+// Package backup creates and restores portable archives of a gochan site, containing its database rows, uploaded
+// files, board configuration files, and template overrides
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/md5" // skipcq: GSC-G501
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+)
+
+const (
+	// FormatVersion is the version of the archive layout, incremented if it changes in a way that older versions of
+	// gochan can't restore
+	FormatVersion = 1
+
+	// ManifestPath is the path of the manifest in the archive. It is written last, after the hashes of the other
+	// files are known
+	ManifestPath = "manifest.json"
+
+	databaseDir         = "database/"
+	uploadsDir          = "uploads/"
+	boardConfigDir      = "boardconfig/"
+	templateOverrideDir = "templates/override/"
+)
+
+var (
+	ErrDatabaseVersionMismatch = errors.New("backup database version doesn't match the current database version")
+	ErrUnsupportedFormat       = errors.New("unsupported backup format version")
+	ErrMissingManifest         = errors.New("backup is missing its manifest")
+)
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	FormatVersion   int       `json:"formatVersion"`
+	GochanVersion   string    `json:"gochanVersion"`
+	DatabaseVersion int       `json:"databaseVersion"`
+	DBType          string    `json:"dbType"`
+	CreatedAt       time.Time `json:"createdAt"`
+	Tables          []Table   `json:"tables"`
+	Files           []File    `json:"files"`
+}
+
+// Table is a database table in the archive, stored in database/<name>.json as one JSON array of column values per line
+type Table struct {
+	// Name is the name of the table, without the database prefix
+	Name    string              `json:"name"`
+	Columns []gcsql.TableColumn `json:"columns"`
+	Rows    int                 `json:"rows"`
+}
+
+// File is a file in the archive, including the database table files
+type File struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	MD5  string `json:"md5"`
+	// Link is the target of a symbolic link (like the thumbnail of an upload that uses its own file or a static
+	// thumbnail), relative to the document root
+	Link string `json:"link,omitempty"`
+}
+
+// Problem is an integrity check failure found while creating, verifying, or restoring a backup that doesn't prevent
+// the rest of it from being used, like an upload that doesn't match the checksum stored in the database
+type Problem struct {
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+}
+
+// archiveWriter writes files to a gzipped tar archive and records them in the manifest
+type archiveWriter struct {
+	tw       *tar.Writer
+	manifest *Manifest
+	// documentRoot is used for storing the targets of symbolic links relative to it, so that they can be restored
+	// to a different document root
+	documentRoot string
+}
+
+// addFile writes the file at srcPath to the archive as name, returning its MD5 hash
+func (aw *archiveWriter) addFile(name string, srcPath string) (string, error) {
+	file, err := os.Open(srcPath) // skipcq: GSC-G304
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	if err = aw.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     int64(config.NormalFileMode),
+		Size:     fi.Size(),
+		ModTime:  fi.ModTime(),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return "", err
+	}
+	hash := md5.New() // skipcq: GO-S1023
+	if _, err = io.Copy(io.MultiWriter(aw.tw, hash), file); err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	aw.manifest.Files = append(aw.manifest.Files, File{Path: name, Size: fi.Size(), MD5: checksum})
+	return checksum, file.Close()
+}
+
+// addSymlink writes the symbolic link at srcPath to the archive as name if its target is in the document root, with
+// the target stored relative to it. Otherwise the file it points to is written instead. Broken links are skipped
+func (aw *archiveWriter) addSymlink(name string, srcPath string) error {
+	target, err := filepath.EvalSymlinks(srcPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	documentRoot, err := filepath.EvalSymlinks(aw.documentRoot)
+	if err != nil {
+		return err
+	}
+	relTarget, err := filepath.Rel(documentRoot, target)
+	if err != nil || !filepath.IsLocal(relTarget) {
+		_, err = aw.addFile(name, target)
+		return err
+	}
+	fi, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+	link := filepath.ToSlash(relTarget)
+	if err = aw.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Linkname: link,
+		Mode:     int64(config.NormalFileMode),
+		ModTime:  fi.ModTime(),
+		Typeflag: tar.TypeSymlink,
+	}); err != nil {
+		return err
+	}
+	aw.manifest.Files = append(aw.manifest.Files, File{Path: name, Link: link})
+	return nil
+}
+
+// addDir writes the regular files and symbolic links in srcDir and its subdirectories to the archive under
+// archiveDir, calling added (if non-nil) with the archive path and MD5 hash of each regular file
+func (aw *archiveWriter) addDir(archiveDir string, srcDir string, added func(name string, checksum string)) error {
+	err := filepath.WalkDir(srcDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		isSymlink := d.Type()&fs.ModeSymlink != 0
+		if !d.Type().IsRegular() && !isSymlink {
+			return nil
+		}
+		relPath, err := filepath.Rel(srcDir, filePath)
+		if err != nil {
+			return err
+		}
+		name := path.Join(archiveDir, filepath.ToSlash(relPath))
+		if isSymlink {
+			return aw.addSymlink(name, filePath)
+		}
+		checksum, err := aw.addFile(name, filePath)
+		if err != nil {
+			return err
+		}
+		if added != nil {
+			added(name, checksum)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// nothing to back up
+		return nil
+	}
+	return err
+}
+
+// addTable writes the rows of the table to a temporary file and then to the archive
+func (aw *archiveWriter) addTable(ctx context.Context, db *gcsql.GCDB, table string) error {
+	columns, err := db.TableColumns(ctx, table)
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp("", "gochan-backup-*.json")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+	}()
+	encoder := json.NewEncoder(tmpFile)
+	var numRows int
+	if err = db.ReadTableRows(ctx, table, columns, func(row []any) error {
+		numRows++
+		return encoder.Encode(row)
+	}); err != nil {
+		return fmt.Errorf("unable to read %s rows: %w", table, err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	name := strings.TrimPrefix(table, "DBPREFIX")
+	if _, err = aw.addFile(databaseDir+name+".json", tmpFile.Name()); err != nil {
+		return err
+	}
+	aw.manifest.Tables = append(aw.manifest.Tables, Table{Name: name, Columns: columns, Rows: numRows})
+	return nil
+}
+
+// uploadChecksums returns a map of the archive paths of the uploads of non-deleted posts to the checksums of the
+// stored files in the database, which are empty for uploads from before they were recorded
+func uploadChecksums(ctx context.Context, db *gcsql.GCDB) (map[string]string, error) {
+	const query = `SELECT dir, filename, stored_checksum FROM DBPREFIXfiles f
+	JOIN DBPREFIXposts p ON p.id = f.post_id
+	JOIN DBPREFIXthreads t ON t.id = p.thread_id
+	JOIN DBPREFIXboards b ON b.id = t.board_id
+	WHERE p.is_deleted = FALSE AND f.filename != 'deleted' AND f.filename NOT LIKE 'embed:%'`
+	rows, err := db.Query(&gcsql.RequestOptions{Context: ctx}, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	checksums := make(map[string]string)
+	for rows.Next() {
+		var dir, filename, checksum string
+		if err = rows.Scan(&dir, &filename, &checksum); err != nil {
+			return nil, err
+		}
+		checksums[path.Join(uploadsDir, dir, "src", filename)] = checksum
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return checksums, rows.Close()
+}
+
+// Create writes a backup of the site to w as a gzipped tar archive, returning its manifest and any uploads that are
+// missing or don't match their checksums in the database. The database must already be connected and up to date
+func Create(ctx context.Context, w io.Writer) (*Manifest, []Problem, error) {
+	db, err := gcsql.GetDatabase()
+	if err != nil {
+		return nil, nil, err
+	}
+	dbVersion, versionFlag, err := gcsql.GetCompleteDatabaseVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+	if versionFlag != gcsql.DBUpToDate {
+		return nil, nil, fmt.Errorf("%w (database version %d, expected %d)", ErrDatabaseVersionMismatch, dbVersion, gcsql.DatabaseVersion)
+	}
+	systemCritical := config.GetSystemCriticalConfig()
+
+	gzw := gzip.NewWriter(w)
+	aw := &archiveWriter{
+		tw:           tar.NewWriter(gzw),
+		documentRoot: systemCritical.DocumentRoot,
+		manifest: &Manifest{
+			FormatVersion:   FormatVersion,
+			GochanVersion:   config.GochanVersion,
+			DatabaseVersion: dbVersion,
+			DBType:          systemCritical.DBtype,
+			CreatedAt:       time.Now().UTC(),
+		},
+	}
+
+	for _, table := range gcsql.TransferTables {
+		if err = aw.addTable(ctx, db, table); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	checksums, err := uploadChecksums(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	boards, err := gcsql.GetAllBoards(false)
+	if err != nil {
+		return nil, nil, err
+	}
+	var problems []Problem
+	for _, board := range boards {
+		for _, subDir := range []string{"src", "thumb"} {
+			if err = aw.addDir(path.Join(uploadsDir, board.Dir, subDir), board.AbsolutePath(subDir), func(name, checksum string) {
+				expected, ok := checksums[name]
+				if !ok {
+					return
+				}
+				delete(checksums, name)
+				if expected != "" && expected != checksum {
+					problems = append(problems, Problem{Path: name, Problem: "upload doesn't match its checksum in the database"})
+				}
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		boardCfgPath := config.GetBoardConfigPath(board.Dir)
+		if _, err = os.Stat(boardCfgPath); err == nil {
+			if _, err = aw.addFile(boardConfigDir+board.Dir+".json", boardCfgPath); err != nil {
+				return nil, nil, err
+			}
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, err
+		}
+	}
+	for name := range checksums {
+		problems = append(problems, Problem{Path: name, Problem: "upload in the database is missing"})
+	}
+	sortProblems(problems)
+
+	if err = aw.addDir(templateOverrideDir, filepath.Join(systemCritical.TemplateDir, "override"), nil); err != nil {
+		return nil, nil, err
+	}
+
+	ba, err := json.MarshalIndent(aw.manifest, "", "\t")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = aw.tw.WriteHeader(&tar.Header{
+		Name:     ManifestPath,
+		Mode:     int64(config.NormalFileMode),
+		Size:     int64(len(ba)),
+		ModTime:  aw.manifest.CreatedAt,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return nil, nil, err
+	}
+	if _, err = aw.tw.Write(ba); err != nil {
+		return nil, nil, err
+	}
+	if err = aw.tw.Close(); err != nil {
+		return nil, nil, err
+	}
+	return aw.manifest, problems, gzw.Close()
+}
@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/md5" // skipcq: GSC-G501
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+
+	_ "github.com/gochan-org/gochan/pkg/gcsql/initsql"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testUploadData = "not really an image"
+)
+
+// setupSite connects to a new SQLite database in dir and sets the document root and template directory to
+// subdirectories of it
+func setupSite(t *testing.T, dir string) {
+	t.Helper()
+	config.SetTestDBConfig("sqlite3", path.Join(dir, "gochan.db"), "gochan.db", "gochan", "password", "gc_")
+	systemCritical := config.GetSystemCriticalConfig()
+	systemCritical.DocumentRoot = path.Join(dir, "html")
+	systemCritical.TemplateDir = path.Join(dir, "templates")
+	config.SetSystemCriticalConfig(systemCritical)
+	if !assert.NoError(t, os.MkdirAll(systemCritical.DocumentRoot, config.DirFileMode)) {
+		t.FailNow()
+	}
+	sqlConfig := config.GetSQLConfig()
+	if !assert.NoError(t, gcsql.ConnectToDB(&sqlConfig)) {
+		t.FailNow()
+	}
+}
+
+// createTestSite creates a site with a post with an upload that was changed when it was processed, a post with an
+// upload that doesn't match its checksum, thumbnails that are symbolic links, and a template override
+func createTestSite(t *testing.T, dir string) {
+	t.Helper()
+	setupSite(t, dir)
+	if !assert.NoError(t, gcsql.CheckAndInitializeDatabase("sqlite3", true)) {
+		t.FailNow()
+	}
+	board, err := gcsql.GetBoardFromDir("test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	md5Sum := md5.Sum([]byte(testUploadData)) // skipcq: GO-S1023
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT INTO DBPREFIXthreads(board_id, stickied) VALUES(?, TRUE)`, []any{board.ID}},
+		{`INSERT INTO DBPREFIXposts(thread_id, is_top_post, ip, message, message_raw, password)
+			VALUES(1, TRUE, INET6_ATON(?), 'OP', 'OP', '')`, []any{"192.168.56.1"}},
+		{`INSERT INTO DBPREFIXposts(thread_id, is_top_post, ip, message, message_raw, password)
+			VALUES(1, FALSE, INET6_ATON(?), 'reply', 'reply', '')`, []any{"2601::1"}},
+		{`INSERT INTO DBPREFIXfiles(post_id, file_order, original_filename, filename, checksum, stored_checksum, file_size,
+			is_spoilered, thumbnail_width, thumbnail_height, width, height)
+			VALUES(1, 0, 'a.png', '1.png', 'unprocessed', ?, 1, FALSE, 1, 1, 1, 1)`,
+			[]any{hex.EncodeToString(md5Sum[:])}},
+		{`INSERT INTO DBPREFIXfiles(post_id, file_order, original_filename, filename, checksum, stored_checksum, file_size,
+			is_spoilered, thumbnail_width, thumbnail_height, width, height)
+			VALUES(2, 0, 'b.png', '2.png', 'unprocessed', 'bad', 1, TRUE, 1, 1, 1, 1)`, nil},
+	}
+	for _, stmt := range statements {
+		if _, err = gcsql.Exec(nil, stmt.query, stmt.args...); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+	for _, filename := range []string{"src/1.png", "src/2.png", "thumb/1t.png"} {
+		assert.NoError(t, os.MkdirAll(path.Dir(board.AbsolutePath(filename)), config.DirFileMode))
+		assert.NoError(t, os.WriteFile(board.AbsolutePath(filename), []byte(testUploadData), config.NormalFileMode))
+	}
+	overrideDir := path.Join(config.GetSystemCriticalConfig().TemplateDir, "override")
+	assert.NoError(t, os.MkdirAll(overrideDir, config.DirFileMode))
+	assert.NoError(t, os.WriteFile(path.Join(overrideDir, "front.html"), []byte("overridden"), config.NormalFileMode))
+	// thumbnails linking to files in the document root are kept as links, others are replaced by the file
+	assert.NoError(t, os.Symlink(board.AbsolutePath("src/2.png"), board.AbsolutePath("thumb/2t.png")))
+	assert.NoError(t, os.Symlink(path.Join(overrideDir, "front.html"), board.AbsolutePath("thumb/2c.png")))
+	assert.NoError(t, os.Symlink(path.Join(dir, "missing.png"), board.AbsolutePath("thumb/3t.png")))
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	_, err := testutil.GoToGochanRoot(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srcDir := t.TempDir()
+	createTestSite(t, srcDir)
+	archivePath := path.Join(srcDir, "backup.tar.gz")
+	file, err := os.Create(archivePath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	manifest, problems, err := Create(context.Background(), file)
+	assert.NoError(t, file.Close())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, gcsql.Close())
+
+	assert.Equal(t, gcsql.DatabaseVersion, manifest.DatabaseVersion)
+	assert.Len(t, manifest.Tables, len(gcsql.TransferTables))
+	expectedProblems := []Problem{{Path: "uploads/test/src/2.png", Problem: "upload doesn't match its checksum in the database"}}
+	assert.Equal(t, expectedProblems, problems)
+
+	verified, problems, err := Verify(archivePath)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Files, verified.Files)
+	assert.Equal(t, expectedProblems, problems)
+
+	dstDir := t.TempDir()
+	setupSite(t, dstDir)
+	defer gcsql.Close()
+	_, problems, err = Restore(context.Background(), archivePath, RestoreOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, expectedProblems, problems)
+
+	var ip string
+	var stickied bool
+	assert.NoError(t, gcsql.QueryRow(nil, `SELECT IP_NTOA, stickied FROM DBPREFIXposts
+		JOIN DBPREFIXthreads ON DBPREFIXthreads.id = thread_id WHERE DBPREFIXposts.id = 2`, nil, []any{&ip, &stickied}))
+	assert.Equal(t, "2601::1", ip)
+	assert.True(t, stickied)
+	var numStaff int
+	assert.NoError(t, gcsql.QueryRow(nil, `SELECT COUNT(*) FROM DBPREFIXstaff`, nil, []any{&numStaff}))
+	assert.Equal(t, 1, numStaff, "restore shouldn't create a default staff account")
+
+	ba, err := os.ReadFile(path.Join(dstDir, "html", "test", "src", "1.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, testUploadData, string(ba))
+	ba, err = os.ReadFile(path.Join(dstDir, "templates", "override", "front.html"))
+	assert.NoError(t, err)
+	assert.Equal(t, "overridden", string(ba))
+	target, err := os.Readlink(path.Join(dstDir, "html", "test", "thumb", "2t.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, path.Join(dstDir, "html", "test", "src", "2.png"), target)
+	ba, err = os.ReadFile(path.Join(dstDir, "html", "test", "thumb", "2c.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "overridden", string(ba))
+	assert.NoFileExists(t, path.Join(dstDir, "html", "test", "thumb", "3t.png"))
+
+	_, _, err = Restore(context.Background(), archivePath, RestoreOptions{})
+	assert.ErrorIs(t, err, gcsql.ErrDatabaseNotEmpty)
+	_, _, err = Restore(context.Background(), archivePath, RestoreOptions{Overwrite: true})
+	assert.NoError(t, err)
+}
+
+func TestRestoreCorruptedBackup(t *testing.T) {
+	_, err := testutil.GoToGochanRoot(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	dir := t.TempDir()
+	archivePath := path.Join(dir, "backup.tar.gz")
+	file, err := os.Create(archivePath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	gzw := gzip.NewWriter(file)
+	tw := tar.NewWriter(gzw)
+	writeFile := func(name string, data []byte) {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(data)), Mode: 0600, Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		assert.NoError(t, err)
+	}
+	writeFile("uploads/test/src/1.png", []byte("changed"))
+	ba, err := json.Marshal(Manifest{
+		FormatVersion:   FormatVersion,
+		DatabaseVersion: gcsql.DatabaseVersion,
+		Files: []File{
+			{Path: "uploads/test/src/1.png", Size: 7, MD5: "original"},
+			{Path: "uploads/test/src/2.png", Size: 7, MD5: "missing"},
+		},
+	})
+	assert.NoError(t, err)
+	writeFile(ManifestPath, ba)
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gzw.Close())
+	assert.NoError(t, file.Close())
+
+	_, problems, err := Verify(archivePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []Problem{
+		{Path: "uploads/test/src/1.png", Problem: "file doesn't match the manifest"},
+		{Path: "uploads/test/src/2.png", Problem: "file in the manifest is missing"},
+	}, problems)
+
+	_, _, err = Restore(context.Background(), archivePath, RestoreOptions{})
+	assert.ErrorIs(t, err, ErrIntegrityCheckFailed)
+}
+
+func TestRestorePathTraversal(t *testing.T) {
+	_, err := testutil.GoToGochanRoot(t)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	testCases := []string{
+		"uploads/../escaped.txt",
+		"uploads/test/../../escaped.txt",
+		"templates/override/../../escaped.txt",
+		"boardconfig/../escaped.json",
+	}
+	for _, name := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			systemCritical := config.GetSystemCriticalConfig()
+			systemCritical.DocumentRoot = path.Join(dir, "html", "root")
+			systemCritical.TemplateDir = path.Join(dir, "html", "templates")
+			config.SetSystemCriticalConfig(systemCritical)
+
+			archivePath := path.Join(dir, "backup.tar.gz")
+			file, err := os.Create(archivePath)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			gzw := gzip.NewWriter(file)
+			tw := tar.NewWriter(gzw)
+			data := []byte("escaped")
+			assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(data)), Mode: 0600, Typeflag: tar.TypeReg}))
+			_, err = tw.Write(data)
+			assert.NoError(t, err)
+			assert.NoError(t, tw.Close())
+			assert.NoError(t, gzw.Close())
+			assert.NoError(t, file.Close())
+
+			_, _, err = Verify(archivePath)
+			assert.ErrorContains(t, err, "invalid file path")
+			_, _, err = Restore(context.Background(), archivePath, RestoreOptions{})
+			assert.ErrorContains(t, err, "invalid file path")
+			assert.NoFileExists(t, path.Join(dir, "html", "escaped.txt"))
+		})
+	}
+}
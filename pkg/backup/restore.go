@@ -0,0 +1,447 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/md5" // skipcq: GSC-G501
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+)
+
+var (
+	ErrIntegrityCheckFailed = errors.New("backup failed the integrity check")
+)
+
+// RestoreOptions sets how a backup is restored
+type RestoreOptions struct {
+	// Overwrite deletes the rows in the database's gochan tables before restoring the backup. If it is false, the
+	// database must not have any gochan tables
+	Overwrite bool
+}
+
+// verifyResult is the result of reading through a backup and checking its files
+type verifyResult struct {
+	manifest *Manifest
+	// archiveProblems are files that are missing from the archive or don't match the manifest, meaning that the
+	// archive is incomplete or corrupted and shouldn't be restored
+	archiveProblems []Problem
+	// uploadProblems are uploads that don't match their checksums in the database
+	uploadProblems []Problem
+}
+
+func (vr *verifyResult) problems() []Problem {
+	return append(slices.Clone(vr.archiveProblems), vr.uploadProblems...)
+}
+
+// readArchive calls fn for each regular file and symbolic link in the gzipped tar archive, in the order they were
+// written
+func readArchive(archivePath string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	file, err := os.Open(archivePath) // skipcq: GSC-G304
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzr, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return file.Close()
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeSymlink {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(hdr.Name)) {
+			return fmt.Errorf("invalid file path %q in backup", hdr.Name)
+		}
+		if hdr.Typeflag == tar.TypeSymlink && !filepath.IsLocal(filepath.FromSlash(hdr.Linkname)) {
+			return fmt.Errorf("invalid link target %q of %q in backup", hdr.Linkname, hdr.Name)
+		}
+		if err = fn(hdr, tr); err != nil {
+			return fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// readTableRows calls fn with each row in a table file, converted from JSON to values that can be inserted into the
+// table's columns
+func readTableRows(r io.Reader, columns []gcsql.TableColumn, fn func(row []any) error) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	for {
+		var row []any
+		err := decoder.Decode(&row)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) != len(columns) {
+			return fmt.Errorf("row has %d values, expected %d", len(row), len(columns))
+		}
+		for c, column := range columns {
+			if number, ok := row[c].(json.Number); ok {
+				if row[c], err = number.Int64(); err != nil {
+					if row[c], err = number.Float64(); err != nil {
+						return err
+					}
+				}
+			}
+			if row[c], err = gcsql.NormalizeColumnValue(column.Kind, row[c]); err != nil {
+				return fmt.Errorf("column %s: %w", column.Name, err)
+			}
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// verify reads through the archive, comparing its files against the manifest and the uploads against their
+// checksums in the files table
+func verify(archivePath string) (*verifyResult, error) {
+	var result verifyResult
+	found := make(map[string]File)
+	var fileRows [][]any
+	systemCritical := config.GetSystemCriticalConfig()
+
+	err := readArchive(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == ManifestPath {
+			result.manifest = new(Manifest)
+			return json.NewDecoder(r).Decode(result.manifest)
+		}
+		if _, err := restorePath(hdr.Name, systemCritical); err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			found[hdr.Name] = File{Path: hdr.Name, Link: hdr.Linkname}
+			return nil
+		}
+		hash := md5.New() // skipcq: GO-S1023
+		tee := io.TeeReader(r, hash)
+		if hdr.Name == databaseDir+"files.json" {
+			// the files table is needed for checking the uploads, but its columns aren't known until the manifest
+			// is read, so keep the rows as they are until then
+			decoder := json.NewDecoder(tee)
+			for {
+				var row []any
+				if err := decoder.Decode(&row); errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					return err
+				}
+				fileRows = append(fileRows, row)
+			}
+		}
+		if _, err := io.Copy(io.Discard, tee); err != nil {
+			return err
+		}
+		found[hdr.Name] = File{Path: hdr.Name, Size: hdr.Size, MD5: hex.EncodeToString(hash.Sum(nil))}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := result.manifest
+	if manifest == nil {
+		return nil, ErrMissingManifest
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedFormat, manifest.FormatVersion)
+	}
+
+	for _, file := range manifest.Files {
+		foundFile, ok := found[file.Path]
+		if !ok {
+			result.archiveProblems = append(result.archiveProblems, Problem{Path: file.Path, Problem: "file in the manifest is missing"})
+			continue
+		}
+		delete(found, file.Path)
+		if foundFile != file {
+			result.archiveProblems = append(result.archiveProblems, Problem{Path: file.Path, Problem: "file doesn't match the manifest"})
+		}
+	}
+	for name := range found {
+		result.archiveProblems = append(result.archiveProblems, Problem{Path: name, Problem: "file isn't in the manifest"})
+	}
+	for _, table := range manifest.Tables {
+		if !slices.Contains(gcsql.TransferTables, "DBPREFIX"+table.Name) {
+			result.archiveProblems = append(result.archiveProblems, Problem{Path: databaseDir + table.Name + ".json", Problem: "unrecognized table"})
+		}
+	}
+
+	// check the uploads against the checksums of the stored files
+	var filenameCol, checksumCol = -1, -1
+	for _, table := range manifest.Tables {
+		if table.Name != "files" {
+			continue
+		}
+		for c, column := range table.Columns {
+			switch column.Name {
+			case "filename":
+				filenameCol = c
+			case "stored_checksum":
+				checksumCol = c
+			}
+		}
+	}
+	checksums := make(map[string]string)
+	if filenameCol > -1 && checksumCol > -1 {
+		for _, row := range fileRows {
+			if len(row) <= max(filenameCol, checksumCol) {
+				continue
+			}
+			filename, _ := row[filenameCol].(string)
+			checksum, _ := row[checksumCol].(string)
+			checksums[filename] = checksum
+		}
+	}
+	for _, file := range manifest.Files {
+		if !strings.HasPrefix(file.Path, uploadsDir) || path.Base(path.Dir(file.Path)) != "src" {
+			continue
+		}
+		if checksum := checksums[path.Base(file.Path)]; file.Link == "" && checksum != "" && checksum != file.MD5 {
+			result.uploadProblems = append(result.uploadProblems, Problem{Path: file.Path, Problem: "upload doesn't match its checksum in the database"})
+		}
+	}
+	sortProblems(result.archiveProblems)
+	sortProblems(result.uploadProblems)
+	return &result, nil
+}
+
+func sortProblems(problems []Problem) {
+	slices.SortFunc(problems, func(a, b Problem) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+}
+
+// verifyRestorable verifies the backup and returns an error if it can't be read or restored by this version of gochan
+func verifyRestorable(archivePath string) (*verifyResult, error) {
+	result, err := verify(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if result.manifest.DatabaseVersion != gcsql.DatabaseVersion {
+		return result, fmt.Errorf("%w (backup database version %d, expected %d)",
+			ErrDatabaseVersionMismatch, result.manifest.DatabaseVersion, gcsql.DatabaseVersion)
+	}
+	return result, nil
+}
+
+// Verify checks that the files in the backup match its manifest and that the uploads match their checksums in the
+// database, without restoring anything. It returns an error if the backup can't be read or restored by this version of
+// gochan, and the manifest and any problems found otherwise
+func Verify(archivePath string) (*Manifest, []Problem, error) {
+	result, err := verifyRestorable(archivePath)
+	if result == nil {
+		return nil, nil, err
+	}
+	return result.manifest, result.problems(), err
+}
+
+// restorePath returns the path that a file in the archive is restored to, or an empty string if it isn't restored as
+// a file. The name is checked again after its directory in the archive is removed, since a name like
+// "uploads/../file" is local to the archive but not to the document root
+func restorePath(archiveName string, systemCritical *config.SystemCriticalConfig) (string, error) {
+	var name, destDir string
+	switch {
+	case strings.HasPrefix(archiveName, uploadsDir):
+		name, destDir = archiveName[len(uploadsDir):], systemCritical.DocumentRoot
+	case strings.HasPrefix(archiveName, templateOverrideDir):
+		name, destDir = archiveName[len(templateOverrideDir):], filepath.Join(systemCritical.TemplateDir, "override")
+	case strings.HasPrefix(archiveName, boardConfigDir):
+		name = archiveName[len(boardConfigDir):]
+	default:
+		return "", nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("invalid file path %q in backup", archiveName)
+	}
+	if destDir == "" {
+		return config.GetBoardConfigPath(strings.TrimSuffix(name, ".json")), nil
+	}
+	return filepath.Join(destDir, filepath.FromSlash(name)), nil
+}
+
+// writeSymlink creates a symbolic link at destPath pointing to target, replacing the file at destPath if it exists
+// and creating its directory if it doesn't
+func writeSymlink(destPath string, target string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), config.DirFileMode); err != nil {
+		return err
+	}
+	if err := os.Remove(destPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(target, destPath)
+}
+
+// writeFile writes the contents of r to destPath, creating its directory if it doesn't exist
+func writeFile(destPath string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), config.DirFileMode); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, config.NormalFileMode) // skipcq: GSC-G304
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = io.Copy(file, r); err != nil {
+		return err
+	}
+	if err = config.TakeOwnershipOfFile(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// prepareDatabase creates gochan's tables in the database if it doesn't have any. Otherwise, it returns an error
+// if overwrite is false or the database isn't up to date
+func prepareDatabase(overwrite bool) error {
+	_, versionFlag, err := gcsql.GetCompleteDatabaseVersion()
+	if err != nil {
+		return err
+	}
+	switch {
+	case versionFlag == gcsql.DBClean:
+		return gcsql.InitializeEmptyDatabase(config.GetSystemCriticalConfig().DBtype)
+	case !overwrite:
+		return gcsql.ErrDatabaseNotEmpty
+	case versionFlag != gcsql.DBUpToDate:
+		return ErrDatabaseVersionMismatch
+	}
+	return nil
+}
+
+// deleteAllRows deletes the rows in gochan's tables, in the reverse order of their foreign key constraints
+func deleteAllRows(opts *gcsql.RequestOptions) error {
+	for t := len(gcsql.TransferTables) - 1; t >= 0; t-- {
+		if _, err := gcsql.Exec(opts, "DELETE FROM "+gcsql.TransferTables[t]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore verifies the backup and then restores it to the database, document root, configuration directory, and
+// template directory. It can be restored to a different database type than the one it was created from. The
+// database must already be connected. It returns the manifest and any uploads that don't match their checksums
+// in the database. If the archive fails the integrity check, nothing is restored
+func Restore(ctx context.Context, archivePath string, opts RestoreOptions) (*Manifest, []Problem, error) {
+	result, err := verifyRestorable(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := result.manifest
+	problems := result.problems()
+	if len(result.archiveProblems) > 0 {
+		return manifest, problems, ErrIntegrityCheckFailed
+	}
+	tables := make(map[string]Table)
+	for _, table := range manifest.Tables {
+		tables[table.Name] = table
+	}
+
+	db, err := gcsql.GetDatabase()
+	if err != nil {
+		return manifest, problems, err
+	}
+	if err = prepareDatabase(opts.Overwrite); err != nil {
+		return manifest, problems, err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return manifest, problems, err
+	}
+	defer tx.Rollback()
+	requestOpts := &gcsql.RequestOptions{Context: ctx, Tx: tx}
+	if opts.Overwrite {
+		if err = deleteAllRows(requestOpts); err != nil {
+			return manifest, problems, err
+		}
+	}
+	var committed bool
+	commit := func() error {
+		if committed {
+			return nil
+		}
+		committed = true
+		return tx.Commit()
+	}
+
+	systemCritical := config.GetSystemCriticalConfig()
+	err = readArchive(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		name, isTable := strings.CutPrefix(hdr.Name, databaseDir)
+		if isTable {
+			// the tables are written before any other files, so the database can be committed before any files are
+			// written, and nothing is written if any rows can't be inserted
+			if committed {
+				return errors.New("table found after other files")
+			}
+			table, ok := tables[strings.TrimSuffix(name, ".json")]
+			if !ok {
+				return errors.New("table isn't in the manifest")
+			}
+			tableName := "DBPREFIX" + table.Name
+			stmt, err := db.PrepareTableInsert(ctx, tx, tableName, table.Columns)
+			if err != nil {
+				return err
+			}
+			defer stmt.Close()
+			if err = readTableRows(r, table.Columns, func(row []any) error {
+				_, err := stmt.ExecContext(ctx, row...)
+				return err
+			}); err != nil {
+				return err
+			}
+			if err = stmt.Close(); err != nil {
+				return err
+			}
+			return db.ResetTableSequence(requestOpts, tableName, table.Columns)
+		}
+		if err := commit(); err != nil {
+			return err
+		}
+
+		destPath, err := restorePath(hdr.Name, systemCritical)
+		if err != nil {
+			return err
+		}
+		if destPath == "" {
+			// manifest
+			return nil
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			return writeSymlink(destPath, filepath.Join(systemCritical.DocumentRoot, filepath.FromSlash(hdr.Linkname)))
+		}
+		return writeFile(destPath, r)
+	})
+	if err == nil {
+		err = commit()
+	}
+	if err != nil {
+		return manifest, problems, err
+	}
+	return manifest, problems, gcsql.ResetViews()
+}
@@ -9,6 +9,7 @@ import (
 	"path"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
@@ -49,6 +50,7 @@ func boolToInt(b bool) int {
 
 // BuildBoardPages builds the front pages for the given board, and returns any error it encountered.
 func BuildBoardPages(board *gcsql.Board, errEv *zerolog.Event) error {
+	defer buildDuration.ObserveSince(time.Now(), "board")
 	if errEv == nil {
 		errEv = gcutil.LogError(nil).
 			Int("boardID", board.ID).
@@ -571,6 +573,7 @@ type boardsListJSON struct {
 
 // BuildBoardListJSON generates a JSON file with info about the boards
 func BuildBoardListJSON() error {
+	defer buildDuration.ObserveSince(time.Now(), "boards_json")
 	boardsJsonPath := path.Join(config.GetSystemCriticalConfig().DocumentRoot, "boards.json")
 	boardListFile, err := os.OpenFile(boardsJsonPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, config.NormalFileMode)
 	errEv := gcutil.LogError(nil).Str("building", "boards.json")
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"maps"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gctemplates"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
 	"github.com/gochan-org/gochan/pkg/posting/uploads"
 	"github.com/gochan-org/gochan/pkg/server/serverutil"
 	"github.com/rs/zerolog"
@@ -25,6 +27,10 @@ import (
 
 var (
 	bbcodeTagRE = regexp.MustCompile(`\[/?[^\[\]\s]+\]`)
+
+	// buildDuration measures the time taken to build each kind of page
+	buildDuration = metrics.NewHistogramVec("gochan_build_duration_seconds",
+		"Time taken to build pages, by page type", nil, "page")
 )
 
 type frontPagePost struct {
@@ -150,6 +156,7 @@ func getFrontPagePosts(errEv *zerolog.Event) ([]frontPagePost, error) {
 
 // BuildFrontPage builds the front page using templates/front.html
 func BuildFrontPage(logWhenDone ...bool) error {
+	defer buildDuration.ObserveSince(time.Now(), "front")
 	errEv := gcutil.LogError(nil).
 		Str("template", "front")
 	defer errEv.Discard()
@@ -221,6 +228,7 @@ func BuildPageFooter(writer io.Writer) (err error) {
 
 // BuildJS minifies (if enabled) consts.js, which is built from a template
 func BuildJS() error {
+	defer buildDuration.ObserveSince(time.Now(), "consts_js")
 	// build consts.js from template
 	err := gctemplates.InitTemplates(gctemplates.JsConsts)
 	errEv := gcutil.LogError(nil).Str("building", "consts.js")
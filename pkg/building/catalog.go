@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
@@ -77,6 +78,7 @@ func getBoardTopPosts(board string) ([]*Post, error) {
 
 // BuildCatalog builds the catalog for a board with a given id
 func BuildCatalog(boardID int) error {
+	defer buildDuration.ObserveSince(time.Now(), "catalog")
 	errEv := gcutil.LogError(nil).
 		Str("building", "catalog").
 		Int("boardID", boardID)
@@ -78,29 +78,30 @@ func doCatalogTest(t *testing.T, minified bool) {
 	)
 
 	mock.ExpectPrepare(`SELECT ` +
-		`id, thread_id, ip, name, tripcode, is_secure_tripcode, email, subject, created_on,\s+last_modified, parent_id, last_bump, ` +
-		`message, message_raw, banned_message, board_id, dir, original_filename, filename,\s+checksum, filesize, tw, th, width, height, ` +
-		`spoiler_file, locked, stickied, cyclic, spoiler_thread, flag, country, is_deleted\s+FROM v_building_posts`).ExpectQuery().WillReturnRows(
+		`id, thread_id, ip, name, tripcode, is_secure_tripcode, is_role_signature, email, subject,\s+created_on, last_modified, ` +
+		`parent_id, last_bump, message, message_raw, banned_message, board_id, dir, original_filename,\s+filename, checksum, ` +
+		`filesize, tw, th, width, height, ` +
+		`spoiler_file, locked, stickied, cyclic, spoiler_thread, flag, country, is_deleted,\s+is_hidden\s+FROM v_building_posts`).ExpectQuery().WillReturnRows(
 		sqlmock.NewRows([]string{
-			"id", "thread_id", "ip", "name", "tripcode", "is_secure_tripcode", "email", "subject", "created_on",
-			"last_modified", "parent_id", "last_bump", "message", "message_raw", "banned_message", "board_id",
+			"id", "thread_id", "ip", "name", "tripcode", "is_secure_tripcode", "is_role_signature", "email", "subject",
+			"created_on", "last_modified", "parent_id", "last_bump", "message", "message_raw", "banned_message", "board_id",
 			"dir", "original_filename", "filename", "checksum", "filesize", "tw", "th", "width", "height",
-			"spoiler_file", "locked", "stickied", "cyclic", "spoiler_thread", "flag", "country", "is_deleted",
+			"spoiler_file", "locked", "stickied", "cyclic", "spoiler_thread", "flag", "country", "is_deleted", "is_hidden",
 		}).AddRows([]driver.Value{
-			1, 1, "192.168.1.1", "Anonymous", "", false, "", "Normal thread", time.Now(),
+			1, 1, "192.168.1.1", "Anonymous", "", false, false, "", "Normal thread", time.Now(),
 			time.Now(), 1, time.Now(), "Lorem ipsum<br/>blah blah blah", "Lorem ipsum\nblah blah blah", "", 1,
 			"test", "test.jpg", "test.jpg", "checksum", 12345, 150, 150, 1920, 1080,
-			false, false, false, false, false, "US", "United States", false,
+			false, false, false, false, false, "US", "United States", false, false,
 		}, []driver.Value{
-			2, 2, "192.168.1.2", "Name", "!Trip", false, "email@example.com", "", time.Now(),
+			2, 2, "192.168.1.2", "Name", "!Trip", false, false, "email@example.com", "", time.Now(),
 			time.Now(), 1, time.Now(), "Thread with name and trip<b>bold</b>", "Thread with name and trip[b]bold[/b]", "", 1,
 			"test", "", "", "", 0, 0, 0, 0, 0,
-			false, false, false, false, false, "CA", "Canada", false,
+			false, false, false, false, false, "CA", "Canada", false, false,
 		}, []driver.Value{
-			3, 3, "192.168.1.3", "", "!Trip", false, "email@example.com", "Status Icons Test (Cyclic, Locked, Stickied)", time.Now(),
+			3, 3, "192.168.1.3", "", "!Trip", false, false, "email@example.com", "Status Icons Test (Cyclic, Locked, Stickied)", time.Now(),
 			time.Now(), 1, time.Now(), "This thread is cyclic, locked, and stickied.", "This thread is cyclic, locked, and stickied.", "", 1,
 			"test", "", "", "", 0, 0, 0, 0, 0,
-			true, true, true, true, false, "GB", "United Kingdom", false,
+			true, true, true, true, false, "GB", "United Kingdom", false, false,
 		}),
 	)
 	mock.ExpectPrepare(`SELECT COUNT\(\*\) FROM posts WHERE thread_id = \(\s*SELECT thread_id FROM posts WHERE id = \?\) AND is_deleted = FALSE AND is_top_post = FALSE`).ExpectQuery().
@@ -5,8 +5,10 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"maps"
 	"net"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,10 +20,15 @@ import (
 )
 
 const (
-	buildingPostsBaseQuery = `SELECT id, thread_id, ip, name, tripcode, is_secure_tripcode, email, subject, created_on,
-		last_modified, parent_id, last_bump, message, message_raw, banned_message, board_id, dir, original_filename, filename,
-		checksum, filesize, tw, th, width, height, spoiler_file, locked, stickied, cyclic, spoiler_thread, flag, country, is_deleted
+	buildingPostsBaseQuery = `SELECT id, thread_id, ip, name, tripcode, is_secure_tripcode, is_role_signature, email, subject,
+		created_on, last_modified, parent_id, last_bump, message, message_raw, banned_message, board_id, dir, original_filename,
+		filename, checksum, filesize, tw, th, width, height, spoiler_file, locked, stickied, cyclic, spoiler_thread, flag, country, is_deleted,
+		is_hidden
 		FROM DBPREFIXv_building_posts `
+
+	// metadataBatchSize is the maximum number of posts whose upload metadata is loaded in one query, keeping the
+	// number of query parameters under the limits of the supported databases
+	metadataBatchSize = 500
 )
 
 func truncateString(msg string, limit int, ellipsis bool) string {
@@ -89,6 +96,25 @@ type Post struct {
 	thread       gcsql.Thread
 	uploadPath   string
 	uniqueID     string
+	opIP         net.IP
+
+	// PosterID is the thread-unique poster ID if the board has ShowPosterID enabled, allowing clients to filter by it
+	PosterID string `json:"id,omitempty"`
+
+	// StaffCapcode is the post's staff capcode (e.g. "mod") if it is a staff signature, which is shown instead of the
+	// tripcode
+	StaffCapcode string `json:"capcode,omitempty"`
+
+	// IsHidden is true if the post has been hidden because of its reports. Its message and upload are not shown
+	IsHidden bool `json:"hidden,omitempty"`
+
+	// UploadMetadata is the metadata of the post's upload (duration, tags, page count, etc), if its type can have any
+	UploadMetadata map[string]string `json:"-"`
+}
+
+// Capcode returns the post's staff capcode if it is a staff signature, or an empty string otherwise
+func (p *Post) Capcode() string {
+	return p.StaffCapcode
 }
 
 // TitleText returns the text to be used for the title of the page
@@ -112,29 +138,58 @@ func (p *Post) ThreadPath() string {
 	return config.WebPath(p.BoardDir, "res", strconv.Itoa(threadID)+".html")
 }
 
+// posterID returns a 6-character hexadecimal ID for the IP address in the thread with the given top post ID. If
+// the board's PosterIDRotation is "daily", the ID also depends on the day (UTC) the post was made and RandomSeed, so
+// that it can't be guessed from the IP address and date. "thread" IDs are calculated the same way they were before
+// rotation was added so that existing IDs don't change
+func posterID(ip net.IP, boardDir string, opID int, createdOn time.Time) string {
+	hash := sha256.New()
+	hash.Write(ip)
+	hash.Write([]byte(boardDir))
+	hash.Write([]byte(strconv.Itoa(opID)))
+	if config.GetBoardConfig(boardDir).PosterIDRotation == "daily" {
+		hash.Write([]byte(createdOn.UTC().Format(time.DateOnly)))
+		hash.Write([]byte(config.GetSystemCriticalConfig().RandomSeed))
+	}
+	return fmt.Sprintf("%02x", hash.Sum(nil)[:3])
+}
+
 // ThreadUniqueID returns a 6-character hexidecimal ID for the user in a thread, allowing anonymity while discouraging sockpuppetting
 func (p *Post) ThreadUniqueID() string {
-	if p.uniqueID != "" {
-		return p.uniqueID
+	if p.uniqueID == "" {
+		p.uniqueID = posterID(p.IP, p.BoardDir, p.ParentID, p.CreatedOn)
 	}
-	hash := sha256.New()
-	hash.Write(p.IP)
-	hash.Write([]byte(p.BoardDir))
-	hash.Write([]byte(strconv.Itoa(p.ParentID)))
-	p.uniqueID = fmt.Sprintf("%02x", hash.Sum(nil)[:3])
 	return p.uniqueID
 }
 
+// ThreadUniqueIDColor returns the background color of the poster ID, either from the board's PosterIDColors palette
+// or derived from the ID itself
+func (p *Post) ThreadUniqueIDColor() string {
+	id := p.ThreadUniqueID()
+	colors := config.GetBoardConfig(p.BoardDir).PosterIDColors
+	if len(colors) == 0 {
+		return "#" + id
+	}
+	idNum, _ := strconv.ParseUint(id, 16, 32)
+	return colors[idNum%uint64(len(colors))]
+}
+
 // ThreadUniqueIDColorIsDark returns true if the color represented by the thread unique ID has a dark luminance
 func (p *Post) ThreadUniqueIDColorIsDark() bool {
-	id := p.ThreadUniqueID()
-	red, _ := strconv.ParseInt(id[0:2], 16, 0)
-	green, _ := strconv.ParseInt(id[2:4], 16, 0)
-	blue, _ := strconv.ParseInt(id[4:6], 16, 0)
+	color := strings.TrimPrefix(p.ThreadUniqueIDColor(), "#")
+	red, _ := strconv.ParseInt(color[0:2], 16, 0)
+	green, _ := strconv.ParseInt(color[2:4], 16, 0)
+	blue, _ := strconv.ParseInt(color[4:6], 16, 0)
 	luminance := 0.299*float32(red) + 0.587*float32(green) + 0.114*float32(blue)
 	return luminance < 128
 }
 
+// IsOPPoster returns true if the post was made by the thread's OP (from the same IP address). It only works for
+// posts retrieved with the rest of their thread, otherwise it only returns true for the top post
+func (p *Post) IsOPPoster() bool {
+	return p.IsTopPost || (p.opIP != nil && p.opIP.Equal(p.IP))
+}
+
 // Timestamp returns the time the post was created.
 // Deprecated: Use CreatedOn instead.
 func (p *Post) Timestamp() time.Time {
@@ -204,6 +259,10 @@ func QueryPosts(query string, params []any, cb func(*Post) error) error {
 	}
 	defer rows.Close()
 
+	// the posts are read before the callback is called so that their upload metadata can be loaded in batches
+	// instead of with one query per upload
+	var posts []*Post
+	var metadataPostIDs []int
 	for rows.Next() {
 		var post Post
 		dest := []any{&post.ID, &post.thread.ID}
@@ -216,12 +275,12 @@ func QueryPosts(query string, params []any, cb func(*Post) error) error {
 		var lastBump time.Time
 		var spoilerFile bool
 		dest = append(dest,
-			&post.Name, &post.Tripcode, &post.IsSecureTripcode, &post.Email, &post.Subject, &post.CreatedOn,
+			&post.Name, &post.Tripcode, &post.IsSecureTripcode, &post.IsRoleSignature, &post.Email, &post.Subject, &post.CreatedOn,
 			&post.LastModified, &post.ParentID, &lastBump, &post.Message, &post.MessageRaw, &post.BannedMessage,
 			&post.BoardID, &post.BoardDir, &post.OriginalFilename, &post.Filename, &post.Checksum, &post.Filesize,
 			&post.ThumbnailWidth, &post.ThumbnailHeight, &post.UploadWidth, &post.UploadHeight, &spoilerFile,
 			&post.thread.Locked, &post.thread.Stickied, &post.thread.Cyclic, &post.thread.IsSpoilered,
-			&post.Country.Flag, &post.Country.Name, &post.IsDeleted)
+			&post.Country.Flag, &post.Country.Name, &post.IsDeleted, &post.IsHidden)
 
 		if err = rows.Scan(dest...); err != nil {
 			return err
@@ -237,15 +296,66 @@ func QueryPosts(query string, params []any, cb func(*Post) error) error {
 				return fmt.Errorf("invalid IP address %q", ip)
 			}
 		}
+		if post.IsRoleSignature {
+			// the capcode is stored in the tripcode column
+			post.StaffCapcode = post.Tripcode
+			post.Tripcode = ""
+		}
+		if post.IsHidden {
+			// hidden until a moderator reviews its reports, don't show its contents anywhere
+			post.Message = ""
+			post.MessageRaw = ""
+			post.PostUploadBase = PostUploadBase{}
+			post.Checksum = ""
+			post.Filesize = 0
+			post.UploadWidth = 0
+			post.UploadHeight = 0
+		}
 		post.IsTopPost = post.ParentID == 0 || post.ParentID == post.ID
 		if post.Filename != "" {
 			post.Extension = path.Ext(post.Filename)
 		}
-		if err = cb(&post); err != nil {
+		if config.GetBoardConfig(post.BoardDir).ShowPosterID {
+			post.PosterID = post.ThreadUniqueID()
+		}
+		if post.Filename != "" && !post.HasEmbed() && uploads.HasMetadata(post.Filename) {
+			metadataPostIDs = append(metadataPostIDs, post.ID)
+		}
+		posts = append(posts, &post)
+	}
+	if err = rows.Close(); err != nil {
+		return err
+	}
+
+	if err = loadUploadMetadata(ctx, posts, metadataPostIDs); err != nil {
+		return err
+	}
+	for _, post := range posts {
+		if err = cb(post); err != nil {
 			return err
 		}
 	}
-	return rows.Close()
+	return nil
+}
+
+// loadUploadMetadata sets the UploadMetadata of the posts with the given IDs, using one query per
+// metadataBatchSize posts
+func loadUploadMetadata(ctx context.Context, posts []*Post, postIDs []int) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+	metadata := make(map[int]map[string]string)
+	for batch := range slices.Chunk(postIDs, metadataBatchSize) {
+		batchMetadata, err := gcsql.GetUploadsMetadata(batch, &gcsql.RequestOptions{Context: ctx})
+		if err != nil {
+			return err
+		}
+		maps.Copy(metadata, batchMetadata)
+	}
+	for _, post := range posts {
+		post.UploadMetadata = metadata[post.ID]
+	}
+	return nil
 }
 
 func GetBuildablePostsByIP(ip string, limit int) ([]*Post, error) {
@@ -262,6 +372,16 @@ func GetBuildablePostsByIP(ip string, limit int) ([]*Post, error) {
 	return posts, err
 }
 
+// setThreadOP sets the OP's IP address for posts in the same thread, used for showing the OP marker
+func setThreadOP(posts []*Post) {
+	if len(posts) == 0 || !posts[0].IsTopPost {
+		return
+	}
+	for _, post := range posts[1:] {
+		post.opIP = posts[0].IP
+	}
+}
+
 func getThreadPosts(thread *gcsql.Thread) ([]*Post, error) {
 	const query = buildingPostsBaseQuery + "WHERE thread_id = ? ORDER BY id ASC"
 	var posts []*Post
@@ -269,9 +389,59 @@ func getThreadPosts(thread *gcsql.Thread) ([]*Post, error) {
 		posts = append(posts, p)
 		return nil
 	})
+	setThreadOP(posts)
 	return posts, err
 }
 
+// GetThreadPostsByPosterID returns the posts in the thread with the given top post ID that have the given poster ID
+func GetThreadPostsByPosterID(opID int, posterID string) ([]*Post, error) {
+	const query = buildingPostsBaseQuery + "WHERE parent_id = ? ORDER BY id ASC"
+	return getPostsByPosterID(query, opID, func(posts []*Post) string {
+		return posterID
+	})
+}
+
+// GetPostsSharingPosterID returns the poster ID of the given post and the posts in the same thread with that ID
+func GetPostsSharingPosterID(postID int) (string, []*Post, error) {
+	const query = buildingPostsBaseQuery + "WHERE thread_id = (SELECT thread_id FROM DBPREFIXposts WHERE id = ?) ORDER BY id ASC"
+	var posterID string
+	posts, err := getPostsByPosterID(query, postID, func(posts []*Post) string {
+		for _, post := range posts {
+			if post.ID == postID {
+				posterID = post.ThreadUniqueID()
+				break
+			}
+		}
+		return posterID
+	})
+	return posterID, posts, err
+}
+
+// getPostsByPosterID gets the posts in a thread using the given query and returns the ones with the poster ID
+// returned by getID
+func getPostsByPosterID(query string, param any, getID func([]*Post) string) ([]*Post, error) {
+	var posts []*Post
+	err := QueryPosts(query, []any{param}, func(p *Post) error {
+		posts = append(posts, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	setThreadOP(posts)
+	posterID := getID(posts)
+	if posterID == "" {
+		return nil, nil
+	}
+	var matching []*Post
+	for _, post := range posts {
+		if post.ThreadUniqueID() == posterID {
+			matching = append(matching, post)
+		}
+	}
+	return matching, nil
+}
+
 func GetRecentPosts(boardid int, limit int) ([]*Post, error) {
 	query := buildingPostsBaseQuery
 	var args []any
@@ -0,0 +1,58 @@
+package building
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadUniqueID(t *testing.T) {
+	config.InitTestConfig()
+	config.SetRandomSeed("test")
+	createdOn := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	op := &Post{IP: net.ParseIP("192.168.56.1"), BoardDir: "posterid", ParentID: 1}
+	op.ID = 1
+	op.IsTopPost = true
+	op.CreatedOn = createdOn
+	reply := &Post{IP: net.ParseIP("192.168.56.1"), BoardDir: "posterid", ParentID: 1}
+	reply.ID = 2
+	reply.CreatedOn = createdOn.Add(48 * time.Hour)
+	other := &Post{IP: net.ParseIP("192.168.56.2"), BoardDir: "posterid", ParentID: 1}
+	other.ID = 3
+	other.CreatedOn = createdOn
+	setThreadOP([]*Post{op, reply, other})
+
+	id := op.ThreadUniqueID()
+	assert.Regexp(t, `^[0-9a-f]{6}$`, id)
+	legacyHash := sha256.Sum256(append(append(net.ParseIP("192.168.56.1"), "posterid"...), "1"...))
+	assert.Equal(t, hex.EncodeToString(legacyHash[:3]), id, "thread IDs should be the same as before rotation was added")
+	assert.Equal(t, id, reply.ThreadUniqueID(), "IDs should not rotate daily by default")
+	assert.NotEqual(t, id, other.ThreadUniqueID())
+	assert.Equal(t, "#"+id, op.ThreadUniqueIDColor())
+	assert.True(t, op.IsOPPoster())
+	assert.True(t, reply.IsOPPoster())
+	assert.False(t, other.IsOPPoster())
+
+	boardConfig := *config.GetBoardConfig("")
+	boardConfig.PosterIDRotation = "daily"
+	boardConfig.PosterIDColors = []string{"#000000", "#ffffff"}
+	if !assert.NoError(t, config.SetBoardConfig("posterid", &boardConfig)) {
+		t.FailNow()
+	}
+	op.uniqueID = ""
+	reply.uniqueID = ""
+	assert.NotEqual(t, op.ThreadUniqueID(), reply.ThreadUniqueID(), "IDs should rotate daily")
+	assert.Contains(t, boardConfig.PosterIDColors, op.ThreadUniqueIDColor())
+	assert.Equal(t, op.ThreadUniqueIDColor() == "#000000", op.ThreadUniqueIDColorIsDark())
+
+	boardConfig.PosterIDRotation = "hourly"
+	assert.Error(t, config.SetBoardConfig("posterid", &boardConfig))
+	boardConfig.PosterIDRotation = "thread"
+	boardConfig.PosterIDColors = []string{"red"}
+	assert.Error(t, config.SetBoardConfig("posterid", &boardConfig))
+}
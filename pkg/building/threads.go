@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"time"
 
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
@@ -43,6 +44,7 @@ func BuildThreads(all bool, board string, threadid int) error {
 
 // BuildThreadPages builds the pages for a thread given the top post. It fails if op is not the top post
 func BuildThreadPages(op *gcsql.Post) error {
+	defer buildDuration.ObserveSince(time.Now(), "thread")
 	errEv := gcutil.LogError(nil).
 		Str("building", "thread").
 		Int("postid", op.ID).
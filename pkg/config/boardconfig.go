@@ -8,12 +8,15 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/gochan-org/gochan/pkg/gcutil"
 	"github.com/gochan-org/gochan/pkg/posting/geoip"
 )
 
+var posterIDColorRE = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
 // BoardConfig contains information about a specific board to be stored in /path/to/board/board.json
 // or all boards if it is stored in the main gochan.json file. If a board doesn't have board.json,
 // the site's default board config (with values set in gochan.json) will be used
@@ -61,9 +64,21 @@ type BoardConfig struct {
 	// Default: Mon, January 02, 2006 3:04:05 PM
 	DateTimeFormat string
 
-	// ShowPosterID determines whether to show the generated thread-unique poster ID in the post header (not yet implemented)
+	// ShowPosterID determines whether to show the generated thread-unique poster ID in the post header
 	ShowPosterID bool
 
+	// PosterIDRotation determines how often poster IDs change if ShowPosterID is true. If it is "thread", a poster
+	// has the same ID for the life of the thread. If it is "daily", their ID in the thread also changes every day (UTC)
+	// Default: thread
+	PosterIDRotation string
+
+	// PosterIDColors is an optional list of hex colors (e.g. "#3366cc") to use for poster ID backgrounds. If it is
+	// empty, the color is derived from the ID itself. Otherwise, each ID is consistently assigned a color from the list
+	PosterIDColors []string
+
+	// PosterIDShowOP determines whether to show an "OP" marker next to the poster ID of the thread's OP
+	PosterIDShowOP bool
+
 	// EnableSpoileredImages determines whether to allow users to spoiler images (not yet implemented)
 	// Default: true
 	EnableSpoileredImages bool
@@ -81,6 +96,16 @@ type BoardConfig struct {
 	// Default: See BoardCooldowns section
 	Cooldowns BoardCooldowns
 
+	// ReportCategories is a list of categories users can choose from when reporting a post on the board, with an ID, a Name
+	// shown to users, a Severity used for sorting the reports page, and optional automatic actions. If it is empty, users
+	// can only give a reason. See ReportCategory section for more information
+	ReportCategories []ReportCategory
+
+	// MaxReportsPerHour is the number of posts a user (by IP) can report in an hour. If it is 0, reports are only limited
+	// by the report cooldown
+	// Default: 20
+	MaxReportsPerHour int
+
 	// RenderURLsAsLinks determines whether to render URLs as clickable links in posts
 	// Default: true
 	RenderURLsAsLinks bool
@@ -138,9 +163,30 @@ func (bc *BoardConfig) validateBoardConfig() error {
 	if bc.Cooldowns.ImageReply <= 0 {
 		bc.Cooldowns.ImageReply = defaultGochanConfig.Cooldowns.ImageReply
 	}
+	if bc.Cooldowns.Report <= 0 {
+		bc.Cooldowns.Report = defaultGochanConfig.Cooldowns.Report
+	}
+	if bc.MaxReportsPerHour < 0 {
+		bc.MaxReportsPerHour = 0
+	}
+	if err := bc.validateReportCategories(); err != nil {
+		return err
+	}
 	if bc.AnonymousName == "" {
 		bc.AnonymousName = defaultGochanConfig.AnonymousName
 	}
+	switch bc.PosterIDRotation {
+	case "":
+		bc.PosterIDRotation = defaultGochanConfig.PosterIDRotation
+	case "thread", "daily":
+	default:
+		return &InvalidValueError{Field: "PosterIDRotation", Value: bc.PosterIDRotation, Details: `must be "thread" or "daily"`}
+	}
+	for _, color := range bc.PosterIDColors {
+		if !posterIDColorRE.MatchString(color) {
+			return &InvalidValueError{Field: "PosterIDColors", Value: color, Details: "colors must be in the format #rrggbb"}
+		}
+	}
 	if bc.AutosageAfter <= 0 {
 		bc.AutosageAfter = defaultGochanConfig.AutosageAfter
 	}
@@ -199,10 +245,62 @@ func (bc *BoardConfig) validateBoardConfig() error {
 	if bc.ThumbHeightCatalog <= 0 {
 		bc.ThumbHeightCatalog = defaultGochanConfig.ThumbHeightCatalog
 	}
+	if bc.RejectVideoStreamTypes == nil {
+		bc.RejectVideoStreamTypes = defaultGochanConfig.RejectVideoStreamTypes
+	}
+	switch bc.TranscodeVideos {
+	case "", "none", "unsafe", "all":
+	default:
+		return &InvalidValueError{
+			Field:   "TranscodeVideos",
+			Value:   bc.TranscodeVideos,
+			Details: `valid values are "", "none", "unsafe", or "all"`,
+		}
+	}
+	if bc.MaxVideoDuration < 0 {
+		bc.MaxVideoDuration = 0
+	}
+	if bc.MaxVideoWidth < 0 {
+		bc.MaxVideoWidth = 0
+	}
+	if bc.MaxVideoHeight < 0 {
+		bc.MaxVideoHeight = 0
+	}
 
 	return bc.validateEmbedMatchers()
 }
 
+func (bc *BoardConfig) validateReportCategories() error {
+	categoryIDs := make(map[string]bool, len(bc.ReportCategories))
+	for c, category := range bc.ReportCategories {
+		field := "ReportCategories[" + strconv.Itoa(c) + "]"
+		if category.ID == "" || len(category.ID) > 45 {
+			return &InvalidValueError{Field: field + ".ID", Value: category.ID, Details: "must be 1-45 characters"}
+		}
+		if categoryIDs[category.ID] {
+			return &InvalidValueError{Field: field + ".ID", Value: category.ID, Details: "category IDs must be unique"}
+		}
+		categoryIDs[category.ID] = true
+		if category.HideAfter < 0 {
+			return &InvalidValueError{Field: field + ".HideAfter", Value: category.HideAfter, Details: "must not be negative"}
+		}
+		if category.Name == "" {
+			bc.ReportCategories[c].Name = category.ID
+		}
+	}
+	return nil
+}
+
+// GetReportCategory returns the board's report category with the given ID, or nil if it doesn't exist
+func (bc *BoardConfig) GetReportCategory(id string) *ReportCategory {
+	for c := range bc.ReportCategories {
+		if bc.ReportCategories[c].ID == id {
+			return &bc.ReportCategories[c]
+		}
+	}
+	return nil
+}
+
 // IsGlobal returns true if this is the global configuration applied to all
 // boards by default, or false if it is an explicitly configured board
 func (bc *BoardConfig) IsGlobal() bool {
@@ -215,7 +313,7 @@ func (bc *BoardConfig) EqualsGlobalConfig() bool {
 	if bc.isGlobal {
 		return true // returned by GetBoardConfig given an empty string or a board without a custom configuration file
 	}
-	globalCfg := cfg.BoardConfig
+	globalCfg := currentConfig().BoardConfig
 
 	bcJSON, err := json.Marshal(bc)
 	if err != nil {
@@ -242,6 +340,31 @@ type BoardCooldowns struct {
 	// NewImageReply is the number of seconds the user must wait after replying to a thread with an upload before they can create another reply.
 	// Default: 7
 	ImageReply int `json:"images"`
+
+	// Report is the number of seconds the user must wait after reporting a post before they can report another one.
+	// Default: 10
+	Report int `json:"reports"`
+}
+
+// ReportCategory is a category users can choose from when reporting a post, e.g. spam or illegal content
+type ReportCategory struct {
+	// ID identifies the category and is stored with reports in it. It must be unique and no longer than 45 characters
+	ID string
+
+	// Name is the name of the category shown to users. If it is not set, the ID is used
+	Name string
+
+	// Severity determines the order of reports on the reports page. Reports in categories with a higher severity are shown
+	// first. Reports without a category have a severity of 0
+	Severity int
+
+	// HideAfter is the number of unhandled reports in this category a post can get before it is hidden until a moderator
+	// dismisses the reports. If it is 0, posts are not hidden automatically
+	HideAfter int
+
+	// NotifyStaff determines whether staff with gochan open in their browser get a desktop notification for new reports
+	// in this category. Reports without a category always create a notification
+	NotifyStaff bool
 }
 
 // PageBanner represents the filename and dimensions of a banner image to display on board and thread pages
@@ -559,9 +682,37 @@ type UploadConfig struct {
 	// The key is the extension (e.g. ".pdf") and the value is the filename of the thumbnail to use in /static
 	AllowOtherExtensions map[string]string
 
+	// AllowPDFUploads enables PDF uploads. The first page is used as the thumbnail if pdftoppm is available, otherwise
+	// static/pdfthumb.png is used. Active content (JavaScript, auto-run actions, embedded files, etc) is disabled
+	AllowPDFUploads bool
+
 	// StripImageMetadata sets what (if any) metadata to remove from uploaded images using exiftool.
 	// Valid values are "", "none" (has the same effect as ""), "exif", or "all" (for stripping all metadata)
 	StripImageMetadata string
+
+	// StripVideoMetadata removes metadata (GPS location, titles, encoder info, chapters, etc) from uploaded videos by remuxing
+	// them with ffmpeg
+	StripVideoMetadata bool
+
+	// TranscodeVideos determines whether uploaded videos are re-encoded with ffmpeg. Valid values are "" or "none" (never re-encoded),
+	// "unsafe" (only streams that aren't H.264/AAC/MP3 in MP4 files or VP8/VP9/AV1/Vorbis/Opus in WebM files are re-encoded),
+	// or "all" (always re-encoded)
+	TranscodeVideos string
+
+	// MaxVideoDuration is the maximum duration in seconds of uploaded videos. If it is 0, the duration is not limited
+	MaxVideoDuration int
+
+	// MaxVideoWidth is the maximum width in pixels of uploaded videos. If it is 0, the width is not limited
+	MaxVideoWidth int
+
+	// MaxVideoHeight is the maximum height in pixels of uploaded videos. If it is 0, the height is not limited
+	MaxVideoHeight int
+
+	// RejectVideoStreamTypes is a list of stream types (as reported by ffprobe, e.g. "subtitle", "attachment", or "data")
+	// that will cause an uploaded video to be rejected. Other streams besides video and audio are removed if the video is
+	// remuxed or re-encoded
+	// Default: ["subtitle", "attachment"]
+	RejectVideoStreamTypes []string
 }
 
 func (uc *UploadConfig) AcceptedExtension(filename string) bool {
@@ -584,7 +735,21 @@ func (uc *UploadConfig) AcceptedExtension(filename string) bool {
 	case ".mp4":
 		fallthrough
 	case ".webm":
+		fallthrough
+	// audio
+	case ".mp3":
+		fallthrough
+	case ".ogg":
+		fallthrough
+	case ".flac":
+		fallthrough
+	case ".opus":
 		return true
+	// documents
+	case ".pdf":
+		if uc.AllowPDFUploads {
+			return true
+		}
 	}
 	// other formats as configured
 	_, ok := uc.AllowOtherExtensions[ext]
@@ -594,6 +759,8 @@ func (uc *UploadConfig) AcceptedExtension(filename string) bool {
 // GetBoardConfig returns the custom configuration for the specified board (if it exists)
 // or the global board configuration if board is an empty string or it doesn't exist
 func GetBoardConfig(board string) *BoardConfig {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
 	if board == "" {
 		return &cfg.BoardConfig
 	}
@@ -613,7 +780,8 @@ func GetBoardConfig(board string) *BoardConfig {
 func GetBoardConfigPath(board string) string {
 	// expected to be called with a board when loading the board configuration file for the first time, may or may not exist
 	// to be created in the same directory as gochan.json when creating or modifying a board
-	if cfg == nil {
+	gcfg := currentConfig()
+	if gcfg == nil {
 		return ""
 	}
 	board = strings.Trim(board, "/")
@@ -626,16 +794,18 @@ func GetBoardConfigPath(board string) string {
 	for p, cPath := range paths {
 		paths[p] = path.Join(path.Dir(cPath), board+"-config.json")
 	}
-	paths = append(paths, path.Join(cfg.DocumentRoot, board, "board.json"))
+	paths = append(paths, path.Join(gcfg.DocumentRoot, board, "board.json"))
 	foundPath := gcutil.FindResource(paths...)
 	if foundPath == "" {
-		return path.Join(path.Dir(cfg.jsonLocation), board+"-config.json")
+		return path.Join(path.Dir(gcfg.jsonLocation), board+"-config.json")
 	}
 	return foundPath
 }
 
 // ReloadBoardConfig updates or establishes the configuration for the given board
 func ReloadBoardConfig(dir string) error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
 	boardCfg := GetBoardConfig(dir)
 	var boardCfgPath string
 	if boardCfg.isGlobal || boardCfg.boardConfigPath == "" {
@@ -649,29 +819,26 @@ func ReloadBoardConfig(dir string) error {
 		// this only happens if this is called with an empty string
 		return errors.New("no board specified")
 	}
-	ba, err := os.ReadFile(boardCfgPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// board doesn't have a custom config, use global config
-			return nil
-		}
-		return err
-	}
-	if err = json.Unmarshal(ba, &boardCfg); err != nil {
-		return err
-	}
-	if err = boardCfg.validateEmbedMatchers(); err != nil {
+	newBoardCfg, err := loadBoardConfigFile(GetBoardConfig(""), boardCfgPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// board doesn't have a custom config, use global config
+		return nil
+	} else if err != nil {
 		return err
 	}
-	boardCfg.isGlobal = false
-	boardCfg.boardConfigPath = boardCfgPath
-	boardConfigs[dir] = *boardCfg
+	configMutex.Lock()
+	boardConfigs[dir] = *newBoardCfg
+	configMutex.Unlock()
 	return nil
 }
 
 // DeleteBoardConfig removes the custom board configuration data, normally should be used
 // when a board is deleted
 func DeleteBoardConfig(dir string) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	configMutex.Lock()
+	defer configMutex.Unlock()
 	delete(boardConfigs, dir)
 }
 
@@ -72,3 +72,24 @@ func TestEqualsBoardConfig(t *testing.T) {
 	}))
 
 }
+
+func TestValidateReportCategories(t *testing.T) {
+	bc := BoardConfig{ReportCategories: []ReportCategory{
+		{ID: "spam", Severity: 1, HideAfter: 5},
+		{ID: "illegal", Name: "Illegal content", Severity: 10, HideAfter: 1, NotifyStaff: true},
+	}}
+	assert.NoError(t, bc.validateReportCategories())
+	assert.Equal(t, "spam", bc.ReportCategories[0].Name, "Name should default to the category ID")
+	if assert.NotNil(t, bc.GetReportCategory("illegal")) {
+		assert.Equal(t, 10, bc.GetReportCategory("illegal").Severity)
+	}
+	assert.Nil(t, bc.GetReportCategory(""))
+	assert.Nil(t, bc.GetReportCategory("nonexistent"))
+
+	bc.ReportCategories = append(bc.ReportCategories, ReportCategory{ID: "spam"})
+	assert.Error(t, bc.validateReportCategories(), "duplicate category IDs should be rejected")
+	bc.ReportCategories = []ReportCategory{{ID: ""}}
+	assert.Error(t, bc.validateReportCategories(), "category IDs are required")
+	bc.ReportCategories = []ReportCategory{{ID: "rules", HideAfter: -1}}
+	assert.Error(t, bc.validateReportCategories())
+}
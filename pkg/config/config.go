@@ -4,9 +4,15 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"io/fs"
+	"maps"
+	"net/netip"
 	"os"
 	"os/exec"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"slices"
@@ -23,13 +29,28 @@ const (
 	DefaultSQLTimeout            = 15
 	DefaultSQLMaxConns           = 10
 	DefaultSQLConnMaxLifetimeMin = 3
+	DefaultShutdownTimeout       = 30
+	DefaultFFmpegTimeout         = 300
+
+	DefaultPluginTimeout       = 5
+	DefaultPluginCallStackSize = 256
+	DefaultPluginRegistrySize  = 5120
+	minPluginRegistrySize      = 128
 
 	GochanVersion = "4.3.0"
+
+	// SecureTripcodeKDF is the SecureTripcodeMode for secure tripcodes derived from TripcodeSecret using Argon2id
+	SecureTripcodeKDF = "kdf"
+	// SecureTripcodeLegacy is the SecureTripcodeMode for the MD5-based secure tripcodes used by older versions of gochan
+	SecureTripcodeLegacy = "legacy"
 )
 
 var (
 	cfg     *GochanConfig
 	cfgPath string
+	// configMutex protects cfg and boardConfigs, which are replaced when the configuration is reloaded. A configuration
+	// isn't modified after it is published, so the pointers returned by the Get*Config functions are safe to keep using
+	configMutex sync.RWMutex
 
 	boardConfigs              = map[string]BoardConfig{}
 	ErrNoMatchingEmbedHandler = errors.New("no matching handler for the embed URL")
@@ -44,12 +65,20 @@ type GochanConfig struct {
 	jsonLocation string
 }
 
+// currentConfig returns the running configuration
+func currentConfig() *GochanConfig {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return cfg
+}
+
 // JSONLocation returns the path to the configuration file, if loaded
 func JSONLocation() string {
-	if cfg == nil {
+	gcfg := currentConfig()
+	if gcfg == nil {
 		return ""
 	}
-	return cfg.jsonLocation
+	return gcfg.jsonLocation
 }
 
 func (gcfg *GochanConfig) updateDeprecatedFields() (changed bool) {
@@ -108,6 +137,40 @@ func (gcfg *GochanConfig) ValidateValues(noWrite ...bool) error {
 		return err
 	}
 
+	if gcfg.TLSCertFile != "" || gcfg.TLSKeyFile != "" {
+		if gcfg.TLSCertFile == "" || gcfg.TLSKeyFile == "" {
+			return &InvalidValueError{Field: "TLSCertFile", Value: gcfg.TLSCertFile, Details: "TLSCertFile and TLSKeyFile must both be set to use TLS"}
+		}
+		if gcfg.UseFastCGI {
+			return &InvalidValueError{Field: "UseFastCGI", Value: gcfg.UseFastCGI, Details: "TLS can't be used with FastCGI"}
+		}
+	}
+	if gcfg.TLSMinVersion == "" {
+		gcfg.TLSMinVersion = defaultGochanConfig.TLSMinVersion
+		changed = true
+	}
+	if gcfg.TLSMinVersion != "1.2" && gcfg.TLSMinVersion != "1.3" {
+		return &InvalidValueError{Field: "TLSMinVersion", Value: gcfg.TLSMinVersion, Details: `must be "1.2" or "1.3"`}
+	}
+	if gcfg.ListenSocketMode == "" {
+		gcfg.ListenSocketMode = defaultGochanConfig.ListenSocketMode
+		changed = true
+	}
+	if _, err = gcfg.ListenSocketFileMode(); err != nil {
+		return &InvalidValueError{Field: "ListenSocketMode", Value: gcfg.ListenSocketMode, Details: "must be an octal file mode, e.g. 0660"}
+	}
+
+	if gcfg.ShutdownTimeoutSeconds < 0 {
+		return &InvalidValueError{Field: "ShutdownTimeoutSeconds", Value: gcfg.ShutdownTimeoutSeconds, Details: "must not be negative"}
+	}
+	if gcfg.FFmpegTimeoutSeconds < 0 {
+		return &InvalidValueError{Field: "FFmpegTimeoutSeconds", Value: gcfg.FFmpegTimeoutSeconds, Details: "must not be negative"}
+	}
+
+	if err = gcfg.parseTrustedProxies(); err != nil {
+		return err
+	}
+
 	if gcfg.DBtype == "postgresql" {
 		gcfg.DBtype = "postgres"
 		changed = true
@@ -137,6 +200,73 @@ func (gcfg *GochanConfig) ValidateValues(noWrite ...bool) error {
 		}
 	}
 
+	if gcfg.LogMaxSizeMB < 0 {
+		return &InvalidValueError{Field: "LogMaxSizeMB", Value: gcfg.LogMaxSizeMB, Details: "must not be negative"}
+	}
+	if gcfg.LogMaxBackups < 0 {
+		return &InvalidValueError{Field: "LogMaxBackups", Value: gcfg.LogMaxBackups, Details: "must not be negative"}
+	}
+	if gcfg.LogRotateInterval != "" {
+		interval, err := durationutil.ParseLongerDuration(gcfg.LogRotateInterval)
+		if err != nil || interval < time.Minute {
+			return &InvalidValueError{Field: "LogRotateInterval", Value: gcfg.LogRotateInterval, Details: "must be a duration of at least one minute, e.g. 1d or 12h"}
+		}
+	}
+	if gcfg.SystemLogger != "" && gcfg.SystemLogger != gcutil.SystemLoggerSyslog && gcfg.SystemLogger != gcutil.SystemLoggerJournald {
+		return &InvalidValueError{Field: "SystemLogger", Value: gcfg.SystemLogger, Details: `valid values are "", "syslog", or "journald"`}
+	}
+
+	for pluginPath, settings := range gcfg.PluginSettings {
+		if settings.TimeoutSeconds < 0 || settings.CallStackSize < 0 || settings.RegistrySize < 0 {
+			return &InvalidValueError{Field: "PluginSettings", Value: pluginPath, Details: "limits must not be negative"}
+		}
+		if settings.RegistrySize > 0 && settings.RegistrySize < minPluginRegistrySize {
+			return &InvalidValueError{Field: "PluginSettings", Value: pluginPath,
+				Details: "RegistrySize must be at least " + strconv.Itoa(minPluginRegistrySize)}
+		}
+	}
+
+	for job, spec := range gcfg.JobSchedules {
+		if _, err = gcutil.ParseSchedule(spec); err != nil {
+			return &InvalidValueError{Field: "JobSchedules", Value: job + ": " + spec, Details: err.Error()}
+		}
+	}
+	if gcfg.BackupsToKeep < 0 {
+		return &InvalidValueError{Field: "BackupsToKeep", Value: gcfg.BackupsToKeep, Details: "must not be negative"}
+	}
+
+	switch gcfg.MetricsAccess {
+	case "":
+		gcfg.MetricsAccess = "local"
+	case "local", "admin", "public":
+	default:
+		return &InvalidValueError{Field: "MetricsAccess", Value: gcfg.MetricsAccess, Details: `valid values are "local", "admin", or "public"`}
+	}
+
+	switch gcfg.HealthAccess {
+	case "":
+		gcfg.HealthAccess = "local"
+	case "local", "admin", "public":
+	default:
+		return &InvalidValueError{Field: "HealthAccess", Value: gcfg.HealthAccess, Details: `valid values are "local", "admin", or "public"`}
+	}
+
+	if gcfg.SecureTripcodeMode == "" {
+		// keep the secure tripcodes of existing sites that were made before TripcodeSecret was added
+		gcfg.SecureTripcodeMode = SecureTripcodeKDF
+		if gcfg.RandomSeed != "" && gcfg.TripcodeSecret == "" {
+			gcfg.SecureTripcodeMode = SecureTripcodeLegacy
+		}
+		changed = true
+	}
+	if gcfg.SecureTripcodeMode != SecureTripcodeKDF && gcfg.SecureTripcodeMode != SecureTripcodeLegacy {
+		return &InvalidValueError{Field: "SecureTripcodeMode", Value: gcfg.SecureTripcodeMode, Details: `must be "kdf" or "legacy"`}
+	}
+	if gcfg.SecureTripcodeMode == SecureTripcodeKDF && gcfg.TripcodeSecret == "" {
+		gcfg.TripcodeSecret = gcutil.RandomString(randomStringSize * 2)
+		changed = true
+	}
+
 	if gcfg.RandomSeed == "" {
 		gcfg.RandomSeed = gcutil.RandomString(randomStringSize)
 		changed = true
@@ -266,6 +396,35 @@ type SystemCriticalConfig struct {
 	// UseFastCGI tells the server to listen on FastCGI instead of HTTP if true
 	UseFastCGI bool
 
+	// ShutdownTimeoutSeconds is the maximum number of seconds to wait for in-flight requests to finish when gochan is
+	// shutting down or handing its listener off to a new process before closing the database and exiting, 0 means no timeout
+	// Default: 30
+	ShutdownTimeoutSeconds int
+
+	// ListenSocket is the path to a Unix domain socket that the server will listen on for HTTP or FastCGI connections
+	// instead of ListenAddress and Port if it is set
+	ListenSocket string
+
+	// ListenSocketMode is the file mode (in octal) of the Unix domain socket set in ListenSocket. The socket will be owned
+	// by Username if it is set, so the web server's user must be able to connect to it using this mode
+	// Default: 0660
+	ListenSocketMode string
+
+	// TLSCertFile is the path to a PEM encoded TLS certificate (including any intermediate certificates). If it and
+	// TLSKeyFile are set, the server will serve HTTPS instead of HTTP. The certificate and key are reloaded when they
+	// are modified, so renewed certificates are used without restarting the server. It can't be used with UseFastCGI
+	TLSCertFile string
+
+	// TLSKeyFile is the path to the PEM encoded private key of the certificate in TLSCertFile
+	TLSKeyFile string
+
+	// TLSMinVersion is the minimum TLS version that the server will accept if TLS is used. Valid values are "1.2" and "1.3"
+	// Default: 1.2
+	TLSMinVersion string
+
+	// DisableHTTP2 disables HTTP/2 support if TLS is used, so that only HTTP/1.1 is served
+	DisableHTTP2 bool
+
 	// DocumentRoot is the path to the directory that contains the served static files
 	DocumentRoot string
 
@@ -279,6 +438,11 @@ type SystemCriticalConfig struct {
 	// but they must be compiled with the same Go version as the server and must be compiled in plugin mode
 	Plugins []string
 
+	// PluginSettings sets the permissions and limits of the plugins in Plugins, using the plugin's path as it appears
+	// in Plugins as the key. Lua plugins that aren't listed can't use the network, the database, or the filesystem
+	// (besides their own directory), and use the default limits. See PluginSettings below
+	PluginSettings map[string]PluginSettings `json:",omitempty"`
+
 	// WebRoot is the base URL path that the server will serve files and generated pages from.
 	// Default: /
 	WebRoot string
@@ -293,6 +457,23 @@ type SystemCriticalConfig struct {
 
 	SQLConfig
 
+	// TrustedProxies is a list of IP addresses and CIDR ranges (e.g. "10.0.0.0/8") of reverse proxies in front of gochan.
+	// The client's IP address is only taken from the Forwarded, X-Forwarded-For, or X-Real-IP headers if the request
+	// comes from one of these or over the Unix socket in ListenSocket, otherwise the address of the connection is used.
+	// Every trusted proxy must overwrite these headers (and X-Forwarded-Proto and CF-Connecting-IP) instead of passing
+	// them on from the client, otherwise clients can choose their own IP address. See
+	// examples/configs/gochan-http.nginx for an example
+	// Default: ["127.0.0.0/8", "::1"]
+	TrustedProxies []string
+
+	// TrustCloudflare adds Cloudflare's IP ranges (read from CloudflareIPsFile) to the trusted proxies, and trusts the
+	// CF-Connecting-IP header from them
+	TrustCloudflare bool
+
+	// CloudflareIPsFile is the path to a file containing Cloudflare's IP ranges, one per line, used if TrustCloudflare
+	// is true. If it is not set, the cloudflare-ips.txt file included with gochan is used
+	CloudflareIPsFile string
+
 	// CheckRequestReferer tells the server to validate the Referer header from requests to prevent CSRF attacks.
 	// Default: true
 	CheckRequestReferer bool
@@ -302,16 +483,124 @@ type SystemCriticalConfig struct {
 	// Default: info
 	LogLevelStr string `json:"LogLevel"`
 
+	// LogMaxSizeMB is the size in megabytes that gochan.log and gochan_access.log can grow to before they are rotated.
+	// 0 means that they aren't rotated by size
+	LogMaxSizeMB int
+
+	// LogRotateInterval is how often gochan.log and gochan_access.log are rotated (e.g. "1d" or "12h"), aligned to UTC.
+	// If it is empty, they aren't rotated by time
+	LogRotateInterval string
+
+	// LogMaxBackups is the number of rotated files to keep for each log file, the oldest ones are deleted when a log
+	// is rotated. 0 means that all of them are kept
+	LogMaxBackups int
+
+	// LogCompress compresses rotated log files with gzip
+	LogCompress bool
+
+	// SystemLogger sends log events (but not access log events) to the system logger as well as gochan.log if it is
+	// set to "syslog" or "journald". This is only supported on Unix-like systems
+	SystemLogger string
+
+	// EnableMetrics serves metrics about gochan's internals at /metrics (relative to WebRoot) in a format that can be
+	// read by Prometheus
+	EnableMetrics bool
+
+	// MetricsAccess determines who can view /metrics if EnableMetrics is true. Valid values are "local", which allows
+	// requests from loopback and private (RFC 1918 or RFC 4193) IP addresses and over the Unix socket in
+	// ListenSocket, "admin", which allows logged in administrators, and "public", which allows everyone. A request
+	// forwarded by a reverse proxy is only local if both the proxy and the client have local addresses
+	// Default: local
+	MetricsAccess string
+
+	// HealthAccess determines who can view the detailed reports of /healthz and /readyz, which include the database
+	// type and host, DocumentRoot, and error messages. Other requests only get the status code. Valid values are the
+	// same as MetricsAccess
+	// Default: local
+	HealthAccess string
+
 	// RandomSeed is a random string used for generating secure tokens. It will be generated if not set and must not be changed
 	RandomSeed string
 
+	// SecureTripcodeMode is the algorithm used for secure tripcodes (Name##password). Valid values are "kdf", which
+	// derives the tripcode from the password and TripcodeSecret using Argon2id, and "legacy", which uses the MD5-based
+	// tripcodes derived from RandomSeed that older versions of gochan used, so that existing secure tripcodes don't change.
+	// If it is not set, it will be set to "legacy" if RandomSeed is already set and TripcodeSecret isn't, or "kdf" otherwise
+	SecureTripcodeMode string
+
+	// TripcodeSecret is a random string used for generating secure tripcodes if SecureTripcodeMode is "kdf". It will be
+	// generated if not set. Changing it changes every secure tripcode
+	TripcodeSecret string
+
 	TimeZone int `json:"-"`
 
 	// ExiftoolPath is the path to the exiftool command. If unset or empty, the system path will be used to find it
 	ExiftoolPath string
 
-	logLevel       zerolog.Level
-	logLevelParsed bool
+	// PdftoppmPath is the path to the pdftoppm command used for rendering PDF thumbnails. If unset or empty, the system path
+	// will be used to find it. If it can't be found, PDF uploads will use a generic thumbnail
+	PdftoppmPath string
+
+	// FFmpegTimeoutSeconds is the maximum number of seconds that ffmpeg and ffprobe can run for while processing an
+	// upload (probing, remuxing or transcoding it, or creating its thumbnail) before they are stopped and the upload is
+	// rejected, 0 means no timeout
+	// Default: 300
+	FFmpegTimeoutSeconds int
+
+	logLevel          zerolog.Level
+	logLevelParsed    bool
+	trustedProxies    []netip.Prefix
+	cloudflareProxies []netip.Prefix
+}
+
+// parseTrustedProxies parses the TrustedProxies ranges, and Cloudflare's ranges from CloudflareIPsFile if
+// TrustCloudflare is true
+func (scc *SystemCriticalConfig) parseTrustedProxies() (err error) {
+	if scc.trustedProxies, err = gcutil.ParseIPPrefixes(scc.TrustedProxies); err != nil {
+		return &InvalidValueError{Field: "TrustedProxies", Value: scc.TrustedProxies, Details: err.Error()}
+	}
+	scc.cloudflareProxies = nil
+	if !scc.TrustCloudflare {
+		return nil
+	}
+	cfIPsFile := scc.CloudflareIPsFile
+	if cfIPsFile == "" {
+		cfIPsFile = gcutil.FindResource(CloudflareIPsSearchPaths...)
+		if cfIPsFile == "" {
+			return &InvalidValueError{Field: "CloudflareIPsFile", Value: "", Details: "unable to find cloudflare-ips.txt, CloudflareIPsFile must be set"}
+		}
+	}
+	if scc.cloudflareProxies, err = gcutil.LoadIPPrefixesFile(cfIPsFile); err != nil {
+		return &InvalidValueError{Field: "CloudflareIPsFile", Value: cfIPsFile, Details: err.Error()}
+	}
+	return nil
+}
+
+// UseTLS returns true if the server should serve HTTPS using TLSCertFile and TLSKeyFile
+func (scc *SystemCriticalConfig) UseTLS() bool {
+	return scc.TLSCertFile != "" && scc.TLSKeyFile != ""
+}
+
+// ListenSocketFileMode returns the parsed file mode of the Unix domain socket in ListenSocket
+func (scc *SystemCriticalConfig) ListenSocketFileMode() (fs.FileMode, error) {
+	mode, err := strconv.ParseUint(scc.ListenSocketMode, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return fs.FileMode(mode), nil
+}
+
+// LogRotateOptions returns the options for rotating the log files
+func (scc *SystemCriticalConfig) LogRotateOptions() gcutil.RotateOptions {
+	options := gcutil.RotateOptions{
+		MaxSize:    int64(scc.LogMaxSizeMB) * 1024 * 1024,
+		MaxBackups: scc.LogMaxBackups,
+		Compress:   scc.LogCompress,
+	}
+	if scc.LogRotateInterval != "" {
+		options.Interval, _ = durationutil.ParseLongerDuration(scc.LogRotateInterval)
+	}
+	return options
 }
 
 // LogLevel returns the minimum log event level to write to the log file
@@ -391,6 +680,24 @@ type SiteConfig struct {
 	// Default: 16
 	FingerprintHashLength int
 
+	// SimilarImageTolerance is the default maximum number of differing bits between two image fingerprints for the
+	// images to be considered similar when searching for similar images or banning an image
+	// Default: 10
+	SimilarImageTolerance int
+
+	// JobSchedules overrides the schedules of background jobs by job name. A schedule is a cron expression with five
+	// fields (minute, hour, day of month, month, and day of week), a descriptor like @hourly or @daily, @every followed
+	// by a duration like 15m, or "off" to disable the job. Jobs that aren't listed use their default schedules
+	JobSchedules map[string]string
+
+	// BackupDir is the directory that the rotateBackups job creates backup archives in
+	BackupDir string
+
+	// BackupsToKeep is the number of backup archives in BackupDir that the rotateBackups job keeps before deleting the
+	// oldest ones. If it is 0, all of them are kept
+	// Default: 7
+	BackupsToKeep int
+
 	cookieMaxAgeDuration time.Duration
 }
 
@@ -402,6 +709,46 @@ func (sc *SiteConfig) CookieMaxAgeDuration() (time.Duration, error) {
 	return sc.cookieMaxAgeDuration, err
 }
 
+// PluginSettings sets what a plugin is allowed to do. Each Lua plugin is loaded in its own Lua state, so it can't
+// affect other plugins, and the permissions only apply to Lua plugins except for Disabled
+type PluginSettings struct {
+	// Disabled keeps the plugin from being loaded. It can also be changed by an administrator at /manage/plugins
+	Disabled bool `json:",omitempty"`
+
+	// AllowNetwork lets the plugin use the http module to make HTTP requests
+	AllowNetwork bool `json:",omitempty"`
+
+	// AllowSQL lets the plugin use the gcsql module to run SQL queries, call the methods of database objects passed to
+	// it (like posts in event data), and see the database settings in config.system_critical_config()
+	AllowSQL bool `json:",omitempty"`
+
+	// FilesystemPaths is a list of files and directories that the plugin can read and write with the io and os modules,
+	// dofile, loadfile, and functions that take file paths like gctemplates.load_template. The plugin's own directory
+	// can always be read
+	FilesystemPaths []string `json:",omitempty"`
+
+	// TimeoutSeconds is the maximum number of seconds that loading the plugin, or a single call to one of its event
+	// handlers, jobs, or other functions, can run, including the time spent waiting for another call to the plugin to
+	// finish. HTTP requests made by the plugin also time out after this long
+	// Default: 5
+	TimeoutSeconds int `json:",omitempty"`
+
+	// CallStackSize is the maximum depth of Lua function calls in the plugin
+	// Default: 256
+	CallStackSize int `json:",omitempty"`
+
+	// RegistrySize is the maximum number of values that can be on the plugin's Lua data stack. It and CallStackSize
+	// only limit the size of the plugin's stack. The memory used by a plugin can't be limited, so only plugins that are
+	// trusted not to use too much of it should be loaded
+	// Default: 5120
+	RegistrySize int `json:",omitempty"`
+}
+
+// Timeout returns the maximum duration of a call to the plugin
+func (ps *PluginSettings) Timeout() time.Duration {
+	return time.Duration(ps.TimeoutSeconds) * time.Second
+}
+
 type CaptchaConfig struct {
 	// Type is the type of captcha to use. Currently only "hcaptcha" is supported
 	Type string
@@ -457,31 +804,75 @@ func GetDefaultConfig() *GochanConfig {
 }
 
 func WriteConfig(path ...string) error {
-	if cfg == nil {
+	gcfg := currentConfig()
+	if gcfg == nil {
 		return errors.New("configuration not loaded")
 	}
 	if len(path) > 0 {
-		cfg.jsonLocation = path[0]
+		gcfg.jsonLocation = path[0]
 	}
-	if cfg.jsonLocation == "" {
+	if gcfg.jsonLocation == "" {
 		return errors.New("configuration file path not set")
 	}
-	return cfg.Write()
+	return gcfg.Write()
 }
 
 // GetSQLConfig returns SQL configuration info. It returns a value instead of a a pointer to it
 // because it is not safe to edit while Gochan is running
 func GetSQLConfig() SQLConfig {
-	return cfg.SQLConfig
+	return currentConfig().SQLConfig
 }
 
 // GetSystemCriticalConfig returns system-critical configuration options like listening IP
 // It returns a value instead of a pointer, because it is not usually safe to edit while Gochan is running.
 func GetSystemCriticalConfig() *SystemCriticalConfig {
-	return &cfg.SystemCriticalConfig
+	return &currentConfig().SystemCriticalConfig
+}
+
+// GetPluginSettings returns the settings of the plugin with the given path (as it appears in Plugins), with the
+// default limits set if they aren't in the configuration
+func GetPluginSettings(pluginPath string) PluginSettings {
+	settings := currentConfig().PluginSettings[pluginPath]
+	settings.FilesystemPaths = slices.Clone(settings.FilesystemPaths)
+	if settings.TimeoutSeconds == 0 {
+		settings.TimeoutSeconds = DefaultPluginTimeout
+	}
+	if settings.CallStackSize == 0 {
+		settings.CallStackSize = DefaultPluginCallStackSize
+	}
+	if settings.RegistrySize == 0 {
+		settings.RegistrySize = DefaultPluginRegistrySize
+	}
+	return settings
+}
+
+// SetPluginDisabled sets the Disabled field of the plugin's settings and writes the configuration to gochan.json so
+// that it is kept after gochan restarts. The setting is changed even if the configuration couldn't be written
+func SetPluginDisabled(pluginPath string, disabled bool) error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+	// the running configuration isn't modified, so the change is made to a copy that replaces it
+	newCfg := new(GochanConfig)
+	*newCfg = *currentConfig()
+	newCfg.PluginSettings = maps.Clone(newCfg.PluginSettings)
+	if newCfg.PluginSettings == nil {
+		newCfg.PluginSettings = make(map[string]PluginSettings)
+	}
+	settings := newCfg.PluginSettings[pluginPath]
+	settings.Disabled = disabled
+	if reflect.ValueOf(settings).IsZero() {
+		// plugins without any settings don't need to be in gochan.json
+		delete(newCfg.PluginSettings, pluginPath)
+	} else {
+		newCfg.PluginSettings[pluginPath] = settings
+	}
+	configMutex.Lock()
+	cfg = newCfg
+	configMutex.Unlock()
+	return WriteConfig()
 }
 
 // GetSiteConfig returns the global site configuration (site name, slogan, etc)
 func GetSiteConfig() *SiteConfig {
-	return &cfg.SiteConfig
+	return &currentConfig().SiteConfig
 }
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
 	"github.com/gochan-org/gochan/pkg/gcutil/testutil"
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -35,6 +37,84 @@ func TestValidateValues(t *testing.T) {
 	cfg.CookieMaxAge = "1y"
 	assert.NoError(t, cfg.ValidateValues())
 
+	useFastCGI := cfg.UseFastCGI
+	cfg.UseFastCGI = false
+	cfg.TLSCertFile = "cert.pem"
+	assert.Error(t, cfg.ValidateValues(), "TLSKeyFile must be set if TLSCertFile is set")
+	cfg.TLSKeyFile = "key.pem"
+	assert.NoError(t, cfg.ValidateValues(true))
+	cfg.UseFastCGI = true
+	assert.Error(t, cfg.ValidateValues(), "TLS can't be used with FastCGI")
+	cfg.UseFastCGI = useFastCGI
+	cfg.TLSCertFile = ""
+	cfg.TLSKeyFile = ""
+	cfg.TLSMinVersion = "1.0"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.TLSMinVersion = "1.2"
+	cfg.ListenSocketMode = "rw-rw----"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.ListenSocketMode = "0660"
+	assert.NoError(t, cfg.ValidateValues(true))
+
+	cfg.TrustedProxies = []string{"10.0.0.0/33"}
+	assert.Error(t, cfg.ValidateValues())
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "::1"}
+	cfg.TrustCloudflare = true
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.Len(t, cfg.trustedProxies, 2)
+	assert.NotEmpty(t, cfg.cloudflareProxies, "the included cloudflare-ips.txt should be loaded")
+	cfg.CloudflareIPsFile = "missing-cloudflare-ips.txt"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.CloudflareIPsFile = ""
+	cfg.TrustCloudflare = false
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.Empty(t, cfg.cloudflareProxies)
+
+	cfg.LogMaxSizeMB = -1
+	assert.Error(t, cfg.ValidateValues())
+	cfg.LogMaxSizeMB = 50
+	cfg.LogRotateInterval = "10s"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.LogRotateInterval = "1d"
+	cfg.LogMaxBackups = 5
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.Equal(t, gcutil.RotateOptions{MaxSize: 50 * 1024 * 1024, Interval: 24 * time.Hour, MaxBackups: 5},
+		cfg.LogRotateOptions())
+	cfg.SystemLogger = "eventlog"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.SystemLogger = ""
+
+	cfg.MetricsAccess = "everyone"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.MetricsAccess = ""
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.Equal(t, "local", cfg.MetricsAccess)
+
+	cfg.HealthAccess = "everyone"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.HealthAccess = ""
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.Equal(t, "local", cfg.HealthAccess)
+
+	cfg.JobSchedules = map[string]string{"expireBans": "@daily", "purgeDeletedPosts": "every day"}
+	assert.Error(t, cfg.ValidateValues())
+	cfg.JobSchedules["purgeDeletedPosts"] = "30 3 * * *"
+	assert.NoError(t, cfg.ValidateValues(true))
+	cfg.BackupsToKeep = -1
+	assert.Error(t, cfg.ValidateValues())
+	cfg.BackupsToKeep = 7
+
+	cfg.SecureTripcodeMode = "md5"
+	assert.Error(t, cfg.ValidateValues())
+	cfg.SecureTripcodeMode = ""
+	cfg.TripcodeSecret = ""
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.Equal(t, SecureTripcodeLegacy, cfg.SecureTripcodeMode, "existing sites should keep their secure tripcodes")
+	assert.Empty(t, cfg.TripcodeSecret)
+	cfg.SecureTripcodeMode = SecureTripcodeKDF
+	assert.NoError(t, cfg.ValidateValues(true))
+	assert.NotEmpty(t, cfg.TripcodeSecret, "TripcodeSecret should be generated")
+
 	SetTestDBConfig("not a valid driver", "127.0.0.1", "gochan", "gochan", "", "")
 	assert.Error(t, cfg.ValidateValues())
 	SetTestDBConfig("postgresql", "127.0.0.1", "gochan", "gochan", "", "")
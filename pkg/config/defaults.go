@@ -12,8 +12,15 @@ var (
 				DBMaxIdleConnections: DefaultSQLMaxConns,
 				DBConnMaxLifetimeMin: DefaultSQLConnMaxLifetimeMin,
 			},
-			CheckRequestReferer: true,
-			logLevel:            zerolog.InfoLevel,
+			ShutdownTimeoutSeconds: DefaultShutdownTimeout,
+			FFmpegTimeoutSeconds:   DefaultFFmpegTimeout,
+			ListenSocketMode:       "0660",
+			TLSMinVersion:          "1.2",
+			CheckRequestReferer:    true,
+			TrustedProxies:         []string{"127.0.0.0/8", "::1"},
+			MetricsAccess:          "local",
+			HealthAccess:           "local",
+			logLevel:               zerolog.InfoLevel,
 		},
 		SiteConfig: SiteConfig{
 			FirstPage:             []string{"index.html", "firstrun.html", "1.html"},
@@ -25,6 +32,8 @@ var (
 			MaxRecentPosts:        15,
 			EnableAppeals:         true,
 			FingerprintHashLength: 16,
+			SimilarImageTolerance: 10,
+			BackupsToKeep:         7,
 		},
 		BoardConfig: BoardConfig{
 			MaxThreads:          200,
@@ -68,8 +77,11 @@ var (
 				ThumbHeightReply:   125,
 				ThumbWidthCatalog:  50,
 				ThumbHeightCatalog: 50,
+
+				RejectVideoStreamTypes: []string{"subtitle", "attachment"},
 			},
 			DateTimeFormat:         "Mon, January 02, 2006 3:04:05 PM",
+			PosterIDRotation:       "thread",
 			EnableSpoileredImages:  true,
 			EnableSpoileredThreads: true,
 			Worksafe:               true,
@@ -77,7 +89,9 @@ var (
 				NewThread:  30,
 				Reply:      7,
 				ImageReply: 7,
+				Report:     10,
 			},
+			MaxReportsPerHour: 20,
 			RenderURLsAsLinks: true,
 			EnableCatalog:     true,
 			isGlobal:          true,
@@ -5,15 +5,38 @@ import (
 	luar "layeh.com/gopher-luar"
 )
 
+// luaSystemCriticalConfig returns a copy of the system critical configuration for Lua plugins, without the secrets
+// used for generating tokens and secure tripcodes
+func luaSystemCriticalConfig() *SystemCriticalConfig {
+	systemCritical := *GetSystemCriticalConfig()
+	systemCritical.RandomSeed = ""
+	systemCritical.TripcodeSecret = ""
+	return &systemCritical
+}
+
+// luaSiteConfig returns a copy of the site configuration for Lua plugins, without the captcha service's secret key or
+// the GeoIP options, which may contain license keys. A GeoIP handler registered by a plugin gets the options when it
+// is initialized
+func luaSiteConfig() *SiteConfig {
+	siteCfg := *GetSiteConfig()
+	if siteCfg.Captcha != nil {
+		captcha := *siteCfg.Captcha
+		captcha.AccountSecret = ""
+		siteCfg.Captcha = &captcha
+	}
+	siteCfg.GeoIPOptions = nil
+	return &siteCfg
+}
+
 func PreloadModule(l *lua.LState) int {
 	t := l.NewTable()
 	l.SetFuncs(t, map[string]lua.LGFunction{
 		"system_critical_config": func(l *lua.LState) int {
-			l.Push(luar.New(l, &cfg.SystemCriticalConfig))
+			l.Push(luar.New(l, luaSystemCriticalConfig()))
 			return 1
 		},
 		"site_config": func(l *lua.LState) int {
-			l.Push(luar.New(l, &cfg.SiteConfig))
+			l.Push(luar.New(l, luaSiteConfig()))
 			return 1
 		},
 		"board_config": func(l *lua.LState) int {
@@ -6,4 +6,5 @@ const (
 
 var (
 	StandardConfigSearchPaths = []string{"gochan.json", "/usr/local/etc/gochan/gochan.json", "/opt/homebrew/etc/gochan/gochan.json", "/etc/gochan/gochan.json"}
+	CloudflareIPsSearchPaths  = []string{"cloudflare-ips.txt", "examples/configs/cloudflare-ips.txt", "/usr/local/share/gochan/examples/configs/cloudflare-ips.txt", "/opt/homebrew/share/gochan/examples/configs/cloudflare-ips.txt"}
 )
@@ -8,4 +8,5 @@ const (
 
 var (
 	StandardConfigSearchPaths = []string{"gochan.json", "/usr/local/etc/gochan/gochan.json", "/etc/gochan/gochan.json"}
+	CloudflareIPsSearchPaths  = []string{"cloudflare-ips.txt", "examples/configs/cloudflare-ips.txt", "/usr/local/share/gochan/examples/configs/cloudflare-ips.txt", "/usr/share/gochan/examples/configs/cloudflare-ips.txt"}
 )
@@ -6,4 +6,5 @@ const (
 
 var (
 	StandardConfigSearchPaths []string = []string{"gochan.json"}
+	CloudflareIPsSearchPaths  []string = []string{"cloudflare-ips.txt", "examples/configs/cloudflare-ips.txt"}
 )
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+var (
+	ErrConfigNotLoaded = errors.New("configuration has not been loaded")
+
+	reloadMutex sync.Mutex
+)
+
+// keepRestartRequiredValue sets newValue to oldValue if they are different and adds field to changed, since the
+// new value can't be applied without restarting gochan
+func keepRestartRequiredValue[T comparable](field string, oldValue T, newValue *T, changed *[]string) {
+	if oldValue != *newValue {
+		*newValue = oldValue
+		*changed = append(*changed, field)
+	}
+}
+
+// keepRestartRequiredValues restores the values in newCfg that are only used on startup (listener, database,
+// file paths, etc) from oldCfg, and returns the names of the fields that were changed in the configuration file
+func keepRestartRequiredValues(oldCfg, newCfg *GochanConfig) []string {
+	var changed []string
+	keepRestartRequiredValue("ListenAddress", oldCfg.ListenAddress, &newCfg.ListenAddress, &changed)
+	keepRestartRequiredValue("Port", oldCfg.Port, &newCfg.Port, &changed)
+	keepRestartRequiredValue("UseFastCGI", oldCfg.UseFastCGI, &newCfg.UseFastCGI, &changed)
+	keepRestartRequiredValue("ListenSocket", oldCfg.ListenSocket, &newCfg.ListenSocket, &changed)
+	keepRestartRequiredValue("ListenSocketMode", oldCfg.ListenSocketMode, &newCfg.ListenSocketMode, &changed)
+	keepRestartRequiredValue("TLSCertFile", oldCfg.TLSCertFile, &newCfg.TLSCertFile, &changed)
+	keepRestartRequiredValue("TLSKeyFile", oldCfg.TLSKeyFile, &newCfg.TLSKeyFile, &changed)
+	keepRestartRequiredValue("TLSMinVersion", oldCfg.TLSMinVersion, &newCfg.TLSMinVersion, &changed)
+	keepRestartRequiredValue("DisableHTTP2", oldCfg.DisableHTTP2, &newCfg.DisableHTTP2, &changed)
+	keepRestartRequiredValue("DocumentRoot", oldCfg.DocumentRoot, &newCfg.DocumentRoot, &changed)
+	keepRestartRequiredValue("LogDir", oldCfg.LogDir, &newCfg.LogDir, &changed)
+	keepRestartRequiredValue("LogMaxSizeMB", oldCfg.LogMaxSizeMB, &newCfg.LogMaxSizeMB, &changed)
+	keepRestartRequiredValue("LogRotateInterval", oldCfg.LogRotateInterval, &newCfg.LogRotateInterval, &changed)
+	keepRestartRequiredValue("LogMaxBackups", oldCfg.LogMaxBackups, &newCfg.LogMaxBackups, &changed)
+	keepRestartRequiredValue("LogCompress", oldCfg.LogCompress, &newCfg.LogCompress, &changed)
+	keepRestartRequiredValue("SystemLogger", oldCfg.SystemLogger, &newCfg.SystemLogger, &changed)
+	keepRestartRequiredValue("WebRoot", oldCfg.WebRoot, &newCfg.WebRoot, &changed)
+	keepRestartRequiredValue("Username", oldCfg.Username, &newCfg.Username, &changed)
+	keepRestartRequiredValue("RandomSeed", oldCfg.RandomSeed, &newCfg.RandomSeed, &changed)
+	if !slices.Equal(oldCfg.Plugins, newCfg.Plugins) {
+		newCfg.Plugins = oldCfg.Plugins
+		changed = append(changed, "Plugins")
+	}
+	if !reflect.DeepEqual(oldCfg.PluginSettings, newCfg.PluginSettings) {
+		newCfg.PluginSettings = oldCfg.PluginSettings
+		changed = append(changed, "PluginSettings")
+	}
+
+	keepRestartRequiredValue("DBtype", oldCfg.DBtype, &newCfg.DBtype, &changed)
+	keepRestartRequiredValue("DBhost", oldCfg.DBhost, &newCfg.DBhost, &changed)
+	keepRestartRequiredValue("DBname", oldCfg.DBname, &newCfg.DBname, &changed)
+	keepRestartRequiredValue("DBusername", oldCfg.DBusername, &newCfg.DBusername, &changed)
+	keepRestartRequiredValue("DBpassword", oldCfg.DBpassword, &newCfg.DBpassword, &changed)
+	keepRestartRequiredValue("DBprefix", oldCfg.DBprefix, &newCfg.DBprefix, &changed)
+	keepRestartRequiredValue("DBTimeoutSeconds", oldCfg.DBTimeoutSeconds, &newCfg.DBTimeoutSeconds, &changed)
+	keepRestartRequiredValue("DBMaxOpenConnections", oldCfg.DBMaxOpenConnections, &newCfg.DBMaxOpenConnections, &changed)
+	keepRestartRequiredValue("DBMaxIdleConnections", oldCfg.DBMaxIdleConnections, &newCfg.DBMaxIdleConnections, &changed)
+	keepRestartRequiredValue("DBConnMaxLifetimeMin", oldCfg.DBConnMaxLifetimeMin, &newCfg.DBConnMaxLifetimeMin, &changed)
+	return changed
+}
+
+// loadBoardConfigFile reads and validates the board configuration file at boardCfgPath, using globalBoardCfg as the
+// base for values that aren't set in it. The global configuration is copied through JSON so that the board's
+// configuration doesn't share any slices or maps with the running configuration, which may be in use
+func loadBoardConfigFile(globalBoardCfg *BoardConfig, boardCfgPath string) (*BoardConfig, error) {
+	ba, err := os.ReadFile(boardCfgPath)
+	if err != nil {
+		return nil, err
+	}
+	globalJSON, err := json.Marshal(globalBoardCfg)
+	if err != nil {
+		return nil, err
+	}
+	var boardCfg BoardConfig
+	if err = json.Unmarshal(globalJSON, &boardCfg); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(ba, &boardCfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", boardCfgPath, err)
+	}
+	if err = boardCfg.validateBoardConfig(); err != nil {
+		return nil, fmt.Errorf("invalid board configuration in %s: %w", boardCfgPath, err)
+	}
+	boardCfg.boardConfigPath = boardCfgPath
+	return &boardCfg, nil
+}
+
+// reloadBoardConfigs re-reads the configuration files of boards with custom configurations, using
+// globalBoardCfg as the base for values that aren't set in the board's file
+func reloadBoardConfigs(globalBoardCfg *BoardConfig) (map[string]BoardConfig, error) {
+	newBoardConfigs := make(map[string]BoardConfig, len(boardConfigs))
+	for dir, boardCfg := range boardConfigs {
+		if boardCfg.boardConfigPath == "" {
+			continue
+		}
+		newBoardCfg, err := loadBoardConfigFile(globalBoardCfg, boardCfg.boardConfigPath)
+		if errors.Is(err, os.ErrNotExist) {
+			// board's configuration file was removed, use the global config
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		newBoardConfigs[dir] = *newBoardCfg
+	}
+	return newBoardConfigs, nil
+}
+
+// ReloadConfig re-reads and validates gochan.json and board configuration files, and replaces the running
+// configuration if they are valid. If there are any errors, the running configuration is left unchanged.
+// Settings that are only used on startup (ListenAddress, Port, database settings, etc) keep their current
+// values, and the names of any that were changed in gochan.json are returned so that the caller can report
+// that a restart is required for them to take effect
+func ReloadConfig() (restartRequired []string, err error) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	if cfg == nil || cfg.jsonLocation == "" {
+		return nil, ErrConfigNotLoaded
+	}
+	newCfg, err := readConfigFile(cfg.jsonLocation)
+	if err != nil {
+		return nil, err
+	}
+	if err = newCfg.ValidateValues(true); err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(newCfg.TemplateDir); err != nil {
+		return nil, err
+	}
+	newCfg.LogDir = gcutil.FindResource(newCfg.LogDir, "log", "/var/log/gochan/")
+	newCfg.setDerivedValues()
+	restartRequired = keepRestartRequiredValues(cfg, newCfg)
+
+	newBoardConfigs, err := reloadBoardConfigs(&newCfg.BoardConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	configMutex.Lock()
+	cfg = newCfg
+	boardConfigs = newBoardConfigs
+	configMutex.Unlock()
+	gcutil.SetTrustedProxies(newCfg.trustedProxies, newCfg.cloudflareProxies)
+	return restartRequired, nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadConfig(t *testing.T) {
+	basePath := t.TempDir()
+
+	assert.NoError(t, initializeExampleConfig(t, basePath, func(c *GochanConfig) {
+		boardCfgModifyReadCfgCallback(t, c, basePath)
+	}))
+	defer resetTestConfig(t)
+	assert.NoError(t, gcutil.InitLogs(path.Join(basePath, "logs"), &gcutil.LogOptions{
+		LogLevel: cfg.logLevel,
+	}))
+	assert.NoError(t, ReloadBoardConfig("changed"))
+	oldPort := cfg.Port
+	oldDBhost := cfg.DBhost
+
+	newCfg := *cfg
+	newCfg.SiteName = "TestReloadConfig"
+	newCfg.DefaultStyle = "reloaded.css"
+	newCfg.Port = oldPort + 1
+	newCfg.DBhost = path.Join(basePath, "other.db")
+	writeJsonFile(t, cfg.jsonLocation, newCfg)
+	changedBoardCfg := newCfg.BoardConfig
+	changedBoardCfg.DefaultStyle = "changed.css"
+	writeJsonFile(t, path.Join(basePath, "changed-config.json"), changedBoardCfg)
+
+	restartRequired, err := ReloadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Port", "DBhost"}, restartRequired)
+	assert.Equal(t, "TestReloadConfig", GetSiteConfig().SiteName)
+	assert.Equal(t, "reloaded.css", GetBoardConfig("").DefaultStyle)
+	assert.Equal(t, "changed.css", GetBoardConfig("changed").DefaultStyle)
+	assert.Equal(t, oldPort, GetSystemCriticalConfig().Port)
+	assert.Equal(t, oldDBhost, GetSystemCriticalConfig().DBhost)
+
+	// an invalid configuration should be rejected without changing the running configuration
+	newCfg.SiteHost = ""
+	newCfg.SiteName = "Invalid"
+	writeJsonFile(t, cfg.jsonLocation, newCfg)
+	_, err = ReloadConfig()
+	assert.Error(t, err)
+	assert.Equal(t, "TestReloadConfig", GetSiteConfig().SiteName)
+}
+
+func TestReloadConfigConcurrentReads(t *testing.T) {
+	basePath := t.TempDir()
+
+	assert.NoError(t, initializeExampleConfig(t, basePath, func(c *GochanConfig) {
+		boardCfgModifyReadCfgCallback(t, c, basePath)
+	}))
+	defer resetTestConfig(t)
+	assert.NoError(t, ReloadBoardConfig("changed"))
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = GetBoardConfig("changed").DefaultStyle
+				_ = GetSiteConfig().SiteName
+			}
+		}
+	}()
+	for range 10 {
+		_, err := ReloadConfig()
+		assert.NoError(t, err)
+		assert.NoError(t, ReloadBoardConfig("changed"))
+	}
+	close(done)
+	wg.Wait()
+
+	// board configurations are validated the same way when they are reloaded
+	invalidBoardCfg := GetBoardConfig("changed")
+	invalidBoardCfg.PosterIDRotation = "hourly"
+	writeJsonFile(t, path.Join(basePath, "changed-config.json"), invalidBoardCfg)
+	assert.Error(t, ReloadBoardConfig("changed"))
+	_, err := ReloadConfig()
+	assert.Error(t, err)
+	assert.Equal(t, "thread", GetBoardConfig("changed").PosterIDRotation)
+}
+
+func TestSetPluginDisabled(t *testing.T) {
+	basePath := t.TempDir()
+
+	assert.NoError(t, initializeExampleConfig(t, basePath, func(c *GochanConfig) {
+		boardCfgModifyReadCfgCallback(t, c, basePath)
+	}))
+	defer resetTestConfig(t)
+	oldCfg := currentConfig()
+
+	assert.NoError(t, SetPluginDisabled("plugin.lua", true))
+	assert.True(t, GetPluginSettings("plugin.lua").Disabled)
+	assert.NotContains(t, oldCfg.PluginSettings, "plugin.lua", "the previously published configuration shouldn't be modified")
+
+	// the setting is written to gochan.json
+	_, err := ReloadConfig()
+	assert.NoError(t, err)
+	assert.True(t, GetPluginSettings("plugin.lua").Disabled)
+
+	assert.NoError(t, SetPluginDisabled("plugin.lua", false))
+	assert.False(t, GetPluginSettings("plugin.lua").Disabled)
+	assert.NotContains(t, currentConfig().PluginSettings, "plugin.lua")
+}
@@ -68,6 +68,8 @@ func SetBoardConfig(board string, boardCfg *BoardConfig) error {
 	}
 
 	boardCfg.isGlobal = board == ""
+	configMutex.Lock()
+	defer configMutex.Unlock()
 	if board == "" {
 		boardCfgPath := cfg.BoardConfig.boardConfigPath
 		cfg.BoardConfig = *boardCfg
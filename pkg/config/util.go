@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"testing"
@@ -88,7 +89,7 @@ func GetUser() (int, int) {
 }
 
 func TakeOwnership(fp string) (err error) {
-	if runtime.GOOS == "windows" || fp == "" || cfg.Username == "" {
+	if runtime.GOOS == "windows" || fp == "" || currentConfig().Username == "" {
 		// Chown returns an error in Windows so skip it, also skip if Username isn't set
 		// because otherwise it'll think we want to switch to uid and gid 0 (root)
 		return nil
@@ -97,7 +98,7 @@ func TakeOwnership(fp string) (err error) {
 }
 
 func TakeOwnershipOfFile(f *os.File) error {
-	if runtime.GOOS == "windows" || f == nil || cfg.Username == "" {
+	if runtime.GOOS == "windows" || f == nil || currentConfig().Username == "" {
 		// Chown returns an error in Windows so skip it, also skip if Username isn't set
 		// because otherwise it'll think we want to switch to uid and gid 0 (root)
 		return nil
@@ -136,7 +137,7 @@ func loadConfig() (err error) {
 		cfg.SiteHost = "127.0.0.1"
 		cfg.RandomSeed = "test"
 		cfg.SiteSlogan = "Gochan testing"
-		cfg.Cooldowns = BoardCooldowns{0, 0, 0}
+		cfg.Cooldowns = BoardCooldowns{0, 0, 0, 0}
 		cfg.BanColors = map[string]string{
 			"admin":   "#0000A0",
 			"somemod": "blue",
@@ -148,22 +149,77 @@ func loadConfig() (err error) {
 		return ErrGochanConfigNotFound
 	}
 	gcutil.LogDebug().Str("configPath", cfgPath).Msg("Found configuration file")
+	loadedCfg, err := readConfigFile(cfgPath)
+	if err != nil {
+		return err
+	}
+	cfg = loadedCfg
+	return nil
+}
 
-	cfgBytes, err := os.ReadFile(cfgPath)
+// readConfigFile parses the configuration file at cfgFilePath, using the default configuration for any
+// values that aren't set in it
+func readConfigFile(cfgFilePath string) (*GochanConfig, error) {
+	gcfg := new(GochanConfig)
+	*gcfg = *defaultGochanConfig
+	cfgBytes, err := os.ReadFile(cfgFilePath)
 	if err != nil {
-		return fmt.Errorf("error reading %s: %w", cfgPath, err)
+		return nil, fmt.Errorf("error reading %s: %w", cfgFilePath, err)
 	}
 
-	if err = json.Unmarshal(cfgBytes, cfg); err != nil {
+	if err = json.Unmarshal(cfgBytes, gcfg); err != nil {
 		var unmarshalTypeError *json.UnmarshalTypeError
 		if errors.As(err, &unmarshalTypeError) {
-			return fmt.Errorf("invalid field type %s in %s: expected %s, found %s",
-				unmarshalTypeError.Field, cfgPath, unmarshalTypeError.Type, unmarshalTypeError.Value)
+			return nil, fmt.Errorf("invalid field type %s in %s: expected %s, found %s",
+				unmarshalTypeError.Field, cfgFilePath, unmarshalTypeError.Type, unmarshalTypeError.Value)
 		}
-		return fmt.Errorf("error parsing %s: %w", cfgPath, err)
+		return nil, fmt.Errorf("error parsing %s: %w", cfgFilePath, err)
 	}
-	cfg.jsonLocation = cfgPath
-	return nil
+	gcfg.jsonLocation = cfgFilePath
+	return gcfg, nil
+}
+
+// CheckConfigFile reads and validates the configuration file at cfgFilePath (or the one returned by
+// GetGochanJSONPath if it is empty) and any board configuration files in the same directory, without applying them or
+// writing default values to them. It returns the path of the configuration file that was checked
+func CheckConfigFile(cfgFilePath string) (string, error) {
+	if cfgFilePath == "" {
+		if cfgFilePath = GetGochanJSONPath(); cfgFilePath == "" {
+			return "", ErrGochanConfigNotFound
+		}
+	}
+	gcfg, err := readConfigFile(cfgFilePath)
+	if err != nil {
+		return cfgFilePath, err
+	}
+	if err = gcfg.ValidateValues(true); err != nil {
+		return cfgFilePath, err
+	}
+	if _, err = os.Stat(gcfg.DocumentRoot); err != nil {
+		return cfgFilePath, err
+	}
+	if _, err = os.Stat(gcfg.TemplateDir); err != nil {
+		return cfgFilePath, err
+	}
+
+	boardCfgPaths, err := filepath.Glob(path.Join(path.Dir(cfgFilePath), "*-config.json"))
+	if err != nil {
+		return cfgFilePath, err
+	}
+	for _, boardCfgPath := range boardCfgPaths {
+		boardCfg := gcfg.BoardConfig
+		ba, err := os.ReadFile(boardCfgPath)
+		if err != nil {
+			return cfgFilePath, err
+		}
+		if err = json.Unmarshal(ba, &boardCfg); err != nil {
+			return cfgFilePath, fmt.Errorf("error parsing %s: %w", boardCfgPath, err)
+		}
+		if err = boardCfg.validateEmbedMatchers(); err != nil {
+			return cfgFilePath, fmt.Errorf("invalid embed matcher in %s: %w", boardCfgPath, err)
+		}
+	}
+	return cfgFilePath, nil
 }
 
 // InitConfig loads and parses gochan.json on startup and verifies its contents
@@ -210,33 +266,39 @@ func InitConfig() (err error) {
 	}
 
 	cfg.LogDir = gcutil.FindResource(cfg.LogDir, "log", "/var/log/gochan/")
+	cfg.setDerivedValues()
+	gcutil.SetTrustedProxies(cfg.trustedProxies, cfg.cloudflareProxies)
+	initialSetupStatus = InitialSetupComplete
+	return nil
+}
 
-	if cfg.Port == 0 {
-		cfg.Port = 80
+// setDerivedValues sets defaults for unset values that can't be set in defaultGochanConfig and normalizes
+// the web root and time zone
+func (gcfg *GochanConfig) setDerivedValues() {
+	if gcfg.Port == 0 {
+		gcfg.Port = 80
 	}
 
-	if len(cfg.FirstPage) == 0 {
-		cfg.FirstPage = []string{"index.html", "1.html", "firstrun.html"}
+	if len(gcfg.FirstPage) == 0 {
+		gcfg.FirstPage = []string{"index.html", "1.html", "firstrun.html"}
 	}
 
-	if cfg.WebRoot == "" {
-		cfg.WebRoot = "/"
+	if gcfg.WebRoot == "" {
+		gcfg.WebRoot = "/"
 	}
 
-	if cfg.WebRoot[0] != '/' {
-		cfg.WebRoot = "/" + cfg.WebRoot
+	if gcfg.WebRoot[0] != '/' {
+		gcfg.WebRoot = "/" + gcfg.WebRoot
 	}
-	if cfg.WebRoot[len(cfg.WebRoot)-1] != '/' {
-		cfg.WebRoot += "/"
+	if gcfg.WebRoot[len(gcfg.WebRoot)-1] != '/' {
+		gcfg.WebRoot += "/"
 	}
 
 	_, zoneOffset := time.Now().Zone()
-	cfg.TimeZone = zoneOffset / 60 / 60
-	initialSetupStatus = InitialSetupComplete
-	return nil
+	gcfg.TimeZone = zoneOffset / 60 / 60
 }
 
 // WebPath returns an absolute path, starting at the web root (which is "/" by default)
 func WebPath(part ...string) string {
-	return path.Join(cfg.WebRoot, path.Join(part...))
+	return path.Join(currentConfig().WebRoot, path.Join(part...))
 }
@@ -78,3 +78,30 @@ func writeJsonFile(t *testing.T, filePath string, data any) {
 		t.FailNow()
 	}
 }
+
+func TestCheckConfigFile(t *testing.T) {
+	basePath := t.TempDir()
+	assert.NoError(t, initializeExampleConfig(t, basePath, func(c *GochanConfig) {
+		boardCfgModifyReadCfgCallback(t, c, basePath)
+	}))
+	defer resetTestConfig(t)
+	cfgPath := path.Join(basePath, "gochan.json")
+
+	checkedPath, err := CheckConfigFile(cfgPath)
+	assert.NoError(t, err)
+	assert.Equal(t, cfgPath, checkedPath)
+
+	badBoardCfgPath := path.Join(basePath, "bad-config.json")
+	assert.NoError(t, os.WriteFile(badBoardCfgPath, []byte("{"), NormalFileMode))
+	_, err = CheckConfigFile(cfgPath)
+	assert.ErrorContains(t, err, badBoardCfgPath)
+	assert.NoError(t, os.Remove(badBoardCfgPath))
+
+	invalidCfg := *cfg
+	invalidCfg.SiteHost = ""
+	writeJsonFile(t, cfgPath, invalidCfg)
+	_, err = CheckConfigFile(cfgPath)
+	var invalidValueErr *InvalidValueError
+	assert.ErrorAs(t, err, &invalidValueErr)
+	assert.Equal(t, "SiteHost", invalidValueErr.Field)
+}
@@ -3,27 +3,82 @@ package events
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
 
 	"github.com/gochan-org/gochan/pkg/gcutil"
 )
 
+const (
+	// PriorityDefault is the priority of handlers registered without WithPriority
+	PriorityDefault = 0
+	// PriorityHigh can be used by handlers that need to see (or modify) the event data before most others
+	PriorityHigh = 100
+	// PriorityLow can be used by handlers that need to see the event data after most others have modified it
+	PriorityLow = -100
+)
+
 var (
-	registeredEvents        = map[string][]EventHandler{}
+	registeredEvents        = map[string][]registeredHandler{}
+	registeredEventsMutex   sync.RWMutex
+	runningAsyncHandlers    sync.WaitGroup
 	ErrRecovered            = errors.New("recovered from a panic in event handler")
 	InvalidArgumentErrorStr = "invalid argument(s) passed to event %q"
 )
 
 type EventHandler func(string, ...any) error
 
+type registeredHandler struct {
+	handler  EventHandler
+	priority int
+	async    bool
+}
+
+// HandlerOption sets how an event handler is called when its event is triggered
+type HandlerOption func(*registeredHandler)
+
+// WithPriority sets the priority of the handler. Handlers with a higher priority are called first, and handlers with
+// the same priority are called in the order they were registered
+func WithPriority(priority int) HandlerOption {
+	return func(h *registeredHandler) {
+		h.priority = priority
+	}
+}
+
+// Async makes the handler run in a separate goroutine after all of the synchronous handlers of the event have returned
+// without an error, so it can't slow down or cancel the action that triggered the event. Errors returned by
+// asynchronous handlers are logged, and they shouldn't modify the event data
+func Async() HandlerOption {
+	return func(h *registeredHandler) {
+		h.async = true
+	}
+}
+
 // RegisterEvent registers a new event handler to be called when any of the elements of triggers are passed
 // to TriggerEvent
-func RegisterEvent(triggers []string, handler func(trigger string, i ...any) error) {
+func RegisterEvent(triggers []string, handler func(trigger string, i ...any) error, opts ...HandlerOption) {
+	registered := registeredHandler{handler: handler, priority: PriorityDefault}
+	for _, opt := range opts {
+		opt(&registered)
+	}
+	registeredEventsMutex.Lock()
+	defer registeredEventsMutex.Unlock()
 	for _, t := range triggers {
-		registeredEvents[t] = append(registeredEvents[t], handler)
+		handlers := registeredEvents[t]
+		// insert after any handlers with the same or higher priority
+		i := slices.IndexFunc(handlers, func(h registeredHandler) bool {
+			return h.priority < registered.priority
+		})
+		if i < 0 {
+			i = len(handlers)
+		}
+		registeredEvents[t] = slices.Insert(handlers, i, registered)
 	}
 }
 
-// TriggerEvent triggers the event handler registered to trigger
+// TriggerEvent triggers the event handlers registered to trigger in order of their priority. If a synchronous handler
+// returns an error, the remaining handlers are not called and the error is returned, allowing handlers to cancel the
+// action that triggered the event. Asynchronous handlers are started after all synchronous handlers have returned
 func TriggerEvent(trigger string, data ...any) (handled bool, err error, recovered bool) {
 	errEv := gcutil.LogError(nil).Caller(1)
 	defer func() {
@@ -37,12 +92,93 @@ func TriggerEvent(trigger string, data ...any) (handled bool, err error, recover
 		}
 		errEv.Discard()
 	}()
-	for _, handler := range registeredEvents[trigger] {
-		if err = handler(trigger, data...); err != nil {
-			handled = true
-			break
-		}
+	registeredEventsMutex.RLock()
+	handlers := registeredEvents[trigger]
+	registeredEventsMutex.RUnlock()
+
+	var asyncHandlers []EventHandler
+	for _, h := range handlers {
 		handled = true
+		if h.async {
+			asyncHandlers = append(asyncHandlers, h.handler)
+			continue
+		}
+		if err = h.handler(trigger, data...); err != nil {
+			return
+		}
+	}
+	for _, handler := range asyncHandlers {
+		runAsync(trigger, handler, data)
 	}
 	return
 }
+
+func runAsync(trigger string, handler EventHandler, data []any) {
+	runningAsyncHandlers.Add(1)
+	go func() {
+		defer runningAsyncHandlers.Done()
+		defer func() {
+			if a := recover(); a != nil {
+				gcutil.LogError(fmt.Errorf("%v", a)).Stack().
+					Str("event", trigger).
+					Msg("Recovered from panic in asynchronous event handler")
+			}
+		}()
+		if err := handler(trigger, data...); err != nil {
+			gcutil.LogError(err).Str("event", trigger).Msg("Error in asynchronous event handler")
+		}
+	}()
+}
+
+// Wait waits for any asynchronous event handlers that are running to return
+func Wait() {
+	runningAsyncHandlers.Wait()
+}
+
+// Event is an event with a typed payload. Handlers registered with RegisterEvent (including ones registered by Lua
+// plugins) are also called when it is triggered, receiving the payload as the only data argument
+type Event[T any] struct {
+	name string
+}
+
+// NewEvent returns an event with the given trigger name and payload type
+func NewEvent[T any](name string) Event[T] {
+	return Event[T]{name: name}
+}
+
+// Name returns the trigger name of the event
+func (e Event[T]) Name() string {
+	return e.name
+}
+
+// Register registers a handler to be called with the payload when the event is triggered
+func (e Event[T]) Register(handler func(payload T) error, opts ...HandlerOption) {
+	RegisterEvent([]string{e.name}, func(trigger string, data ...any) error {
+		if len(data) < 1 {
+			return fmt.Errorf(InvalidArgumentErrorStr, trigger)
+		}
+		payload, ok := data[0].(T)
+		if !ok {
+			return fmt.Errorf(InvalidArgumentErrorStr, trigger)
+		}
+		return handler(payload)
+	}, opts...)
+}
+
+// Trigger calls the event's handlers with the payload, returning the error returned by the first synchronous handler
+// that failed, or ErrRecovered if one of them panicked. It should be used when the event can be cancelled
+func (e Event[T]) Trigger(payload T) error {
+	_, err, recovered := TriggerEvent(e.name, payload)
+	if recovered {
+		return ErrRecovered
+	}
+	return err
+}
+
+// Notify calls the event's handlers with the payload and logs any errors instead of returning them. It should be used
+// when the action that triggered the event has already happened and can't be cancelled
+func (e Event[T]) Notify(payload T) {
+	if err := e.Trigger(payload); err != nil {
+		gcutil.LogError(err).Caller(1).Str("event", e.name).Msg("Error running event handlers")
+	}
+}
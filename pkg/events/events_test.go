@@ -1,6 +1,8 @@
 package events
 
 import (
+	"errors"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -46,3 +48,98 @@ func TestMultipleEventTriggers(t *testing.T) {
 	assert.True(t, aTriggered, "'a' event should be triggered")
 	assert.True(t, bTriggered, "'b' event should be triggered")
 }
+
+func TestEventPriority(t *testing.T) {
+	var order []string
+	handler := func(name string) EventHandler {
+		return func(_ string, _ ...any) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	RegisterEvent([]string{"TestEventPriority"}, handler("default"))
+	RegisterEvent([]string{"TestEventPriority"}, handler("low"), WithPriority(PriorityLow))
+	RegisterEvent([]string{"TestEventPriority"}, handler("high"), WithPriority(PriorityHigh))
+	RegisterEvent([]string{"TestEventPriority"}, handler("default2"))
+	TriggerEvent("TestEventPriority")
+	assert.Equal(t, []string{"high", "default", "default2", "low"}, order)
+}
+
+func TestEventCancel(t *testing.T) {
+	var calledAfterCancel, asyncCalled bool
+	errCancel := errors.New("cancelled")
+	RegisterEvent([]string{"TestEventCancel"}, func(_ string, _ ...any) error {
+		asyncCalled = true
+		return nil
+	}, Async(), WithPriority(PriorityHigh))
+	RegisterEvent([]string{"TestEventCancel"}, func(_ string, _ ...any) error {
+		return errCancel
+	})
+	RegisterEvent([]string{"TestEventCancel"}, func(_ string, _ ...any) error {
+		calledAfterCancel = true
+		return nil
+	})
+	handled, err, _ := TriggerEvent("TestEventCancel")
+	Wait()
+	assert.True(t, handled)
+	assert.ErrorIs(t, err, errCancel)
+	assert.False(t, calledAfterCancel, "handlers after the one that returned an error shouldn't be called")
+	assert.False(t, asyncCalled, "asynchronous handlers shouldn't be called if the event is cancelled")
+}
+
+func TestAsyncEvent(t *testing.T) {
+	release := make(chan struct{})
+	var asyncErrCalled, asyncPanicCalled atomic.Bool
+	RegisterEvent([]string{"TestAsyncEvent"}, func(_ string, _ ...any) error {
+		<-release
+		asyncErrCalled.Store(true)
+		return errors.New("logged, not returned")
+	}, Async())
+	RegisterEvent([]string{"TestAsyncEvent"}, func(_ string, _ ...any) error {
+		asyncPanicCalled.Store(true)
+		panic("recovered in the handler's goroutine")
+	}, Async())
+
+	handled, err, recovered := TriggerEvent("TestAsyncEvent")
+	assert.True(t, handled)
+	assert.NoError(t, err)
+	assert.False(t, recovered)
+	assert.False(t, asyncErrCalled.Load(), "TriggerEvent shouldn't wait for asynchronous handlers")
+	close(release)
+	Wait()
+	assert.True(t, asyncErrCalled.Load())
+	assert.True(t, asyncPanicCalled.Load())
+}
+
+type testPayload struct {
+	Message string
+}
+
+func TestTypedEvent(t *testing.T) {
+	event := NewEvent[*testPayload]("TestTypedEvent")
+	event.Register(func(payload *testPayload) error {
+		payload.Message += " modified"
+		return nil
+	})
+	var untypedData []any
+	RegisterEvent([]string{event.Name()}, func(_ string, data ...any) error {
+		untypedData = data
+		return nil
+	}, WithPriority(PriorityLow))
+
+	payload := &testPayload{Message: "message"}
+	assert.NoError(t, event.Trigger(payload))
+	assert.Equal(t, "message modified", payload.Message)
+	assert.Equal(t, []any{payload}, untypedData, "untyped handlers should receive the payload")
+
+	// triggering the event by name with the wrong data should return an error instead of panicking
+	_, err, recovered := TriggerEvent(event.Name(), "wrong type")
+	assert.ErrorContains(t, err, "invalid argument")
+	assert.False(t, recovered)
+
+	panicEvent := NewEvent[*testPayload]("TestTypedEventPanic")
+	panicEvent.Register(func(payload *testPayload) error {
+		panic("panicked")
+	})
+	assert.ErrorIs(t, panicEvent.Trigger(payload), ErrRecovered)
+}
@@ -4,25 +4,41 @@ import (
 	"errors"
 
 	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
 	lua "github.com/yuin/gopher-lua"
-	luar "layeh.com/gopher-luar"
+)
+
+var (
+	// luaHandlerErrors counts errors returned or raised by event handlers registered by Lua plugins
+	luaHandlerErrors = metrics.NewCounterVec("gochan_lua_event_handler_errors_total",
+		"Number of errors returned or raised by Lua event handlers, by event", "event")
 )
 
 func luaEventRegisterHandlerAdapter(l *lua.LState, fn *lua.LFunction) EventHandler {
 	return func(trigger string, data ...any) error {
-		args := []lua.LValue{
-			luar.New(l, trigger),
+		defer func() {
+			if a := recover(); a != nil {
+				// Lua errors are raised as panics, which are recovered from by TriggerEvent
+				luaHandlerErrors.Inc(trigger)
+				panic(a)
+			}
+		}()
+		args := append([]any{trigger}, data...)
+		rets, err := luautil.CallFunction(l, fn, 1, false, args...)
+		if errors.Is(err, luautil.ErrPluginDisabled) {
+			// disabled plugins don't handle events
+			return nil
 		}
-		for _, i := range data {
-			args = append(args, luar.New(l, i))
+		if err != nil {
+			// the plugin took too long to finish a previous call, so its handler is skipped instead of holding up the event
+			luaHandlerErrors.Inc(trigger)
+			gcutil.LogWarning().Err(err).Str("event", trigger).Msg("Skipped Lua event handler")
+			return nil
 		}
-		l.CallByParam(lua.P{
-			Fn:   fn,
-			NRet: 1,
-			// Protect: true,
-		}, args...)
-		errStr := lua.LVAsString(l.Get(-1))
+		errStr := lua.LVAsString(rets[0])
 		if errStr != "" {
+			luaHandlerErrors.Inc(trigger)
 			return errors.New(errStr)
 		}
 		return nil
@@ -33,13 +49,23 @@ func PreloadModule(l *lua.LState) int {
 	t := l.NewTable()
 	l.SetFuncs(t, map[string]lua.LGFunction{
 		"register_event": func(l *lua.LState) int {
-			table := l.CheckTable(-2)
+			table := l.CheckTable(1)
 			var triggers []string
 			table.ForEach(func(_, val lua.LValue) {
 				triggers = append(triggers, val.String())
 			})
-			fn := l.CheckFunction(-1)
-			RegisterEvent(triggers, luaEventRegisterHandlerAdapter(l, fn))
+			fn := l.CheckFunction(2)
+			var opts []HandlerOption
+			if optsTable := l.OptTable(3, nil); optsTable != nil {
+				if priority, ok := optsTable.RawGetString("priority").(lua.LNumber); ok {
+					opts = append(opts, WithPriority(int(priority)))
+				}
+				if lua.LVAsBool(optsTable.RawGetString("async")) {
+					// a Lua state can't be used by multiple goroutines at the same time
+					l.ArgError(3, "Lua event handlers can't be asynchronous")
+				}
+			}
+			RegisterEvent(triggers, luaEventRegisterHandlerAdapter(l, fn), opts...)
 			return 0
 		},
 		"trigger_event": func(l *lua.LState) int {
@@ -50,7 +76,14 @@ func PreloadModule(l *lua.LState) int {
 				v := l.CheckAny(i)
 				data = append(data, luautil.LValueToInterface(l, v))
 			}
-			TriggerEvent(trigger, data...)
+			if sandbox := luautil.GetSandbox(l); sandbox != nil {
+				// let the plugin's own handlers for the event use the Lua state while this call waits for them
+				sandbox.Unlocked(func() {
+					TriggerEvent(trigger, data...)
+				})
+			} else {
+				TriggerEvent(trigger, data...)
+			}
 			return 0
 		},
 	})
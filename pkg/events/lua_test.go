@@ -102,3 +102,57 @@ events.trigger_event("event2", buffer, "a");`,
 		})
 	}
 }
+
+func TestLuaEventHandlerErrorMetric(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+	l.PreloadModule("events", PreloadModule)
+	assert.NoError(t, l.DoString(`local events = require("events");
+events.register_event({"metric_error_test"}, function(trigger, data)
+	return "uh oh";
+end);
+events.register_event({"metric_raise_test"}, function(trigger, data)
+	error("raised error");
+end);`))
+
+	_, err, _ := TriggerEvent("metric_error_test")
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, luaHandlerErrors.Value("metric_error_test"))
+
+	_, _, recovered := TriggerEvent("metric_raise_test")
+	assert.True(t, recovered)
+	assert.Equal(t, 1.0, luaHandlerErrors.Value("metric_raise_test"))
+}
+
+func TestLuaEventOptions(t *testing.T) {
+	l := lua.NewState()
+	defer l.Close()
+	l.PreloadModule("events", PreloadModule)
+	var order []string
+	RegisterEvent([]string{"lua_options_test"}, func(_ string, _ ...any) error {
+		order = append(order, "go")
+		return nil
+	})
+	l.SetGlobal("record", luar.New(l, func(name string) {
+		order = append(order, name)
+	}))
+	assert.NoError(t, l.DoString(`local events = require("events");
+events.register_event({"lua_options_test"}, function(trigger, payload)
+	record("lua high");
+	payload.Message = payload.Message .. " from lua";
+end, {priority = 100});
+events.register_event({"lua_options_test"}, function(trigger, payload)
+	record("lua low");
+	return "cancelled by lua";
+end, {priority = -100});`))
+
+	payload := &testPayload{Message: "message"}
+	err := NewEvent[*testPayload]("lua_options_test").Trigger(payload)
+	assert.EqualError(t, err, "cancelled by lua")
+	assert.Equal(t, []string{"lua high", "go", "lua low"}, order)
+	assert.Equal(t, "message from lua", payload.Message, "Lua handlers should be able to modify the payload")
+
+	assert.Error(t, l.DoString(`local events = require("events");
+events.register_event({"lua_async_test"}, function() end, {async = true});`),
+		"Lua handlers can't be asynchronous")
+}
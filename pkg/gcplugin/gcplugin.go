@@ -3,16 +3,17 @@ package gcplugin
 import (
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"path"
 	"plugin"
+	"sync"
 
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/events"
+	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
 	"github.com/gochan-org/gochan/pkg/gcsql"
-	"github.com/gochan-org/gochan/pkg/gctemplates"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/jobs"
 	"github.com/gochan-org/gochan/pkg/manage"
 	"github.com/gochan-org/gochan/pkg/posting"
 	"github.com/gochan-org/gochan/pkg/posting/geoip"
@@ -20,42 +21,60 @@ import (
 	"github.com/gochan-org/gochan/pkg/server/serverutil"
 	luar "layeh.com/gopher-luar"
 
-	"github.com/cjoudrey/gluahttp"
-
 	async "github.com/CuberL/glua-async"
-	luaFilePath "github.com/vadv/gopher-lua-libs/filepath"
 	luaStrings "github.com/vadv/gopher-lua-libs/strings"
 	lua "github.com/yuin/gopher-lua"
 	luajson "layeh.com/gopher-json"
 )
 
+const (
+	PluginTypeLua    = "lua"
+	PluginTypeNative = "native"
+)
+
 var (
-	lState             *lua.LState
+	plugins      []*Plugin
+	pluginsMutex sync.RWMutex
+
 	ErrInvalidInitFunc = errors.New("invalid InitPlugin, expected function with 0 arguments and 1 return value (error type)")
+	ErrPluginNotFound  = errors.New("plugin not found")
 )
 
-func initLua() {
-	if lState == nil {
-		lState = lua.NewState()
-		preloadLua()
-	}
+// Plugin is a plugin in the Plugins configuration field and the result of loading it
+type Plugin struct {
+	Path string
+	// Type is either PluginTypeLua or PluginTypeNative
+	Type     string
+	Settings config.PluginSettings
+	// Loaded is true if the plugin was loaded without errors
+	Loaded bool
+	// LoadError is the error returned while loading the plugin, if any
+	LoadError error
+
+	lState  *lua.LState
+	sandbox *luautil.Sandbox
 }
 
-func ClosePlugins() {
-	if lState != nil {
-		lState.Close()
-		lState = nil
-	}
+// Enabled returns true if the plugin hasn't been disabled in its settings or by an administrator
+func (p *Plugin) Enabled() bool {
+	return !p.Settings.Disabled
 }
 
-func preloadLua() {
-	luaFilePath.Preload(lState)
-	luaStrings.Preload(lState)
-	async.Init(lState)
-	luajson.Preload(lState)
+// newPluginState creates a Lua state for the plugin with the modules that it is allowed to use
+func newPluginState(pluginPath string, settings *config.PluginSettings) *lua.LState {
+	l := lua.NewState(lua.Options{
+		SkipOpenLibs:  true,
+		CallStackSize: settings.CallStackSize,
+		RegistrySize:  settings.RegistrySize,
+	})
+	sb := newSandbox(pluginPath, settings)
+	sb.openLibs(l)
+	sb.preloadRestrictedModules(l)
+	luaStrings.Preload(l)
+	async.Init(l)
+	luajson.Preload(l)
 
-	lState.PreloadModule("http", gluahttp.NewHttpModule(&http.Client{}).Loader)
-	lState.PreloadModule("url", func(l *lua.LState) int {
+	l.PreloadModule("url", func(l *lua.LState) int {
 		t := l.NewTable()
 		l.SetFuncs(t, map[string]lua.LGFunction{
 			"join_path": func(l *lua.LState) int {
@@ -98,51 +117,170 @@ func preloadLua() {
 		return 1
 	})
 
-	lState.PreloadModule("config", config.PreloadModule)
-	lState.PreloadModule("events", events.PreloadModule)
-	lState.PreloadModule("gclog", gcutil.PreloadModule)
-	lState.PreloadModule("gcsql", gcsql.PreloadModule)
-	lState.PreloadModule("gctemplates", gctemplates.PreloadModule)
-	lState.PreloadModule("geoip", geoip.PreloadModule)
-	lState.PreloadModule("manage", manage.PreloadModule)
-	lState.PreloadModule("uploads", uploads.PreloadModule)
-	lState.PreloadModule("serverutil", serverutil.PreloadModule)
-	lState.PreloadModule("bbcode", posting.PreloadBBCodeModule)
-
-	lState.SetGlobal("_GOCHAN_VERSION", lua.LString(config.GochanVersion))
-	lState.SetGlobal("_DATABASE_VERSION", lua.LNumber(gcsql.DatabaseVersion))
+	l.PreloadModule("events", events.PreloadModule)
+	l.PreloadModule("gclog", gcutil.PreloadModule)
+	l.PreloadModule("geoip", geoip.PreloadModule)
+	l.PreloadModule("jobs", jobs.PreloadModule)
+	l.PreloadModule("manage", manage.PreloadModule)
+	l.PreloadModule("uploads", uploads.PreloadModule)
+	l.PreloadModule("serverutil", serverutil.PreloadModule)
+	l.PreloadModule("bbcode", posting.PreloadBBCodeModule)
+
+	l.SetGlobal("_GOCHAN_VERSION", lua.LString(config.GochanVersion))
+	l.SetGlobal("_DATABASE_VERSION", lua.LNumber(gcsql.DatabaseVersion))
+	return l
 }
 
+// ClosePlugins closes the Lua states of the loaded Lua plugins. Native plugins can't be unloaded
+func ClosePlugins() {
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+	for _, p := range plugins {
+		if p.lState != nil {
+			luautil.RemoveSandbox(p.lState)
+			p.lState.Close()
+			p.lState = nil
+		}
+	}
+	plugins = nil
+}
+
+func loadLuaPlugin(p *Plugin) error {
+	p.lState = newPluginState(p.Path, &p.Settings)
+	p.sandbox = luautil.NewSandbox(p.lState, p.Path, p.Settings.Timeout())
+	if err := p.sandbox.Run(p.lState, func() error {
+		return p.lState.DoFile(p.Path)
+	}); err != nil {
+		// handlers that the plugin registered before the error are kept from being called, since the plugin may not
+		// have finished setting up what they use
+		p.sandbox.SetEnabled(false)
+		return err
+	}
+	return nil
+}
+
+func loadNativePlugin(p *Plugin) error {
+	nativePlugin, err := plugin.Open(p.Path)
+	if err != nil {
+		return err
+	}
+	initFuncSymbol, err := nativePlugin.Lookup("InitPlugin")
+	if err != nil {
+		return err
+	}
+	initFunc, ok := initFuncSymbol.(func() error)
+	if !ok {
+		return ErrInvalidInitFunc
+	}
+	return initFunc()
+}
+
+// LoadPlugins loads the plugins in the given paths, each Lua plugin in its own Lua state with the permissions and
+// limits set in its PluginSettings. A plugin that fails to load doesn't stop the others from being loaded, and the
+// errors of all of the plugins that failed are returned together
 func LoadPlugins(paths []string) error {
-	var err error
-	initLua()
+	var errs []error
 	for _, pluginPath := range paths {
-		ext := path.Ext(pluginPath)
-		gcutil.LogInfo().Str("pluginPath", pluginPath).Msg("Loading plugin")
-		switch ext {
+		p := &Plugin{
+			Path:     pluginPath,
+			Settings: config.GetPluginSettings(pluginPath),
+		}
+		infoEv := gcutil.LogInfo().Str("pluginPath", pluginPath)
+		switch path.Ext(pluginPath) {
 		case ".lua":
-			if err = lState.DoFile(pluginPath); err != nil {
-				return err
-			}
+			p.Type = PluginTypeLua
 		case ".so":
-			nativePlugin, err := plugin.Open(pluginPath)
-			if err != nil {
-				return err
-			}
-			initFuncSymbol, err := nativePlugin.Lookup("InitPlugin")
-			if err != nil {
-				return err
-			}
-			initFunc, ok := initFuncSymbol.(func() error)
-			if !ok {
-				return ErrInvalidInitFunc
-			}
-			if err = initFunc(); err != nil {
-				return err
-			}
+			p.Type = PluginTypeNative
 		default:
-			return fmt.Errorf("unrecognized plugin type (expected .lua or .so extension): %s", pluginPath)
+			infoEv.Discard()
+			p.LoadError = fmt.Errorf("unrecognized plugin type (expected .lua or .so extension): %s", pluginPath)
+		}
+
+		if p.LoadError == nil && p.Settings.Disabled {
+			infoEv.Msg("Plugin is disabled, not loading it")
+		} else if p.LoadError == nil {
+			infoEv.Msg("Loading plugin")
+			if p.Type == PluginTypeLua {
+				p.LoadError = loadLuaPlugin(p)
+			} else {
+				p.LoadError = loadNativePlugin(p)
+			}
+			p.Loaded = p.LoadError == nil
+		}
+		if p.LoadError != nil {
+			gcutil.LogError(p.LoadError).Str("pluginPath", pluginPath).Msg("Failed loading plugin")
+			errs = append(errs, p.LoadError)
 		}
+		pluginsMutex.Lock()
+		plugins = append(plugins, p)
+		pluginsMutex.Unlock()
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// PluginStatus is the status of a plugin shown to administrators
+type PluginStatus struct {
+	Path      string
+	Type      string
+	Enabled   bool
+	Loaded    bool
+	LoadError string `json:",omitempty"`
+	// RestartRequired is true if gochan needs to be restarted for the plugin's enabled status to take effect, which
+	// is the case for plugins that were disabled when gochan started and for native plugins, which can't be unloaded
+	RestartRequired bool
+
+	AllowNetwork    bool
+	AllowSQL        bool
+	FilesystemPaths []string `json:",omitempty"`
+	TimeoutSeconds  int
+}
+
+// PluginStatuses returns the status of each plugin in the order they were loaded
+func PluginStatuses() []PluginStatus {
+	pluginsMutex.RLock()
+	defer pluginsMutex.RUnlock()
+	statuses := make([]PluginStatus, 0, len(plugins))
+	for _, p := range plugins {
+		status := PluginStatus{
+			Path:            p.Path,
+			Type:            p.Type,
+			Enabled:         p.Enabled(),
+			Loaded:          p.Loaded,
+			AllowNetwork:    p.Settings.AllowNetwork,
+			AllowSQL:        p.Settings.AllowSQL,
+			FilesystemPaths: p.Settings.FilesystemPaths,
+			TimeoutSeconds:  p.Settings.TimeoutSeconds,
+		}
+		if p.LoadError != nil {
+			status.LoadError = p.LoadError.Error()
+		}
+		status.RestartRequired = (status.Enabled && !p.Loaded && p.LoadError == nil) ||
+			(!status.Enabled && p.Type == PluginTypeNative && p.Loaded)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// SetPluginEnabled enables or disables the plugin with the given path and saves the setting to the configuration file.
+// Disabling a Lua plugin stops its event handlers, jobs, and other functions from being called until it is enabled
+// again. Plugins that weren't loaded when gochan started and native plugins need gochan to be restarted
+func SetPluginEnabled(pluginPath string, enabled bool) error {
+	pluginsMutex.Lock()
+	var p *Plugin
+	for _, loaded := range plugins {
+		if loaded.Path == pluginPath {
+			p = loaded
+			break
+		}
+	}
+	if p == nil {
+		pluginsMutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrPluginNotFound, pluginPath)
+	}
+	p.Settings.Disabled = !enabled
+	if p.sandbox != nil && p.Loaded {
+		p.sandbox.SetEnabled(enabled)
+	}
+	pluginsMutex.Unlock()
+	return config.SetPluginDisabled(pluginPath, !enabled)
 }
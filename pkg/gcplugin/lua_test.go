@@ -38,13 +38,18 @@ local board_cfg = config.board_config()
 return { ListenAddress = system_critical_cfg.ListenAddress, SiteSlogan = site_cfg.SiteSlogan, DefaultStyle = board_cfg.DefaultStyle }`
 )
 
-func initPluginTests() {
+// initPluginTests returns a Lua state with the permissions of a plugin that isn't in PluginSettings
+func initPluginTests(t *testing.T) *lua.LState {
+	t.Helper()
 	config.InitTestConfig()
-	initLua()
+	settings := config.GetPluginSettings("test.lua")
+	l := newPluginState("test.lua", &settings)
+	t.Cleanup(l.Close)
+	return l
 }
 
 func TestVersionFunction(t *testing.T) {
-	initPluginTests()
+	lState := initPluginTests(t)
 	err := lState.DoString(versionStr)
 	assert.NoError(t, err)
 	testingVersionStr := lState.Get(-1).(lua.LString)
@@ -52,7 +57,7 @@ func TestVersionFunction(t *testing.T) {
 }
 
 func TestStructPassing(t *testing.T) {
-	initPluginTests()
+	lState := initPluginTests(t)
 	p := &gcsql.Post{
 		Name:       "Joe Poster",
 		Email:      "joeposter@gmail.com",
@@ -68,7 +73,7 @@ func TestStructPassing(t *testing.T) {
 }
 
 func TestEventModule(t *testing.T) {
-	initPluginTests()
+	lState := initPluginTests(t)
 	err := lState.DoString(eventsTestingStr)
 	assert.NoError(t, err)
 }
@@ -76,7 +81,7 @@ func TestEventModule(t *testing.T) {
 func TestConfigModule(t *testing.T) {
 	testutil.GoToGochanRoot(t)
 	config.InitConfig()
-	initPluginTests()
+	lState := initPluginTests(t)
 	err := lState.DoString(configTestingStr)
 	assert.NoError(t, err)
 	returnTable := lState.CheckTable(-1)
@@ -86,7 +91,7 @@ func TestConfigModule(t *testing.T) {
 }
 
 func TestLuaURL(t *testing.T) {
-	initPluginTests()
+	lState := initPluginTests(t)
 	err := lState.DoString(`local url = require("url")
 local joined = url.join_path("test", "path")
 local path_escaped = url.path_escape("test +/string")
@@ -107,16 +112,15 @@ return joined, query_escaped, query_unescaped, err`)
 	assert.Equal(t, "test+%2B%2Fstring", queryEscaped)
 	assert.Equal(t, "test +/string", queryUnescaped)
 	assert.Equal(t, errLV.Type(), lua.LTNil)
-	ClosePlugins()
 }
 
 func TestLoadPlugin(t *testing.T) {
 	testutil.GoToGochanRoot(t)
-	initPluginTests()
+	config.InitTestConfig()
+	t.Cleanup(ClosePlugins)
 	assert.NoError(t, LoadPlugins([]string{"examples/plugins/uploadfilenameupper.lua"}))
 	assert.NoError(t, LoadPlugins(nil))
 	assert.Error(t, LoadPlugins([]string{"not_a_file.lua"}))
 	assert.Error(t, LoadPlugins([]string{"invalid_ext.dll"}))
 	assert.ErrorContains(t, LoadPlugins([]string{"not_a_file.so"}), "realpath failed")
-	ClosePlugins()
 }
@@ -0,0 +1,155 @@
+package luautil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+)
+
+var (
+	// ErrPluginDisabled is returned by CallFunction if the plugin that owns the Lua state was disabled
+	ErrPluginDisabled = errors.New("plugin is disabled")
+	// ErrPluginBusy is returned by CallFunction if the plugin was still running another call when the call timed out
+	ErrPluginBusy = errors.New("timed out waiting for plugin to finish another call")
+
+	sandboxes sync.Map // *lua.Global -> *Sandbox
+)
+
+// Sandbox keeps a plugin's Lua state from being used by more than one goroutine at a time, stops calls to it that take
+// longer than its timeout, and lets it be disabled without closing the state
+type Sandbox struct {
+	// Name is used to identify the plugin in errors and logs
+	Name    string
+	timeout time.Duration
+	// sem is held by the goroutine that is using the Lua state
+	sem      chan struct{}
+	disabled atomic.Bool
+}
+
+// NewSandbox creates a sandbox for the Lua state. Functions in the state should then be called from Go with
+// CallFunction instead of using the state directly. If timeout is 0, calls can run indefinitely
+func NewSandbox(l *lua.LState, name string, timeout time.Duration) *Sandbox {
+	sandbox := &Sandbox{
+		Name:    name,
+		timeout: timeout,
+		sem:     make(chan struct{}, 1),
+	}
+	// coroutines share the global state of the state that created them, so they use the same sandbox
+	sandboxes.Store(l.G, sandbox)
+	return sandbox
+}
+
+// GetSandbox returns the sandbox of the Lua state (or a coroutine created by it), or nil if it doesn't have one
+func GetSandbox(l *lua.LState) *Sandbox {
+	sandbox, ok := sandboxes.Load(l.G)
+	if !ok {
+		return nil
+	}
+	return sandbox.(*Sandbox)
+}
+
+// RemoveSandbox removes the Lua state's sandbox, which should be done when the state is closed
+func RemoveSandbox(l *lua.LState) {
+	sandboxes.Delete(l.G)
+}
+
+// SetEnabled enables or disables the plugin. Calls to a disabled plugin return ErrPluginDisabled
+func (s *Sandbox) SetEnabled(enabled bool) {
+	s.disabled.Store(!enabled)
+}
+
+// Enabled returns true if the plugin hasn't been disabled
+func (s *Sandbox) Enabled() bool {
+	return !s.disabled.Load()
+}
+
+// Run calls fn while holding the sandbox's lock, with the Lua state's context set to time out after the sandbox's
+// timeout. It can be used to run Lua code in the state (for example l.DoFile) when CallFunction isn't enough
+func (s *Sandbox) Run(l *lua.LState, fn func() error) error {
+	if !s.Enabled() {
+		return fmt.Errorf("%w: %s", ErrPluginDisabled, s.Name)
+	}
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if s.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+	}
+	defer cancel()
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s", ErrPluginBusy, s.Name)
+	}
+	defer func() {
+		<-s.sem
+	}()
+	oldCtx := l.Context()
+	l.SetContext(ctx)
+	defer func() {
+		if oldCtx != nil {
+			l.SetContext(oldCtx)
+		} else {
+			l.RemoveContext()
+		}
+	}()
+	return fn()
+}
+
+// Unlocked releases the sandbox's lock while fn runs, then waits to get it back. It should be used by Go functions
+// called from Lua that may call back into the same Lua state from Go, like triggering an event that the plugin
+// handles, which would otherwise wait for the lock held by the call that is already running
+func (s *Sandbox) Unlocked(fn func()) {
+	select {
+	case <-s.sem:
+	default:
+		// the lock isn't held, so there is nothing to release
+		fn()
+		return
+	}
+	defer func() {
+		s.sem <- struct{}{}
+	}()
+	fn()
+}
+
+// CallFunction calls the Lua function fn in the state with the given arguments (converted to Lua values with luar if
+// they aren't already) and returns the nret values that it returned. If the state has a sandbox, the call holds its
+// lock and is stopped if it runs longer than its timeout. If protect is false, errors raised by the Lua function are
+// raised as panics (after the lock is released) instead of being returned, which TriggerEvent recovers from
+func CallFunction(l *lua.LState, fn lua.LValue, nret int, protect bool, args ...any) ([]lua.LValue, error) {
+	var rets []lua.LValue
+	call := func() error {
+		lArgs := make([]lua.LValue, len(args))
+		for i, arg := range args {
+			lArgs[i] = luar.New(l, arg)
+		}
+		if err := l.CallByParam(lua.P{Fn: fn, NRet: nret, Protect: true}, lArgs...); err != nil {
+			return err
+		}
+		rets = make([]lua.LValue, nret)
+		for i := range nret {
+			rets[i] = l.Get(i - nret)
+		}
+		l.Pop(nret)
+		return nil
+	}
+	var err error
+	if sandbox := GetSandbox(l); sandbox != nil {
+		err = sandbox.Run(l, call)
+		if errors.Is(err, ErrPluginDisabled) || errors.Is(err, ErrPluginBusy) {
+			// the function was never called, so there is no Lua error to raise
+			return nil, err
+		}
+	} else {
+		err = call()
+	}
+	if err != nil && !protect {
+		panic(err)
+	}
+	return rets, err
+}
@@ -0,0 +1,62 @@
+package gcplugin
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gctemplates"
+	"github.com/gochan-org/gochan/pkg/manage"
+	"github.com/gochan-org/gochan/pkg/server"
+	"github.com/gochan-org/gochan/pkg/server/serverutil"
+	"github.com/rs/zerolog"
+)
+
+// RegisterManagePage registers the /manage/plugins page, which shows administrators the status of each plugin and lets
+// them enable or disable it. It is registered here instead of in the manage package, which plugins depend on
+func RegisterManagePage() {
+	manage.RegisterManagePage("plugins", "Plugins", manage.AdminPerms, manage.OptionalJSON, pluginsCallback)
+}
+
+func pluginsCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	var message string
+	if request.FormValue("disable") != "" || request.FormValue("enable") != "" {
+		if err = manage.CheckStateChangingRequest(request); err != nil {
+			logger.Warn().Err(err).Str("referer", request.Referer()).Msg("Rejected request to change plugin status")
+			return nil, err
+		}
+		key := "enable"
+		if request.FormValue("disable") != "" {
+			key = "disable"
+		}
+		pluginPath := request.FormValue(key)
+		logger = logger.With().Str(key, pluginPath).Logger()
+		err = SetPluginEnabled(pluginPath, key == "enable")
+		if errors.Is(err, ErrPluginNotFound) {
+			logger.Warn().Err(err).Caller().Send()
+			return nil, server.NewServerError(err, http.StatusNotFound)
+		} else if err != nil {
+			// the plugin's status was still changed, but it will be reset when gochan restarts
+			logger.Err(err).Caller().Msg("Unable to save plugin settings to the configuration file")
+			message = "Plugin " + key + "d, but the configuration file couldn't be updated, so the change will be lost when gochan restarts"
+		} else {
+			logger.Info().Msg("Plugin " + key + "d")
+			message = "Plugin " + pluginPath + " " + key + "d"
+		}
+	}
+
+	statuses := PluginStatuses()
+	if wantsJSON {
+		return statuses, nil
+	}
+	buf := bytes.NewBufferString("")
+	if err = serverutil.MinifyTemplate(gctemplates.ManagePlugins, map[string]any{
+		"plugins": statuses,
+		"message": message,
+	}, buf, "text/html"); err != nil {
+		logger.Err(err).Str("template", gctemplates.ManagePlugins).Caller().Send()
+		return "", err
+	}
+	return buf.String(), nil
+}
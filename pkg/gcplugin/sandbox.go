@@ -0,0 +1,324 @@
+package gcplugin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cjoudrey/gluahttp"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gctemplates"
+	luaFilePath "github.com/vadv/gopher-lua-libs/filepath"
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+)
+
+var (
+	// safeLibs are the standard Lua libraries that plugins can use without any permissions. The io and os libraries
+	// are opened separately with their unsafe functions removed or restricted to the plugin's FilesystemPaths
+	safeLibs = []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.LoadLibName, lua.OpenPackage},
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.IoLibName, lua.OpenIo},
+		{lua.OsLibName, lua.OpenOs},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+		{lua.CoroutineLibName, lua.OpenCoroutine},
+	}
+	// removedFunctions are removed from the standard libraries because they can run programs, end the gochan process,
+	// or read its environment variables (which may contain secrets)
+	removedFunctions = map[string][]string{
+		lua.IoLibName: {"popen"},
+		lua.OsLibName: {"execute", "exit", "getenv", "setenv", "setlocale", "tmpname"},
+	}
+
+	// gcsqlPkgPath is the package path of the types whose methods plugins need the AllowSQL permission to use
+	gcsqlPkgPath = reflect.TypeFor[gcsql.Post]().PkgPath()
+
+	ErrPathNotAllowed = errors.New("plugin doesn't have permission to access the path")
+)
+
+// sandbox holds the permissions of a Lua plugin
+type sandbox struct {
+	pluginPath string
+	settings   *config.PluginSettings
+	// readPaths are the absolute paths of the files and directories that the plugin can read, including its own
+	// directory. The plugin can only write to the paths in its settings
+	readPaths  []string
+	writePaths []string
+}
+
+func newSandbox(pluginPath string, settings *config.PluginSettings) *sandbox {
+	sb := &sandbox{pluginPath: pluginPath, settings: settings}
+	for _, allowed := range settings.FilesystemPaths {
+		if abs := resolvePath(allowed); abs != "" {
+			sb.readPaths = append(sb.readPaths, abs)
+			sb.writePaths = append(sb.writePaths, abs)
+		}
+	}
+	if pluginDir := resolvePath(filepath.Dir(pluginPath)); pluginDir != "" {
+		sb.readPaths = append(sb.readPaths, pluginDir)
+	}
+	return sb
+}
+
+// resolvePath returns the absolute path with any symbolic links resolved, so that they can't be used to get around
+// the allowed paths. If the path doesn't exist, its parent directory is resolved instead
+func resolvePath(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(dir, filepath.Base(abs))
+	}
+	return abs
+}
+
+// checkPath returns an error if the plugin isn't allowed to read (or write, if write is true) the given path
+func (sb *sandbox) checkPath(p string, write bool) error {
+	allowed := sb.readPaths
+	if write {
+		allowed = sb.writePaths
+	}
+	resolved := resolvePath(p)
+	for _, allowedPath := range allowed {
+		if resolved == allowedPath || strings.HasPrefix(resolved, allowedPath+string(os.PathSeparator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrPathNotAllowed, p)
+}
+
+// restrictPaths replaces the function in the table with one that raises an error if the string arguments at the given
+// indexes (or all of them if argIndexes is empty) are paths that the plugin isn't allowed to access
+func (sb *sandbox) restrictPaths(l *lua.LState, table *lua.LTable, name string, write bool, argIndexes ...int) {
+	fn, ok := table.RawGetString(name).(*lua.LFunction)
+	if !ok || fn.GFunction == nil {
+		return
+	}
+	table.RawSetString(name, l.NewFunction(func(l *lua.LState) int {
+		indexes := argIndexes
+		if len(indexes) == 0 {
+			for i := 1; i <= l.GetTop(); i++ {
+				indexes = append(indexes, i)
+			}
+		}
+		for _, i := range indexes {
+			p, ok := l.Get(i).(lua.LString)
+			if !ok {
+				continue
+			}
+			openWrite := write
+			if name == "open" {
+				// io.open only writes to the file if the mode isn't "r" or "rb"
+				mode := l.OptString(2, "r")
+				openWrite = strings.ContainsAny(mode, "wa+")
+			}
+			if err := sb.checkPath(string(p), openWrite); err != nil {
+				l.RaiseError("%s", err.Error())
+			}
+		}
+		return fn.GFunction(l)
+	}))
+}
+
+// openLibs opens the safe standard libraries and restricts the functions that access the filesystem
+func (sb *sandbox) openLibs(l *lua.LState) {
+	for _, lib := range safeLibs {
+		l.Push(l.NewFunction(lib.open))
+		l.Push(lua.LString(lib.name))
+		l.Call(1, 0)
+	}
+	for lib, names := range removedFunctions {
+		libTable := l.GetGlobal(lib).(*lua.LTable)
+		for _, name := range names {
+			libTable.RawSetString(name, lua.LNil)
+		}
+	}
+	globals := l.Get(lua.GlobalsIndex).(*lua.LTable)
+	// loadfile and dofile read stdin if they aren't given a file, so a path is always required
+	for _, name := range []string{"dofile", "loadfile"} {
+		sb.restrictPaths(l, globals, name, false, 1)
+		restricted := globals.RawGetString(name).(*lua.LFunction)
+		globals.RawSetString(name, l.NewFunction(func(l *lua.LState) int {
+			l.CheckString(1)
+			return restricted.GFunction(l)
+		}))
+	}
+
+	ioTable := l.GetGlobal(lua.IoLibName).(*lua.LTable)
+	sb.restrictPaths(l, ioTable, "open", false, 1)
+	sb.restrictPaths(l, ioTable, "lines", false, 1)
+	sb.restrictPaths(l, ioTable, "input", false, 1)
+	sb.restrictPaths(l, ioTable, "output", true, 1)
+	osTable := l.GetGlobal(lua.OsLibName).(*lua.LTable)
+	sb.restrictPaths(l, osTable, "remove", true, 1)
+	sb.restrictPaths(l, osTable, "rename", true, 1, 2)
+
+	// modules can only be loaded from the plugin's directory. require gets its loaders from the registry, so
+	// replacing the standard Lua loader (which searches package.path) in that table means that it can't be reached
+	// by the plugin. package.path and package.cpath are only kept for reference and can't be changed
+	packageTable := l.GetGlobal(lua.LoadLibName).(*lua.LTable)
+	loaders := l.GetField(l.Get(lua.RegistryIndex), "_LOADERS").(*lua.LTable)
+	loaders.RawSetInt(2, l.NewFunction(sb.loadModule))
+	// the fields need to be missing from the table itself for __newindex to be called when they're set
+	packageTable.RawSetString("path", lua.LNil)
+	packageTable.RawSetString("cpath", lua.LNil)
+	readOnly := l.NewTable()
+	readOnly.RawSetString("path", lua.LString(filepath.Join(filepath.Dir(sb.pluginPath), "?.lua")))
+	readOnly.RawSetString("cpath", lua.LString(""))
+	packageMeta := l.NewTable()
+	packageMeta.RawSetString("__index", readOnly)
+	packageMeta.RawSetString("__newindex", l.NewFunction(func(l *lua.LState) int {
+		key := l.CheckAny(2)
+		if readOnly.RawGet(key) != lua.LNil {
+			l.RaiseError("package.%s can't be changed by plugins", key.String())
+		}
+		l.RawSet(l.CheckTable(1), key, l.Get(3))
+		return 0
+	}))
+	l.SetMetatable(packageTable, packageMeta)
+}
+
+// loadModule is the package loader that replaces the standard Lua loader. It ignores package.path and only looks
+// for the module in the plugin's directory, so a plugin can't use require to run or read files outside of it
+func (sb *sandbox) loadModule(l *lua.LState) int {
+	name := l.CheckString(1)
+	modulePath := filepath.FromSlash(strings.ReplaceAll(name, ".", "/")) + ".lua"
+	if !filepath.IsLocal(modulePath) {
+		l.Push(lua.LString(fmt.Sprintf("invalid module name '%s'", name)))
+		return 1
+	}
+	modulePath = filepath.Join(filepath.Dir(sb.pluginPath), modulePath)
+	if err := sb.checkPath(modulePath, false); err != nil {
+		l.RaiseError("%s", err.Error())
+	}
+	if _, err := os.Stat(modulePath); err != nil {
+		l.Push(lua.LString(fmt.Sprintf("no file '%s'", modulePath)))
+		return 1
+	}
+	fn, err := l.LoadFile(modulePath)
+	if err != nil {
+		l.RaiseError("%s", err.Error())
+	}
+	l.Push(fn)
+	return 1
+}
+
+// wrapModule returns a module loader that calls loader and then modifies the module's table with fn
+func wrapModule(loader lua.LGFunction, fn func(l *lua.LState, module *lua.LTable)) lua.LGFunction {
+	return func(l *lua.LState) int {
+		ret := loader(l)
+		if module, ok := l.Get(-1).(*lua.LTable); ok {
+			fn(l, module)
+		}
+		return ret
+	}
+}
+
+// deniedModule returns a module loader that raises an error saying that the plugin needs the permission to use it
+func (sb *sandbox) deniedModule(module string, permission string) lua.LGFunction {
+	return func(l *lua.LState) int {
+		l.RaiseError("plugin %s doesn't have permission to use the %s module (%s isn't set in its PluginSettings)",
+			sb.pluginPath, module, permission)
+		return 0
+	}
+}
+
+// preloadRestrictedModules preloads the modules that need permissions, or loaders that raise an error if the plugin
+// doesn't have them
+func (sb *sandbox) preloadRestrictedModules(l *lua.LState) {
+	if sb.settings.AllowNetwork {
+		client := &http.Client{Timeout: sb.settings.Timeout()}
+		l.PreloadModule("http", gluahttp.NewHttpModule(client).Loader)
+	} else {
+		l.PreloadModule("http", sb.deniedModule("http", "AllowNetwork"))
+	}
+	if sb.settings.AllowSQL {
+		l.PreloadModule("gcsql", gcsql.PreloadModule)
+		l.PreloadModule("config", config.PreloadModule)
+	} else {
+		luar.GetConfig(l).MethodNames = sqlMethodNames
+		l.PreloadModule("gcsql", sb.deniedModule("gcsql", "AllowSQL"))
+		l.PreloadModule("config", wrapModule(config.PreloadModule, func(l *lua.LState, module *lua.LTable) {
+			// the database settings aren't visible to plugins that can't use the database
+			getSystemCritical := module.RawGetString("system_critical_config").(*lua.LFunction)
+			module.RawSetString("system_critical_config", l.NewFunction(func(l *lua.LState) int {
+				getSystemCritical.GFunction(l)
+				// the config module returns a copy, so it can be changed
+				ud := l.CheckUserData(-1)
+				ud.Value.(*config.SystemCriticalConfig).SQLConfig = config.SQLConfig{}
+				return 1
+			}))
+		}))
+	}
+	l.PreloadModule("filepath", wrapModule(luaFilePath.Loader, func(l *lua.LState, module *lua.LTable) {
+		sb.restrictPaths(l, module, "glob", false, 1)
+		sb.restrictPaths(l, module, "eval_symlinks", false, 1)
+	}))
+	l.PreloadModule("gctemplates", wrapModule(gctemplates.PreloadModule, func(l *lua.LState, module *lua.LTable) {
+		sb.restrictPaths(l, module, "load_template", false)
+	}))
+}
+
+// isSQLType returns true if t (or the type it points to) is from the gcsql package
+func isSQLType(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.PkgPath() == gcsqlPkgPath
+}
+
+// isSQLMethod returns true if the method with the given name of type t belongs to a gcsql type, either directly or
+// by being promoted from an embedded gcsql field
+func isSQLMethod(t reflect.Type, name string) bool {
+	if isSQLType(t) {
+		return true
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() != reflect.Pointer {
+			fieldType = reflect.PointerTo(fieldType)
+		}
+		if _, ok := fieldType.MethodByName(name); ok && isSQLMethod(field.Type, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlMethodNames is used as the luar MethodNames function of plugins without the AllowSQL permission. It hides the
+// methods of gcsql values passed to the plugin (like the posts and uploads in event data), since they query and modify
+// the database, while leaving their fields accessible. Other methods use luar's default names
+func sqlMethodNames(t reflect.Type, m reflect.Method) []string {
+	if isSQLMethod(t, m.Name) {
+		return nil
+	}
+	first, size := utf8.DecodeRuneInString(m.Name)
+	return []string{m.Name, string(unicode.ToLower(first)) + m.Name[size:]}
+}
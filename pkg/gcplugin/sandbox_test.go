@@ -0,0 +1,272 @@
+package gcplugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/events"
+	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// setupSandboxTest writes the plugins to a temporary directory and sets their settings, returning the directory
+func setupSandboxTest(t *testing.T, plugins map[string]string, settings map[string]config.PluginSettings) string {
+	t.Helper()
+	config.InitTestConfig()
+	dir := t.TempDir()
+	systemCritical := config.GetSystemCriticalConfig()
+	systemCritical.PluginSettings = make(map[string]config.PluginSettings)
+	for name, code := range plugins {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(code), 0600))
+	}
+	for name, pluginSettings := range settings {
+		systemCritical.PluginSettings[filepath.Join(dir, name)] = pluginSettings
+	}
+	t.Cleanup(func() {
+		ClosePlugins()
+		systemCritical.PluginSettings = nil
+	})
+	return dir
+}
+
+func TestSandboxModulePermissions(t *testing.T) {
+	config.InitTestConfig()
+	systemCritical := config.GetSystemCriticalConfig()
+	systemCritical.DBpassword = "hunter2"
+	systemCritical.RandomSeed = "seed"
+	systemCritical.TripcodeSecret = "secret"
+	siteCfg := config.GetSiteConfig()
+	siteCfg.Captcha = &config.CaptchaConfig{SiteKey: "sitekey", AccountSecret: "captchasecret"}
+	t.Cleanup(func() {
+		systemCritical.DBpassword = ""
+		systemCritical.RandomSeed = ""
+		systemCritical.TripcodeSecret = ""
+		siteCfg.Captcha = nil
+	})
+
+	denied := &config.PluginSettings{}
+	l := newPluginState("test.lua", denied)
+	defer l.Close()
+	assert.ErrorContains(t, l.DoString(`require("http")`), "AllowNetwork")
+	assert.ErrorContains(t, l.DoString(`require("gcsql")`), "AllowSQL")
+	assert.NoError(t, l.DoString(`return require("config").system_critical_config().DBpassword`))
+	assert.Equal(t, "", l.Get(-1).String())
+	for _, removed := range []string{"os.execute", "os.exit", "os.getenv", "io.popen"} {
+		assert.NoError(t, l.DoString("return "+removed), removed)
+		assert.Equal(t, lua.LNil, l.Get(-1), removed)
+	}
+
+	allowed := &config.PluginSettings{AllowNetwork: true, AllowSQL: true}
+	l2 := newPluginState("test.lua", allowed)
+	defer l2.Close()
+	assert.NoError(t, l2.DoString(`require("http")`))
+	assert.NoError(t, l2.DoString(`require("gcsql")`))
+	assert.NoError(t, l2.DoString(`return require("config").system_critical_config().DBpassword`))
+	assert.Equal(t, "hunter2", l2.Get(-1).String())
+
+	for _, state := range []*lua.LState{l, l2} {
+		assert.NoError(t, state.DoString(`local config = require("config")
+			local systemCritical = config.system_critical_config()
+			local siteCfg = config.site_config()
+			return systemCritical.RandomSeed .. systemCritical.TripcodeSecret .. siteCfg.Captcha.AccountSecret`))
+		assert.Equal(t, "", state.Get(-1).String(), "secrets should never be visible to plugins")
+		assert.NoError(t, state.DoString(`return require("config").site_config().Captcha.SiteKey`))
+		assert.Equal(t, "sitekey", state.Get(-1).String())
+	}
+	assert.Equal(t, "seed", systemCritical.RandomSeed)
+	assert.Equal(t, "captchasecret", siteCfg.Captcha.AccountSecret, "the configuration itself shouldn't be changed")
+}
+
+// embeddedPost is used for testing that gcsql methods promoted to other types are hidden
+type embeddedPost struct {
+	gcsql.Post
+}
+
+func (embeddedPost) Describe() string {
+	return "embedded"
+}
+
+func TestSandboxSQLMethods(t *testing.T) {
+	config.InitTestConfig()
+	const code = `function check(post, embedded)
+		post.MessageRaw = "changed"
+		return post.Delete == nil and post.delete == nil and embedded.Delete == nil,
+			post.Message .. " " .. embedded.Message .. " " .. embedded:Describe()
+	end`
+	for _, allowSQL := range []bool{false, true} {
+		l := newPluginState("test.lua", &config.PluginSettings{AllowSQL: allowSQL})
+		require.NoError(t, l.DoString(code))
+		post := &gcsql.Post{ID: 1, Message: "message"}
+		embedded := &embeddedPost{Post: gcsql.Post{Message: "embedded message"}}
+		rets, err := luautil.CallFunction(l, l.GetGlobal("check"), 2, true, post, embedded)
+		if assert.NoError(t, err) {
+			assert.Equal(t, lua.LBool(!allowSQL), rets[0], "gcsql methods should only be visible with AllowSQL")
+			assert.Equal(t, "message embedded message embedded", rets[1].String())
+			assert.Equal(t, "changed", post.MessageRaw, "fields should still be writable")
+		}
+		l.Close()
+	}
+}
+
+func TestSandboxFilesystem(t *testing.T) {
+	config.InitTestConfig()
+	pluginDir := t.TempDir()
+	dataDir := t.TempDir()
+	pluginPath := filepath.Join(pluginDir, "fs.lua")
+	require.NoError(t, os.WriteFile(pluginPath, []byte(`return 1`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "helper.lua"), []byte(`return "helper"`), 0600))
+
+	settings := &config.PluginSettings{FilesystemPaths: []string{dataDir}}
+	l := newPluginState(pluginPath, settings)
+	defer l.Close()
+	l.SetGlobal("plugin_path", lua.LString(pluginPath))
+	l.SetGlobal("data_path", lua.LString(filepath.Join(dataDir, "data.txt")))
+	l.SetGlobal("outside_path", lua.LString(filepath.Join(pluginDir, "..", filepath.Base(dataDir)+"-outside.txt")))
+
+	// the plugin can read its own directory and load modules from it
+	assert.NoError(t, l.DoString(`local f = assert(io.open(plugin_path)); f:close()`))
+	assert.NoError(t, l.DoString(`assert(require("helper") == "helper")`))
+	assert.ErrorContains(t, l.DoString(`io.open(plugin_path, "w")`), ErrPathNotAllowed.Error())
+
+	// and read and write its FilesystemPaths
+	assert.NoError(t, l.DoString(`local f = assert(io.open(data_path, "w")); f:write("data"); f:close()`))
+	assert.NoError(t, l.DoString(`for line in io.lines(data_path) do assert(line == "data") end`))
+	assert.NoError(t, l.DoString(`assert(os.remove(data_path))`))
+
+	// but not anything else
+	assert.ErrorContains(t, l.DoString(`io.open(outside_path, "w")`), ErrPathNotAllowed.Error())
+	assert.ErrorContains(t, l.DoString(`io.open("/etc/passwd")`), ErrPathNotAllowed.Error())
+	assert.ErrorContains(t, l.DoString(`dofile("/etc/passwd")`), ErrPathNotAllowed.Error())
+	assert.ErrorContains(t, l.DoString(`os.rename(plugin_path, data_path)`), ErrPathNotAllowed.Error())
+	assert.ErrorContains(t, l.DoString(`require("filepath").glob("/etc/*")`), ErrPathNotAllowed.Error())
+	assert.Error(t, l.DoString(`dofile()`))
+
+	// modules can't be loaded from outside of the plugin's directory, even if package.path is changed
+	outsideDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outsideDir, "outside.lua"), []byte(`return "outside"`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte(`secret contents`), 0600))
+	l.SetGlobal("outside_dir", lua.LString(outsideDir))
+	assert.ErrorContains(t, l.DoString(`package.path = outside_dir .. "/?.lua"`), "can't be changed")
+	assert.ErrorContains(t, l.DoString(`package.cpath = outside_dir .. "/?.so"`), "can't be changed")
+	assert.NoError(t, l.DoString(`assert(package.path:sub(-6) == "/?.lua")`))
+	assert.NoError(t, l.DoString(`rawset(package, "path", outside_dir .. "/?.txt;" .. outside_dir .. "/?.lua")`))
+	err := l.DoString(`require("outside")`)
+	assert.ErrorContains(t, err, "not found")
+	err = l.DoString(`require("secret")`)
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "secret contents")
+	}
+	assert.ErrorContains(t, l.DoString(`require("..outside")`), "not found")
+	l.SetGlobal("relative_name", lua.LString("../"+filepath.Base(outsideDir)+"/outside"))
+	assert.ErrorContains(t, l.DoString(`require(relative_name)`), "not found")
+	assert.NoError(t, l.DoString(`package.preload.custom = function() return "custom" end; assert(require("custom") == "custom")`))
+
+	// symbolic links are resolved before checking the path
+	link := filepath.Join(dataDir, "link")
+	require.NoError(t, os.Symlink("/etc", link))
+	l.SetGlobal("link_path", lua.LString(filepath.Join(link, "passwd")))
+	assert.ErrorContains(t, l.DoString(`io.open(link_path)`), ErrPathNotAllowed.Error())
+}
+
+func TestLoadPluginsIsolation(t *testing.T) {
+	dir := setupSandboxTest(t, map[string]string{
+		"broken.lua":  `error("broken plugin")`,
+		"loop.lua":    `while true do end`,
+		"handler.lua": `require("events").register_event({"sandbox-test"}, function(tr) return "handled" end)`,
+	}, map[string]config.PluginSettings{
+		"loop.lua": {TimeoutSeconds: 1},
+	})
+	paths := []string{
+		filepath.Join(dir, "broken.lua"),
+		filepath.Join(dir, "loop.lua"),
+		filepath.Join(dir, "handler.lua"),
+	}
+	err := LoadPlugins(paths)
+	assert.ErrorContains(t, err, "broken plugin")
+	assert.ErrorContains(t, err, "context deadline exceeded")
+
+	statuses := PluginStatuses()
+	require.Len(t, statuses, 3)
+	assert.False(t, statuses[0].Loaded)
+	assert.Contains(t, statuses[0].LoadError, "broken plugin")
+	assert.False(t, statuses[1].Loaded)
+	assert.Equal(t, 1, statuses[1].TimeoutSeconds)
+	assert.True(t, statuses[2].Loaded)
+	assert.True(t, statuses[2].Enabled)
+	assert.Empty(t, statuses[2].LoadError)
+
+	// the plugin that failed doesn't keep the others from handling events
+	_, err, _ = events.TriggerEvent("sandbox-test")
+	assert.EqualError(t, err, "handled")
+
+	// disabling a plugin stops its handlers from being called. The setting can't be saved without a configuration file
+	assert.Error(t, SetPluginEnabled(paths[2], false))
+	_, err, _ = events.TriggerEvent("sandbox-test")
+	assert.NoError(t, err)
+	assert.False(t, PluginStatuses()[2].Enabled)
+	assert.True(t, config.GetPluginSettings(paths[2]).Disabled)
+
+	assert.Error(t, SetPluginEnabled(paths[2], true))
+	_, err, _ = events.TriggerEvent("sandbox-test")
+	assert.EqualError(t, err, "handled")
+
+	assert.ErrorIs(t, SetPluginEnabled(filepath.Join(dir, "missing.lua"), false), ErrPluginNotFound)
+}
+
+func TestLoadDisabledPlugin(t *testing.T) {
+	dir := setupSandboxTest(t, map[string]string{
+		"disabled.lua": `error("disabled plugins shouldn't be loaded")`,
+	}, map[string]config.PluginSettings{
+		"disabled.lua": {Disabled: true},
+	})
+	assert.NoError(t, LoadPlugins([]string{filepath.Join(dir, "disabled.lua")}))
+	statuses := PluginStatuses()
+	require.Len(t, statuses, 1)
+	assert.False(t, statuses[0].Enabled)
+	assert.False(t, statuses[0].Loaded)
+	assert.False(t, statuses[0].RestartRequired)
+
+	// enabling it requires a restart since it was never loaded
+	assert.Error(t, SetPluginEnabled(statuses[0].Path, true))
+	assert.True(t, PluginStatuses()[0].RestartRequired)
+}
+
+func TestSandboxBusy(t *testing.T) {
+	config.InitTestConfig()
+	settings := config.GetPluginSettings("busy.lua")
+	l := newPluginState("busy.lua", &settings)
+	defer l.Close()
+	sandbox := luautil.NewSandbox(l, "busy.lua", 100*time.Millisecond)
+	defer luautil.RemoveSandbox(l)
+	require.NoError(t, l.DoString(`function get_value() return "value" end`))
+	fn := l.GetGlobal("get_value")
+
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sandbox.Run(l, func() error {
+			close(started)
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		})
+	}()
+	<-started
+	_, err := luautil.CallFunction(l, fn, 1, true)
+	assert.ErrorIs(t, err, luautil.ErrPluginBusy)
+	<-done
+
+	rets, err := luautil.CallFunction(l, fn, 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, "value", rets[0].String())
+
+	sandbox.SetEnabled(false)
+	_, err = luautil.CallFunction(l, fn, 1, false)
+	assert.ErrorIs(t, err, luautil.ErrPluginDisabled)
+}
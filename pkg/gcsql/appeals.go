@@ -4,13 +4,54 @@ import (
 	"database/sql"
 	"errors"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// AppealOpen means that the appeal is waiting for a response from staff
+	AppealOpen AppealStatus = "open"
+	// AppealAwaitingUser means that staff replied to the appeal and are waiting for the banned user to respond
+	AppealAwaitingUser AppealStatus = "awaiting_user"
+	// AppealDenied means that staff denied the appeal, and the ban is still in effect
+	AppealDenied AppealStatus = "denied"
+	// AppealApproved means that staff approved the appeal and the ban was deactivated
+	AppealApproved AppealStatus = "approved"
+
+	selectAppealsBaseSQL = `SELECT id, staff_id, staff_username, ip_ban_id, appeal_text, is_denied, status, staff_unread, ` +
+		`is_ban_active, ban_expires_at, permanent, ban_appeal_at, timestamp FROM DBPREFIXv_appeals`
+	unresolvedAppealsClause = `status IN ('open', 'awaiting_user')`
 )
 
 var (
 	ErrAppealDoesNotExist = errors.New("appeal does not exist or has already been processed")
 	ErrBanNotActive       = errors.New("ban is not active")
+	ErrEmptyAppealMessage = errors.New("appeal message can not be empty")
 )
 
+// AppealStatus represents where an appeal is in the conversation between the banned user and staff
+type AppealStatus string
+
+// IsResolved returns true if the appeal has been approved or denied, ending the conversation
+func (as AppealStatus) IsResolved() bool {
+	return as == AppealDenied || as == AppealApproved
+}
+
+// Title returns the status as it is shown to staff and banned users
+func (as AppealStatus) Title() string {
+	switch as {
+	case AppealOpen:
+		return "Open"
+	case AppealAwaitingUser:
+		return "Awaiting user response"
+	case AppealDenied:
+		return "Denied"
+	case AppealApproved:
+		return "Approved"
+	}
+	return string(as)
+}
+
 // AppealsQueryOptions holds options for getting a list of appeals, including SQL request options
 type AppealsQueryOptions struct {
 	*RequestOptions
@@ -20,12 +61,35 @@ type AppealsQueryOptions struct {
 	Active BooleanFilter
 	// Unexpired is used to optionally limit results to only those with unexpired/expired bans
 	Unexpired BooleanFilter
+	// Resolved is used to optionally limit results to only those that have been approved or denied, or only those that
+	// are still open or awaiting a response from the banned user
+	Resolved BooleanFilter
 	// OrderDescending specifies whether results should be in descending order
 	OrderDescending bool
 	// Limit specifies the maximum number of results to return if greater than 0, otherwise no limit is applied
 	Limit int
 }
 
+func scanAppeal(rows interface{ Scan(...any) error }) (*Appeal, error) {
+	var appeal Appeal
+	var staffID *int
+	var staffUsername *string
+	if err := rows.Scan(
+		&appeal.ID, &staffID, &staffUsername, &appeal.IPBanID, &appeal.AppealText, &appeal.IsDenied, &appeal.Status,
+		&appeal.StaffUnread, &appeal.IsBanActive, &appeal.BanExpiresAt, &appeal.Permanent, &appeal.BanAppealAt,
+		&appeal.Timestamp,
+	); err != nil {
+		return nil, err
+	}
+	if staffID != nil {
+		appeal.StaffID = *staffID
+	}
+	if staffUsername != nil {
+		appeal.StaffUsername = *staffUsername
+	}
+	return &appeal, nil
+}
+
 // GetAppeals returns an array of appeals, optionally limiting them to a specific ban or ordering them in descending order
 func GetAppeals(options ...AppealsQueryOptions) ([]Appeal, error) {
 	var opts AppealsQueryOptions
@@ -40,19 +104,32 @@ func GetAppeals(options ...AppealsQueryOptions) ([]Appeal, error) {
 	}
 	opts.RequestOptions = setupOptionsWithTimeout(opts.RequestOptions)
 
-	query := `SELECT id, staff_id, staff_username, ip_ban_id, appeal_text, is_denied, is_ban_active, ban_expires_at, timestamp FROM DBPREFIXv_appeals`
+	var conditions []string
+	var params []any
 	if opts.BanID > 0 {
-		query += " WHERE ip_ban_id = ?"
+		conditions = append(conditions, "ip_ban_id = ?")
+		params = append(params, opts.BanID)
+	}
+	if clause := opts.Active.whereClause("is_ban_active", false); clause != "" {
+		conditions = append(conditions, strings.TrimPrefix(clause, " WHERE "))
 	}
-	query += opts.Active.whereClause("is_ban_active", false)
-
 	switch opts.Unexpired {
 	case OnlyTrue:
-		query += " AND (ban_expires_at > CURRENT_TIMESTAMP OR permanent = TRUE)"
+		conditions = append(conditions, "(ban_expires_at > CURRENT_TIMESTAMP OR permanent = TRUE)")
+	case OnlyFalse:
+		conditions = append(conditions, "(ban_expires_at <= CURRENT_TIMESTAMP AND permanent = FALSE)")
+	}
+	switch opts.Resolved {
+	case OnlyTrue:
+		conditions = append(conditions, "status IN ('denied', 'approved')")
 	case OnlyFalse:
-		query += " AND (ban_expires_at <= CURRENT_TIMESTAMP AND permanent = FALSE)"
+		conditions = append(conditions, unresolvedAppealsClause)
 	}
 
+	query := selectAppealsBaseSQL
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
 	if opts.OrderDescending {
 		query += " ORDER BY id DESC"
 	} else {
@@ -62,42 +139,171 @@ func GetAppeals(options ...AppealsQueryOptions) ([]Appeal, error) {
 		query += " LIMIT " + strconv.Itoa(opts.Limit)
 	}
 
-	var rows *sql.Rows
-	var err error
-	if opts.BanID > 0 {
-		rows, err = Query(opts.RequestOptions, query, opts.BanID)
-	} else {
-		rows, err = Query(opts.RequestOptions, query)
-	}
+	rows, err := Query(opts.RequestOptions, query, params...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var appeals []Appeal
 	for rows.Next() {
-		var appeal Appeal
-		var staffID *int
+		appeal, err := scanAppeal(rows)
+		if err != nil {
+			return nil, err
+		}
+		appeals = append(appeals, *appeal)
+	}
+	return appeals, nil
+}
+
+// GetAppealByID returns the appeal with the given ID, or ErrAppealDoesNotExist if it doesn't exist
+func GetAppealByID(appealID int, requestOptions ...*RequestOptions) (*Appeal, error) {
+	opts := setupOptionsWithTimeout(requestOptions...)
+	if len(requestOptions) == 0 {
+		defer opts.Cancel()
+	}
+	rows, err := Query(opts, selectAppealsBaseSQL+" WHERE id = ?", appealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrAppealDoesNotExist
+	}
+	return scanAppeal(rows)
+}
+
+// GetAppealMessages returns the replies to the appeal from staff and the banned user, in the order they were sent
+func GetAppealMessages(appealID int) ([]AppealMessage, error) {
+	const query = `SELECT id, appeal_id, ip_ban_id, staff_id, staff_username, message_text, timestamp
+		FROM DBPREFIXv_appeal_messages WHERE appeal_id = ? ORDER BY id ASC`
+	rows, cancel, err := QueryTimeoutSQL(nil, query, appealID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		cancel()
+		rows.Close()
+	}()
+	var messages []AppealMessage
+	for rows.Next() {
+		var message AppealMessage
 		var staffUsername *string
-		if err = rows.Scan(
-			&appeal.ID, &staffID, &staffUsername, &appeal.IPBanID, &appeal.AppealText, &appeal.IsDenied,
-			&appeal.IsBanActive, &appeal.BanExpiresAt, &appeal.Timestamp,
-		); err != nil {
+		if err = rows.Scan(&message.ID, &message.AppealID, &message.IPBanID, &message.StaffID, &staffUsername,
+			&message.MessageText, &message.Timestamp); err != nil {
 			return nil, err
 		}
-		if staffID != nil {
-			appeal.StaffID = *staffID
-			appeal.StaffUsername = *staffUsername
+		if staffUsername != nil {
+			message.StaffUsername = *staffUsername
 		}
-		appeals = append(appeals, appeal)
+		messages = append(messages, message)
 	}
-	return appeals, nil
+	return messages, rows.Close()
+}
+
+// AddAppealMessage adds a reply to an appeal that hasn't been approved or denied. If staffID is 0, the message is from the
+// banned user, and the appeal is reopened and marked as unread. Otherwise it is from a staff member, and the appeal
+// waits for the banned user to respond
+func AddAppealMessage(appealID int, staffID int, message string) error {
+	const insertMessageSQL = `INSERT INTO DBPREFIXip_ban_appeals_messages (appeal_id, staff_id, message_text) VALUES(?, ?, ?)`
+	const updateAppealSQL = `UPDATE DBPREFIXip_ban_appeals SET status = ?, staff_unread = ? WHERE id = ? AND ` +
+		unresolvedAppealsClause
+	if strings.TrimSpace(message) == "" {
+		return ErrEmptyAppealMessage
+	}
+
+	opts := setupOptionsWithTimeout()
+	defer opts.Cancel()
+	var err error
+	if opts.Tx, err = BeginContextTx(opts.Context); err != nil {
+		return err
+	}
+	defer opts.Tx.Rollback()
+
+	status := AppealAwaitingUser
+	var messageStaffID *int
+	if staffID > 0 {
+		messageStaffID = &staffID
+	} else {
+		status = AppealOpen
+	}
+	result, err := Exec(opts, updateAppealSQL, status, staffID == 0, appealID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrAppealDoesNotExist
+	}
+	if _, err = Exec(opts, insertMessageSQL, appealID, messageStaffID, message); err != nil {
+		return err
+	}
+	return opts.Tx.Commit()
+}
+
+// MarkAppealRead marks the appeal as read by staff, removing it from the unread appeals count
+func MarkAppealRead(appealID int) error {
+	_, err := ExecTimeoutSQL(nil, `UPDATE DBPREFIXip_ban_appeals SET staff_unread = FALSE WHERE id = ?`, appealID)
+	return err
+}
+
+// GetUnreadAppealCount returns the number of open appeals of active bans that have new messages from banned users that
+// staff haven't read yet
+func GetUnreadAppealCount() (int, error) {
+	const query = `SELECT COUNT(*) FROM DBPREFIXv_appeals WHERE staff_unread = TRUE AND status = 'open'
+		AND is_ban_active = TRUE AND (ban_expires_at > CURRENT_TIMESTAMP OR permanent = TRUE)`
+	var count int
+	err := QueryRowTimeoutSQL(nil, query, nil, []any{&count})
+	return count, err
+}
+
+// DenyAppeal closes the appeal without deactivating its ban. If appealAt is not zero, the banned user will not be able
+// to submit a new appeal until then
+func DenyAppeal(appealID int, staffID int, appealAt time.Time) error {
+	const denyAppealSQL = `UPDATE DBPREFIXip_ban_appeals SET status = 'denied', is_denied = TRUE, staff_id = ?,
+		staff_unread = FALSE WHERE id = ? AND ` + unresolvedAppealsClause
+	const insertAppealAuditSQL = `INSERT INTO DBPREFIXip_ban_appeals_audit (appeal_id, appeal_text, staff_id, is_denied)
+		SELECT id, appeal_text, staff_id, is_denied FROM DBPREFIXip_ban_appeals WHERE id = ?`
+	const updateAppealAtSQL = `UPDATE DBPREFIXip_ban SET appeal_at = ?
+		WHERE id = (SELECT ip_ban_id FROM DBPREFIXip_ban_appeals WHERE id = ?)`
+
+	opts := setupOptionsWithTimeout()
+	defer opts.Cancel()
+	var err error
+	if opts.Tx, err = BeginContextTx(opts.Context); err != nil {
+		return err
+	}
+	defer opts.Tx.Rollback()
+
+	result, err := Exec(opts, denyAppealSQL, staffID, appealID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrAppealDoesNotExist
+	}
+	if _, err = Exec(opts, insertAppealAuditSQL, appealID); err != nil {
+		return err
+	}
+	if !appealAt.IsZero() {
+		if _, err = Exec(opts, updateAppealAtSQL, appealAt, appealID); err != nil {
+			return err
+		}
+	}
+	return opts.Tx.Commit()
 }
 
 // ApproveAppeal deactivates the ban that the appeal was submitted for
 func ApproveAppeal(appealID int, staffID int) error {
-	const checkAppealSQL = "SELECT ip_ban_id, is_ban_active FROM DBPREFIXv_appeals WHERE id = ? AND is_denied = FALSE"
+	const checkAppealSQL = "SELECT ip_ban_id, is_ban_active FROM DBPREFIXv_appeals WHERE id = ? AND " + unresolvedAppealsClause
+	const approveAppealSQL = `UPDATE DBPREFIXip_ban_appeals SET status = 'approved', staff_id = ?, staff_unread = FALSE WHERE id = ?`
 	const insertAppealAudit = `INSERT INTO DBPREFIXip_ban_appeals_audit (appeal_id, appeal_text, staff_id, is_denied)
-		VALUES(?, (SELECT appeal_text FROM DBPREFIXip_ban_appeals WHERE id = ?), ?, 'Appeal approved, ban deactivated.', FALSE)`
+		VALUES(?, (SELECT appeal_text FROM DBPREFIXip_ban_appeals WHERE id = ?), ?, FALSE)`
 
 	opts := setupOptionsWithTimeout()
 	defer opts.Cancel()
@@ -125,6 +331,10 @@ func ApproveAppeal(appealID int, staffID int) error {
 		return err
 	}
 
+	if _, err = Exec(opts, approveAppealSQL, staffID, appealID); err != nil {
+		return err
+	}
+
 	_, err = Exec(opts, insertAppealAudit, appealID, appealID, staffID)
 	if err != nil {
 		return err
@@ -95,7 +95,8 @@ func testRunnerGetAppeals(t *testing.T, tC *testCaseGetAppeals, driver string) {
 		return
 	}
 
-	query := `SELECT id, staff_id, staff_username, ip_ban_id, appeal_text, is_denied, is_ban_active, ban_expires_at, timestamp FROM v_appeals`
+	query := `SELECT id, staff_id, staff_username, ip_ban_id, appeal_text, is_denied, status, staff_unread, is_ban_active, ` +
+		`ban_expires_at, permanent, ban_appeal_at, timestamp FROM v_appeals`
 	if tC.args.BanID > 0 {
 		// switch driver {
 		// case "mysql":
@@ -119,12 +120,14 @@ func testRunnerGetAppeals(t *testing.T, tC *testCaseGetAppeals, driver string) {
 		expectQuery.WithArgs(tC.args.BanID)
 	}
 
-	expectedRows := sqlmock.NewRows([]string{"id", "staff_id", "staff_username", "ip_ban_id", "appeal_text", "is_denied", "is_ban_active", "ban_expires_at", "timestamp"})
+	expectedRows := sqlmock.NewRows([]string{"id", "staff_id", "staff_username", "ip_ban_id", "appeal_text", "is_denied", "status",
+		"staff_unread", "is_ban_active", "ban_expires_at", "permanent", "ban_appeal_at", "timestamp"})
 	if len(tC.expectReturn) > 0 {
 		for _, expectedAppeal := range tC.expectReturn {
 			expectedRows.AddRow(
 				expectedAppeal.ID, expectedAppeal.StaffID, expectedAppeal.StaffUsername, expectedAppeal.IPBanID, expectedAppeal.AppealText,
-				expectedAppeal.IsDenied, expectedAppeal.IsBanActive, expectedAppeal.BanExpiresAt, expectedAppeal.Timestamp,
+				expectedAppeal.IsDenied, string(expectedAppeal.Status), expectedAppeal.StaffUnread, expectedAppeal.IsBanActive,
+				expectedAppeal.BanExpiresAt, expectedAppeal.Permanent, expectedAppeal.BanAppealAt, expectedAppeal.Timestamp,
 			)
 		}
 	}
@@ -178,14 +181,14 @@ func testRunnerApproveAppeal(t *testing.T, tC *testCaseApproveAppeals, sqlDriver
 	checkAppealsSQL += `\?`
 	deactivateSQL += `\?`
 	insertBanAudit += `\?, \?, FALSE, \?, \?, \?, \?, \?, \?, \?\)`
-	insertAppealsAudit += `\?, \(SELECT appeal_text FROM ip_ban_appeals WHERE id = \?\), \?, 'Appeal approved, ban deactivated.', FALSE\)`
+	insertAppealsAudit += `\?, \(SELECT appeal_text FROM ip_ban_appeals WHERE id = \?\), \?, FALSE\)`
 	// case "sqlite3", "postgres":
 	// 	checkAppealsSQL += `\$1`
 	// 	deactivateSQL += `\$1`
 	// 	insertBanAudit += `\$1, \$2, FALSE, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)`
-	// 	insertAppealsAudit += `\$1, \(SELECT appeal_text FROM ip_ban_appeals WHERE id = \$2\), \$3, 'Appeal approved, ban deactivated.', FALSE\)`
+	// 	insertAppealsAudit += `\$1, \(SELECT appeal_text FROM ip_ban_appeals WHERE id = \$2\), \$3, FALSE\)`
 	// }
-	checkAppealsSQL += " AND is_denied = FALSE"
+	checkAppealsSQL += ` AND status IN \('open', 'awaiting_user'\)`
 
 	mock.ExpectBegin()
 	mock.ExpectPrepare(checkAppealsSQL).ExpectQuery().WithArgs(tC.appealID).
@@ -201,6 +204,9 @@ func testRunnerApproveAppeal(t *testing.T, tC *testCaseApproveAppeals, sqlDriver
 				tC.ban.Permanent, tC.ban.StaffNote, tC.ban.Message, tC.ban.CanAppeal).
 			WillReturnResult(driver.ResultNoRows)
 
+		mock.ExpectPrepare(`UPDATE ip_ban_appeals SET status = 'approved', staff_id = \?, staff_unread = FALSE WHERE id = \?`).
+			ExpectExec().WithArgs(tC.staffID, tC.appealID).WillReturnResult(driver.ResultNoRows)
+
 		mock.ExpectPrepare(insertAppealsAudit).ExpectExec().
 			WithArgs(tC.appealID, tC.appealID, tC.staffID).
 			WillReturnResult(driver.ResultNoRows)
@@ -240,3 +246,96 @@ func TestApproveAppeal(t *testing.T) {
 		}
 	}
 }
+
+func TestAddAppealMessage(t *testing.T) {
+	const updateAppealSQL = `UPDATE ip_ban_appeals SET status = \?, staff_unread = \? WHERE id = \? AND status IN \('open', 'awaiting_user'\)`
+	const insertMessageSQL = `INSERT INTO ip_ban_appeals_messages \(appeal_id, staff_id, message_text\) VALUES\(\?, \?, \?\)`
+	for _, sqlDriver := range testingDBDrivers {
+		t.Run(sqlDriver, func(t *testing.T) {
+			config.SetTestDBConfig(sqlDriver, "localhost", "gochan", "gochan", "gochan", "")
+			db, mock, err := sqlmock.New()
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			if !assert.NoError(t, SetTestingDB(sqlDriver, "gochan", "", db)) {
+				t.FailNow()
+			}
+
+			assert.ErrorIs(t, AddAppealMessage(1, 0, " "), ErrEmptyAppealMessage)
+
+			// staff reply, waits for the banned user
+			mock.ExpectBegin()
+			mock.ExpectPrepare(updateAppealSQL).ExpectExec().
+				WithArgs(AppealAwaitingUser, false, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectPrepare(insertMessageSQL).ExpectExec().
+				WithArgs(1, 2, "Why should we unban you?").WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+			assert.NoError(t, AddAppealMessage(1, 2, "Why should we unban you?"))
+
+			// banned user reply, reopens the appeal and marks it unread
+			mock.ExpectBegin()
+			mock.ExpectPrepare(updateAppealSQL).ExpectExec().
+				WithArgs(AppealOpen, true, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectPrepare(insertMessageSQL).ExpectExec().
+				WithArgs(1, nil, "Because I'm sorry").WillReturnResult(sqlmock.NewResult(2, 1))
+			mock.ExpectCommit()
+			assert.NoError(t, AddAppealMessage(1, 0, "Because I'm sorry"))
+
+			// appeal already approved or denied
+			mock.ExpectBegin()
+			mock.ExpectPrepare(updateAppealSQL).ExpectExec().
+				WithArgs(AppealOpen, true, 1).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			assert.ErrorIs(t, AddAppealMessage(1, 0, "Hello?"), ErrAppealDoesNotExist)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+			closeMock(t, mock)
+		})
+	}
+}
+
+func TestDenyAppeal(t *testing.T) {
+	const denyAppealSQL = `UPDATE ip_ban_appeals SET status = 'denied', is_denied = TRUE, staff_id = \?,\s+staff_unread = FALSE WHERE id = \? AND status IN \('open', 'awaiting_user'\)`
+	const insertAuditSQL = `INSERT INTO ip_ban_appeals_audit \(appeal_id, appeal_text, staff_id, is_denied\)\s+SELECT id, appeal_text, staff_id, is_denied FROM ip_ban_appeals WHERE id = \?`
+	const updateAppealAtSQL = `UPDATE ip_ban SET appeal_at = \?\s+WHERE id = \(SELECT ip_ban_id FROM ip_ban_appeals WHERE id = \?\)`
+	for _, sqlDriver := range testingDBDrivers {
+		t.Run(sqlDriver, func(t *testing.T) {
+			config.SetTestDBConfig(sqlDriver, "localhost", "gochan", "gochan", "gochan", "")
+			db, mock, err := sqlmock.New()
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			if !assert.NoError(t, SetTestingDB(sqlDriver, "gochan", "", db)) {
+				t.FailNow()
+			}
+
+			mock.ExpectBegin()
+			mock.ExpectPrepare(denyAppealSQL).ExpectExec().
+				WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectPrepare(insertAuditSQL).ExpectExec().
+				WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			assert.NoError(t, DenyAppeal(1, 1, time.Time{}))
+
+			appealAt := time.Now().Add(7 * 24 * time.Hour)
+			mock.ExpectBegin()
+			mock.ExpectPrepare(denyAppealSQL).ExpectExec().
+				WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectPrepare(insertAuditSQL).ExpectExec().
+				WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectPrepare(updateAppealAtSQL).ExpectExec().
+				WithArgs(appealAt, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			assert.NoError(t, DenyAppeal(2, 1, appealAt))
+
+			mock.ExpectBegin()
+			mock.ExpectPrepare(denyAppealSQL).ExpectExec().
+				WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+			assert.ErrorIs(t, DenyAppeal(3, 1, time.Time{}), ErrAppealDoesNotExist)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+			closeMock(t, mock)
+		})
+	}
+}
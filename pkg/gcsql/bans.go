@@ -55,7 +55,12 @@ func NewIPBan(ban *IPBan, requestOpts ...*RequestOptions) error {
 		return err
 	}
 	if shouldCommit {
-		return opts.Tx.Commit()
+		if err = opts.Tx.Commit(); err != nil {
+			return err
+		}
+		// bans inserted as part of a larger transaction (like a database migration) don't trigger the event, since
+		// they may still be rolled back
+		BanIssuedEvent.Notify(&BanIssuedPayload{Ban: ban})
 	}
 
 	return nil
@@ -150,9 +155,11 @@ func (ipb *IPBan) Appeal(msg string) error {
 	if err != nil {
 		return err
 	}
-	_, err = Exec(nil, insertAppealAuditSQL, appealID)
-
-	return err
+	if _, err = Exec(nil, insertAppealAuditSQL, appealID); err != nil {
+		return err
+	}
+	AppealCreatedEvent.Notify(&AppealCreatedPayload{Ban: ipb, AppealID: appealID, Message: msg})
+	return nil
 }
 
 // IsGlobalBan returns true if BoardID is a nil int, meaning they are banned on all boards, as opposed to a specific one
@@ -190,3 +197,19 @@ func DeactivateBan(banID int, staffID int, opts ...*RequestOptions) error {
 	return nil
 
 }
+
+// DeactivateExpiredBans deactivates all non-permanent bans that have expired and returns the number of bans that were
+// deactivated. Unlike DeactivateBan, it doesn't add an audit entry since no staff member deactivated them
+func DeactivateExpiredBans(opts ...*RequestOptions) (int64, error) {
+	const query = `UPDATE DBPREFIXip_ban SET is_active = FALSE
+		WHERE is_active AND NOT permanent AND expires_at < CURRENT_TIMESTAMP`
+	opt := setupOptionsWithTimeout(opts...)
+	if opt.Cancel != nil {
+		defer opt.Cancel()
+	}
+	result, err := Exec(opt, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
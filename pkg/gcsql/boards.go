@@ -272,7 +272,8 @@ func (board *Board) Delete() error {
 	return nil
 }
 
-// DeleteOldThreads deletes old threads that exceed the limit set by maxThreads and returns the post IDs in those threads
+// DeleteOldThreads deletes old threads that exceed the limit set by maxThreads and returns the post IDs in those threads.
+// PostDeletedEvent is triggered for each post in them that wasn't already deleted
 func (board *Board) DeleteOldThreads(maxThreads int) ([]int, error) {
 	if maxThreads < 1 {
 		return nil, nil
@@ -322,7 +323,8 @@ func (board *Board) DeleteOldThreads(maxThreads int) ([]int, error) {
 		return nil, err
 	}
 
-	if rows, err = QueryContextSQL(ctx, tx, `SELECT id FROM DBPREFIXposts WHERE thread_id in `+idSetStr,
+	if rows, err = QueryContextSQL(ctx, tx,
+		`SELECT id, thread_id, is_top_post, is_deleted FROM DBPREFIXposts WHERE thread_id in `+idSetStr,
 		threadIDs...); err != nil {
 		return nil, err
 	}
@@ -331,11 +333,17 @@ func (board *Board) DeleteOldThreads(maxThreads int) ([]int, error) {
 	}()
 
 	var postIDs []int
+	var deleted []*PostDeletedPayload
 	for rows.Next() {
-		if err = rows.Scan(&id); err != nil {
+		var wasDeleted bool
+		payload := &PostDeletedPayload{BoardDir: board.Dir}
+		if err = rows.Scan(&payload.PostID, &payload.ThreadID, &payload.IsTopPost, &wasDeleted); err != nil {
 			return nil, err
 		}
-		postIDs = append(postIDs, id)
+		postIDs = append(postIDs, payload.PostID)
+		if !wasDeleted {
+			deleted = append(deleted, payload)
+		}
 	}
 
 	if _, err = ExecContextSQL(ctx, tx, `UPDATE DBPREFIXposts SET is_deleted = TRUE WHERE thread_id in `+idSetStr,
@@ -343,7 +351,13 @@ func (board *Board) DeleteOldThreads(maxThreads int) ([]int, error) {
 		return nil, err
 	}
 
-	return postIDs, tx.Commit()
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, payload := range deleted {
+		PostDeletedEvent.Notify(payload)
+	}
+	return postIDs, nil
 }
 
 func (board *Board) GetThreads(onlyNotDeleted bool, orderLastByBump bool, stickiedFirst bool) ([]Thread, error) {
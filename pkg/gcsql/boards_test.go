@@ -0,0 +1,47 @@
+package gcsql
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteOldThreadsNotifiesDeletedPosts(t *testing.T) {
+	mock := SetupMockDB(t, "sqlite3")
+	var deleted []PostDeletedPayload
+	PostDeletedEvent.Register(func(payload *PostDeletedPayload) error {
+		if payload.BoardDir == "prunetest" {
+			deleted = append(deleted, *payload)
+		}
+		return nil
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare(`SELECT id FROM threads\s+WHERE board_id = \? AND is_deleted = FALSE AND stickied = FALSE\s+ORDER BY last_bump DESC`).
+		ExpectQuery().WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(2).AddRow(1))
+	mock.ExpectPrepare(`UPDATE threads SET is_deleted = TRUE WHERE id in \(\?,\?\)`).ExpectExec().
+		WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare(`SELECT id, thread_id, is_top_post, is_deleted FROM posts WHERE thread_id in \(\?,\?\)`).ExpectQuery().
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "thread_id", "is_top_post", "is_deleted"}).
+			AddRow(2, 2, true, false).
+			AddRow(4, 2, false, true).
+			AddRow(1, 1, true, false))
+	mock.ExpectPrepare(`UPDATE posts SET is_deleted = TRUE WHERE thread_id in \(\?,\?\)`).ExpectExec().
+		WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	board := &Board{ID: 1, Dir: "prunetest"}
+	postIDs, err := board.DeleteOldThreads(1)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []int{2, 4, 1}, postIDs)
+	assert.Equal(t, []PostDeletedPayload{
+		{PostID: 2, ThreadID: 2, IsTopPost: true, BoardDir: "prunetest"},
+		{PostID: 1, ThreadID: 1, IsTopPost: true, BoardDir: "prunetest"},
+	}, deleted, "the event should only be triggered for posts that weren't already deleted")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
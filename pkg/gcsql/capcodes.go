@@ -0,0 +1,49 @@
+package gcsql
+
+import "strings"
+
+var (
+	// Capcodes maps the capcodes that staff can sign their posts with to the minimum staff rank required to use them. A
+	// post's capcode is stored in its tripcode column, with is_role_signature set
+	Capcodes = map[string]int{
+		"admin":   3,
+		"mod":     2,
+		"janitor": 1,
+	}
+
+	capcodeTitles = map[string]string{
+		"admin":   "Admin",
+		"mod":     "Mod",
+		"janitor": "Janitor",
+	}
+	capcodeAliases = map[string]string{
+		"administrator": "admin",
+		"moderator":     "mod",
+	}
+)
+
+// CapcodeFromString returns the capcode matching the string (e.g. "Admin" or "moderator"), ignoring case and
+// surrounding whitespace, or an empty string if it isn't a capcode
+func CapcodeFromString(str string) string {
+	str = strings.ToLower(strings.TrimSpace(str))
+	if alias, ok := capcodeAliases[str]; ok {
+		return alias
+	}
+	if _, ok := Capcodes[str]; ok {
+		return str
+	}
+	return ""
+}
+
+// CapcodeTitle returns the text shown for the capcode on posts, e.g. "Mod" for "mod"
+func CapcodeTitle(capcode string) string {
+	return capcodeTitles[capcode]
+}
+
+// Capcode returns the post's staff capcode if it is a staff signature, or an empty string otherwise
+func (p *Post) Capcode() string {
+	if !p.IsRoleSignature {
+		return ""
+	}
+	return p.Tripcode
+}
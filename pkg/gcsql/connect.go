@@ -1,6 +1,7 @@
 package gcsql
 
 import (
+	"context"
 	"database/sql"
 	"os"
 	"regexp"
@@ -17,6 +18,14 @@ func ConnectToDB(cfg *config.SQLConfig) error {
 	return err
 }
 
+// Ping verifies that the database can be reached, returning ErrNotConnected if ConnectToDB hasn't been called
+func Ping(ctx context.Context) error {
+	if gcdb == nil || gcdb.db == nil {
+		return ErrNotConnected
+	}
+	return gcdb.db.PingContext(ctx)
+}
+
 // SetDB sets the global database connection (mainly used by gochan-migration)
 func SetDB(db *GCDB) {
 	gcdb = db
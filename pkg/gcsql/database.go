@@ -20,12 +20,13 @@ import (
 
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
 )
 
 const (
 	// gochanVersionKeyConstant is the key value used in the version table of the database to store and receive the (database) version of base gochan
 	gochanVersionKeyConstant = "gochan"
-	DatabaseVersion          = 7
+	DatabaseVersion          = 13
 	UnsupportedSQLVersionMsg = `syntax error in SQL query, confirm you are using a supported driver and SQL server (error text: %s)`
 	MySQLConnStr             = "%s:%s@tcp(%s)/%s?parseTime=true&collation=utf8mb4_unicode_ci"
 	PostgresConnStr          = "postgres://%s:%s@%s/%s?sslmode=disable"
@@ -65,6 +66,11 @@ var (
 		"PARAM_NTOA", "INET6_NTOA(?)",
 	}
 	ipFuncRE = regexp.MustCompile(`(INET6_NTOA|INET6_ATON)\(([^)]+)\)`) // used for more flexible replacement based on SQL driver
+
+	// queryDuration measures the time taken by GCDB's Exec, QueryRow, and Query methods, including preparing the
+	// statement. For Query, the time taken to read the returned rows isn't included
+	queryDuration = metrics.NewHistogramVec("gochan_db_query_duration_seconds",
+		"Time taken by database queries, by operation (exec, query_row, or query)", nil, "operation")
 )
 
 type GCDB struct {
@@ -132,6 +138,7 @@ func (db *GCDB) PrepareContextSQL(ctx context.Context, query string, tx *sql.Tx)
 func (db *GCDB) Exec(opts *RequestOptions, query string, values ...any) (sql.Result, error) {
 	logger := gcutil.Logger()
 	logger.Trace().Str("sql", query).Msg("Exec")
+	defer queryDuration.ObserveSince(time.Now(), "exec")
 	opts = setupOptions(opts)
 	stmt, err := db.PrepareContextSQL(opts.Context, query, opts.Tx)
 	if err != nil {
@@ -224,6 +231,7 @@ func (db *GCDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, erro
 func (db *GCDB) QueryRow(opts *RequestOptions, query string, values []any, out []any) error {
 	logger := gcutil.Logger()
 	logger.Trace().Str("sql", query).Msg("QueryRow")
+	defer queryDuration.ObserveSince(time.Now(), "query_row")
 	opts = setupOptions(opts)
 	stmt, err := db.PrepareContextSQL(opts.Context, query, opts.Tx)
 	if err != nil {
@@ -294,6 +302,7 @@ func (db *GCDB) QueryRowTxSQL(tx *sql.Tx, query string, values, out []any) error
 func (db *GCDB) Query(opts *RequestOptions, query string, a ...any) (*sql.Rows, error) {
 	logger := gcutil.Logger()
 	logger.Trace().Str("sql", query).Msg("Query")
+	defer queryDuration.ObserveSince(time.Now(), "query")
 	opts = setupOptions(opts)
 	stmt, err := db.PrepareContextSQL(opts.Context, query, opts.Tx)
 	if err != nil {
@@ -499,7 +508,7 @@ func OptimizeDatabase() error {
 	switch config.GetSQLConfig().DBtype {
 	case "mysql":
 		return optimizeMySQL()
-	case "postgresql":
+	case "postgres", "postgresql":
 		return optimizePostgres()
 	case "sqlite3":
 		return optimizeSqlite3()
@@ -67,15 +67,30 @@ func UpdateDatabase() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
+	if _, err = MigrateUp(ctx, 0, false); err != nil {
+		return err
+	}
+
+	gcutil.LogInfo().
+		Int("DBVersion", gcsql.DatabaseVersion).
+		Msg("Database updated successfully")
+	return nil
+}
+
+// updateLegacyDatabase updates a database older than legacyVersion with the per-database update functions, since
+// databases from before migrations were added don't have a consistent layout for a given version
+func updateLegacyDatabase(ctx context.Context, sqlConfig *config.SQLConfig) (err error) {
+	errEv := gcutil.LogError(nil)
+
 	var filterTableExists bool
-	filterTableExists, err = migrationutil.TableExists(ctx, db, nil, "DBPREFIXfilters", &sqlConfig)
+	filterTableExists, err = migrationutil.TableExists(ctx, nil, nil, "DBPREFIXfilters", sqlConfig)
 	if err != nil {
 		return err
 	}
 
 	if !filterTableExists {
 		// DBPREFIXfilters not found, create it and migrate data from DBPREFIXfile_ban, DBPREFIXfilename_ban, and DBPREFIXusername_ban,
-		if err = addFilterTables(ctx, nil, &sqlConfig, errEv); err != nil {
+		if err = addFilterTables(ctx, nil, sqlConfig, errEv); err != nil {
 			return err
 		}
 	}
@@ -87,11 +102,11 @@ func UpdateDatabase() error {
 
 	switch sqlConfig.DBtype {
 	case "mysql":
-		err = updateMysqlDB(ctx, &sqlConfig, errEv)
+		err = updateMysqlDB(ctx, sqlConfig, errEv)
 	case "postgres":
-		err = updatePostgresDB(ctx, &sqlConfig, errEv)
+		err = updatePostgresDB(ctx, sqlConfig, errEv)
 	case "sqlite3":
-		err = updateSqliteDB(ctx, &sqlConfig, errEv)
+		err = updateSqliteDB(ctx, sqlConfig, errEv)
 	}
 	if err != nil {
 		return err
@@ -101,7 +116,11 @@ func UpdateDatabase() error {
 		return err
 	}
 
-	if err = updateFilters(ctx, &sqlConfig, errEv); err != nil {
+	if err = updateFilters(ctx, sqlConfig, errEv); err != nil {
+		return err
+	}
+
+	if err = addMissingTables(ctx, sqlConfig, errEv); err != nil {
 		return err
 	}
 
@@ -112,15 +131,8 @@ func UpdateDatabase() error {
 	}
 
 	query := `UPDATE DBPREFIXdatabase_version SET version = ? WHERE component = 'gochan'`
-	_, err = gcsql.ExecContextSQL(ctx, nil, query, gcsql.DatabaseVersion)
-	if err != nil {
-		return err
-	}
-
-	gcutil.LogInfo().
-		Int("DBVersion", gcsql.DatabaseVersion).
-		Msg("Database updated successfully")
-	return nil
+	_, err = gcsql.ExecContextSQL(ctx, nil, query, legacyVersion)
+	return err
 }
 
 func isUpdated() (bool, error) {
@@ -0,0 +1,359 @@
+package dbupdate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcsql/migrationutil"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+// legacyVersion is the last database version reached by the per-database update functions (updateMysqlDB,
+// updatePostgresDB, and updateSqliteDB). Schema changes after it are made by the migrations in the migrations list
+const legacyVersion = 11
+
+const createMigrationsTableSQL = `CREATE TABLE IF NOT EXISTS DBPREFIXschema_migrations(
+	version INT NOT NULL PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+var (
+	ErrIrreversibleMigration = errors.New("migration can't be rolled back")
+	ErrDatabaseAhead         = errors.New("database version is newer than the latest migration")
+	ErrInvalidMigrationSteps = errors.New("invalid number of migrations")
+	ErrInvalidTargetVersion  = errors.New("invalid target version")
+)
+
+// Migration is a numbered schema change. Up applies it and Down reverts it, and both map a database type (mysql,
+// postgres, or sqlite3) to the statements to run for it, which may use DBPREFIX like other gochan queries. An empty
+// list of statements is allowed if the migration doesn't change anything for that database type
+type Migration struct {
+	Version int
+	Name    string
+	Up      map[string][]string
+	Down    map[string][]string
+}
+
+// Statements returns the statements that apply the migration (or revert it if down is true) for the database type
+func (m *Migration) Statements(dbType string, down bool) ([]string, error) {
+	scripts := m.Up
+	if down {
+		scripts = m.Down
+	}
+	statements, ok := scripts[dbType]
+	if !ok && down {
+		return nil, fmt.Errorf("%w: migration %d (%s) has no %s statements", ErrIrreversibleMigration, m.Version, m.Name, dbType)
+	} else if !ok {
+		return nil, fmt.Errorf("migration %d (%s) has no %s statements", m.Version, m.Name, dbType)
+	}
+	return statements, nil
+}
+
+// migrations is the list of migrations after legacyVersion, in order and without gaps. When a migration is added,
+// gcsql.DatabaseVersion must be set to its version and initdb_*.sql must be updated to include its changes, since
+// new installations are created at gcsql.DatabaseVersion
+var migrations = []Migration{
+	{
+		Version: 12,
+		Name:    "add_webhooks",
+		Up: map[string][]string{
+			"mysql": {`CREATE TABLE DBPREFIXwebhooks(
+	id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,
+	name VARCHAR(45) NOT NULL,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	boards TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	payload_template TEXT NOT NULL,
+	max_retries SMALLINT NOT NULL DEFAULT 3,
+	is_active BOOL NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`},
+			"postgres": {`CREATE TABLE DBPREFIXwebhooks(
+	id BIGSERIAL PRIMARY KEY,
+	name VARCHAR(45) NOT NULL,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	boards TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	payload_template TEXT NOT NULL,
+	max_retries SMALLINT NOT NULL DEFAULT 3,
+	is_active BOOL NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`},
+			"sqlite3": {`CREATE TABLE DBPREFIXwebhooks(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name VARCHAR(45) NOT NULL,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	boards TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	payload_template TEXT NOT NULL,
+	max_retries SMALLINT NOT NULL DEFAULT 3,
+	is_active BOOL NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`},
+		},
+		Down: map[string][]string{
+			"mysql":    {"DROP TABLE DBPREFIXwebhooks"},
+			"postgres": {"DROP TABLE DBPREFIXwebhooks"},
+			"sqlite3":  {"DROP TABLE DBPREFIXwebhooks"},
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_files_stored_checksum",
+		Up: map[string][]string{
+			"mysql":    {"ALTER TABLE DBPREFIXfiles ADD COLUMN stored_checksum VARCHAR(45) NOT NULL DEFAULT ''"},
+			"postgres": {"ALTER TABLE DBPREFIXfiles ADD COLUMN stored_checksum VARCHAR(45) NOT NULL DEFAULT ''"},
+			"sqlite3":  {"ALTER TABLE DBPREFIXfiles ADD COLUMN stored_checksum VARCHAR(45) NOT NULL DEFAULT ''"},
+		},
+		Down: map[string][]string{
+			"mysql":    {"ALTER TABLE DBPREFIXfiles DROP COLUMN stored_checksum"},
+			"postgres": {"ALTER TABLE DBPREFIXfiles DROP COLUMN stored_checksum"},
+			"sqlite3":  {"ALTER TABLE DBPREFIXfiles DROP COLUMN stored_checksum"},
+		},
+	},
+}
+
+// latestVersion returns the version of the database after all migrations are applied
+func latestVersion() int {
+	if len(migrations) == 0 {
+		return legacyVersion
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationStatus is a migration and whether it has been applied to the database
+type MigrationStatus struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+	// AppliedAt is nil if the migration was applied by creating the database at or after its version
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// MigrationReport is the migration status of the database
+type MigrationReport struct {
+	DatabaseVersion int `json:"databaseVersion"`
+	LatestVersion   int `json:"latestVersion"`
+	// LegacyUpdateRequired is true if the database is older than legacyVersion, in which case it is updated by the
+	// per-database update functions before any migrations are applied
+	LegacyUpdateRequired bool              `json:"legacyUpdateRequired"`
+	Migrations           []MigrationStatus `json:"migrations"`
+}
+
+// Pending returns the migrations that haven't been applied
+func (mr *MigrationReport) Pending() []Migration {
+	var pending []Migration
+	for m, status := range mr.Migrations {
+		if !status.Applied {
+			pending = append(pending, migrations[m])
+		}
+	}
+	return pending
+}
+
+// GetMigrationStatus returns the current database version and whether each migration has been applied. The
+// database version is authoritative, the schema_migrations table only records when each migration was applied
+func GetMigrationStatus(ctx context.Context) (*MigrationReport, error) {
+	sqlConfig := config.GetSQLConfig()
+	dbVersion, err := gcsql.GetComponentVersion("gochan")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gcsql.ErrInvalidVersion
+	}
+	if err != nil {
+		return nil, err
+	}
+	report := &MigrationReport{
+		DatabaseVersion:      dbVersion,
+		LatestVersion:        latestVersion(),
+		LegacyUpdateRequired: dbVersion < legacyVersion,
+		Migrations:           make([]MigrationStatus, len(migrations)),
+	}
+	if dbVersion > report.LatestVersion {
+		return nil, fmt.Errorf("%w (database version: %d, latest migration: %d)", ErrDatabaseAhead, dbVersion, report.LatestVersion)
+	}
+
+	appliedAt := make(map[int]time.Time)
+	tableExists, err := migrationutil.TableExists(ctx, nil, nil, "DBPREFIXschema_migrations", &sqlConfig)
+	if err != nil {
+		return nil, err
+	}
+	if tableExists {
+		rows, err := gcsql.QueryContextSQL(ctx, nil, "SELECT version, applied_at FROM DBPREFIXschema_migrations")
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int
+			var applied time.Time
+			if err = rows.Scan(&version, &applied); err != nil {
+				return nil, err
+			}
+			appliedAt[version] = applied
+		}
+		if err = rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	for m, migration := range migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: migration.Version <= dbVersion,
+		}
+		if applied, ok := appliedAt[migration.Version]; ok && status.Applied {
+			status.AppliedAt = &applied
+		}
+		report.Migrations[m] = status
+	}
+	return report, nil
+}
+
+// MigrateUp applies the pending migrations up to and including the target version (or all of them if target is 0),
+// running the legacy update functions first if the database is older than the first migration. If dryRun is true,
+// it only returns the migrations that would be applied. Each migration is applied in a transaction, except on MySQL,
+// which implicitly commits schema changes
+func MigrateUp(ctx context.Context, target int, dryRun bool) ([]Migration, error) {
+	report, err := GetMigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if target == 0 {
+		target = report.LatestVersion
+	}
+	if minVersion := max(report.DatabaseVersion, legacyVersion); target < minVersion || target > report.LatestVersion {
+		return nil, fmt.Errorf("%w %d, must be between %d and the latest version (%d)",
+			ErrInvalidTargetVersion, target, minVersion, report.LatestVersion)
+	}
+	var applying []Migration
+	for _, migration := range report.Pending() {
+		if migration.Version <= target {
+			applying = append(applying, migration)
+		}
+	}
+	if dryRun {
+		return applying, nil
+	}
+
+	sqlConfig := config.GetSQLConfig()
+	if report.LegacyUpdateRequired {
+		if err = updateLegacyDatabase(ctx, &sqlConfig); err != nil {
+			return nil, err
+		}
+	}
+	if len(applying) > 0 {
+		if _, err = gcsql.ExecContextSQL(ctx, nil, createMigrationsTableSQL); err != nil {
+			return nil, err
+		}
+	}
+	for m, migration := range applying {
+		if err = runMigration(ctx, &sqlConfig, &migration, false); err != nil {
+			return applying[:m], err
+		}
+		gcutil.LogInfo().
+			Int("version", migration.Version).
+			Str("name", migration.Name).
+			Msg("Applied database migration")
+	}
+	return applying, nil
+}
+
+// MigrateDown rolls back the given number of applied migrations, starting with the newest. The database can't be
+// rolled back to a version older than the first migration. If dryRun is true, it only returns the migrations that
+// would be rolled back
+func MigrateDown(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidMigrationSteps, steps)
+	}
+	report, err := GetMigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var rollingBack []Migration
+	for m := len(report.Migrations) - 1; m >= 0 && len(rollingBack) < steps; m-- {
+		if report.Migrations[m].Applied {
+			rollingBack = append(rollingBack, migrations[m])
+		}
+	}
+	if len(rollingBack) < steps {
+		return nil, fmt.Errorf("%w: unable to roll back %d migrations, only %d have been applied since version %d",
+			ErrInvalidMigrationSteps, steps, len(rollingBack), legacyVersion)
+	}
+	sqlConfig := config.GetSQLConfig()
+	for _, migration := range rollingBack {
+		// check all of them before rolling any back, so that an irreversible migration doesn't stop it partway
+		if _, err = migration.Statements(sqlConfig.DBtype, true); err != nil {
+			return nil, err
+		}
+	}
+	if dryRun {
+		return rollingBack, nil
+	}
+
+	if _, err = gcsql.ExecContextSQL(ctx, nil, createMigrationsTableSQL); err != nil {
+		return nil, err
+	}
+	for m, migration := range rollingBack {
+		if err = runMigration(ctx, &sqlConfig, &migration, true); err != nil {
+			return rollingBack[:m], err
+		}
+		gcutil.LogInfo().
+			Int("version", migration.Version).
+			Str("name", migration.Name).
+			Msg("Rolled back database migration")
+	}
+	return rollingBack, nil
+}
+
+// runMigration applies or reverts the migration and updates the database version and schema_migrations table
+func runMigration(ctx context.Context, sqlConfig *config.SQLConfig, migration *Migration, down bool) (err error) {
+	statements, err := migration.Statements(sqlConfig.DBtype, down)
+	if err != nil {
+		return err
+	}
+	var tx *sql.Tx
+	if sqlConfig.DBtype != "mysql" {
+		// MySQL implicitly commits most schema changes, so a transaction wouldn't be able to roll them back
+		if tx, err = gcsql.BeginContextTx(ctx); err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	for _, statement := range statements {
+		if _, err = gcsql.ExecContextSQL(ctx, tx, statement); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	newVersion := migration.Version
+	if down {
+		newVersion = migration.Version - 1
+		_, err = gcsql.ExecContextSQL(ctx, tx, "DELETE FROM DBPREFIXschema_migrations WHERE version = ?", migration.Version)
+	} else {
+		_, err = gcsql.ExecContextSQL(ctx, tx, "INSERT INTO DBPREFIXschema_migrations (version, name) VALUES(?, ?)",
+			migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = gcsql.ExecContextSQL(ctx, tx,
+		"UPDATE DBPREFIXdatabase_version SET version = ? WHERE component = 'gochan'", newVersion,
+	); err != nil {
+		return err
+	}
+	if tx != nil {
+		return tx.Commit()
+	}
+	return nil
+}
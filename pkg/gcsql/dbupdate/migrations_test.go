@@ -0,0 +1,220 @@
+package dbupdate
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	_ "github.com/gochan-org/gochan/pkg/gcsql/initsql"
+	"github.com/gochan-org/gochan/pkg/gcsql/migrationutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = []Migration{
+	{
+		Version: legacyVersion + 1,
+		Name:    "add_test_table",
+		Up: map[string][]string{
+			"sqlite3": {"CREATE TABLE DBPREFIXmigration_test(id INTEGER PRIMARY KEY)"},
+		},
+		Down: map[string][]string{
+			"sqlite3": {"DROP TABLE DBPREFIXmigration_test"},
+		},
+	},
+	{
+		Version: legacyVersion + 2,
+		Name:    "add_test_column",
+		Up: map[string][]string{
+			"sqlite3": {"ALTER TABLE DBPREFIXmigration_test ADD COLUMN name VARCHAR(45) NOT NULL DEFAULT ''"},
+		},
+		Down: map[string][]string{
+			"sqlite3": {"ALTER TABLE DBPREFIXmigration_test DROP COLUMN name"},
+		},
+	},
+}
+
+// setupMigrationTestDB creates a SQLite database at the given version and replaces the list of migrations
+func setupMigrationTestDB(t *testing.T, dbVersion int, testMigrations []Migration) {
+	t.Helper()
+	config.SetTestDBConfig("sqlite3", filepath.Join(t.TempDir(), "gochan.db"), "gochan", "gochan", "gochan", "")
+	sqlConfig := config.GetSQLConfig()
+	if !assert.NoError(t, gcsql.ConnectToDB(&sqlConfig)) {
+		t.FailNow()
+	}
+	db, err := gcsql.GetDatabase()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	_, err = gcsql.ExecSQL("CREATE TABLE DBPREFIXdatabase_version(component VARCHAR(40) NOT NULL PRIMARY KEY, version INT NOT NULL)")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = gcsql.ExecSQL("INSERT INTO DBPREFIXdatabase_version(component, version) VALUES('gochan', ?)", dbVersion)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	oldMigrations := migrations
+	migrations = testMigrations
+	t.Cleanup(func() {
+		migrations = oldMigrations
+	})
+}
+
+func getTestDBVersion(t *testing.T) int {
+	t.Helper()
+	version, err := gcsql.GetComponentVersion("gochan")
+	assert.NoError(t, err)
+	return version
+}
+
+func TestMigrationsList(t *testing.T) {
+	for m, migration := range migrations {
+		assert.Equal(t, legacyVersion+m+1, migration.Version, "migration versions must be consecutive")
+		assert.NotEmpty(t, migration.Name)
+		for _, dbType := range []string{"mysql", "postgres", "sqlite3"} {
+			_, err := migration.Statements(dbType, false)
+			assert.NoError(t, err)
+		}
+	}
+	assert.Equal(t, gcsql.DatabaseVersion, latestVersion(),
+		"the latest migration version must match gcsql.DatabaseVersion")
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	setupMigrationTestDB(t, legacyVersion, testMigrations)
+	ctx := context.Background()
+	sqlConfig := config.GetSQLConfig()
+
+	report, err := GetMigrationStatus(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, legacyVersion, report.DatabaseVersion)
+	assert.Equal(t, legacyVersion+2, report.LatestVersion)
+	assert.False(t, report.LegacyUpdateRequired)
+	assert.Len(t, report.Pending(), 2)
+
+	applied, err := MigrateUp(ctx, 0, true)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 2)
+	exists, err := migrationutil.TableExists(ctx, nil, nil, "DBPREFIXmigration_test", &sqlConfig)
+	assert.NoError(t, err)
+	assert.False(t, exists, "dry run shouldn't apply migrations")
+	assert.Equal(t, legacyVersion, getTestDBVersion(t))
+
+	applied, err = MigrateUp(ctx, legacyVersion+1, false)
+	assert.NoError(t, err)
+	if assert.Len(t, applied, 1) {
+		assert.Equal(t, "add_test_table", applied[0].Name)
+	}
+	assert.Equal(t, legacyVersion+1, getTestDBVersion(t))
+	exists, err = migrationutil.TableExists(ctx, nil, nil, "DBPREFIXmigration_test", &sqlConfig)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	applied, err = MigrateUp(ctx, 0, false)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.Equal(t, legacyVersion+2, getTestDBVersion(t))
+	columnType, err := migrationutil.ColumnType(ctx, nil, nil, "name", "DBPREFIXmigration_test", &sqlConfig)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, columnType)
+
+	report, err = GetMigrationStatus(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Pending())
+	for _, status := range report.Migrations {
+		assert.True(t, status.Applied)
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	_, err = MigrateDown(ctx, 3, false)
+	assert.ErrorIs(t, err, ErrInvalidMigrationSteps, "migrations before the first one can't be rolled back")
+	rolledBack, err := MigrateDown(ctx, 2, false)
+	assert.NoError(t, err)
+	if assert.Len(t, rolledBack, 2) {
+		assert.Equal(t, legacyVersion+2, rolledBack[0].Version, "newest migrations should be rolled back first")
+	}
+	assert.Equal(t, legacyVersion, getTestDBVersion(t))
+	exists, err = migrationutil.TableExists(ctx, nil, nil, "DBPREFIXmigration_test", &sqlConfig)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	report, err = GetMigrationStatus(ctx)
+	assert.NoError(t, err)
+	for _, status := range report.Migrations {
+		assert.False(t, status.Applied)
+		assert.Nil(t, status.AppliedAt)
+	}
+}
+
+func TestMigrateUpRollsBackFailedMigration(t *testing.T) {
+	setupMigrationTestDB(t, legacyVersion, []Migration{
+		testMigrations[0],
+		{
+			Version: legacyVersion + 2,
+			Name:    "broken",
+			Up: map[string][]string{
+				"sqlite3": {
+					"ALTER TABLE DBPREFIXmigration_test ADD COLUMN name VARCHAR(45) NOT NULL DEFAULT ''",
+					"ALTER TABLE DBPREFIXmissing_table ADD COLUMN name VARCHAR(45)",
+				},
+			},
+		},
+	})
+	ctx := context.Background()
+	sqlConfig := config.GetSQLConfig()
+
+	applied, err := MigrateUp(ctx, 0, false)
+	assert.ErrorContains(t, err, "migration 13 (broken) failed")
+	assert.Len(t, applied, 1, "migrations before the failed one should be applied")
+	assert.Equal(t, legacyVersion+1, getTestDBVersion(t))
+	columnType, err := migrationutil.ColumnType(ctx, nil, nil, "name", "DBPREFIXmigration_test", &sqlConfig)
+	assert.NoError(t, err)
+	assert.Empty(t, columnType, "the failed migration should be rolled back")
+
+	_, err = MigrateDown(ctx, 1, false)
+	assert.NoError(t, err)
+	_, err = MigrateUp(ctx, legacyVersion+2, false)
+	assert.Error(t, err)
+	assert.Equal(t, legacyVersion+1, getTestDBVersion(t))
+	_, err = MigrateDown(ctx, 2, true)
+	assert.ErrorIs(t, err, ErrInvalidMigrationSteps)
+}
+
+func TestMigrationVersionChecks(t *testing.T) {
+	setupMigrationTestDB(t, legacyVersion+1, testMigrations)
+	ctx := context.Background()
+
+	report, err := GetMigrationStatus(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, report.Migrations, 2) {
+		assert.True(t, report.Migrations[0].Applied, "migrations up to the database version should be applied")
+		assert.Nil(t, report.Migrations[0].AppliedAt)
+		assert.False(t, report.Migrations[1].Applied)
+	}
+
+	_, err = MigrateUp(ctx, legacyVersion, false)
+	assert.ErrorIs(t, err, ErrInvalidTargetVersion)
+	_, err = MigrateUp(ctx, legacyVersion+3, false)
+	assert.ErrorIs(t, err, ErrInvalidTargetVersion)
+	_, err = MigrateDown(ctx, 0, false)
+	assert.ErrorIs(t, err, ErrInvalidMigrationSteps)
+
+	irreversible := testMigrations[0]
+	irreversible.Down = nil
+	migrations = []Migration{irreversible, testMigrations[1]}
+	_, err = MigrateDown(ctx, 1, true)
+	assert.ErrorIs(t, err, ErrIrreversibleMigration)
+
+	_, err = gcsql.ExecSQL("UPDATE DBPREFIXdatabase_version SET version = ? WHERE component = 'gochan'", legacyVersion+5)
+	assert.NoError(t, err)
+	_, err = GetMigrationStatus(ctx)
+	assert.ErrorIs(t, err, ErrDatabaseAhead)
+}
@@ -0,0 +1,82 @@
+package dbupdate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcsql/migrationutil"
+	"github.com/rs/zerolog"
+)
+
+// addedTables is a list of tables that have been added to the init file since the filter tables were added
+// (DB version 4). They are created from their respective CREATE TABLE statements in the init file if they
+// don't already exist, and should be listed in the order they appear in the file so that foreign keys resolve
+var addedTables = []string{
+	"DBPREFIXfile_metadata",     // DB version 8
+	"DBPREFIXfile_fingerprints", // DB version 9
+	"DBPREFIXreport_bans",       // DB version 10
+}
+
+// getInitCreateTableStatements reads the SQL init file for the configured database type and returns
+// a map of table names (with DBPREFIX) to their CREATE TABLE statements
+func getInitCreateTableStatements(sqlConfig *config.SQLConfig) (map[string]string, error) {
+	filePath, err := migrationutil.GetInitFilePath("initdb_" + sqlConfig.DBtype + ".sql")
+	if err != nil {
+		return nil, err
+	}
+	ba, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	sqlStr := gcsql.CommentRemover.ReplaceAllString(string(ba), " ")
+	statements := make(map[string]string)
+	for stmtStr := range strings.SplitSeq(sqlStr, ";") {
+		stmtStr = strings.TrimSpace(stmtStr)
+		tableName, found := strings.CutPrefix(stmtStr, "CREATE TABLE ")
+		if !found {
+			continue
+		}
+		if paren := strings.Index(tableName, "("); paren > -1 {
+			tableName = strings.TrimSpace(tableName[:paren])
+		}
+		statements[tableName] = stmtStr
+	}
+	return statements, nil
+}
+
+// addMissingTables creates any tables in addedTables that don't already exist in the database
+func addMissingTables(ctx context.Context, sqlConfig *config.SQLConfig, errEv *zerolog.Event) (err error) {
+	defer func() {
+		if err != nil {
+			errEv.Err(err).Caller(1).Send()
+		}
+	}()
+	var statements map[string]string
+	for _, table := range addedTables {
+		var exists bool
+		exists, err = migrationutil.TableExists(ctx, nil, nil, table, sqlConfig)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if statements == nil {
+			if statements, err = getInitCreateTableStatements(sqlConfig); err != nil {
+				return err
+			}
+		}
+		stmt, ok := statements[table]
+		if !ok {
+			return fmt.Errorf("unable to find CREATE TABLE statement for %s in init file", table)
+		}
+		if _, err = gcsql.ExecContextSQL(ctx, nil, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -146,5 +146,57 @@ func updatePostgresDB(ctx context.Context, sqlConfig *config.SQLConfig, errEv *z
 		}
 	}
 
+	// add is_hidden column to DBPREFIXposts
+	dataType, err = migrationutil.ColumnType(ctx, nil, nil, "is_hidden", "DBPREFIXposts", sqlConfig)
+	if err != nil {
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.ExecContextSQL(ctx, nil, "ALTER TABLE DBPREFIXposts ADD COLUMN is_hidden BOOL NOT NULL DEFAULT FALSE"); err != nil {
+			return err
+		}
+	}
+
+	// add category column to DBPREFIXreports
+	dataType, err = migrationutil.ColumnType(ctx, nil, nil, "category", "DBPREFIXreports", sqlConfig)
+	if err != nil {
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.ExecContextSQL(ctx, nil, "ALTER TABLE DBPREFIXreports ADD COLUMN category VARCHAR(45) NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+
+	// add status column to DBPREFIXip_ban_appeals
+	dataType, err = migrationutil.ColumnType(ctx, nil, nil, "status", "DBPREFIXip_ban_appeals", sqlConfig)
+	if err != nil {
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.ExecContextSQL(ctx, nil, "ALTER TABLE DBPREFIXip_ban_appeals ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'open'"); err != nil {
+			return err
+		}
+		// appeals that were denied before appeal statuses were added
+		if _, err = gcsql.ExecContextSQL(ctx, nil, "UPDATE DBPREFIXip_ban_appeals SET status = 'denied' WHERE is_denied = TRUE"); err != nil {
+			return err
+		}
+	}
+
+	// add staff_unread column to DBPREFIXip_ban_appeals
+	dataType, err = migrationutil.ColumnType(ctx, nil, nil, "staff_unread", "DBPREFIXip_ban_appeals", sqlConfig)
+	if err != nil {
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.ExecContextSQL(ctx, nil, "ALTER TABLE DBPREFIXip_ban_appeals ADD COLUMN staff_unread BOOL NOT NULL DEFAULT TRUE"); err != nil {
+			return err
+		}
+		// only appeals made after the column was added are new to staff
+		if _, err = gcsql.ExecContextSQL(ctx, nil, "UPDATE DBPREFIXip_ban_appeals SET staff_unread = FALSE"); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
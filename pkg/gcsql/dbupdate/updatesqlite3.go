@@ -249,5 +249,59 @@ func updateSqliteDB(ctx context.Context, sqlConfig *config.SQLConfig, errEv *zer
 		}
 	}
 
+	if dataType, err = migrationutil.ColumnType(ctx, nil, nil, "is_hidden", "DBPREFIXposts", sqlConfig); err != nil {
+		errEv.Err(err).Caller().Send()
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.Exec(opts, "ALTER TABLE DBPREFIXposts ADD COLUMN is_hidden BOOL NOT NULL DEFAULT FALSE"); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
+	}
+
+	if dataType, err = migrationutil.ColumnType(ctx, nil, nil, "category", "DBPREFIXreports", sqlConfig); err != nil {
+		errEv.Err(err).Caller().Send()
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.Exec(opts, "ALTER TABLE DBPREFIXreports ADD COLUMN category VARCHAR(45) NOT NULL DEFAULT ''"); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
+	}
+
+	if dataType, err = migrationutil.ColumnType(ctx, nil, nil, "status", "DBPREFIXip_ban_appeals", sqlConfig); err != nil {
+		errEv.Err(err).Caller().Send()
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.Exec(opts, "ALTER TABLE DBPREFIXip_ban_appeals ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'open'"); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
+		// appeals that were denied before appeal statuses were added
+		if _, err = gcsql.Exec(opts, "UPDATE DBPREFIXip_ban_appeals SET status = 'denied' WHERE is_denied = TRUE"); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
+	}
+
+	if dataType, err = migrationutil.ColumnType(ctx, nil, nil, "staff_unread", "DBPREFIXip_ban_appeals", sqlConfig); err != nil {
+		errEv.Err(err).Caller().Send()
+		return err
+	}
+	if dataType == "" {
+		if _, err = gcsql.Exec(opts, "ALTER TABLE DBPREFIXip_ban_appeals ADD COLUMN staff_unread BOOL NOT NULL DEFAULT TRUE"); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
+		// only appeals made after the column was added are new to staff
+		if _, err = gcsql.Exec(opts, "UPDATE DBPREFIXip_ban_appeals SET staff_unread = FALSE"); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,84 @@
+package gcsql
+
+import "github.com/gochan-org/gochan/pkg/events"
+
+// These events are triggered after the change they describe has been committed to the database, so their handlers
+// can't cancel it. Handlers registered for the event names with events.RegisterEvent (including Lua handlers) receive
+// the payload as their only data argument
+var (
+	// PostCreatedEvent is triggered after a new post or thread is created and its board is rebuilt
+	PostCreatedEvent = events.NewEvent[*PostCreatedPayload]("post-created")
+	// ThreadCreatedEvent is triggered after PostCreatedEvent if the new post is a thread
+	ThreadCreatedEvent = events.NewEvent[*PostCreatedPayload]("thread-created")
+	// PostDeletedEvent is triggered for each post that is deleted by its poster, a staff member (including with the
+	// delpost command), by being pruned from a cyclic thread, or by its thread being pruned after the board exceeds
+	// MaxThreads. If a thread is deleted, it is also triggered for each of its replies
+	PostDeletedEvent = events.NewEvent[*PostDeletedPayload]("post-deleted")
+	// ThreadMovedEvent is triggered after a thread is moved to another board
+	ThreadMovedEvent = events.NewEvent[*ThreadMovedPayload]("thread-moved")
+	// BanIssuedEvent is triggered after an IP ban is created by a staff member or a filter
+	BanIssuedEvent = events.NewEvent[*BanIssuedPayload]("ban-issued")
+	// AppealCreatedEvent is triggered after a banned user appeals their ban
+	AppealCreatedEvent = events.NewEvent[*AppealCreatedPayload]("appeal-created")
+	// ReportCreatedEvent is triggered after a post is reported
+	ReportCreatedEvent = events.NewEvent[*ReportCreatedPayload]("report-created")
+	// FilterHitEvent is triggered after a filter matches an incoming post and the hit is logged, before the filter's
+	// action is taken
+	FilterHitEvent = events.NewEvent[*FilterHitPayload]("filter-hit")
+	// StaffLoginEvent is triggered after a staff member logs in
+	StaffLoginEvent = events.NewEvent[*StaffLoginPayload]("staff-login")
+)
+
+// PostCreatedPayload is the payload of PostCreatedEvent and ThreadCreatedEvent
+type PostCreatedPayload struct {
+	Post  *Post
+	Board *Board
+}
+
+// PostDeletedPayload is the payload of PostDeletedEvent
+type PostDeletedPayload struct {
+	PostID    int
+	ThreadID  int
+	IsTopPost bool
+	BoardDir  string
+	// Staff is the staff member that deleted the post, or nil if it was deleted by its poster, from the command line,
+	// or pruned
+	Staff *Staff
+}
+
+// ThreadMovedPayload is the payload of ThreadMovedEvent
+type ThreadMovedPayload struct {
+	// Post is the top post of the thread
+	Post      *Post
+	FromBoard *Board
+	ToBoard   *Board
+}
+
+// BanIssuedPayload is the payload of BanIssuedEvent
+type BanIssuedPayload struct {
+	Ban *IPBan
+}
+
+// AppealCreatedPayload is the payload of AppealCreatedEvent
+type AppealCreatedPayload struct {
+	Ban      *IPBan
+	AppealID int
+	Message  string
+}
+
+// ReportCreatedPayload is the payload of ReportCreatedEvent
+type ReportCreatedPayload struct {
+	Report *Report
+}
+
+// FilterHitPayload is the payload of FilterHitEvent
+type FilterHitPayload struct {
+	Filter *Filter
+	Post   *Post
+}
+
+// StaffLoginPayload is the payload of StaffLoginEvent
+type StaffLoginPayload struct {
+	Staff *Staff
+	IP    string
+}
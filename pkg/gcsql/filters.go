@@ -437,6 +437,7 @@ func (f *Filter) handleMatch(post *Post, upload *Upload, request *http.Request)
 	if _, err = ExecTimeoutSQL(nil, `INSERT INTO DBPREFIXfilter_hits(filter_id,post_data) VALUES(?,?)`, f.ID, string(ba)); err != nil {
 		return err
 	}
+	FilterHitEvent.Notify(&FilterHitPayload{Filter: f, Post: post})
 
 	switch f.MatchAction {
 	case "reject":
@@ -0,0 +1,188 @@
+package gcsql
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/config"
+)
+
+const (
+	// FingerprintAlgorithm is the perceptual hash algorithm used for upload fingerprints
+	FingerprintAlgorithm = "ahash"
+
+	// fingerprintToleranceSeparator separates the fingerprint and the maximum Hamming distance in an ahash
+	// filter condition's search string, e.g. "ffe0c0...~8"
+	fingerprintToleranceSeparator = "~"
+)
+
+var (
+	ErrInvalidFingerprint          = errors.New("invalid image fingerprint")
+	ErrFingerprintLengthMismatch   = errors.New("image fingerprints have different lengths")
+	ErrInvalidFingerprintTolerance = errors.New("invalid image fingerprint tolerance")
+)
+
+// SimilarUpload is an upload with a fingerprint that is within the searched Hamming distance of another fingerprint
+type SimilarUpload struct {
+	Upload
+	BoardDir string
+	OpID     int
+	Distance int
+}
+
+// WebPath returns the path to the post that the upload is attached to
+func (su *SimilarUpload) WebPath() string {
+	return config.WebPath(su.BoardDir, "res/", strconv.Itoa(su.OpID)+".html#"+strconv.Itoa(su.PostID))
+}
+
+// FingerprintDistance returns the Hamming distance (the number of differing bits) between two hex encoded
+// fingerprints of the same length
+func FingerprintDistance(fingerprint1, fingerprint2 string) (int, error) {
+	ba1, err := hex.DecodeString(fingerprint1)
+	if err != nil {
+		return 0, ErrInvalidFingerprint
+	}
+	ba2, err := hex.DecodeString(fingerprint2)
+	if err != nil {
+		return 0, ErrInvalidFingerprint
+	}
+	if len(ba1) != len(ba2) {
+		return 0, ErrFingerprintLengthMismatch
+	}
+	var distance int
+	for b := range ba1 {
+		distance += bits.OnesCount8(ba1[b] ^ ba2[b])
+	}
+	return distance, nil
+}
+
+// ParseFingerprintSearch parses an ahash filter condition search string in the form "fingerprint" or
+// "fingerprint~tolerance", where tolerance is the maximum Hamming distance for an upload to be considered a match
+func ParseFingerprintSearch(search string) (fingerprint string, tolerance int, err error) {
+	fingerprint, toleranceStr, hasTolerance := strings.Cut(strings.TrimSpace(search), fingerprintToleranceSeparator)
+	fingerprint = strings.ToLower(strings.TrimSpace(fingerprint))
+	if _, err = hex.DecodeString(fingerprint); err != nil || fingerprint == "" {
+		return "", 0, ErrInvalidFingerprint
+	}
+	if hasTolerance {
+		if tolerance, err = strconv.Atoi(strings.TrimSpace(toleranceStr)); err != nil || tolerance < 0 {
+			return "", 0, ErrInvalidFingerprintTolerance
+		}
+	}
+	return fingerprint, tolerance, nil
+}
+
+// FingerprintSearch returns an ahash filter condition search string for the fingerprint and tolerance
+func FingerprintSearch(fingerprint string, tolerance int) string {
+	if tolerance <= 0 {
+		return fingerprint
+	}
+	return fingerprint + fingerprintToleranceSeparator + strconv.Itoa(tolerance)
+}
+
+// setFingerprint inserts the upload's fingerprint (if it has one) into the DBPREFIXfile_fingerprints table
+func (u *Upload) setFingerprint(opts *RequestOptions) error {
+	if u.Fingerprint == "" {
+		return nil
+	}
+	const insertSQL = `INSERT INTO DBPREFIXfile_fingerprints (file_id, algorithm, fingerprint) VALUES(?,?,?)`
+	_, err := Exec(opts, insertSQL, u.ID, FingerprintAlgorithm, u.Fingerprint)
+	return err
+}
+
+// GetPostUploadFingerprint returns the stored fingerprint of the upload attached to the given post ID, or an
+// empty string if the post doesn't have an upload or the upload hasn't been fingerprinted
+func GetPostUploadFingerprint(postID int, requestOpts ...*RequestOptions) (string, error) {
+	opts := setupOptions(requestOpts...)
+	const query = `SELECT fingerprint FROM DBPREFIXfile_fingerprints
+		WHERE algorithm = ? AND file_id IN (SELECT id FROM DBPREFIXfiles WHERE post_id = ?)`
+	var fingerprint string
+	err := QueryRow(opts, query, []any{FingerprintAlgorithm, postID}, []any{&fingerprint})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return fingerprint, err
+}
+
+// SetPostUploadFingerprint stores the fingerprint of the upload attached to the given post ID, replacing the
+// previously stored fingerprint if there is one. It is used for indexing uploads that were attached before
+// fingerprints were stored
+func SetPostUploadFingerprint(postID int, fingerprint string, requestOpts ...*RequestOptions) error {
+	opts := setupOptions(requestOpts...)
+	shouldCommit := opts.Tx == nil
+	var err error
+	if shouldCommit {
+		if opts.Tx, err = BeginTx(); err != nil {
+			return err
+		}
+		defer opts.Tx.Rollback()
+	}
+	const deleteSQL = `DELETE FROM DBPREFIXfile_fingerprints
+		WHERE algorithm = ? AND file_id IN (SELECT id FROM DBPREFIXfiles WHERE post_id = ?)`
+	if _, err = Exec(opts, deleteSQL, FingerprintAlgorithm, postID); err != nil {
+		return err
+	}
+	const insertSQL = `INSERT INTO DBPREFIXfile_fingerprints (file_id, algorithm, fingerprint)
+		SELECT id, ?, ? FROM DBPREFIXfiles WHERE post_id = ? AND filename != 'deleted'`
+	if _, err = Exec(opts, insertSQL, FingerprintAlgorithm, fingerprint, postID); err != nil {
+		return err
+	}
+	if shouldCommit {
+		return opts.Tx.Commit()
+	}
+	return nil
+}
+
+// GetSimilarUploads returns the non-deleted uploads on all boards with a fingerprint within the given Hamming
+// distance of the given fingerprint, sorted by distance (most similar first) and then by newest post. Stored
+// fingerprints with a different length (e.g. created before FingerprintHashLength was changed) are skipped.
+// If limit is greater than 0, at most limit uploads are returned
+func GetSimilarUploads(fingerprint string, tolerance int, limit int, requestOpts ...*RequestOptions) ([]SimilarUpload, error) {
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return nil, ErrInvalidFingerprint
+	}
+	opts := setupOptions(requestOpts...)
+	const query = `SELECT f.id, f.post_id, f.file_order, f.original_filename, f.filename, f.checksum, f.file_size,
+		f.is_spoilered, f.thumbnail_width, f.thumbnail_height, f.width, f.height, fp.fingerprint, v.op_id, v.dir
+		FROM DBPREFIXfile_fingerprints fp
+		JOIN DBPREFIXfiles f ON f.id = fp.file_id
+		JOIN DBPREFIXposts p ON p.id = f.post_id
+		JOIN DBPREFIXv_top_post_board_dir v ON v.id = p.id
+		WHERE fp.algorithm = ? AND p.is_deleted = FALSE AND f.filename != 'deleted'`
+	rows, err := Query(opts, query, FingerprintAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var similar []SimilarUpload
+	for rows.Next() {
+		var upload SimilarUpload
+		if err = rows.Scan(
+			&upload.ID, &upload.PostID, &upload.FileOrder, &upload.OriginalFilename, &upload.Filename, &upload.Checksum,
+			&upload.FileSize, &upload.IsSpoilered, &upload.ThumbnailWidth, &upload.ThumbnailHeight, &upload.Width,
+			&upload.Height, &upload.Fingerprint, &upload.OpID, &upload.BoardDir,
+		); err != nil {
+			return nil, err
+		}
+		if upload.Distance, err = FingerprintDistance(fingerprint, upload.Fingerprint); err != nil || upload.Distance > tolerance {
+			continue
+		}
+		similar = append(similar, upload)
+	}
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(similar, func(a, b SimilarUpload) int {
+		return cmp.Or(cmp.Compare(a.Distance, b.Distance), cmp.Compare(b.PostID, a.PostID))
+	})
+	if limit > 0 && len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
@@ -0,0 +1,50 @@
+package gcsql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprintDistance(t *testing.T) {
+	distance, err := FingerprintDistance("ff00ff00", "ff00ff00")
+	assert.NoError(t, err)
+	assert.Zero(t, distance)
+
+	distance, err = FingerprintDistance("ff00ff00", "fe00ff80")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, distance)
+
+	distance, err = FingerprintDistance("00000000", "FFFFFFFF")
+	assert.NoError(t, err)
+	assert.Equal(t, 32, distance)
+
+	_, err = FingerprintDistance("ff00", "ff00ff00")
+	assert.ErrorIs(t, err, ErrFingerprintLengthMismatch)
+
+	_, err = FingerprintDistance("ff00", "zz00")
+	assert.ErrorIs(t, err, ErrInvalidFingerprint)
+}
+
+func TestParseFingerprintSearch(t *testing.T) {
+	fingerprint, tolerance, err := ParseFingerprintSearch("FF00ff00")
+	assert.NoError(t, err)
+	assert.Equal(t, "ff00ff00", fingerprint)
+	assert.Zero(t, tolerance)
+
+	fingerprint, tolerance, err = ParseFingerprintSearch(" ff00ff00 ~ 12 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "ff00ff00", fingerprint)
+	assert.Equal(t, 12, tolerance)
+	assert.Equal(t, "ff00ff00~12", FingerprintSearch(fingerprint, tolerance))
+	assert.Equal(t, "ff00ff00", FingerprintSearch(fingerprint, 0))
+
+	_, _, err = ParseFingerprintSearch("")
+	assert.ErrorIs(t, err, ErrInvalidFingerprint)
+	_, _, err = ParseFingerprintSearch("not a fingerprint")
+	assert.ErrorIs(t, err, ErrInvalidFingerprint)
+	_, _, err = ParseFingerprintSearch("ff00~-1")
+	assert.ErrorIs(t, err, ErrInvalidFingerprintTolerance)
+	_, _, err = ParseFingerprintSearch("ff00~a")
+	assert.ErrorIs(t, err, ErrInvalidFingerprintTolerance)
+}
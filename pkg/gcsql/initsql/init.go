@@ -198,22 +198,36 @@ func init() {
 		if u == nil {
 			return false, nil
 		}
-		boardID, err := strconv.Atoi(r.PostFormValue("boardid"))
+		search, tolerance, err := gcsql.ParseFingerprintSearch(fc.Search)
 		if err != nil {
-			// boardid is assumed to have already been checked, but just in case...
 			return false, err
 		}
-		dir, err := gcsql.GetBoardDir(boardID)
-		if err != nil {
-			return false, err
+		fingerprint := u.Fingerprint
+		if fingerprint == "" {
+			boardID, err := strconv.Atoi(r.PostFormValue("boardid"))
+			if err != nil {
+				// boardid is assumed to have already been checked, but just in case...
+				return false, err
+			}
+			dir, err := gcsql.GetBoardDir(boardID)
+			if err != nil {
+				return false, err
+			}
+			fingerprint, err = uploads.GetFileFingerprint(path.Join(
+				config.GetSystemCriticalConfig().DocumentRoot,
+				dir, "src", u.Filename))
+			if errors.Is(err, uploads.ErrVideoThumbFingerprint) || errors.Is(err, uploads.ErrUnsupportedFileExt) {
+				// admin hasn't enabled video thumbnail fingerprinting in the config or the upload isn't an image, let it through
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
 		}
-		fingerprint, err := uploads.GetFileFingerprint(path.Join(
-			config.GetSystemCriticalConfig().DocumentRoot,
-			dir, "src", u.Filename))
-		if errors.Is(err, uploads.ErrVideoThumbFingerprint) {
-			// admin hasn't enabled video thumbnail fingerprinting in the config, let it through
+		distance, err := gcsql.FingerprintDistance(search, fingerprint)
+		if errors.Is(err, gcsql.ErrFingerprintLengthMismatch) {
+			// the filter was created with a different FingerprintHashLength value
 			return false, nil
 		}
-		return fingerprint == fc.Search, err
+		return err == nil && distance <= tolerance, err
 	})
 }
@@ -0,0 +1,147 @@
+package gcsql
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// postPasswordCost is the bcrypt cost of post deletion passwords. It is the same as staff passwords
+	postPasswordCost = 10
+
+	// bcrypt only uses the first 72 bytes of a password, and GenerateFromPassword rejects longer ones
+	maxPostPasswordLength = 72
+)
+
+// HashPostPassword returns a salted bcrypt hash of the post deletion password, to be stored in the post's Password field.
+// Only the first 72 bytes of the password are used
+func HashPostPassword(password string) (string, error) {
+	if len(password) > maxPostPasswordLength {
+		password = password[:maxPostPasswordLength]
+	}
+	digest, err := bcrypt.GenerateFromPassword([]byte(password), postPasswordCost)
+	return string(digest), err
+}
+
+// isLegacyPostPassword returns true if the checksum is an unsalted MD5 hash of the password, used by older versions
+// of gochan
+func isLegacyPostPassword(checksum string) bool {
+	if len(checksum) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(checksum)
+	return err == nil
+}
+
+// postPasswordMatches returns true if the password matches the checksum, and whether the checksum should be replaced by
+// a bcrypt hash because it is a legacy MD5 hash
+func postPasswordMatches(checksum string, password string) (matches bool, upgrade bool, err error) {
+	if checksum == "" {
+		return false, false, nil
+	}
+	if isLegacyPostPassword(checksum) {
+		passwordMD5 := gcutil.Md5Sum(password)
+		matches = subtle.ConstantTimeCompare([]byte(strings.ToLower(checksum)), []byte(passwordMD5)) == 1
+		return matches, matches, nil
+	}
+	if len(password) > maxPostPasswordLength {
+		password = password[:maxPostPasswordLength]
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(checksum), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, false, nil
+	}
+	return err == nil, false, err
+}
+
+// upgradePostPasswords replaces the legacy MD5 checksums of the posts' deletion passwords with a bcrypt hash of the
+// password, returning the new checksum
+func upgradePostPasswords(password string, postIDs []any, opts *RequestOptions) (string, error) {
+	checksum, err := HashPostPassword(password)
+	if err != nil {
+		return "", err
+	}
+	_, err = Exec(opts, `UPDATE DBPREFIXposts SET password = ? WHERE id IN `+createArrayPlaceholder(postIDs),
+		append([]any{checksum}, postIDs...)...)
+	return checksum, err
+}
+
+// CheckPassword returns true if the given password matches the post's deletion password. If it does and the post's
+// password was stored as a legacy MD5 checksum, it is replaced with a bcrypt hash
+func (p *Post) CheckPassword(password string, requestOptions ...*RequestOptions) (bool, error) {
+	matches, upgrade, err := postPasswordMatches(p.Password, password)
+	if err != nil || !upgrade {
+		return matches, err
+	}
+	checksum, err := upgradePostPasswords(password, []any{p.ID}, setupOptions(requestOptions...))
+	if err != nil {
+		return true, err
+	}
+	p.Password = checksum
+	return true, nil
+}
+
+// CheckPostPasswords returns true if the given password matches the deletion passwords of all of the posts with the
+// given IDs, upgrading any legacy MD5 checksums that match it
+func CheckPostPasswords(password string, postIDs []any, requestOptions ...*RequestOptions) (bool, error) {
+	if len(postIDs) == 0 {
+		return false, nil
+	}
+	// the same post can be selected more than once, but only one row is returned for it
+	uniqueIDs := make([]any, 0, len(postIDs))
+	seen := make(map[any]bool, len(postIDs))
+	for _, id := range postIDs {
+		if !seen[id] {
+			seen[id] = true
+			uniqueIDs = append(uniqueIDs, id)
+		}
+	}
+	postIDs = uniqueIDs
+	opts := setupOptions(requestOptions...)
+	rows, err := Query(opts, `SELECT id, password FROM DBPREFIXposts WHERE id IN `+createArrayPlaceholder(postIDs), postIDs...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	checksums := make(map[int]string, len(postIDs))
+	for rows.Next() {
+		var id int
+		var checksum string
+		if err = rows.Scan(&id, &checksum); err != nil {
+			return false, err
+		}
+		checksums[id] = checksum
+	}
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+	if err = rows.Close(); err != nil {
+		return false, err
+	}
+	if len(checksums) != len(postIDs) {
+		// one or more posts don't exist
+		return false, nil
+	}
+
+	var upgradeIDs []any
+	for id, checksum := range checksums {
+		matches, upgrade, err := postPasswordMatches(checksum, password)
+		if err != nil || !matches {
+			return false, err
+		}
+		if upgrade {
+			upgradeIDs = append(upgradeIDs, id)
+		}
+	}
+	if len(upgradeIDs) > 0 {
+		if _, err = upgradePostPasswords(password, upgradeIDs, opts); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
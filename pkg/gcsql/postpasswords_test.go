@@ -0,0 +1,118 @@
+package gcsql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostPasswordMatches(t *testing.T) {
+	checksum, err := HashPostPassword("password")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotEqual(t, "password", checksum)
+	otherChecksum, err := HashPostPassword("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, checksum, otherChecksum, "post password hashes should be salted")
+
+	longPassword := strings.Repeat("a", 100)
+	longChecksum, err := HashPostPassword(longPassword)
+	assert.NoError(t, err, "passwords longer than 72 bytes should be accepted")
+
+	testCases := []struct {
+		desc            string
+		checksum        string
+		password        string
+		expectMatches   bool
+		expectUpgrading bool
+	}{
+		{desc: "bcrypt match", checksum: checksum, password: "password", expectMatches: true},
+		{desc: "bcrypt mismatch", checksum: checksum, password: "wrong"},
+		{desc: "long password match", checksum: longChecksum, password: longPassword, expectMatches: true},
+		{desc: "legacy MD5 match", checksum: gcutil.Md5Sum("password"), password: "password", expectMatches: true, expectUpgrading: true},
+		{desc: "legacy MD5 mismatch", checksum: gcutil.Md5Sum("password"), password: "wrong"},
+		{desc: "no stored password", checksum: "", password: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			matches, upgrade, err := postPasswordMatches(tc.checksum, tc.password)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectMatches, matches)
+			assert.Equal(t, tc.expectUpgrading, upgrade)
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		t.Run(driver, func(t *testing.T) {
+			mock := setupPostTest(t, driver)
+			post := Post{ID: 1, Password: gcutil.Md5Sum("password")}
+
+			matches, err := post.CheckPassword("wrong")
+			assert.NoError(t, err)
+			assert.False(t, matches)
+			assert.Equal(t, gcutil.Md5Sum("password"), post.Password, "password shouldn't be upgraded if it doesn't match")
+
+			mock.ExpectPrepare(`UPDATE posts SET password = \? WHERE id IN \(\?\)`).ExpectExec().
+				WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			matches, err = post.CheckPassword("password")
+			assert.NoError(t, err)
+			assert.True(t, matches)
+			assert.True(t, strings.HasPrefix(post.Password, "$2a$"), "legacy MD5 password should be upgraded to bcrypt")
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			matches, err = post.CheckPassword("password")
+			assert.NoError(t, err)
+			assert.True(t, matches)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCheckPostPasswords(t *testing.T) {
+	checksum, err := HashPostPassword("password")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		t.Run(driver, func(t *testing.T) {
+			mock := setupPostTest(t, driver)
+			query := `SELECT id, password FROM posts WHERE id IN \(\?,\?\)`
+
+			mock.ExpectPrepare(query).ExpectQuery().WithArgs(1, 2).WillReturnRows(
+				mock.NewRows([]string{"id", "password"}).AddRow(1, checksum).AddRow(2, gcutil.Md5Sum("password")))
+			mock.ExpectPrepare(`UPDATE posts SET password = \? WHERE id IN \(\?\)`).ExpectExec().
+				WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+			matches, err := CheckPostPasswords("password", []any{1, 2})
+			assert.NoError(t, err)
+			assert.True(t, matches)
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			mock.ExpectPrepare(query).ExpectQuery().WithArgs(1, 2).WillReturnRows(
+				mock.NewRows([]string{"id", "password"}).AddRow(1, checksum).AddRow(2, gcutil.Md5Sum("other")))
+			matches, err = CheckPostPasswords("password", []any{1, 2})
+			assert.NoError(t, err)
+			assert.False(t, matches, "all posts should have the same password")
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			mock.ExpectPrepare(query).ExpectQuery().WithArgs(1, 2).WillReturnRows(
+				mock.NewRows([]string{"id", "password"}).AddRow(1, checksum))
+			matches, err = CheckPostPasswords("password", []any{1, 2})
+			assert.NoError(t, err)
+			assert.False(t, matches, "missing posts shouldn't match")
+			assert.NoError(t, mock.ExpectationsWereMet())
+
+			mock.ExpectPrepare(query).ExpectQuery().WithArgs(1, 2).WillReturnRows(
+				mock.NewRows([]string{"id", "password"}).AddRow(1, checksum).AddRow(2, checksum))
+			matches, err = CheckPostPasswords("password", []any{1, 2, 1})
+			assert.NoError(t, err)
+			assert.True(t, matches, "duplicate post IDs should only be checked once")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -237,6 +237,13 @@ func SinceLastThread(postIP string) (int, error) {
 	return int(time.Since(when).Seconds()), nil
 }
 
+// SetPostHidden sets whether the post is hidden from the public until a moderator reviews it. Hidden posts are shown
+// without their message and upload
+func SetPostHidden(postID int, hidden bool, requestOptions ...*RequestOptions) error {
+	_, err := Exec(setupOptions(requestOptions...), `UPDATE DBPREFIXposts SET is_hidden = ? WHERE id = ?`, hidden, postID)
+	return err
+}
+
 // UpdateContents updates the email, subject, and message text of the post
 func (p *Post) UpdateContents(email string, subject string, message template.HTML, messageRaw string) error {
 	const sqlUpdate = `UPDATE DBPREFIXposts SET email = ?, subject = ?, message = ?, message_raw = ? WHERE ID = ?`
@@ -0,0 +1,55 @@
+package gcsql
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// NewReportBan inserts the ban into the database, preventing the IP address from reporting posts until the ban expires
+// (or indefinitely if it is permanent)
+func NewReportBan(ban *ReportBan, requestOptions ...*RequestOptions) error {
+	const query = `INSERT INTO DBPREFIXreport_bans (staff_id, ip, issued_at, expires_at, permanent, reason)
+		VALUES(?, INET6_ATON(?), ?, ?, ?, ?)`
+	if ban.ID > 0 {
+		return ErrBanAlreadyInserted
+	}
+	opts := setupOptions(requestOptions...)
+	shouldCommit := opts.Tx == nil
+	var err error
+	if shouldCommit {
+		if opts.Tx, err = BeginContextTx(opts.Context); err != nil {
+			return err
+		}
+		defer func() {
+			opts.Tx.Rollback()
+			opts.Tx = nil
+		}()
+	}
+	if _, err = Exec(opts, query, ban.StaffID, ban.IP, ban.IssuedAt, ban.ExpiresAt, ban.Permanent, ban.Reason); err != nil {
+		return err
+	}
+	if ban.ID, err = getLatestID(opts, "DBPREFIXreport_bans"); err != nil {
+		return err
+	}
+	if shouldCommit {
+		return opts.Tx.Commit()
+	}
+	return nil
+}
+
+// CheckReportBan returns the latest active report ban of the IP address, or nil if it isn't banned from reporting posts
+func CheckReportBan(ip string) (*ReportBan, error) {
+	const query = `SELECT id, staff_id, INET6_NTOA(ip), issued_at, expires_at, permanent, reason
+		FROM DBPREFIXreport_bans WHERE IP_CMP(ip, ?) = 0 AND (expires_at > CURRENT_TIMESTAMP OR permanent)
+		ORDER BY id DESC LIMIT 1`
+	var ban ReportBan
+	err := QueryRowTimeoutSQL(nil, query, []any{ip}, []any{
+		&ban.ID, &ban.StaffID, &ban.IP, &ban.IssuedAt, &ban.ExpiresAt, &ban.Permanent, &ban.Reason,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
@@ -0,0 +1,79 @@
+package gcsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReportBan(t *testing.T) {
+	const checkReportBanSQL = `SELECT id, staff_id, .*ip.*, issued_at, expires_at, permanent, reason\s+FROM report_bans WHERE .*ip.* = 0 AND \(expires_at > CURRENT_TIMESTAMP OR permanent\)\s+ORDER BY id DESC LIMIT 1`
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		t.Run(driver, func(t *testing.T) {
+			mock := setupPostTest(t, driver)
+			columns := []string{"id", "staff_id", "ip", "issued_at", "expires_at", "permanent", "reason"}
+
+			mock.ExpectPrepare(checkReportBanSQL).ExpectQuery().WithArgs("192.168.56.1").
+				WillReturnRows(sqlmock.NewRows(columns))
+			ban, err := CheckReportBan("192.168.56.1")
+			assert.NoError(t, err)
+			assert.Nil(t, ban)
+
+			now := time.Now()
+			mock.ExpectPrepare(checkReportBanSQL).ExpectQuery().WithArgs("192.168.56.1").
+				WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "192.168.56.1", now, now.Add(time.Hour), false, "report spam"))
+			ban, err = CheckReportBan("192.168.56.1")
+			assert.NoError(t, err)
+			if assert.NotNil(t, ban) {
+				assert.Equal(t, 1, ban.ID)
+				assert.Equal(t, "report spam", ban.Reason)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCheckPostReports(t *testing.T) {
+	const checkReportsSQL = `SELECT COUNT\(\*\), MAX\(is_cleared\) FROM reports\s+WHERE post_id = \? AND \(is_cleared = 2 OR \(is_cleared = FALSE AND .*ip.* = 0\)`
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		t.Run(driver, func(t *testing.T) {
+			mock := setupPostTest(t, driver)
+
+			mock.ExpectPrepare(checkReportsSQL+` OR \(category = '' AND reason = \?\)\)`).ExpectQuery().
+				WithArgs(1, "192.168.56.1", "spam").
+				WillReturnRows(sqlmock.NewRows([]string{"count", "max"}).AddRow(1, int64(0)))
+			isDuplicate, isBlocked, err := CheckPostReports(1, "192.168.56.1", "", "spam")
+			assert.NoError(t, err)
+			assert.True(t, isDuplicate)
+			assert.False(t, isBlocked)
+
+			// categorized reports are only compared by reporter, not by reason
+			mock.ExpectPrepare(checkReportsSQL+`\)$`).ExpectQuery().
+				WithArgs(1, "192.168.56.1").
+				WillReturnRows(sqlmock.NewRows([]string{"count", "max"}).AddRow(1, int64(2)))
+			isDuplicate, isBlocked, err = CheckPostReports(1, "192.168.56.1", "spam", "")
+			assert.NoError(t, err)
+			assert.True(t, isDuplicate)
+			assert.True(t, isBlocked)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCountReportsFromIP(t *testing.T) {
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		t.Run(driver, func(t *testing.T) {
+			mock := setupPostTest(t, driver)
+			since := time.Now().Add(-time.Hour)
+			mock.ExpectPrepare(`SELECT COUNT\(\*\) FROM reports WHERE .*ip.* = 0 AND timestamp > \?`).ExpectQuery().
+				WithArgs("192.168.56.1", since).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			count, err := CountReportsFromIP("192.168.56.1", since)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, count)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -6,9 +6,10 @@ import (
 )
 
 // CreateReport inserts a new report into the database and returns a Report pointer and any
-// errors encountered
-func CreateReport(postID int, ip string, reason string) (*Report, error) {
-	insertSQL := `INSERT INTO DBPREFIXreports (post_id, ip, reason, is_cleared) VALUES(?, INET6_ATON(?), ?, FALSE)`
+// errors encountered. category is the ID of the board's report category, or an empty string if it isn't in one
+func CreateReport(postID int, ip string, category string, reason string) (*Report, error) {
+	insertSQL := `INSERT INTO DBPREFIXreports (post_id, ip, reason, category, is_cleared, timestamp)
+		VALUES(?, INET6_ATON(?), ?, ?, FALSE, ?)`
 	currentTime := time.Now()
 
 	ctx, cancel := setupTimeoutContext(context.Background(), gcdb)
@@ -20,7 +21,7 @@ func CreateReport(postID int, ip string, reason string) (*Report, error) {
 	}
 	defer tx.Rollback()
 
-	result, err := ExecContextSQL(ctx, tx, insertSQL, postID, ip, reason)
+	result, err := ExecContextSQL(ctx, tx, insertSQL, postID, ip, reason, category, currentTime)
 	if err != nil {
 		return nil, err
 	}
@@ -36,13 +37,16 @@ func CreateReport(postID int, ip string, reason string) (*Report, error) {
 	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
-	return &Report{
+	report := &Report{
 		ID:        int(reportID),
 		PostID:    postID,
 		IP:        ip,
 		Reason:    reason,
+		Category:  category,
 		IsCleared: false,
-	}, nil
+	}
+	ReportCreatedEvent.Notify(&ReportCreatedPayload{Report: report})
+	return report, nil
 }
 
 // ClearReport dismisses the report with the given `id`. If `block` is true, future reports of the post will
@@ -79,22 +83,47 @@ func ClearReport(id int, staffID int, block bool) (bool, error) {
 	return affected > 0, tx.Commit()
 }
 
-// CheckPostReports checks to see if the given post ID has already been reported, and if a report of the post has been
-// dismissed with prejudice (so that more reports of that post can't be made)
-func CheckPostReports(postID int, reason string) (bool, bool, error) {
+// CheckPostReports checks to see if the given post ID has already been reported by the IP address (or with the same
+// reason if the report isn't in a category), and if a report of the post has been dismissed with prejudice (so that
+// more reports of that post can't be made)
+func CheckPostReports(postID int, ip string, category string, reason string) (bool, bool, error) {
 	sql := `SELECT COUNT(*), MAX(is_cleared) FROM DBPREFIXreports
-		WHERE post_id = ? AND (reason = ? OR is_cleared = 2)`
+		WHERE post_id = ? AND (is_cleared = 2 OR (is_cleared = FALSE AND IP_CMP(ip, ?) = 0)`
+	params := []any{postID, ip}
+	if category == "" {
+		sql += ` OR (category = '' AND reason = ?)`
+		params = append(params, reason)
+	}
+	sql += ")"
 	var num int
 	var isCleared any
-	err := QueryRowTimeoutSQL(nil, sql, []any{postID, reason}, []any{&num, &isCleared})
+	err := QueryRowTimeoutSQL(nil, sql, params, []any{&num, &isCleared})
 	isClearedInt, _ := isCleared.(int64)
 	return num > 0, isClearedInt == 2, err
 }
 
+// CountReportsFromIP returns the number of reports made by the IP address since the given time, used for limiting how
+// often users can report posts
+func CountReportsFromIP(ip string, since time.Time) (int, error) {
+	const query = `SELECT COUNT(*) FROM DBPREFIXreports WHERE IP_CMP(ip, ?) = 0 AND timestamp > ?`
+	var count int
+	err := QueryRowTimeoutSQL(nil, query, []any{ip, since}, []any{&count})
+	return count, err
+}
+
+// CountPostReports returns the number of reports of the post in the given category that have not been handled
+func CountPostReports(postID int, category string) (int, error) {
+	const query = `SELECT COUNT(*) FROM DBPREFIXreports WHERE post_id = ? AND category = ? AND is_cleared = FALSE`
+	var count int
+	err := QueryRowTimeoutSQL(nil, query, []any{postID, category}, []any{&count})
+	return count, err
+}
+
 // GetReports returns a Report array and any errors encountered. If `includeCleared` is true,
 // the array will include reports that have already been dismissed
 func GetReports(includeCleared bool) ([]PostReport, error) {
-	sql := `SELECT id, staff_id, staff_user, post_id, thread_op, board, reporter_ip, poster_ip, reason, is_cleared FROM DBPREFIXv_post_reports`
+	sql := `SELECT id, staff_id, staff_user, post_id, thread_op, board, reporter_ip, poster_ip, reason, category, is_cleared,
+		timestamp, post_message, post_hidden FROM DBPREFIXv_post_reports`
 	if !includeCleared {
 		sql += ` WHERE is_cleared = FALSE`
 	}
@@ -111,7 +140,8 @@ func GetReports(includeCleared bool) ([]PostReport, error) {
 	for rows.Next() {
 		var report PostReport
 		err = rows.Scan(&report.ID, &report.StaffID, &report.StaffUser, &report.PostID, &report.ThreadOP, &report.Board, &report.ReporterIP,
-			&report.PosterIP, &report.Reason, &report.IsCleared)
+			&report.PosterIP, &report.Reason, &report.Category, &report.IsCleared, &report.Timestamp, &report.PostMessage,
+			&report.PostHidden)
 		if err != nil {
 			return nil, err
 		}
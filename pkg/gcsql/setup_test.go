@@ -25,24 +25,28 @@ var (
 		`CREATE TABLE boards\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+section_id BIGINT NOT NULL,\s+uri VARCHAR\(45\) NOT NULL,\s+dir VARCHAR\(45\) NOT NULL,\s+navbar_position SMALLINT NOT NULL,\s+title VARCHAR\(45\) NOT NULL,\s+subtitle VARCHAR\(64\) NOT NULL,\s+description VARCHAR\(64\) NOT NULL,\s+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT boards_section_id_fk FOREIGN KEY\(section_id\) REFERENCES sections\(id\),\s+CONSTRAINT boards_dir_unique UNIQUE\(dir\),\s+CONSTRAINT boards_uri_unique UNIQUE\(uri\)\s*\)`,
 		`CREATE TABLE threads\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+board_id BIGINT NOT NULL,\s+locked BOOL NOT NULL DEFAULT FALSE,\s+stickied BOOL NOT NULL DEFAULT FALSE,\s+anchored BOOL NOT NULL DEFAULT FALSE,\s+cyclic BOOL NOT NULL DEFAULT FALSE,\s+is_spoilered BOOL NOT NULL DEFAULT FALSE,\s+last_bump TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_deleted BOOL NOT NULL DEFAULT FALSE,\s+CONSTRAINT threads_board_id_fk\s+FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE\s*\)`,
 		createThreadDeletedIndexRE,
-		`CREATE TABLE posts\( id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY, thread_id BIGINT NOT NULL, is_top_post BOOL NOT NULL DEFAULT FALSE, ip VARBINARY\(16\) NOT NULL, created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, name VARCHAR\(50\) NOT NULL DEFAULT '', tripcode VARCHAR\(10\) NOT NULL DEFAULT '', is_secure_tripcode BOOL NOT NULL DEFAULT FALSE, is_role_signature BOOL NOT NULL DEFAULT FALSE, email VARCHAR\(50\) NOT NULL DEFAULT '', subject VARCHAR\(100\) NOT NULL DEFAULT '', message TEXT NOT NULL, message_raw TEXT NOT NULL, password TEXT NOT NULL, deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, is_deleted BOOL NOT NULL DEFAULT FALSE, banned_message TEXT, flag VARCHAR\(45\) NOT NULL DEFAULT '', country VARCHAR\(80\) NOT NULL DEFAULT '', CONSTRAINT posts_thread_id_fk FOREIGN KEY\(thread_id\) REFERENCES threads\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE posts\( id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY, thread_id BIGINT NOT NULL, is_top_post BOOL NOT NULL DEFAULT FALSE, ip VARBINARY\(16\) NOT NULL, created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, name VARCHAR\(50\) NOT NULL DEFAULT '', tripcode VARCHAR\(10\) NOT NULL DEFAULT '', is_secure_tripcode BOOL NOT NULL DEFAULT FALSE, is_role_signature BOOL NOT NULL DEFAULT FALSE, email VARCHAR\(50\) NOT NULL DEFAULT '', subject VARCHAR\(100\) NOT NULL DEFAULT '', message TEXT NOT NULL, message_raw TEXT NOT NULL, password TEXT NOT NULL, deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, is_deleted BOOL NOT NULL DEFAULT FALSE, is_hidden BOOL NOT NULL DEFAULT FALSE, banned_message TEXT, flag VARCHAR\(45\) NOT NULL DEFAULT '', country VARCHAR\(80\) NOT NULL DEFAULT '', CONSTRAINT posts_thread_id_fk FOREIGN KEY\(thread_id\) REFERENCES threads\(id\) ON DELETE CASCADE \)`,
 		createTopPostIndexRE,
-		`CREATE TABLE files\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+post_id BIGINT NOT NULL,\s+file_order INT NOT NULL,\s+original_filename VARCHAR\(255\) NOT NULL,\s+filename VARCHAR\(45\) NOT NULL,\s+checksum TEXT NOT NULL,\s+file_size INT NOT NULL,\s+is_spoilered BOOL NOT NULL,\s+thumbnail_width INT NOT NULL,\s+thumbnail_height INT NOT NULL,\s+width INT NOT NULL,\s+height INT NOT NULL,\s+CONSTRAINT files_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE,\s+CONSTRAINT files_post_id_file_order_unique UNIQUE\(post_id, file_order\) \)`,
+		`CREATE TABLE files\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+post_id BIGINT NOT NULL,\s+file_order INT NOT NULL,\s+original_filename VARCHAR\(255\) NOT NULL,\s+filename VARCHAR\(45\) NOT NULL,\s+checksum TEXT NOT NULL,\s+stored_checksum VARCHAR\(45\) NOT NULL DEFAULT '',\s+file_size INT NOT NULL,\s+is_spoilered BOOL NOT NULL,\s+thumbnail_width INT NOT NULL,\s+thumbnail_height INT NOT NULL,\s+width INT NOT NULL,\s+height INT NOT NULL,\s+CONSTRAINT files_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE,\s+CONSTRAINT files_post_id_file_order_unique UNIQUE\(post_id, file_order\) \)`,
 		`CREATE TABLE staff\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+username VARCHAR\(45\) NOT NULL,\s+password_checksum VARCHAR\(120\) NOT NULL,\s+global_rank INT,\s+added_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+last_login TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_active BOOL NOT NULL DEFAULT TRUE,\s+CONSTRAINT staff_username_unique UNIQUE\(username\) \)`,
 		`CREATE TABLE sessions\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+staff_id BIGINT NOT NULL,\s+expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+data VARCHAR\(45\) NOT NULL,\s+CONSTRAINT sessions_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE board_staff\(\s+board_id BIGINT NOT NULL,\s+staff_id BIGINT NOT NULL,  CONSTRAINT board_staff_board_id_fk\s+FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE,\s+CONSTRAINT board_staff_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE CASCADE,\s+CONSTRAINT board_staff_pk PRIMARY KEY \(board_id,staff_id\) \)`,
 		`CREATE TABLE announcements\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+staff_id BIGINT NOT NULL,\s+subject VARCHAR\(45\) NOT NULL,\s+message TEXT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT announcements_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) \)`,
 		`CREATE TABLE ip_ban\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+staff_id BIGINT NOT NULL, board_id BIGINT, banned_for_post_id BIGINT, copy_post_text TEXT NOT NULL, is_thread_ban BOOL NOT NULL, is_active BOOL NOT NULL, range_start VARBINARY\(16\) NOT NULL, range_end VARBINARY\(16\) NOT NULL, issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, appeal_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, permanent BOOL NOT NULL, staff_note VARCHAR\(255\) NOT NULL, message TEXT NOT NULL, can_appeal BOOL NOT NULL, CONSTRAINT ip_ban_board_id_fk FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE, CONSTRAINT ip_ban_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\), CONSTRAINT ip_ban_banned_for_post_id_fk FOREIGN KEY\(banned_for_post_id\) REFERENCES posts\(id\) ON DELETE SET NULL \)`,
 		`CREATE TABLE ip_ban_audit\(\s+ip_ban_id BIGINT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+staff_id BIGINT NOT NULL,\s+is_active BOOL NOT NULL,\s+is_thread_ban BOOL NOT NULL,\s+expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+appeal_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+permanent BOOL NOT NULL,\s+staff_note VARCHAR\(255\) NOT NULL,\s+message TEXT NOT NULL,\s+can_appeal BOOL NOT NULL,\s+PRIMARY KEY\(ip_ban_id, timestamp\),\s+CONSTRAINT ip_ban_audit_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE,\s+CONSTRAINT ip_ban_audit_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s+\)`,
-		`CREATE TABLE ip_ban_appeals\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+staff_id BIGINT,\s+ip_ban_id BIGINT NOT NULL,\s+appeal_text TEXT NOT NULL,\s+is_denied BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT ip_ban_appeals_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE ip_ban_appeals\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+staff_id BIGINT,\s+ip_ban_id BIGINT NOT NULL,\s+appeal_text TEXT NOT NULL,\s+is_denied BOOL NOT NULL,\s+status VARCHAR\(16\) NOT NULL DEFAULT 'open',\s+staff_unread BOOL NOT NULL DEFAULT TRUE,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT ip_ban_appeals_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE ip_ban_appeals_audit\( appeal_id BIGINT NOT NULL, timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, staff_id BIGINT, appeal_text TEXT NOT NULL, is_denied BOOL NOT NULL, PRIMARY KEY\(appeal_id, timestamp\), CONSTRAINT ip_ban_appeals_audit_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\), CONSTRAINT ip_ban_appeals_audit_appeal_id_fk FOREIGN KEY\(appeal_id\) REFERENCES ip_ban_appeals\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE ip_ban_appeals_messages\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+appeal_id BIGINT NOT NULL,\s+staff_id BIGINT,\s+message_text TEXT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_messages_appeal_id_fk\s+FOREIGN KEY\(appeal_id\) REFERENCES ip_ban_appeals\(id\) ON DELETE CASCADE,\s+CONSTRAINT ip_ban_appeals_messages_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE SET NULL\s+\)`,
-		`CREATE TABLE reports\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+handled_by_staff_id BIGINT,\s+post_id BIGINT NOT NULL,\s+ip VARBINARY\(16\) NOT NULL,\s+reason TEXT NOT NULL,\s+is_cleared BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT reports_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),  CONSTRAINT reports_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE reports\(\s+id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s+handled_by_staff_id BIGINT,\s+post_id BIGINT NOT NULL,\s+ip VARBINARY\(16\) NOT NULL,\s+reason TEXT NOT NULL,\s+category VARCHAR\(45\) NOT NULL DEFAULT '',\s+is_cleared BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT reports_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),  CONSTRAINT reports_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE reports_audit\(\s+report_id BIGINT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+handled_by_staff_id BIGINT,\s+is_cleared BOOL NOT NULL,\s+CONSTRAINT reports_audit_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT reports_audit_report_id_fk\s+FOREIGN KEY\(report_id\) REFERENCES reports\(id\) ON DELETE CASCADE\s+\)`,
 		`CREATE TABLE filters\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*staff_id BIGINT,\s*staff_note VARCHAR\(255\) NOT NULL,\s*issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*match_action VARCHAR\(45\) NOT NULL DEFAULT 'replace',\s*match_detail TEXT NOT NULL,\s*handle_if_any BOOL NOT NULL DEFAULT FALSE,\s*is_active BOOL NOT NULL,\s*CONSTRAINT filters_staff_id_fk\s*FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s*ON DELETE SET NULL\s*\)`,
 		`CREATE TABLE filter_boards\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*filter_id BIGINT NOT NULL,\s*board_id BIGINT NOT NULL,\s*CONSTRAINT filter_boards_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT filter_boards_board_id_fk\s*FOREIGN KEY\(board_id\)\s*REFERENCES boards\(id\)\s*ON DELETE CASCADE\s*\)`,
 		`CREATE TABLE filter_conditions\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*filter_id BIGINT NOT NULL,\s*match_mode SMALLINT NOT NULL,\s*search VARCHAR\(75\) NOT NULL,\s*field VARCHAR\(75\) NOT NULL,\s*CONSTRAINT filter_conditions_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT filter_conditions_search_check CHECK \(search <> '' OR match_mode = 3\)\s*\)`,
 		`CREATE TABLE filter_hits\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*filter_id BIGINT NOT NULL,\s*post_data TEXT NOT NULL,\s*match_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*CONSTRAINT filter_hits_filter_id_fk\s*FOREIGN KEY\(filter_id\)\s*REFERENCES filters\(id\)\s*ON DELETE CASCADE\s*\)`,
+		`CREATE TABLE file_metadata\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*file_id BIGINT NOT NULL,\s*name VARCHAR\(45\) NOT NULL,\s*value TEXT NOT NULL,\s*CONSTRAINT file_metadata_file_id_fk\s*FOREIGN KEY\(file_id\) REFERENCES files\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT file_metadata_file_id_name_unique UNIQUE\(file_id, name\)\s*\)`,
+		`CREATE TABLE file_fingerprints\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*file_id BIGINT NOT NULL,\s*algorithm VARCHAR\(16\) NOT NULL,\s*fingerprint VARCHAR\(255\) NOT NULL,\s*CONSTRAINT file_fingerprints_file_id_fk\s*FOREIGN KEY\(file_id\) REFERENCES files\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT file_fingerprints_file_id_algorithm_unique UNIQUE\(file_id, algorithm\)\s*\)`,
+		`CREATE TABLE report_bans\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*staff_id BIGINT,\s*ip VARBINARY\(16\) NOT NULL,\s*issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*permanent BOOL NOT NULL DEFAULT FALSE,\s*reason TEXT NOT NULL,\s*CONSTRAINT report_bans_staff_id_fk\s*FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s*ON DELETE SET NULL\s*\)`,
+		`CREATE TABLE webhooks\(\s*id BIGINT NOT NULL AUTO_INCREMENT UNIQUE PRIMARY KEY,\s*name VARCHAR\(45\) NOT NULL,\s*url TEXT NOT NULL,\s*events TEXT NOT NULL,\s*boards TEXT NOT NULL,\s*secret VARCHAR\(255\) NOT NULL,\s*payload_template TEXT NOT NULL,\s*max_retries SMALLINT NOT NULL DEFAULT 3,\s*is_active BOOL NOT NULL DEFAULT TRUE,\s*created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\s*\)`,
 		insertGochanDatabaseVersionStmt,
 	}
 	testInitDBPostgresStatements = []string{
@@ -51,24 +55,28 @@ var (
 		`CREATE TABLE boards\(\s*id BIGSERIAL PRIMARY KEY,\s+section_id BIGINT NOT NULL,\s+uri VARCHAR\(45\) NOT NULL,\s+dir VARCHAR\(45\) NOT NULL,\s+navbar_position SMALLINT NOT NULL,\s+title VARCHAR\(45\) NOT NULL,\s+subtitle VARCHAR\(64\) NOT NULL,\s+description VARCHAR\(64\) NOT NULL,\s+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT boards_section_id_fk\s+FOREIGN KEY\(section_id\) REFERENCES sections\(id\),\s+CONSTRAINT boards_dir_unique UNIQUE\(dir\),\s+CONSTRAINT boards_uri_unique UNIQUE\(uri\)\s*\)`,
 		`CREATE TABLE threads\(\s*id BIGSERIAL PRIMARY KEY,\s+board_id BIGINT NOT NULL,\s+locked BOOL NOT NULL DEFAULT FALSE,\s+stickied BOOL NOT NULL DEFAULT FALSE,\s+anchored BOOL NOT NULL DEFAULT FALSE,\s+cyclic BOOL NOT NULL DEFAULT FALSE,\s+is_spoilered BOOL NOT NULL DEFAULT FALSE,\s+last_bump TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_deleted BOOL NOT NULL DEFAULT FALSE,\s+CONSTRAINT threads_board_id_fk\s+FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE\s*\)`,
 		createThreadDeletedIndexRE,
-		`CREATE TABLE posts\(\s+id BIGSERIAL PRIMARY KEY,\s+thread_id BIGINT NOT NULL,\s+is_top_post BOOL NOT NULL DEFAULT FALSE,\s+ip INET NOT NULL,\s+created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+name VARCHAR\(50\) NOT NULL DEFAULT '',\s+tripcode VARCHAR\(10\) NOT NULL DEFAULT '',\s+is_secure_tripcode BOOL NOT NULL DEFAULT FALSE,\s+is_role_signature BOOL NOT NULL DEFAULT FALSE,  email VARCHAR\(50\) NOT NULL DEFAULT '',\s+subject VARCHAR\(100\) NOT NULL DEFAULT '',\s+message TEXT NOT NULL,\s+message_raw TEXT NOT NULL,\s+password TEXT NOT NULL,\s+deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_deleted BOOL NOT NULL DEFAULT FALSE,\s+banned_message TEXT,\s+flag VARCHAR\(45\) NOT NULL DEFAULT '',\s+country VARCHAR\(80\) NOT NULL DEFAULT '',\s+CONSTRAINT posts_thread_id_fk\s+FOREIGN KEY\(thread_id\) REFERENCES threads\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE posts\(\s+id BIGSERIAL PRIMARY KEY,\s+thread_id BIGINT NOT NULL,\s+is_top_post BOOL NOT NULL DEFAULT FALSE,\s+ip INET NOT NULL,\s+created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+name VARCHAR\(50\) NOT NULL DEFAULT '',\s+tripcode VARCHAR\(10\) NOT NULL DEFAULT '',\s+is_secure_tripcode BOOL NOT NULL DEFAULT FALSE,\s+is_role_signature BOOL NOT NULL DEFAULT FALSE,  email VARCHAR\(50\) NOT NULL DEFAULT '',\s+subject VARCHAR\(100\) NOT NULL DEFAULT '',\s+message TEXT NOT NULL,\s+message_raw TEXT NOT NULL,\s+password TEXT NOT NULL,\s+deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_deleted BOOL NOT NULL DEFAULT FALSE,\s+is_hidden BOOL NOT NULL DEFAULT FALSE,\s+banned_message TEXT,\s+flag VARCHAR\(45\) NOT NULL DEFAULT '',\s+country VARCHAR\(80\) NOT NULL DEFAULT '',\s+CONSTRAINT posts_thread_id_fk\s+FOREIGN KEY\(thread_id\) REFERENCES threads\(id\) ON DELETE CASCADE \)`,
 		createTopPostIndexRE,
-		`CREATE TABLE files\(\s+id BIGSERIAL PRIMARY KEY,\s+post_id BIGINT NOT NULL,\s+file_order INT NOT NULL,\s+original_filename VARCHAR\(255\) NOT NULL,\s+filename VARCHAR\(45\) NOT NULL,\s+checksum TEXT NOT NULL,\s+file_size INT NOT NULL,\s+is_spoilered BOOL NOT NULL,\s+thumbnail_width INT NOT NULL,\s+thumbnail_height INT NOT NULL,\s+width INT NOT NULL,\s+height INT NOT NULL,\s+CONSTRAINT files_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE,\s+CONSTRAINT files_post_id_file_order_unique UNIQUE\(post_id, file_order\) \)`,
+		`CREATE TABLE files\(\s+id BIGSERIAL PRIMARY KEY,\s+post_id BIGINT NOT NULL,\s+file_order INT NOT NULL,\s+original_filename VARCHAR\(255\) NOT NULL,\s+filename VARCHAR\(45\) NOT NULL,\s+checksum TEXT NOT NULL,\s+stored_checksum VARCHAR\(45\) NOT NULL DEFAULT '',\s+file_size INT NOT NULL,\s+is_spoilered BOOL NOT NULL,\s+thumbnail_width INT NOT NULL,\s+thumbnail_height INT NOT NULL,\s+width INT NOT NULL,\s+height INT NOT NULL,\s+CONSTRAINT files_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE,\s+CONSTRAINT files_post_id_file_order_unique UNIQUE\(post_id, file_order\) \)`,
 		`CREATE TABLE staff\(\s+id BIGSERIAL PRIMARY KEY,\s+username VARCHAR\(45\) NOT NULL,\s+password_checksum VARCHAR\(120\) NOT NULL,\s+global_rank INT,\s+added_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+last_login TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_active BOOL NOT NULL DEFAULT TRUE,\s+CONSTRAINT staff_username_unique UNIQUE\(username\) \)`,
 		`CREATE TABLE sessions\(\s+id BIGSERIAL PRIMARY KEY,\s+staff_id BIGINT NOT NULL,\s+expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+data VARCHAR\(45\) NOT NULL,\s+CONSTRAINT sessions_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE board_staff\(\s+board_id BIGINT NOT NULL,\s+staff_id BIGINT NOT NULL,  CONSTRAINT board_staff_board_id_fk\s+FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE,\s+CONSTRAINT board_staff_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE CASCADE,\s+CONSTRAINT board_staff_pk PRIMARY KEY \(board_id,staff_id\) \)`,
 		`CREATE TABLE announcements\(\s+id BIGSERIAL PRIMARY KEY,\s+staff_id BIGINT NOT NULL,\s+subject VARCHAR\(45\) NOT NULL,\s+message TEXT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT announcements_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) \)`,
 		`CREATE TABLE ip_ban\(\s+id BIGSERIAL PRIMARY KEY,\s+staff_id BIGINT NOT NULL, board_id BIGINT, banned_for_post_id BIGINT, copy_post_text TEXT NOT NULL, is_thread_ban BOOL NOT NULL, is_active BOOL NOT NULL, range_start INET NOT NULL, range_end INET NOT NULL, issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, appeal_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, permanent BOOL NOT NULL, staff_note VARCHAR\(255\) NOT NULL, message TEXT NOT NULL, can_appeal BOOL NOT NULL, CONSTRAINT ip_ban_board_id_fk FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE, CONSTRAINT ip_ban_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\), CONSTRAINT ip_ban_banned_for_post_id_fk FOREIGN KEY\(banned_for_post_id\) REFERENCES posts\(id\) ON DELETE SET NULL \)`,
 		`CREATE TABLE ip_ban_audit\(\s+ip_ban_id BIGINT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+staff_id BIGINT NOT NULL,\s+is_active BOOL NOT NULL,\s+is_thread_ban BOOL NOT NULL,\s+expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+appeal_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+permanent BOOL NOT NULL,\s+staff_note VARCHAR\(255\) NOT NULL,\s+message TEXT NOT NULL,\s+can_appeal BOOL NOT NULL,\s+PRIMARY KEY\(ip_ban_id, timestamp\),\s+CONSTRAINT ip_ban_audit_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE,\s+CONSTRAINT ip_ban_audit_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s+\)`,
-		`CREATE TABLE ip_ban_appeals\(\s+id BIGSERIAL PRIMARY KEY,\s+staff_id BIGINT,\s+ip_ban_id BIGINT NOT NULL,\s+appeal_text TEXT NOT NULL,\s+is_denied BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT ip_ban_appeals_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE ip_ban_appeals\(\s+id BIGSERIAL PRIMARY KEY,\s+staff_id BIGINT,\s+ip_ban_id BIGINT NOT NULL,\s+appeal_text TEXT NOT NULL,\s+is_denied BOOL NOT NULL,\s+status VARCHAR\(16\) NOT NULL DEFAULT 'open',\s+staff_unread BOOL NOT NULL DEFAULT TRUE,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT ip_ban_appeals_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE ip_ban_appeals_audit\( appeal_id BIGINT NOT NULL, timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, staff_id BIGINT, appeal_text TEXT NOT NULL, is_denied BOOL NOT NULL, PRIMARY KEY\(appeal_id, timestamp\), CONSTRAINT ip_ban_appeals_audit_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\), CONSTRAINT ip_ban_appeals_audit_appeal_id_fk FOREIGN KEY\(appeal_id\) REFERENCES ip_ban_appeals\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE ip_ban_appeals_messages\(\s+id BIGSERIAL PRIMARY KEY,\s+appeal_id BIGINT NOT NULL,\s+staff_id BIGINT,\s+message_text TEXT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_messages_appeal_id_fk\s+FOREIGN KEY\(appeal_id\) REFERENCES ip_ban_appeals\(id\) ON DELETE CASCADE,\s+CONSTRAINT ip_ban_appeals_messages_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE SET NULL\s+\)`,
-		`CREATE TABLE reports\(\s+id BIGSERIAL PRIMARY KEY,\s+handled_by_staff_id BIGINT,\s+post_id BIGINT NOT NULL,\s+ip INET NOT NULL,\s+reason TEXT NOT NULL,\s+is_cleared BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT reports_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),  CONSTRAINT reports_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE reports\(\s+id BIGSERIAL PRIMARY KEY,\s+handled_by_staff_id BIGINT,\s+post_id BIGINT NOT NULL,\s+ip INET NOT NULL,\s+reason TEXT NOT NULL,\s+category VARCHAR\(45\) NOT NULL DEFAULT '',\s+is_cleared BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT reports_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),  CONSTRAINT reports_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE reports_audit\(\s+report_id BIGINT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+handled_by_staff_id BIGINT,\s+is_cleared BOOL NOT NULL,\s+CONSTRAINT reports_audit_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT reports_audit_report_id_fk\s+FOREIGN KEY\(report_id\) REFERENCES reports\(id\) ON DELETE CASCADE\s+\)`,
 		`CREATE TABLE filters\(\s*id BIGSERIAL PRIMARY KEY,\s*staff_id BIGINT,\s*staff_note VARCHAR\(255\) NOT NULL,\s*issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*match_action VARCHAR\(45\) NOT NULL DEFAULT 'replace',\s*match_detail TEXT NOT NULL,\s*handle_if_any BOOL NOT NULL DEFAULT FALSE,\s*is_active BOOL NOT NULL,\s*CONSTRAINT filters_staff_id_fk\s*FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s*ON DELETE SET NULL\s*\)`,
 		`CREATE TABLE filter_boards\(\s*id BIGSERIAL PRIMARY KEY,\s*filter_id BIGINT NOT NULL,\s*board_id BIGINT NOT NULL,\s*CONSTRAINT filter_boards_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT filter_boards_board_id_fk\s*FOREIGN KEY\(board_id\) REFERENCES boards\(id\)\s*ON DELETE CASCADE\s*\)`,
 		`CREATE TABLE filter_conditions\(\s*id BIGSERIAL PRIMARY KEY,\s*filter_id BIGINT NOT NULL,\s*match_mode SMALLINT NOT NULL,\s*search VARCHAR\(75\) NOT NULL,\s*field VARCHAR\(75\) NOT NULL,\s*CONSTRAINT filter_conditions_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT filter_conditions_search_check CHECK \(search <> '' OR match_mode = 3\)\s*\)`,
 		`CREATE TABLE filter_hits\(\s*id BIGSERIAL PRIMARY KEY,\s*filter_id BIGINT NOT NULL,\s*post_data TEXT NOT NULL,\s*match_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*CONSTRAINT filter_hits_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE\s*\)`,
+		`CREATE TABLE file_metadata\(\s*id BIGSERIAL PRIMARY KEY,\s*file_id BIGINT NOT NULL,\s*name VARCHAR\(45\) NOT NULL,\s*value TEXT NOT NULL,\s*CONSTRAINT file_metadata_file_id_fk\s*FOREIGN KEY\(file_id\) REFERENCES files\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT file_metadata_file_id_name_unique UNIQUE\(file_id, name\)\s*\)`,
+		`CREATE TABLE file_fingerprints\(\s*id BIGSERIAL PRIMARY KEY,\s*file_id BIGINT NOT NULL,\s*algorithm VARCHAR\(16\) NOT NULL,\s*fingerprint VARCHAR\(255\) NOT NULL,\s*CONSTRAINT file_fingerprints_file_id_fk\s*FOREIGN KEY\(file_id\) REFERENCES files\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT file_fingerprints_file_id_algorithm_unique UNIQUE\(file_id, algorithm\)\s*\)`,
+		`CREATE TABLE report_bans\(\s*id BIGSERIAL PRIMARY KEY,\s*staff_id BIGINT,\s*ip INET NOT NULL,\s*issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*permanent BOOL NOT NULL DEFAULT FALSE,\s*reason TEXT NOT NULL,\s*CONSTRAINT report_bans_staff_id_fk\s*FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s*ON DELETE SET NULL\s*\)`,
+		`CREATE TABLE webhooks\(\s*id BIGSERIAL PRIMARY KEY,\s*name VARCHAR\(45\) NOT NULL,\s*url TEXT NOT NULL,\s*events TEXT NOT NULL,\s*boards TEXT NOT NULL,\s*secret VARCHAR\(255\) NOT NULL,\s*payload_template TEXT NOT NULL,\s*max_retries SMALLINT NOT NULL DEFAULT 3,\s*is_active BOOL NOT NULL DEFAULT TRUE,\s*created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\s*\)`,
 		insertGochanDatabaseVersionStmt,
 	}
 	testInitDBSQLite3Statements = []string{
@@ -77,24 +85,28 @@ var (
 		`CREATE TABLE boards\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+section_id BIGINT NOT NULL,\s+uri VARCHAR\(45\) NOT NULL,\s+dir VARCHAR\(45\) NOT NULL,\s+navbar_position SMALLINT NOT NULL,\s+title VARCHAR\(45\) NOT NULL,\s+subtitle VARCHAR\(64\) NOT NULL,\s+description VARCHAR\(64\) NOT NULL,\s+created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT boards_section_id_fk\s+FOREIGN KEY\(section_id\) REFERENCES sections\(id\),\s+CONSTRAINT boards_dir_unique UNIQUE\(dir\),\s+CONSTRAINT boards_uri_unique UNIQUE\(uri\)\s*\)`,
 		`CREATE TABLE threads\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+board_id BIGINT NOT NULL,\s+locked BOOL NOT NULL DEFAULT FALSE,\s+stickied BOOL NOT NULL DEFAULT FALSE,\s+anchored BOOL NOT NULL DEFAULT FALSE,\s+cyclic BOOL NOT NULL DEFAULT FALSE,\s+is_spoilered BOOL NOT NULL DEFAULT FALSE,\s+last_bump TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_deleted BOOL NOT NULL DEFAULT FALSE,\s+CONSTRAINT threads_board_id_fk\s+FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE\s*\)`,
 		createThreadDeletedIndexRE,
-		`CREATE TABLE posts\( id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, thread_id BIGINT NOT NULL, is_top_post BOOL NOT NULL DEFAULT FALSE, ip VARBINARY\(16\) NOT NULL, created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, name VARCHAR\(50\) NOT NULL DEFAULT '', tripcode VARCHAR\(10\) NOT NULL DEFAULT '', is_secure_tripcode BOOL NOT NULL DEFAULT FALSE, is_role_signature BOOL NOT NULL DEFAULT FALSE, email VARCHAR\(50\) NOT NULL DEFAULT '', subject VARCHAR\(100\) NOT NULL DEFAULT '', message TEXT NOT NULL, message_raw TEXT NOT NULL, password TEXT NOT NULL, deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, is_deleted BOOL NOT NULL DEFAULT FALSE, banned_message TEXT, flag VARCHAR\(45\) NOT NULL DEFAULT '', country VARCHAR\(80\) NOT NULL DEFAULT '', CONSTRAINT posts_thread_id_fk FOREIGN KEY\(thread_id\) REFERENCES threads\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE posts\( id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, thread_id BIGINT NOT NULL, is_top_post BOOL NOT NULL DEFAULT FALSE, ip VARBINARY\(16\) NOT NULL, created_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, name VARCHAR\(50\) NOT NULL DEFAULT '', tripcode VARCHAR\(10\) NOT NULL DEFAULT '', is_secure_tripcode BOOL NOT NULL DEFAULT FALSE, is_role_signature BOOL NOT NULL DEFAULT FALSE, email VARCHAR\(50\) NOT NULL DEFAULT '', subject VARCHAR\(100\) NOT NULL DEFAULT '', message TEXT NOT NULL, message_raw TEXT NOT NULL, password TEXT NOT NULL, deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, is_deleted BOOL NOT NULL DEFAULT FALSE, is_hidden BOOL NOT NULL DEFAULT FALSE, banned_message TEXT, flag VARCHAR\(45\) NOT NULL DEFAULT '', country VARCHAR\(80\) NOT NULL DEFAULT '', CONSTRAINT posts_thread_id_fk FOREIGN KEY\(thread_id\) REFERENCES threads\(id\) ON DELETE CASCADE \)`,
 		createTopPostIndexRE,
-		`CREATE TABLE files\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+post_id BIGINT NOT NULL,\s+file_order INT NOT NULL,\s+original_filename VARCHAR\(255\) NOT NULL,\s+filename VARCHAR\(45\) NOT NULL,\s+checksum TEXT NOT NULL,\s+file_size INT NOT NULL,\s+is_spoilered BOOL NOT NULL,\s+thumbnail_width INT NOT NULL,\s+thumbnail_height INT NOT NULL,\s+width INT NOT NULL,\s+height INT NOT NULL,\s+CONSTRAINT files_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE,\s+CONSTRAINT files_post_id_file_order_unique UNIQUE\(post_id, file_order\) \)`,
+		`CREATE TABLE files\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+post_id BIGINT NOT NULL,\s+file_order INT NOT NULL,\s+original_filename VARCHAR\(255\) NOT NULL,\s+filename VARCHAR\(45\) NOT NULL,\s+checksum TEXT NOT NULL,\s+stored_checksum VARCHAR\(45\) NOT NULL DEFAULT '',\s+file_size INT NOT NULL,\s+is_spoilered BOOL NOT NULL,\s+thumbnail_width INT NOT NULL,\s+thumbnail_height INT NOT NULL,\s+width INT NOT NULL,\s+height INT NOT NULL,\s+CONSTRAINT files_post_id_fk\s+FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE,\s+CONSTRAINT files_post_id_file_order_unique UNIQUE\(post_id, file_order\) \)`,
 		`CREATE TABLE staff\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+username VARCHAR\(45\) NOT NULL,\s+password_checksum VARCHAR\(120\) NOT NULL,\s+global_rank INT,\s+added_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+last_login TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+is_active BOOL NOT NULL DEFAULT TRUE,\s+CONSTRAINT staff_username_unique UNIQUE\(username\) \)`,
 		`CREATE TABLE sessions\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+staff_id BIGINT NOT NULL,\s+expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+data VARCHAR\(45\) NOT NULL,\s+CONSTRAINT sessions_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE board_staff\(\s+board_id BIGINT NOT NULL,\s+staff_id BIGINT NOT NULL,  CONSTRAINT board_staff_board_id_fk\s+FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE,\s+CONSTRAINT board_staff_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE CASCADE,\s+CONSTRAINT board_staff_pk PRIMARY KEY \(board_id,staff_id\) \)`,
 		`CREATE TABLE announcements\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+staff_id BIGINT NOT NULL,\s+subject VARCHAR\(45\) NOT NULL,\s+message TEXT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT announcements_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) \)`,
 		`CREATE TABLE ip_ban\( id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, staff_id BIGINT NOT NULL, board_id BIGINT, banned_for_post_id BIGINT, copy_post_text TEXT NOT NULL, is_thread_ban BOOL NOT NULL, is_active BOOL NOT NULL, range_start VARBINARY\(16\) NOT NULL, range_end VARBINARY\(16\) NOT NULL, issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, appeal_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, permanent BOOL NOT NULL, staff_note VARCHAR\(255\) NOT NULL, message TEXT NOT NULL, can_appeal BOOL NOT NULL, CONSTRAINT ip_ban_board_id_fk FOREIGN KEY\(board_id\) REFERENCES boards\(id\) ON DELETE CASCADE, CONSTRAINT ip_ban_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\), CONSTRAINT ip_ban_banned_for_post_id_fk FOREIGN KEY\(banned_for_post_id\) REFERENCES posts\(id\) ON DELETE SET NULL \)`,
 		`CREATE TABLE ip_ban_audit\(\s+ip_ban_id BIGINT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+staff_id BIGINT NOT NULL,\s+is_active BOOL NOT NULL,\s+is_thread_ban BOOL NOT NULL,\s+expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+appeal_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+permanent BOOL NOT NULL,\s+staff_note VARCHAR\(255\) NOT NULL,\s+message TEXT NOT NULL,\s+can_appeal BOOL NOT NULL,\s+PRIMARY KEY\(ip_ban_id, timestamp\),\s+CONSTRAINT ip_ban_audit_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE,\s+CONSTRAINT ip_ban_audit_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s+\)`,
-		`CREATE TABLE ip_ban_appeals\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+staff_id BIGINT,\s+ip_ban_id BIGINT NOT NULL,\s+appeal_text TEXT NOT NULL,\s+is_denied BOOL NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT ip_ban_appeals_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE ip_ban_appeals\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+staff_id BIGINT,\s+ip_ban_id BIGINT NOT NULL,\s+appeal_text TEXT NOT NULL,\s+is_denied BOOL NOT NULL,\s+status VARCHAR\(16\) NOT NULL DEFAULT 'open',\s+staff_unread BOOL NOT NULL DEFAULT TRUE,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT ip_ban_appeals_ip_ban_id_fk\s+FOREIGN KEY\(ip_ban_id\) REFERENCES ip_ban\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE ip_ban_appeals_audit\( appeal_id BIGINT NOT NULL, timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, staff_id BIGINT, appeal_text TEXT NOT NULL, is_denied BOOL NOT NULL, PRIMARY KEY\(appeal_id, timestamp\), CONSTRAINT ip_ban_appeals_audit_staff_id_fk FOREIGN KEY\(staff_id\) REFERENCES staff\(id\), CONSTRAINT ip_ban_appeals_audit_appeal_id_fk FOREIGN KEY\(appeal_id\) REFERENCES ip_ban_appeals\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE ip_ban_appeals_messages\(\s+id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s+appeal_id BIGINT NOT NULL,\s+staff_id BIGINT,\s+message_text TEXT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+CONSTRAINT ip_ban_appeals_messages_appeal_id_fk\s+FOREIGN KEY\(appeal_id\) REFERENCES ip_ban_appeals\(id\) ON DELETE CASCADE,\s+CONSTRAINT ip_ban_appeals_messages_staff_id_fk\s+FOREIGN KEY\(staff_id\) REFERENCES staff\(id\) ON DELETE SET NULL\s+\)`,
-		`CREATE TABLE reports\( id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, handled_by_staff_id BIGINT, post_id BIGINT NOT NULL, ip VARBINARY\(16\) NOT NULL, reason TEXT NOT NULL, is_cleared BOOL NOT NULL, timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, CONSTRAINT reports_handled_by_staff_id_fk FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\), CONSTRAINT reports_post_id_fk FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE \)`,
+		`CREATE TABLE reports\( id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, handled_by_staff_id BIGINT, post_id BIGINT NOT NULL, ip VARBINARY\(16\) NOT NULL, reason TEXT NOT NULL, category VARCHAR\(45\) NOT NULL DEFAULT '', is_cleared BOOL NOT NULL, timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, CONSTRAINT reports_handled_by_staff_id_fk FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\), CONSTRAINT reports_post_id_fk FOREIGN KEY\(post_id\) REFERENCES posts\(id\) ON DELETE CASCADE \)`,
 		`CREATE TABLE reports_audit\(\s+report_id BIGINT NOT NULL,\s+timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s+handled_by_staff_id BIGINT,\s+is_cleared BOOL NOT NULL,\s+CONSTRAINT reports_audit_handled_by_staff_id_fk\s+FOREIGN KEY\(handled_by_staff_id\) REFERENCES staff\(id\),\s+CONSTRAINT reports_audit_report_id_fk\s+FOREIGN KEY\(report_id\) REFERENCES reports\(id\) ON DELETE CASCADE\s+\)`,
 		`CREATE TABLE filters\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*staff_id BIGINT,\s*staff_note VARCHAR\(255\) NOT NULL,\s*issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*match_action VARCHAR\(45\) NOT NULL DEFAULT 'replace',\s*match_detail TEXT NOT NULL,\s*handle_if_any BOOL NOT NULL DEFAULT FALSE,\s*is_active BOOL NOT NULL,\s*CONSTRAINT filters_staff_id_fk\s*FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s*ON DELETE SET NULL\s*\)`,
 		`CREATE TABLE filter_boards\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*filter_id BIGINT NOT NULL,\s*board_id BIGINT NOT NULL,\s*CONSTRAINT filter_boards_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT filter_boards_board_id_fk\s*FOREIGN KEY\(board_id\) REFERENCES boards\(id\)\s*ON DELETE CASCADE\s*\)`,
 		`CREATE TABLE filter_conditions\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*filter_id BIGINT NOT NULL,\s*match_mode SMALLINT NOT NULL,\s*search VARCHAR\(75\) NOT NULL,\s*field VARCHAR\(75\) NOT NULL,\s*CONSTRAINT filter_conditions_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT filter_conditions_search_check CHECK \(search <> '' OR match_mode = 3\)\s*\)`,
 		`CREATE TABLE filter_hits\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*filter_id BIGINT NOT NULL,\s*post_data TEXT NOT NULL,\s*match_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*CONSTRAINT filter_hits_filter_id_fk\s*FOREIGN KEY\(filter_id\) REFERENCES filters\(id\)\s*ON DELETE CASCADE\s*\)`,
+		`CREATE TABLE file_metadata\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*file_id BIGINT NOT NULL,\s*name VARCHAR\(45\) NOT NULL,\s*value TEXT NOT NULL,\s*CONSTRAINT file_metadata_file_id_fk\s*FOREIGN KEY\(file_id\) REFERENCES files\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT file_metadata_file_id_name_unique UNIQUE\(file_id, name\)\s*\)`,
+		`CREATE TABLE file_fingerprints\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*file_id BIGINT NOT NULL,\s*algorithm VARCHAR\(16\) NOT NULL,\s*fingerprint VARCHAR\(255\) NOT NULL,\s*CONSTRAINT file_fingerprints_file_id_fk\s*FOREIGN KEY\(file_id\) REFERENCES files\(id\)\s*ON DELETE CASCADE,\s*CONSTRAINT file_fingerprints_file_id_algorithm_unique UNIQUE\(file_id, algorithm\)\s*\)`,
+		`CREATE TABLE report_bans\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*staff_id BIGINT,\s*ip VARBINARY\(16\) NOT NULL,\s*issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,\s*permanent BOOL NOT NULL DEFAULT FALSE,\s*reason TEXT NOT NULL,\s*CONSTRAINT report_bans_staff_id_fk\s*FOREIGN KEY\(staff_id\) REFERENCES staff\(id\)\s*ON DELETE SET NULL\s*\)`,
+		`CREATE TABLE webhooks\(\s*id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\s*name VARCHAR\(45\) NOT NULL,\s*url TEXT NOT NULL,\s*events TEXT NOT NULL,\s*boards TEXT NOT NULL,\s*secret VARCHAR\(255\) NOT NULL,\s*payload_template TEXT NOT NULL,\s*max_retries SMALLINT NOT NULL DEFAULT 3,\s*is_active BOOL NOT NULL DEFAULT TRUE,\s*created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP\s*\)`,
 		insertGochanDatabaseVersionStmt,
 	}
 )
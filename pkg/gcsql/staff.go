@@ -11,6 +11,7 @@ import (
 	"github.com/Eggbertx/durationutil"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
 )
 
 var (
@@ -18,8 +19,24 @@ var (
 	ErrInvalidStaffRank     = errors.New("invalid staff rank")
 	ErrInvalidStaffPassword = errors.New("blank staff passwords are not allowed")
 	ErrStaffAlreadyExists   = errors.New("staff account already exists")
+
+	_ = metrics.NewGaugeFunc("gochan_staff_sessions_active", "Number of staff login sessions that haven't expired",
+		countActiveSessions)
 )
 
+// countActiveSessions returns the number of staff login sessions that haven't expired yet
+func countActiveSessions() (float64, error) {
+	if gcdb == nil {
+		return 0, ErrNotConnected
+	}
+	const query = `SELECT COUNT(*) FROM DBPREFIXsessions WHERE expires > CURRENT_TIMESTAMP`
+	var count int
+	if err := QueryRowTimeoutSQL(nil, query, nil, []any{&count}); err != nil {
+		return 0, err
+	}
+	return float64(count), nil
+}
+
 // createDefaultAdminIfNoStaff creates a new default admin account if no accounts exist
 func createDefaultAdminIfNoStaff() error {
 	const query = `SELECT COUNT(id) FROM DBPREFIXstaff`
@@ -194,6 +211,21 @@ func EndStaffSession(writer http.ResponseWriter, request *http.Request) error {
 	return nil
 }
 
+// DeleteExpiredSessions deletes all staff login sessions that have expired and returns the number of sessions that
+// were deleted
+func DeleteExpiredSessions(opts ...*RequestOptions) (int64, error) {
+	const query = `DELETE FROM DBPREFIXsessions WHERE expires < CURRENT_TIMESTAMP`
+	opt := setupOptionsWithTimeout(opts...)
+	if opt.Cancel != nil {
+		defer opt.Cancel()
+	}
+	result, err := Exec(opt, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func DeactivateStaff(username string) error {
 	s := Staff{Username: username}
 	return s.SetActive(false)
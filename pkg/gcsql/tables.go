@@ -92,12 +92,24 @@ type Upload struct {
 	OriginalFilename string // sql: original_filename
 	Filename         string // sql: filename
 	Checksum         string // sql: checksum
-	FileSize         int    // sql: file_size
-	IsSpoilered      bool   // sql: is_spoilered
-	ThumbnailWidth   int    // sql: thumbnail_width
-	ThumbnailHeight  int    // sql: thumbnail_height
-	Width            int    // sql: width
-	Height           int    // sql: height
+	// StoredChecksum is the MD5 checksum of the file as it was stored, after any metadata stripping, sanitizing, or
+	// transcoding, which may make it different from Checksum (the checksum of the file that was uploaded). It is
+	// empty for files uploaded before it was added
+	StoredChecksum  string // sql: stored_checksum
+	FileSize        int    // sql: file_size
+	IsSpoilered     bool   // sql: is_spoilered
+	ThumbnailWidth  int    // sql: thumbnail_width
+	ThumbnailHeight int    // sql: thumbnail_height
+	Width           int    // sql: width
+	Height          int    // sql: height
+
+	// Metadata holds optional information about the upload (duration, bitrate, tags, etc) set by the
+	// upload handler. It is stored in DBPREFIXfile_metadata when the upload is attached to a post
+	Metadata map[string]string
+
+	// Fingerprint is the upload's perceptual hash, if it is an image or a video with a fingerprinted thumbnail.
+	// It is stored in DBPREFIXfile_fingerprints when the upload is attached to a post
+	Fingerprint string
 }
 
 // IsEmbed returns true if the upload is an embed
@@ -197,8 +209,10 @@ type ipBanAppealBase struct {
 
 // table: DBPREFIXip_ban_appeals
 type IPBanAppeal struct {
-	ID      int `json:"id"`     // sql: id
-	IPBanID int `json:"ban_id"` // sql: ip_ban_id
+	ID          int          `json:"id"`           // sql: id
+	IPBanID     int          `json:"ban_id"`       // sql: ip_ban_id
+	Status      AppealStatus `json:"status"`       // sql: status
+	StaffUnread bool         `json:"staff_unread"` // sql: staff_unread
 	ipBanAppealBase
 }
 
@@ -208,6 +222,15 @@ type IPBanAppealAudit struct {
 	ipBanAppealBase
 }
 
+// table: DBPREFIXip_ban_appeals_messages
+type IPBanAppealMessage struct {
+	ID          int       `json:"id"`        // sql: id
+	AppealID    int       `json:"appeal_id"` // sql: appeal_id
+	StaffID     *int      `json:"-"`         // sql: staff_id
+	MessageText string    `json:"message"`   // sql: message_text
+	Timestamp   time.Time `json:"timestamp"` // sql: timestamp
+}
+
 // table: DBPREFIXposts
 type Post struct {
 	ID               int           `json:"no"`    // sql: id
@@ -242,6 +265,7 @@ type Report struct {
 	PostID           int    `json:"post_id"`              // sql: post_id
 	IP               string `json:"ip"`                   // sql: ip
 	Reason           string `json:"reason"`               // sql: reason
+	Category         string `json:"category,omitempty"`   // sql: category
 	IsCleared        bool   `json:"is_cleared,omitempty"` // sql: is_cleared
 }
 
@@ -253,6 +277,18 @@ type ReportAudit struct {
 	IsCleared        bool      // sql: is_cleared
 }
 
+// ReportBan prevents an IP address from reporting posts, for example if it has been used to abuse the report system.
+// table: DBPREFIXreport_bans
+type ReportBan struct {
+	ID        int       `json:"id"`                 // sql: id
+	StaffID   *int      `json:"staff_id,omitempty"` // sql: staff_id
+	IP        string    `json:"ip"`                 // sql: ip
+	IssuedAt  time.Time `json:"issued_at"`          // sql: issued_at
+	ExpiresAt time.Time `json:"expires_at"`         // sql: expires_at
+	Permanent bool      `json:"permanent"`          // sql: permanent
+	Reason    string    `json:"reason"`             // sql: reason
+}
+
 // table: DBPREFIXsections
 type Section struct {
 	ID           int    // sql: id
@@ -295,6 +331,22 @@ type Thread struct {
 	IsDeleted   bool      // sql: is_deleted
 }
 
+// Webhook sends an HTTP POST request to a URL when any of its events are triggered. Events and Boards are comma
+// separated lists, and an empty Boards list means that the webhook watches all boards.
+// table: DBPREFIXwebhooks
+type Webhook struct {
+	ID              int       `json:"id"`               // sql: id
+	Name            string    `json:"name"`             // sql: name
+	URL             string    `json:"url"`              // sql: url
+	Events          string    `json:"events"`           // sql: events
+	Boards          string    `json:"boards"`           // sql: boards
+	Secret          string    `json:"-"`                // sql: secret
+	PayloadTemplate string    `json:"payload_template"` // sql: payload_template
+	MaxRetries      int       `json:"max_retries"`      // sql: max_retries
+	IsActive        bool      `json:"is_active"`        // sql: is_active
+	CreatedAt       time.Time `json:"created_at"`       // sql: created_at
+}
+
 // Wordfilter is used for filters that are expected to have a single FilterCondition and a "replace" MatchAction
 type Wordfilter struct {
 	Filter
@@ -0,0 +1,228 @@
+package gcsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ColumnValue is a column whose values can be copied between database types as they are (integers and strings)
+	ColumnValue ColumnKind = "value"
+	// ColumnBool is a boolean column, which MySQL and SQLite store as integers
+	ColumnBool ColumnKind = "bool"
+	// ColumnTime is a timestamp column
+	ColumnTime ColumnKind = "time"
+	// ColumnIP is an IP address column, stored as VARBINARY(16) in MySQL and SQLite and INET in PostgreSQL
+	ColumnIP ColumnKind = "ip"
+)
+
+var (
+	ErrDatabaseNotEmpty = errors.New("database already contains gochan tables")
+
+	// TransferTables lists gochan's tables (except database_version) in an order that satisfies their foreign key
+	// constraints, so that rows can be copied into an empty database one table at a time
+	TransferTables = []string{
+		"DBPREFIXsections",
+		"DBPREFIXboards",
+		"DBPREFIXstaff",
+		"DBPREFIXsessions",
+		"DBPREFIXboard_staff",
+		"DBPREFIXannouncements",
+		"DBPREFIXthreads",
+		"DBPREFIXposts",
+		"DBPREFIXfiles",
+		"DBPREFIXfile_metadata",
+		"DBPREFIXfile_fingerprints",
+		"DBPREFIXip_ban",
+		"DBPREFIXip_ban_audit",
+		"DBPREFIXip_ban_appeals",
+		"DBPREFIXip_ban_appeals_audit",
+		"DBPREFIXip_ban_appeals_messages",
+		"DBPREFIXreports",
+		"DBPREFIXreports_audit",
+		"DBPREFIXfilters",
+		"DBPREFIXfilter_boards",
+		"DBPREFIXfilter_conditions",
+		"DBPREFIXfilter_hits",
+		"DBPREFIXreport_bans",
+		"DBPREFIXwebhooks",
+	}
+)
+
+// ColumnKind determines how a column's values are converted when copying them between database types
+type ColumnKind string
+
+// TableColumn is a column in a table being copied from one database to another
+type TableColumn struct {
+	Name string     `json:"name"`
+	Kind ColumnKind `json:"kind"`
+}
+
+// columnKindFromType returns the kind of column based on the type name returned by the SQL driver
+func columnKindFromType(typeName string) ColumnKind {
+	typeName = strings.ToUpper(typeName)
+	if paren := strings.IndexByte(typeName, '('); paren > -1 {
+		typeName = typeName[:paren]
+	}
+	switch strings.TrimSpace(typeName) {
+	case "BOOL", "BOOLEAN", "TINYINT":
+		return ColumnBool
+	case "TIMESTAMP", "TIMESTAMPTZ", "DATETIME":
+		return ColumnTime
+	case "INET", "VARBINARY":
+		return ColumnIP
+	}
+	return ColumnValue
+}
+
+// NormalizeColumnValue converts a value read from (or decoded for) a column to a form that can be written to any of the
+// supported database types. Booleans are returned as bools, timestamps as time.Time in UTC, and IP addresses and
+// byte slices as strings
+func NormalizeColumnValue(kind ColumnKind, value any) (any, error) {
+	if ba, ok := value.([]byte); ok {
+		value = string(ba)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	switch kind {
+	case ColumnBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case ColumnTime:
+		switch v := value.(type) {
+		case time.Time:
+			return v.UTC(), nil
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				t, err = ParseSQLTimeString(v)
+			}
+			return t.UTC(), err
+		}
+	case ColumnIP:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	default:
+		return value, nil
+	}
+	return nil, fmt.Errorf("unexpected %T value for %s column", value, kind)
+}
+
+// TableColumns returns the columns of the given table (e.g. DBPREFIXposts) in the order they were created
+func (db *GCDB) TableColumns(ctx context.Context, table string) ([]TableColumn, error) {
+	rows, err := db.Query(&RequestOptions{Context: ctx}, "SELECT * FROM "+table+" WHERE 1 = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]TableColumn, len(columnTypes))
+	for c, columnType := range columnTypes {
+		columns[c] = TableColumn{
+			Name: columnType.Name(),
+			Kind: columnKindFromType(columnType.DatabaseTypeName()),
+		}
+	}
+	return columns, rows.Close()
+}
+
+// ReadTableRows calls fn with the normalized values (see NormalizeColumnValue) of each row in the table, in the order
+// of columns. The row slice is reused between calls
+func (db *GCDB) ReadTableRows(ctx context.Context, table string, columns []TableColumn, fn func(row []any) error) error {
+	selectColumns := make([]string, len(columns))
+	for c, column := range columns {
+		selectColumns[c] = column.Name
+		if column.Kind == ColumnIP {
+			selectColumns[c] = "INET6_NTOA(" + column.Name + ")"
+		}
+	}
+	rows, err := db.Query(&RequestOptions{Context: ctx},
+		"SELECT "+strings.Join(selectColumns, ",")+" FROM "+table+" ORDER BY 1")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	row := make([]any, len(columns))
+	scanned := make([]any, len(columns))
+	for c := range scanned {
+		scanned[c] = &row[c]
+	}
+	for rows.Next() {
+		if err = rows.Scan(scanned...); err != nil {
+			return err
+		}
+		for c, column := range columns {
+			if row[c], err = NormalizeColumnValue(column.Kind, row[c]); err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column.Name, err)
+			}
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// PrepareTableInsert returns a statement for inserting rows into the table with the given columns, including their IDs.
+// Values passed to it should be normalized with NormalizeColumnValue. The caller is responsible for closing it
+func (db *GCDB) PrepareTableInsert(ctx context.Context, tx *sql.Tx, table string, columns []TableColumn) (*sql.Stmt, error) {
+	columnNames := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for c, column := range columns {
+		columnNames[c] = column.Name
+		placeholders[c] = "?"
+		if column.Kind == ColumnIP {
+			placeholders[c] = "INET6_ATON(?)"
+		}
+	}
+	return db.PrepareContextSQL(ctx,
+		"INSERT INTO "+table+"("+strings.Join(columnNames, ",")+") VALUES("+strings.Join(placeholders, ",")+")", tx)
+}
+
+// ResetTableSequence updates the table's id sequence after rows have been inserted with explicit IDs, so that the next
+// inserted row gets the next unused ID. Only PostgreSQL needs this, since MySQL and SQLite update it automatically
+func (db *GCDB) ResetTableSequence(opts *RequestOptions, table string, columns []TableColumn) error {
+	hasID := slices.ContainsFunc(columns, func(column TableColumn) bool {
+		return column.Name == "id"
+	})
+	if db.driver != "postgres" || !hasID {
+		return nil
+	}
+	_, err := db.Exec(opts,
+		"SELECT setval(pg_get_serial_sequence('"+table+"', 'id'), MAX(id)) FROM "+table+" HAVING MAX(id) IS NOT NULL")
+	return err
+}
+
+// InitializeEmptyDatabase creates gochan's tables in an empty database without creating the default staff account,
+// section, and board, so that they can be filled with rows from another database or a backup
+func InitializeEmptyDatabase(dbType string) error {
+	_, versionFlag, err := GetCompleteDatabaseVersion()
+	if err != nil {
+		return err
+	}
+	if versionFlag != DBClean {
+		return ErrDatabaseNotEmpty
+	}
+	return initDB("initdb_" + dbType + ".sql")
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/gochan-org/gochan/pkg/events"
@@ -103,9 +105,9 @@ func (p *Post) AddAttachment(upload *Upload, requestOpts ...*RequestOptions) err
 	}
 
 	const insertSQL = `INSERT INTO DBPREFIXfiles (
-		post_id, file_order, original_filename, filename, checksum, file_size,
+		post_id, file_order, original_filename, filename, checksum, stored_checksum, file_size,
 		is_spoilered, thumbnail_width, thumbnail_height, width, height)
-	VALUES(?,?,?,?,?,?,?,?,?,?,?)`
+	VALUES(?,?,?,?,?,?,?,?,?,?,?,?)`
 	if upload.FileOrder < 1 {
 		upload.FileOrder, err = p.NextFileOrder(opts)
 		if err != nil {
@@ -114,8 +116,8 @@ func (p *Post) AddAttachment(upload *Upload, requestOpts ...*RequestOptions) err
 	}
 	upload.PostID = p.ID
 	if _, err = Exec(opts, insertSQL,
-		&upload.PostID, &upload.FileOrder, &upload.OriginalFilename, &upload.Filename, &upload.Checksum, &upload.FileSize,
-		&upload.IsSpoilered, &upload.ThumbnailWidth, &upload.ThumbnailHeight, &upload.Width, &upload.Height,
+		&upload.PostID, &upload.FileOrder, &upload.OriginalFilename, &upload.Filename, &upload.Checksum, &upload.StoredChecksum,
+		&upload.FileSize, &upload.IsSpoilered, &upload.ThumbnailWidth, &upload.ThumbnailHeight, &upload.Width, &upload.Height,
 	); err != nil {
 		return err
 	}
@@ -124,6 +126,12 @@ func (p *Post) AddAttachment(upload *Upload, requestOpts ...*RequestOptions) err
 	if err != nil {
 		return err
 	}
+	if err = upload.setMetadata(opts); err != nil {
+		return err
+	}
+	if err = upload.setFingerprint(opts); err != nil {
+		return err
+	}
 	if shouldCommit {
 		if err = opts.Tx.Commit(); err != nil {
 			return err
@@ -154,3 +162,70 @@ func GetUploadFilenameAndBoard(postID int) (string, string, error) {
 	}
 	return filename, dir, nil
 }
+
+// setMetadata inserts the upload's metadata (if any) into the DBPREFIXfile_metadata table
+func (u *Upload) setMetadata(opts *RequestOptions) error {
+	const insertSQL = `INSERT INTO DBPREFIXfile_metadata (file_id, name, value) VALUES(?,?,?)`
+	for _, name := range slices.Sorted(maps.Keys(u.Metadata)) {
+		if _, err := Exec(opts, insertSQL, u.ID, name, u.Metadata[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUploadsMetadata returns the metadata of the uploads attached to the given post IDs in one query, mapped by post
+// ID. Posts without an upload or whose upload doesn't have any metadata aren't in the returned map
+func GetUploadsMetadata(postIDs []int, requestOpts ...*RequestOptions) (map[int]map[string]string, error) {
+	metadata := make(map[int]map[string]string)
+	if len(postIDs) == 0 {
+		return metadata, nil
+	}
+	opts := setupOptions(requestOpts...)
+	params := make([]any, len(postIDs))
+	for i, id := range postIDs {
+		params[i] = id
+	}
+	query := `SELECT f.post_id, m.name, m.value FROM DBPREFIXfile_metadata m
+		JOIN DBPREFIXfiles f ON f.id = m.file_id
+		WHERE f.post_id IN ` + createArrayPlaceholder(params)
+	rows, err := Query(opts, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int
+		var name, value string
+		if err = rows.Scan(&postID, &name, &value); err != nil {
+			return nil, err
+		}
+		if metadata[postID] == nil {
+			metadata[postID] = make(map[string]string)
+		}
+		metadata[postID][name] = value
+	}
+	return metadata, rows.Close()
+}
+
+// GetUploadMetadata returns the metadata of the upload attached to the given post ID, or an empty map
+// if the post doesn't have an upload or the upload doesn't have any metadata
+func GetUploadMetadata(postID int, requestOpts ...*RequestOptions) (map[string]string, error) {
+	opts := setupOptions(requestOpts...)
+	const query = `SELECT name, value FROM DBPREFIXfile_metadata
+		WHERE file_id IN (SELECT id FROM DBPREFIXfiles WHERE post_id = ?)`
+	rows, err := Query(opts, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	metadata := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err = rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		metadata[name] = value
+	}
+	return metadata, rows.Close()
+}
@@ -18,9 +18,22 @@ type Appeal struct {
 	IsBanActive   bool      `json:"-"`
 	BanExpiresAt  time.Time `json:"expires"`
 	Permanent     bool      `json:"permanent"`
+	BanAppealAt   time.Time `json:"appeal_at"`
 	Timestamp     time.Time `json:"timestamp"`
 }
 
+// view: DBPREFIXv_appeal_messages
+type AppealMessage struct {
+	IPBanAppealMessage
+	IPBanID       int    `json:"ban_id"`
+	StaffUsername string `json:"staff,omitempty"`
+}
+
+// IsFromStaff returns true if the message was written by a staff member instead of the banned user
+func (am AppealMessage) IsFromStaff() bool {
+	return am.StaffID != nil
+}
+
 // view: DBPREFIXv_post_reports
 type PostReport struct {
 	ID         int     `json:"id"`
@@ -32,7 +45,14 @@ type PostReport struct {
 	ReporterIP string  `json:"reporter_ip"`
 	PosterIP   string  `json:"poster_ip"`
 	Reason     string  `json:"reason"`
+	Category   string  `json:"category,omitempty"`
 	IsCleared  bool    `json:"is_cleared"`
+	// Timestamp is when the report was made
+	Timestamp time.Time `json:"timestamp"`
+	// PostMessage is the raw text of the reported post, so that it can be reviewed if the post has been hidden
+	PostMessage string `json:"post_message"`
+	// PostHidden is true if the reported post has been hidden because of its reports
+	PostHidden bool `json:"post_hidden"`
 }
 
 func ResetViews() error {
@@ -0,0 +1,147 @@
+package gcsql
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const webhooksQueryBase = `SELECT id, name, url, events, boards, secret, payload_template, max_retries, is_active, created_at
+	FROM DBPREFIXwebhooks`
+
+var (
+	ErrWebhookNotFound        = errors.New("webhook not found")
+	ErrWebhookAlreadyInserted = errors.New("webhook is already in the database")
+)
+
+// GetWebhooks returns the webhooks in the database, optionally only returning the active or inactive ones
+func GetWebhooks(active BooleanFilter) ([]Webhook, error) {
+	rows, cancel, err := QueryTimeoutSQL(nil, webhooksQueryBase+active.whereClause("is_active", false)+" ORDER BY id")
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var webhooks []Webhook
+	for rows.Next() {
+		var webhook Webhook
+		if err = rows.Scan(
+			&webhook.ID, &webhook.Name, &webhook.URL, &webhook.Events, &webhook.Boards, &webhook.Secret,
+			&webhook.PayloadTemplate, &webhook.MaxRetries, &webhook.IsActive, &webhook.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Close()
+}
+
+// GetWebhookByID returns the webhook with the given ID, or ErrWebhookNotFound if it doesn't exist
+func GetWebhookByID(id int) (*Webhook, error) {
+	var webhook Webhook
+	err := QueryRowTimeoutSQL(nil, webhooksQueryBase+" WHERE id = ?", []any{id}, []any{
+		&webhook.ID, &webhook.Name, &webhook.URL, &webhook.Events, &webhook.Boards, &webhook.Secret,
+		&webhook.PayloadTemplate, &webhook.MaxRetries, &webhook.IsActive, &webhook.CreatedAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook inserts the webhook into the database and sets its ID
+func CreateWebhook(webhook *Webhook, requestOptions ...*RequestOptions) error {
+	const query = `INSERT INTO DBPREFIXwebhooks (name, url, events, boards, secret, payload_template, max_retries, is_active)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`
+	if webhook.ID > 0 {
+		return ErrWebhookAlreadyInserted
+	}
+	opts := setupOptions(requestOptions...)
+	shouldCommit := opts.Tx == nil
+	var err error
+	if shouldCommit {
+		if opts.Tx, err = BeginContextTx(opts.Context); err != nil {
+			return err
+		}
+		defer func() {
+			opts.Tx.Rollback()
+			opts.Tx = nil
+		}()
+	}
+	if _, err = Exec(opts, query, webhook.Name, webhook.URL, webhook.Events, webhook.Boards, webhook.Secret,
+		webhook.PayloadTemplate, webhook.MaxRetries, webhook.IsActive,
+	); err != nil {
+		return err
+	}
+	if webhook.ID, err = getLatestID(opts, "DBPREFIXwebhooks"); err != nil {
+		return err
+	}
+	if shouldCommit {
+		return opts.Tx.Commit()
+	}
+	return nil
+}
+
+// Update saves the webhook's fields (except for the ID and creation time) to the database
+func (wh *Webhook) Update() error {
+	const query = `UPDATE DBPREFIXwebhooks SET name = ?, url = ?, events = ?, boards = ?, secret = ?,
+		payload_template = ?, max_retries = ?, is_active = ? WHERE id = ?`
+	result, err := ExecTimeoutSQL(nil, query, wh.Name, wh.URL, wh.Events, wh.Boards, wh.Secret, wh.PayloadTemplate,
+		wh.MaxRetries, wh.IsActive, wh.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SetWebhookActive enables or disables the webhook with the given ID
+func SetWebhookActive(id int, active bool) error {
+	_, err := ExecTimeoutSQL(nil, `UPDATE DBPREFIXwebhooks SET is_active = ? WHERE id = ?`, active, id)
+	return err
+}
+
+// DeleteWebhook deletes the webhook with the given ID from the database
+func DeleteWebhook(id int) error {
+	_, err := ExecTimeoutSQL(nil, `DELETE FROM DBPREFIXwebhooks WHERE id = ?`, id)
+	return err
+}
+
+// splitList splits a comma separated list, trimming spaces and ignoring empty values
+func splitList(list string) []string {
+	var values []string
+	for value := range strings.SplitSeq(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// EventList returns the events that trigger the webhook
+func (wh *Webhook) EventList() []string {
+	return splitList(wh.Events)
+}
+
+// BoardList returns the directories of the boards that the webhook watches, or nil if it watches all boards
+func (wh *Webhook) BoardList() []string {
+	return splitList(wh.Boards)
+}
+
+// HasEvent returns true if the webhook is triggered by the given event
+func (wh *Webhook) HasEvent(event string) bool {
+	return slices.Contains(wh.EventList(), event)
+}
+
+// WatchesBoard returns true if the webhook watches all boards or the board with the given directory
+func (wh *Webhook) WatchesBoard(dir string) bool {
+	boards := wh.BoardList()
+	return len(boards) == 0 || slices.Contains(boards, dir)
+}
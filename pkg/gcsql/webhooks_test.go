@@ -0,0 +1,46 @@
+package gcsql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWebhooks(t *testing.T) {
+	const getWebhooksSQL = `SELECT id, name, url, events, boards, secret, payload_template, max_retries, is_active, created_at\s+FROM webhooks`
+	columns := []string{"id", "name", "url", "events", "boards", "secret", "payload_template", "max_retries", "is_active", "created_at"}
+	for _, driver := range []string{"mysql", "postgres", "sqlite3"} {
+		t.Run(driver, func(t *testing.T) {
+			mock := setupPostTest(t, driver)
+			now := time.Now()
+			mock.ExpectPrepare(getWebhooksSQL + ` WHERE is_active = TRUE ORDER BY id`).ExpectQuery().
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(1, "Staff chat", "https://example.com/hook", "report-created,ban-issued", "", "secret", "", 3, true, now))
+			webhooks, err := GetWebhooks(OnlyTrue)
+			assert.NoError(t, err)
+			if assert.Len(t, webhooks, 1) {
+				assert.Equal(t, "Staff chat", webhooks[0].Name)
+				assert.Equal(t, []string{"report-created", "ban-issued"}, webhooks[0].EventList())
+			}
+
+			mock.ExpectPrepare(getWebhooksSQL + ` WHERE id = \?`).ExpectQuery().WithArgs(2).
+				WillReturnRows(sqlmock.NewRows(columns))
+			_, err = GetWebhookByID(2)
+			assert.ErrorIs(t, err, ErrWebhookNotFound)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestWebhookFilters(t *testing.T) {
+	webhook := Webhook{Events: "thread-created, staff-login", Boards: ""}
+	assert.True(t, webhook.HasEvent("staff-login"))
+	assert.False(t, webhook.HasEvent("ban-issued"))
+	assert.True(t, webhook.WatchesBoard("test"), "webhooks without any boards should watch all boards")
+
+	webhook.Boards = "test,random"
+	assert.True(t, webhook.WatchesBoard("random"))
+	assert.False(t, webhook.WatchesBoard("other"))
+}
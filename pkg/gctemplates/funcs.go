@@ -13,6 +13,7 @@ import (
 	"maps"
 
 	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gcutil"
 )
 
@@ -71,6 +72,24 @@ var funcMap = template.FuncMap{
 		}
 		return fmt.Sprintf("%0.2f GB", size/1024/1024/1024)
 	},
+	"formatDuration": func(seconds string) string {
+		secondsFloat, err := strconv.ParseFloat(seconds, 64)
+		if err != nil {
+			return seconds
+		}
+		duration := int(secondsFloat + 0.5)
+		if duration >= 3600 {
+			return fmt.Sprintf("%d:%02d:%02d", duration/3600, duration%3600/60, duration%60)
+		}
+		return fmt.Sprintf("%d:%02d", duration/60, duration%60)
+	},
+	"formatBitrate": func(bitrate string) string {
+		bitrateInt, err := strconv.Atoi(bitrate)
+		if err != nil {
+			return bitrate
+		}
+		return fmt.Sprintf("%d kbps", bitrateInt/1000)
+	},
 	"formatTimestamp": func(t time.Time) string {
 		return t.UTC().Format(config.GetBoardConfig("").DateTimeFormat)
 	},
@@ -158,7 +177,8 @@ var funcMap = template.FuncMap{
 	"customFlagsEnabled": func(board string) bool {
 		return config.GetBoardConfig(board).CustomFlags != nil
 	},
-	"webPath": config.WebPath,
+	"capcodeTitle": gcsql.CapcodeTitle,
+	"webPath":      config.WebPath,
 	"webPathDir": func(part ...string) string {
 		dir := config.WebPath(part...)
 		if dir == "" {
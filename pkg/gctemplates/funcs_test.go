@@ -180,6 +180,53 @@ func TestFormatFilesizeTmplFunc(t *testing.T) {
 	}
 }
 
+func TestFormatDurationTmplFunc(t *testing.T) {
+	const tmplStr = "{{formatDuration .Seconds}}"
+	testCases := []struct {
+		desc     string
+		Seconds  string
+		expected string
+	}{
+		{
+			desc:     "seconds",
+			Seconds:  "7.4",
+			expected: "0:07",
+		},
+		{
+			desc:     "minutes",
+			Seconds:  "215.6",
+			expected: "3:36",
+		},
+		{
+			desc:     "hours",
+			Seconds:  "3725.000000",
+			expected: "1:02:05",
+		},
+		{
+			desc:     "invalid duration",
+			Seconds:  "N/A",
+			expected: "N/A",
+		},
+	}
+	tmpl := template.Must(template.New("name").Funcs(funcMap).Parse(tmplStr))
+	buf := bytes.NewBuffer(nil)
+
+	for _, tC := range testCases {
+		buf.Reset()
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.NoError(t, tmpl.Execute(buf, tC))
+			assert.Equal(t, tC.expected, buf.String())
+		})
+	}
+}
+
+func TestFormatBitrateTmplFunc(t *testing.T) {
+	tmpl := template.Must(template.New("name").Funcs(funcMap).Parse("{{formatBitrate .}}"))
+	buf := bytes.NewBuffer(nil)
+	assert.NoError(t, tmpl.Execute(buf, "320000"))
+	assert.Equal(t, "320 kbps", buf.String())
+}
+
 func TestFormatTimestampTmplFunc(t *testing.T) {
 	config.InitTestConfig()
 
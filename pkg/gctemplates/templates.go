@@ -2,6 +2,7 @@ package gctemplates
 
 import (
 	"errors"
+	"fmt"
 	"html/template"
 	"os"
 	"path"
@@ -30,14 +31,19 @@ const (
 	ManageFilterHits         = "manage_filter_hits.html"
 	ManageFixThumbnails      = "manage_fixthumbnails.html"
 	ManageIPSearch           = "manage_ipsearch.html"
+	ManageJobs               = "manage_jobs.html"
 	ManageLogin              = "manage_login.html"
+	ManagePlugins            = "manage_plugins.html"
+	ManagePosterID           = "manage_posterid.html"
 	ManageRecentPosts        = "manage_recentposts.html"
 	ManageReports            = "manage_reports.html"
 	ManageSections           = "manage_sections.html"
+	ManageSimilarImages      = "manage_similarimages.html"
 	ManageStaff              = "manage_staff.html"
 	ManageTemplates          = "manage_templateoverride.html"
 	ManageThreadAttrs        = "manage_threadattrs.html"
 	ManageViewLog            = "manage_viewlog.html"
+	ManageWebhooks           = "manage_webhooks.html"
 	ManageWordfilters        = "manage_wordfilters.html"
 	MoveThreadPage           = "movethreadpage.html"
 	PageFooter               = "page_footer.html"
@@ -105,9 +111,18 @@ var (
 		ManageIPSearch: {
 			files: []string{"manage_ipsearch.html"},
 		},
+		ManageJobs: {
+			files: []string{"manage_jobs.html"},
+		},
 		ManageLogin: {
 			files: []string{"manage_login.html"},
 		},
+		ManagePlugins: {
+			files: []string{"manage_plugins.html"},
+		},
+		ManagePosterID: {
+			files: []string{"manage_posterid.html"},
+		},
 		ManageRecentPosts: {
 			files: []string{"manage_recentposts.html"},
 		},
@@ -117,6 +132,9 @@ var (
 		ManageSections: {
 			files: []string{"manage_sections.html"},
 		},
+		ManageSimilarImages: {
+			files: []string{"manage_similarimages.html"},
+		},
 		ManageStaff: {
 			files: []string{"manage_staff.html"},
 		},
@@ -129,6 +147,9 @@ var (
 		ManageViewLog: {
 			files: []string{"manage_viewlog.html"},
 		},
+		ManageWebhooks: {
+			files: []string{"manage_webhooks.html"},
+		},
 		ManageWordfilters: {
 			files: []string{"manage_wordfilters.html"},
 		},
@@ -226,6 +247,18 @@ func GetTemplateList() []string {
 	return templateList
 }
 
+// CheckTemplates loads any templates that haven't been loaded yet, and returns an error for each template that
+// can't be loaded
+func CheckTemplates() error {
+	var errs []error
+	for _, name := range GetTemplateList() {
+		if _, err := GetTemplate(name); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // ParseTemplate initializes a new template with the given name and parses it
 func ParseTemplate(name, tmplStr string) (*template.Template, error) {
 	return template.New(name).Funcs(funcMap).Parse(tmplStr)
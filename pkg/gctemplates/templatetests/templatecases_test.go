@@ -60,6 +60,7 @@ var (
 					},
 				},
 				"ip":         "192.168.56.1",
+				"canAppeal":  true,
 				"siteConfig": testingSiteConfig,
 				"systemCritical": config.SystemCriticalConfig{
 					WebRoot: "/",
@@ -136,6 +137,7 @@ var (
 					},
 				},
 				"ip":         "192.168.56.1",
+				"canAppeal":  true,
 				"siteConfig": testingSiteConfig,
 				"systemCritical": config.SystemCriticalConfig{
 					WebRoot: "/",
@@ -189,6 +191,50 @@ var (
 				assert.Equal(t, "You are banned from posting onall boardsfor the following reason:ban message goes hereYour ban was placed onMon,January 01,0001 12:00:00 AM and will expire on Mon, January 01, 0001 12:00:00 AM.Your IP address is192.168.56.1.You maynot appeal this ban.", doc.Find("#ban-info").Text())
 			},
 		},
+		{
+			desc: "appeal awaiting user response",
+			data: map[string]any{
+				"ban": &gcsql.IPBan{
+					ID:         1,
+					RangeStart: "192.168.56.0",
+					RangeEnd:   "192.168.56.255",
+					IPBanBase: gcsql.IPBanBase{
+						IsActive:  true,
+						CanAppeal: true,
+						StaffID:   1,
+						Message:   "ban message goes here",
+					},
+				},
+				"appeal": &gcsql.Appeal{IPBanAppeal: gcsql.IPBanAppeal{
+					ID:      2,
+					IPBanID: 1,
+					Status:  gcsql.AppealAwaitingUser,
+				}},
+				"appealMessages": []gcsql.AppealMessage{
+					{IPBanAppealMessage: gcsql.IPBanAppealMessage{StaffID: new(int), MessageText: "Why should we unban you?"}},
+				},
+				"board":      simpleBoard1,
+				"ip":         "192.168.56.1",
+				"siteConfig": testingSiteConfig,
+				"systemCritical": config.SystemCriticalConfig{
+					WebRoot: "/",
+				},
+				"boardConfig": config.BoardConfig{
+					DefaultStyle: "pipes.css",
+				},
+			},
+			validationFunc: func(t *testing.T, reader io.Reader) {
+				doc, err := goquery.NewDocumentFromReader(reader)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				assert.Equal(t, "Awaiting user response", doc.Find(".appeal-status").Text())
+				assert.Equal(t, 2, doc.Find(".appeal-message").Length())
+				assert.Equal(t, "Why should we unban you?", doc.Find(".appeal-message-staff .appeal-message-text").Text())
+				assert.Equal(t, "2", doc.Find("#appeal-reply-form input[name=appealid]").AttrOr("value", ""))
+				assert.Equal(t, 0, doc.Find("#appeal-form").Length(), "a new appeal can't be submitted while one is open")
+			},
+		},
 	}
 
 	boardPageTestCases = []templateTestCase{
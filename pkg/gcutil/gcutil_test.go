@@ -2,7 +2,6 @@ package gcutil
 
 import (
 	"errors"
-	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -77,27 +76,6 @@ func TestMarshalJSON(t *testing.T) {
 	}
 }
 
-func TestGetRealIP(t *testing.T) {
-	const remoteAddr = "192.168.56.1"
-	const testIP = "192.168.56.2"
-	const cfIP = "192.168.56.3"
-	const forwardedIP = "192.168.56.4"
-	req := &http.Request{
-		RemoteAddr: remoteAddr,
-		Header:     make(http.Header),
-	}
-	assert.Equal(t, remoteAddr, GetRealIP(req))
-
-	req.Header.Set("X-Forwarded-For", forwardedIP)
-	assert.Equal(t, forwardedIP, GetRealIP(req))
-
-	req.Header.Set("HTTP_CF_CONNECTING_IP", cfIP)
-	assert.Equal(t, cfIP, GetRealIP(req))
-
-	t.Setenv(TestingIPEnvVar, testIP)
-	assert.Equal(t, testIP, GetRealIP(req))
-}
-
 func TestHackyStringToInt(t *testing.T) {
 	i := HackyStringToInt("not an int")
 	assert.Zero(t, i)
@@ -1,7 +1,9 @@
 package gcutil
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"net/http"
 	"os"
@@ -15,11 +17,19 @@ import (
 const (
 	logFlags                = os.O_CREATE | os.O_APPEND | os.O_WRONLY
 	logFileMode fs.FileMode = 0644
+
+	// SystemLoggerSyslog is the LogOptions.SystemLogger value for sending log events to syslog
+	SystemLoggerSyslog = "syslog"
+	// SystemLoggerJournald is the LogOptions.SystemLogger value for sending log events to the systemd journal
+	SystemLoggerJournald = "journald"
 )
 
 var (
-	logFile    *os.File
-	accessFile *os.File
+	ErrInvalidSystemLogger = errors.New("invalid system logger")
+
+	logFile      *rotatingFile
+	accessFile   *rotatingFile
+	systemLogger io.Closer
 
 	logger       zerolog.Logger
 	accessLogger zerolog.Logger
@@ -95,13 +105,15 @@ func RunningInTerminal() bool {
 
 func init() {
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-	// guarantee that the logger is always available, even before the configuration is loaded
+	// guarantee that the logger is always available, even before the configuration is loaded. It writes to stderr
+	// so that it doesn't get mixed in with the output of command line subcommands
 	logger = zerolog.New(zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
+		w.Out = os.Stderr
 		w.NoColor = !RunningInTerminal()
 	})).With().Timestamp().Logger()
 }
 
-func initLog(logPath string, level zerolog.Level, noConsole bool) (err error) {
+func initLog(logPath string, options *LogOptions) (err error) {
 	if logFile != nil {
 		// log already initialized
 		if err = logFile.Close(); err != nil {
@@ -109,9 +121,13 @@ func initLog(logPath string, level zerolog.Level, noConsole bool) (err error) {
 			return err
 		}
 	}
-	logFile, err = os.OpenFile(logPath, logFlags, logFileMode) // skipcq: GSC-G302
+	if systemLogger != nil {
+		systemLogger.Close()
+		systemLogger = nil
+	}
+	logFile, err = openRotatingFile(logPath, options.Rotate, options.UID, options.GID)
 	if err != nil {
-		if noConsole {
+		if options.FileOnly {
 			fmt.Fprintln(os.Stderr, "Unable to open log file:", err)
 		} else {
 			logger.Err(err).Msg("Unable to open log file")
@@ -119,27 +135,33 @@ func initLog(logPath string, level zerolog.Level, noConsole bool) (err error) {
 		return err
 	}
 
-	var writer zerolog.LevelWriter
-	if noConsole {
-		writer = zerolog.MultiLevelWriter(logFile)
-	} else {
-		writer = zerolog.MultiLevelWriter(logFile, zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
+	writers := []io.Writer{logFile}
+	if !options.FileOnly {
+		writers = append(writers, zerolog.NewConsoleWriter(func(w *zerolog.ConsoleWriter) {
 			w.NoColor = !RunningInTerminal()
 		}))
 	}
-	logger = zerolog.New(writer).With().Timestamp().Logger().Level(level)
+	if options.SystemLogger != "" {
+		systemWriter, closer, err := newSystemLogWriter(options.SystemLogger)
+		if err != nil {
+			return fmt.Errorf("unable to connect to %s: %w", options.SystemLogger, err)
+		}
+		writers = append(writers, systemWriter)
+		systemLogger = closer
+	}
+	logger = zerolog.New(zerolog.MultiLevelWriter(writers...)).With().Timestamp().Logger().Level(options.LogLevel)
 
 	return nil
 }
 
-func initAccessLog(logPath string) (err error) {
+func initAccessLog(logPath string, options *LogOptions) (err error) {
 	if accessFile != nil {
 		// access log already initialized, close it first before reopening
 		if err = accessFile.Close(); err != nil {
 			return err
 		}
 	}
-	accessFile, err = os.OpenFile(logPath, logFlags, logFileMode) // skipcq: GSC-G302
+	accessFile, err = openRotatingFile(logPath, options.Rotate, options.UID, options.GID)
 	if err != nil {
 		return err
 	}
@@ -158,6 +180,11 @@ type LogOptions struct {
 	GID int
 	// FileOnly is true if the log file should be used only, and not the console
 	FileOnly bool
+	// Rotate configures the rotation of the log files. If it isn't set, the log files are never rotated
+	Rotate RotateOptions
+	// SystemLogger is "syslog" or "journald" if log events (but not access log events) should also be sent to
+	// the system logger. This is only supported on Unix-like systems
+	SystemLogger string
 }
 
 func InitLogs(logDir string, options *LogOptions) (err error) {
@@ -165,25 +192,31 @@ func InitLogs(logDir string, options *LogOptions) (err error) {
 		options = &LogOptions{}
 	}
 
-	if err = initLog(path.Join(logDir, "gochan.log"), options.LogLevel, options.FileOnly); err != nil {
+	if err = initLog(path.Join(logDir, ErrorLogFilename), options); err != nil {
 		return err
 	}
-	if options.UID > 0 && options.GID > 0 {
-		if err = logFile.Chown(options.UID, options.GID); err != nil {
+	return initAccessLog(path.Join(logDir, AccessLogFilename), options)
+}
+
+// ReopenLogs closes and reopens the log files so that logs are written to new files after the old ones were moved,
+// e.g. by logrotate
+func ReopenLogs() error {
+	if logFile != nil {
+		if err := logFile.Reopen(); err != nil {
 			return err
 		}
 	}
-
-	if err = initAccessLog(path.Join(logDir, "gochan_access.log")); err != nil {
-		return err
-	}
-
-	if options.UID > 0 && options.GID > 0 {
-		return accessFile.Chown(options.UID, options.GID)
+	if accessFile != nil {
+		return accessFile.Reopen()
 	}
 	return nil
 }
 
+// SetLogLevel sets the minimum level of events written to the log, e.g. when the configuration is reloaded
+func SetLogLevel(level zerolog.Level) {
+	logger = logger.Level(level)
+}
+
 func Logger() zerolog.Logger {
 	return logger
 }
@@ -247,6 +280,10 @@ func LogDebug() *zerolog.Event {
 }
 
 func CloseLogs() error {
+	if systemLogger != nil {
+		systemLogger.Close()
+		systemLogger = nil
+	}
 	if logFile == nil {
 		return nil
 	}
@@ -0,0 +1,222 @@
+package gcutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ErrorLogFilename is the name of the main log file in the log directory
+	ErrorLogFilename = "gochan.log"
+	// AccessLogFilename is the name of the access log file in the log directory
+	AccessLogFilename = "gochan_access.log"
+
+	logReadChunkSize = 64 * 1024
+)
+
+var (
+	// logBoardFields are the fields that the board directory of a log entry may be logged as
+	logBoardFields = []string{"board", "boardDir", "dir"}
+	// logIPFields are the fields that the IP address of a log entry may be logged as
+	logIPFields = []string{"IP", "ip"}
+)
+
+// LogEntry is a parsed line from the JSON log files
+type LogEntry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// LogFilter is used to filter the log entries returned by ReadLogEntries. Unset fields are not used for filtering
+type LogFilter struct {
+	// Levels is the list of log levels to show (e.g. "error", "warn"). If it is empty, all levels are shown
+	Levels []string
+	// Since and Until are the (inclusive) time range to show entries from
+	Since time.Time
+	Until time.Time
+	// IP is an IP address or CIDR range that the entry's IP or ip field must be in
+	IP string
+	// Action is the management action that the entry was logged by
+	Action string
+	// Board is the board directory that the entry was logged with
+	Board string
+	// Text is a case-insensitive string that must be in the log line
+	Text string
+
+	ipPrefix netip.Prefix
+}
+
+func (lf *LogFilter) prepare() error {
+	lf.Text = strings.ToLower(lf.Text)
+	if lf.IP == "" {
+		return nil
+	}
+	prefixes, err := ParseIPPrefixes([]string{lf.IP})
+	if err != nil {
+		return err
+	}
+	if len(prefixes) > 0 {
+		lf.ipPrefix = prefixes[0]
+	}
+	return nil
+}
+
+func fieldString(fields map[string]any, key string) string {
+	str, _ := fields[key].(string)
+	return str
+}
+
+func (lf *LogFilter) matches(entry *LogEntry, line []byte) bool {
+	if len(lf.Levels) > 0 && !slices.Contains(lf.Levels, entry.Level) {
+		return false
+	}
+	if !lf.Since.IsZero() && entry.Time.Before(lf.Since) {
+		return false
+	}
+	if !lf.Until.IsZero() && entry.Time.After(lf.Until) {
+		return false
+	}
+	if lf.ipPrefix.IsValid() {
+		if !slices.ContainsFunc(logIPFields, func(key string) bool {
+			addr, err := netip.ParseAddr(fieldString(entry.Fields, key))
+			return err == nil && lf.ipPrefix.Contains(addr.Unmap())
+		}) {
+			return false
+		}
+	}
+	if lf.Action != "" && fieldString(entry.Fields, "action") != lf.Action {
+		return false
+	}
+	if lf.Board != "" && !slices.ContainsFunc(logBoardFields, func(key string) bool {
+		return fieldString(entry.Fields, key) == lf.Board
+	}) {
+		return false
+	}
+	if lf.Text != "" && !bytes.Contains(bytes.ToLower(line), []byte(lf.Text)) {
+		return false
+	}
+	return true
+}
+
+// parseLogEntry parses a JSON line from the log. Lines that aren't valid JSON (e.g. a panic's stack trace) are
+// returned as an entry with the line as the message and no level
+func parseLogEntry(line []byte) LogEntry {
+	var entry LogEntry
+	if err := json.Unmarshal(line, &entry.Fields); err != nil {
+		entry.Message = string(line)
+		entry.Fields = nil
+		return entry
+	}
+	entry.Level = fieldString(entry.Fields, "level")
+	entry.Message = fieldString(entry.Fields, "message")
+	entry.Time, _ = time.Parse(time.RFC3339, fieldString(entry.Fields, "time"))
+	delete(entry.Fields, "level")
+	delete(entry.Fields, "message")
+	delete(entry.Fields, "time")
+	return entry
+}
+
+// reverseLineReader reads the lines of a file from the end to the beginning without reading the whole file into
+// memory
+type reverseLineReader struct {
+	file    io.ReaderAt
+	offset  int64
+	partial []byte
+	lines   [][]byte
+}
+
+func newReverseLineReader(file io.ReaderAt, size int64) *reverseLineReader {
+	return &reverseLineReader{file: file, offset: size}
+}
+
+// next returns the previous non-empty line in the file, or io.EOF if the beginning of the file has been reached
+func (r *reverseLineReader) next() ([]byte, error) {
+	for len(r.lines) == 0 {
+		if r.offset == 0 {
+			line := bytes.TrimSpace(r.partial)
+			r.partial = nil
+			if len(line) == 0 {
+				return nil, io.EOF
+			}
+			return line, nil
+		}
+		chunkSize := min(int64(logReadChunkSize), r.offset)
+		r.offset -= chunkSize
+		chunk := make([]byte, chunkSize, chunkSize+int64(len(r.partial)))
+		if _, err := r.file.ReadAt(chunk, r.offset); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		chunk = append(chunk, r.partial...)
+		// the first line may continue in the previous chunk
+		first, rest, found := bytes.Cut(chunk, []byte{'\n'})
+		r.partial = first
+		if !found {
+			continue
+		}
+		for line := range bytes.SplitSeq(rest, []byte{'\n'}) {
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				r.lines = append(r.lines, line)
+			}
+		}
+	}
+	line := r.lines[len(r.lines)-1]
+	r.lines = r.lines[:len(r.lines)-1]
+	return line, nil
+}
+
+// ReadLogEntries reads the log file at logPath from the end and returns up to limit entries that match the filter,
+// newest first, after skipping the first offset matching entries. more is true if there are older matching entries.
+// The file is streamed in chunks, so only the entries that are needed are kept in memory
+func ReadLogEntries(logPath string, filter *LogFilter, offset int, limit int) (entries []LogEntry, more bool, err error) {
+	if filter == nil {
+		filter = &LogFilter{}
+	}
+	if err = filter.prepare(); err != nil {
+		return nil, false, err
+	}
+	fi, err := os.Open(logPath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer fi.Close()
+	stat, err := fi.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	reader := newReverseLineReader(fi, stat.Size())
+	matched := 0
+	for {
+		line, err := reader.next()
+		if errors.Is(err, io.EOF) {
+			return entries, false, nil
+		} else if err != nil {
+			return nil, false, err
+		}
+		entry := parseLogEntry(line)
+		if !filter.Since.IsZero() && !entry.Time.IsZero() && entry.Time.Before(filter.Since) {
+			// entries are in chronological order, so the rest are too old
+			return entries, false, nil
+		}
+		if !filter.matches(&entry, line) {
+			continue
+		}
+		matched++
+		if matched <= offset {
+			continue
+		}
+		if len(entries) == limit {
+			return entries, true, nil
+		}
+		entries = append(entries, entry)
+	}
+}
@@ -0,0 +1,186 @@
+package gcutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	logReaderStartTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// writeTestLog writes numEntries zerolog entries to a temporary log file, one minute apart. Every third entry is an
+// error from 192.168.56.1 on /test/, and the rest are info entries from 192.168.56.2 from the bans action, with the
+// IP address logged in a lowercase field
+func writeTestLog(t *testing.T, numEntries int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	testLogger := zerolog.New(&buf)
+	for i := range numEntries {
+		timestamp := logReaderStartTime.Add(time.Duration(i) * time.Minute)
+		if i%3 == 0 {
+			testLogger.Error().Time("time", timestamp).Str("IP", "192.168.56.1").Str("board", "test").
+				Int("entry", i).Msg("Something went wrong")
+		} else {
+			testLogger.Info().Time("time", timestamp).Str("ip", "192.168.56.2").Str("action", "bans").
+				Int("entry", i).Msg("Something happened " + strings.Repeat("x", 100))
+		}
+	}
+	logPath := filepath.Join(t.TempDir(), ErrorLogFilename)
+	if !assert.NoError(t, os.WriteFile(logPath, buf.Bytes(), 0644)) {
+		t.FailNow()
+	}
+	return logPath
+}
+
+func entryNumbers(entries []LogEntry) []int {
+	numbers := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		numbers = append(numbers, int(entry.Fields["entry"].(float64)))
+	}
+	return numbers
+}
+
+func TestReadLogEntries(t *testing.T) {
+	// large enough to be read in several chunks
+	const numEntries = 2000
+	logPath := writeTestLog(t, numEntries)
+
+	entries, more, err := ReadLogEntries(logPath, nil, 0, 3)
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, []int{1999, 1998, 1997}, entryNumbers(entries))
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "info", entries[0].Level)
+		assert.Equal(t, "error", entries[1].Level)
+		assert.Equal(t, "Something went wrong", entries[1].Message)
+		assert.Equal(t, logReaderStartTime.Add(1998*time.Minute), entries[1].Time.UTC())
+		assert.NotContains(t, entries[1].Fields, "level")
+	}
+
+	// all of the entries should be read in reverse order across chunk boundaries
+	entries, more, err = ReadLogEntries(logPath, nil, 0, numEntries)
+	assert.NoError(t, err)
+	assert.False(t, more)
+	numbers := entryNumbers(entries)
+	if assert.Len(t, numbers, numEntries) {
+		for i, n := range numbers {
+			if n != numEntries-1-i {
+				assert.Fail(t, fmt.Sprintf("expected entry %d at index %d, got %d", numEntries-1-i, i, n))
+				break
+			}
+		}
+	}
+
+	// last page
+	entries, more, err = ReadLogEntries(logPath, nil, numEntries-2, 5)
+	assert.NoError(t, err)
+	assert.False(t, more)
+	assert.Equal(t, []int{1, 0}, entryNumbers(entries))
+
+	_, _, err = ReadLogEntries(filepath.Join(t.TempDir(), "missing.log"), nil, 0, 10)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadLogEntriesFilter(t *testing.T) {
+	logPath := writeTestLog(t, 30)
+
+	testCases := []struct {
+		desc     string
+		filter   LogFilter
+		offset   int
+		expected []int
+		more     bool
+	}{
+		{
+			desc:     "level",
+			filter:   LogFilter{Levels: []string{"error"}},
+			expected: []int{27, 24, 21},
+			more:     true,
+		},
+		{
+			desc:     "level, second page",
+			filter:   LogFilter{Levels: []string{"error"}},
+			offset:   9,
+			expected: []int{0},
+		},
+		{
+			desc:     "IP range",
+			filter:   LogFilter{IP: "192.168.56.0/31"},
+			expected: []int{27, 24, 21},
+			more:     true,
+		},
+		{
+			desc:     "IP in lowercase field",
+			filter:   LogFilter{IP: "192.168.56.2"},
+			expected: []int{29, 28, 26},
+			more:     true,
+		},
+		{
+			desc:     "action",
+			filter:   LogFilter{Action: "bans"},
+			expected: []int{29, 28, 26},
+			more:     true,
+		},
+		{
+			desc:     "board",
+			filter:   LogFilter{Board: "test", Until: logReaderStartTime.Add(10 * time.Minute)},
+			expected: []int{9, 6, 3},
+			more:     true,
+		},
+		{
+			desc: "time range",
+			filter: LogFilter{
+				Since: logReaderStartTime.Add(4 * time.Minute),
+				Until: logReaderStartTime.Add(5 * time.Minute),
+			},
+			expected: []int{5, 4},
+		},
+		{
+			desc:     "text",
+			filter:   LogFilter{Text: "WENT WRONG", Since: logReaderStartTime.Add(20 * time.Minute)},
+			expected: []int{27, 24, 21},
+		},
+		{
+			desc:     "no matches",
+			filter:   LogFilter{IP: "10.0.0.1"},
+			expected: []int{},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			entries, more, err := ReadLogEntries(logPath, &tC.filter, tC.offset, 3)
+			assert.NoError(t, err)
+			assert.Equal(t, tC.more, more)
+			assert.Equal(t, tC.expected, entryNumbers(entries))
+		})
+	}
+
+	_, _, err := ReadLogEntries(logPath, &LogFilter{IP: "not an IP"}, 0, 3)
+	assert.ErrorIs(t, err, ErrInvalidIP)
+}
+
+func TestReadLogEntriesNonJSON(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), ErrorLogFilename)
+	assert.NoError(t, os.WriteFile(logPath, []byte(
+		`{"level":"info","time":"2024-01-01T00:00:00Z","message":"first"}`+"\n\n"+
+			"panic: something bad\n"+
+			`{"level":"warn","time":"2024-01-01T00:01:00Z","message":"last"}`), 0644))
+	entries, more, err := ReadLogEntries(logPath, nil, 0, 10)
+	assert.NoError(t, err)
+	assert.False(t, more)
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "last", entries[0].Message)
+		assert.Equal(t, "panic: something bad", entries[1].Message)
+		assert.Empty(t, entries[1].Level)
+		assert.Equal(t, "first", entries[2].Message)
+	}
+}
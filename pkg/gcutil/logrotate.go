@@ -0,0 +1,276 @@
+package gcutil
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rotatedLogTimeLayout is the layout of the timestamp added to the names of rotated log files
+	rotatedLogTimeLayout = "2006-01-02T15-04-05"
+	compressedLogExt     = ".gz"
+)
+
+// RotateOptions configures the rotation of a log file. If both MaxSize and Interval are 0, the file is never rotated
+type RotateOptions struct {
+	// MaxSize is the size in bytes that the log file can grow to before it is rotated, 0 means it isn't rotated by size
+	MaxSize int64
+	// Interval is how often the log file is rotated (aligned to UTC), 0 means it isn't rotated by time
+	Interval time.Duration
+	// MaxBackups is the number of rotated log files to keep, the oldest ones are deleted. 0 means all of them are kept
+	MaxBackups int
+	// Compress compresses rotated log files with gzip
+	Compress bool
+}
+
+// rotatingFile is a log file that is rotated according to its RotateOptions. Rotated files are renamed with a
+// timestamp added to their names (e.g. gochan.log.2024-01-02T15-04-05), and are compressed and pruned in the background
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	options  RotateOptions
+	uid      int
+	gid      int
+	file     *os.File
+	size     int64
+	period   time.Time
+	cleanup  sync.Mutex
+	cleaning sync.WaitGroup
+}
+
+func openRotatingFile(logPath string, options RotateOptions, uid, gid int) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:    logPath,
+		options: options,
+		uid:     uid,
+		gid:     gid,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) currentPeriod(t time.Time) time.Time {
+	if rf.options.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(rf.options.Interval)
+}
+
+// open opens the log file at rf.path, creating it if it doesn't exist. The caller must hold rf.mu unless the file
+// hasn't been returned by openRotatingFile yet
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, logFlags, logFileMode) // skipcq: GSC-G302
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if rf.uid > 0 && rf.gid > 0 {
+		if err = file.Chown(rf.uid, rf.gid); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	rf.file = file
+	rf.size = info.Size()
+	rf.period = rf.currentPeriod(time.Now())
+	if rf.size > 0 {
+		// if the log was last written to in a previous period, it will be rotated on the next write
+		rf.period = rf.currentPeriod(info.ModTime())
+	}
+	return nil
+}
+
+func (rf *rotatingFile) shouldRotate(writeLen int) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.options.MaxSize > 0 && rf.size+int64(writeLen) > rf.options.MaxSize {
+		return true
+	}
+	return rf.options.Interval > 0 && !rf.currentPeriod(time.Now()).Equal(rf.period)
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.shouldRotate(len(p)) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotatedPath returns an unused path for the rotated log file
+func (rf *rotatingFile) rotatedPath() string {
+	base := rf.path + "." + time.Now().UTC().Format(rotatedLogTimeLayout)
+	rotated := base
+	for i := 1; ; i++ {
+		_, err := os.Stat(rotated)
+		_, gzErr := os.Stat(rotated + compressedLogExt)
+		if os.IsNotExist(err) && os.IsNotExist(gzErr) {
+			return rotated
+		}
+		rotated = fmt.Sprintf("%s.%d", base, i)
+	}
+}
+
+// rotate renames the current log file and opens a new one. The caller must hold rf.mu
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+	rotated := rf.rotatedPath()
+	if err := os.Rename(rf.path, rotated); err != nil {
+		// keep writing to the current file
+		if openErr := rf.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.cleaning.Add(1)
+	go rf.cleanupRotated(rotated)
+	return nil
+}
+
+// cleanupRotated compresses the rotated log file if compression is enabled and deletes the oldest rotated files
+// if there are more than MaxBackups
+func (rf *rotatingFile) cleanupRotated(rotated string) {
+	defer rf.cleaning.Done()
+	rf.cleanup.Lock()
+	defer rf.cleanup.Unlock()
+	if rf.options.Compress {
+		if err := compressFile(rotated); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to compress rotated log file:", err)
+		}
+	}
+	if rf.options.MaxBackups > 0 {
+		if err := rf.pruneBackups(); err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to delete old log files:", err)
+		}
+	}
+}
+
+// backups returns the paths of the rotated log files, oldest first
+func (rf *rotatingFile) backups() ([]string, error) {
+	dir := filepath.Dir(rf.path)
+	prefix := filepath.Base(rf.path) + "."
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		timestamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), compressedLogExt)
+		if len(timestamp) < len(rotatedLogTimeLayout) {
+			continue
+		}
+		if _, err = time.Parse(rotatedLogTimeLayout, timestamp[:len(rotatedLogTimeLayout)]); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	slices.SortFunc(backups, func(a, b string) int {
+		return strings.Compare(strings.TrimSuffix(a, compressedLogExt), strings.TrimSuffix(b, compressedLogExt))
+	})
+	return backups, nil
+}
+
+func (rf *rotatingFile) pruneBackups() error {
+	backups, err := rf.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > rf.options.MaxBackups {
+		if err = os.Remove(backups[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// compressFile compresses the file at filePath to filePath.gz and deletes the original
+func compressFile(filePath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	gzPath := filePath + compressedLogExt
+	dst, err := os.OpenFile(gzPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode()) // skipcq: GSC-G302
+	if err != nil {
+		return err
+	}
+	gzWriter := gzip.NewWriter(dst)
+	gzWriter.Name = filepath.Base(filePath)
+	gzWriter.ModTime = info.ModTime()
+	if _, err = io.Copy(gzWriter, src); err == nil {
+		err = gzWriter.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(gzPath)
+		return err
+	}
+	src.Close()
+	return os.Remove(filePath)
+}
+
+// Reopen closes and reopens the log file, e.g. after it was moved by an external tool like logrotate
+func (rf *rotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file != nil {
+		if err := rf.file.Close(); err != nil {
+			return err
+		}
+		rf.file = nil
+	}
+	return rf.open()
+}
+
+// Close closes the log file and waits for any rotated files to finish being compressed
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	var err error
+	if rf.file != nil {
+		err = rf.file.Close()
+		rf.file = nil
+	}
+	rf.mu.Unlock()
+	rf.cleaning.Wait()
+	return err
+}
@@ -0,0 +1,134 @@
+package gcutil
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestRotatingFile(t *testing.T, options RotateOptions) *rotatingFile {
+	t.Helper()
+	rf, err := openRotatingFile(filepath.Join(t.TempDir(), ErrorLogFilename), options, 0, 0)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		rf.Close()
+	})
+	return rf
+}
+
+func writeTestLines(t *testing.T, rf *rotatingFile, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		_, err := rf.Write([]byte(line + "\n"))
+		assert.NoError(t, err)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	rf := openTestRotatingFile(t, RotateOptions{MaxSize: 10})
+	writeTestLines(t, rf, "first", "second", "third")
+	rf.cleaning.Wait()
+
+	backups, err := rf.backups()
+	assert.NoError(t, err)
+	if assert.Len(t, backups, 2) {
+		ba, err := os.ReadFile(backups[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "first\n", string(ba))
+		ba, err = os.ReadFile(backups[1])
+		assert.NoError(t, err)
+		assert.Equal(t, "second\n", string(ba))
+	}
+	ba, err := os.ReadFile(rf.path)
+	assert.NoError(t, err)
+	assert.Equal(t, "third\n", string(ba))
+}
+
+func TestRotateByInterval(t *testing.T) {
+	rf := openTestRotatingFile(t, RotateOptions{Interval: time.Hour})
+	writeTestLines(t, rf, "first")
+	writeTestLines(t, rf, "second")
+	backups, err := rf.backups()
+	assert.NoError(t, err)
+	assert.Empty(t, backups)
+
+	// pretend that the current file was started in a previous hour
+	rf.period = rf.period.Add(-time.Hour)
+	writeTestLines(t, rf, "third")
+	rf.cleaning.Wait()
+	backups, err = rf.backups()
+	assert.NoError(t, err)
+	if assert.Len(t, backups, 1) {
+		ba, err := os.ReadFile(backups[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", string(ba))
+	}
+}
+
+func TestRotateCompressAndPrune(t *testing.T) {
+	rf := openTestRotatingFile(t, RotateOptions{MaxSize: 1, MaxBackups: 2, Compress: true})
+	// files from a previous run and unrelated files in the log directory
+	dir := filepath.Dir(rf.path)
+	for _, name := range []string{
+		ErrorLogFilename + ".2000-01-01T00-00-00.gz",
+		ErrorLogFilename + ".notatimestamp",
+		AccessLogFilename + ".2000-01-01T00-00-00",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, logFileMode))
+	}
+	writeTestLines(t, rf, "first", "second", "third", "fourth")
+	rf.cleaning.Wait()
+
+	backups, err := rf.backups()
+	assert.NoError(t, err)
+	if !assert.Len(t, backups, 2) {
+		t.FailNow()
+	}
+	var contents []string
+	for _, backup := range backups {
+		assert.True(t, strings.HasSuffix(backup, compressedLogExt))
+		file, err := os.Open(backup)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		gzReader, err := gzip.NewReader(file)
+		if assert.NoError(t, err) {
+			ba, err := io.ReadAll(gzReader)
+			assert.NoError(t, err)
+			contents = append(contents, string(ba))
+		}
+		file.Close()
+	}
+	assert.Equal(t, []string{"second\n", "third\n"}, contents)
+	assert.NoFileExists(t, filepath.Join(dir, ErrorLogFilename+".2000-01-01T00-00-00.gz"))
+	assert.FileExists(t, filepath.Join(dir, ErrorLogFilename+".notatimestamp"))
+	assert.FileExists(t, filepath.Join(dir, AccessLogFilename+".2000-01-01T00-00-00"))
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	rf := openTestRotatingFile(t, RotateOptions{})
+	writeTestLines(t, rf, "first")
+	moved := rf.path + ".1"
+	assert.NoError(t, os.Rename(rf.path, moved))
+	assert.NoError(t, rf.Reopen())
+	writeTestLines(t, rf, "second")
+
+	ba, err := os.ReadFile(moved)
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", string(ba))
+	ba, err = os.ReadFile(rf.path)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(ba))
+
+	assert.NoError(t, rf.Close())
+	_, err = rf.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
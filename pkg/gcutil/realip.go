@@ -0,0 +1,311 @@
+package gcutil
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	proxies atomic.Pointer[trustedProxies]
+)
+
+type realIPContextKey struct{}
+
+// trustedProxies contains the IP ranges of the reverse proxies whose forwarding headers are trusted
+type trustedProxies struct {
+	trusted    []netip.Prefix
+	cloudflare []netip.Prefix
+}
+
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (tp *trustedProxies) isTrusted(addr netip.Addr) bool {
+	return prefixesContain(tp.trusted, addr) || prefixesContain(tp.cloudflare, addr)
+}
+
+// clientFromChain walks a chain of forwarded addresses (client first) from right to left and returns the first
+// address that isn't a trusted proxy, since anything to the left of it may have been set by the client. If every
+// address is a trusted proxy, the leftmost one is returned. If an address in the chain is invalid, the address
+// to its right (or peer if it is the rightmost) is returned
+func (tp *trustedProxies) clientFromChain(chain []string, peer netip.Addr) netip.Addr {
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, err := parseForwardedAddr(chain[i])
+		if err != nil {
+			break
+		}
+		client = addr
+		if !tp.isTrusted(addr) {
+			break
+		}
+	}
+	return client
+}
+
+// ParseIPPrefixes parses a list of IP addresses and CIDR ranges (e.g. "10.0.0.0/8"), skipping blank entries.
+// A single IP address is treated as a range containing only that address
+func ParseIPPrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidIP, entry)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSubnet, entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// LoadIPPrefixesFile reads a list of IP addresses and CIDR ranges from the file at filePath, one per line.
+// Blank lines and lines starting with # are ignored
+func LoadIPPrefixesFile(filePath string) ([]netip.Prefix, error) {
+	fi, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(fi)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	prefixes, err := ParseIPPrefixes(entries)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	return prefixes, nil
+}
+
+// SetTrustedProxies sets the IP ranges of the reverse proxies that GetRealIP trusts the X-Forwarded-For, Forwarded,
+// and X-Real-IP headers from, and the Cloudflare ranges that the CF-Connecting-IP header is also trusted from.
+// Forwarding headers are ignored if the request doesn't come from one of these ranges or over a Unix socket
+func SetTrustedProxies(trusted []netip.Prefix, cloudflare []netip.Prefix) {
+	proxies.Store(&trustedProxies{
+		trusted:    trusted,
+		cloudflare: cloudflare,
+	})
+}
+
+// parseForwardedAddr parses an IP address from a forwarding header, which may be quoted or include a port
+// (e.g. "192.168.56.1:1234" or "[2001:db8::1]:1234")
+func parseForwardedAddr(str string) (netip.Addr, error) {
+	str = strings.Trim(strings.TrimSpace(str), `"`)
+	if host, _, err := net.SplitHostPort(str); err == nil {
+		str = host
+	} else {
+		str = strings.TrimSuffix(strings.TrimPrefix(str, "["), "]")
+	}
+	addr, err := netip.ParseAddr(str)
+	if err != nil {
+		return addr, err
+	}
+	return addr.Unmap().WithZone(""), nil
+}
+
+// splitHeaderList splits the comma separated values of all of the given header's fields
+func splitHeaderList(header http.Header, key string) []string {
+	var values []string
+	for _, field := range header.Values(key) {
+		for value := range strings.SplitSeq(field, ",") {
+			values = append(values, strings.TrimSpace(value))
+		}
+	}
+	return values
+}
+
+// forwardedForChain returns the addresses in the for parameters of the Forwarded header (RFC 7239), client first.
+// Elements without a for parameter are returned as empty strings so that they stop the chain from being trusted
+func forwardedForChain(header http.Header) []string {
+	elements := splitHeaderList(header, "Forwarded")
+	chain := make([]string, 0, len(elements))
+	for _, element := range elements {
+		var forAddr string
+		for pair := range strings.SplitSeq(element, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(key, "for") {
+				forAddr = value
+				break
+			}
+		}
+		chain = append(chain, forAddr)
+	}
+	return chain
+}
+
+// forwardedProto returns the proto parameter of the last element of the Forwarded header, which was set by the proxy
+// that gochan received the request from, or an empty string if it isn't set
+func forwardedProto(header http.Header) string {
+	elements := splitHeaderList(header, "Forwarded")
+	if len(elements) == 0 {
+		return ""
+	}
+	for pair := range strings.SplitSeq(elements[len(elements)-1], ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		if strings.EqualFold(key, "proto") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// unixSocketPeer is the remote address that Go's HTTP server gives requests received over a Unix socket
+const unixSocketPeer = "@"
+
+// requestPeer returns the host of the request's remote address, its parsed IP address (which isn't valid if the request
+// came over a Unix socket), the trusted proxies, and whether the peer is trusted to set forwarding headers. Peers
+// connected over a Unix socket are always trusted, since only processes on the same machine (like a reverse proxy)
+// can connect to it
+func requestPeer(request *http.Request) (string, netip.Addr, *trustedProxies, bool) {
+	remoteHost, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		remoteHost = request.RemoteAddr
+	}
+	tp := proxies.Load()
+	if tp == nil {
+		tp = &trustedProxies{}
+	}
+	peer, err := parseForwardedAddr(remoteHost)
+	if err != nil {
+		return remoteHost, peer, tp, request.RemoteAddr == unixSocketPeer
+	}
+	return remoteHost, peer, tp, tp.isTrusted(peer)
+}
+
+// IsForwardedHTTPS returns true if the request came from a trusted proxy (see SetTrustedProxies) that received it over
+// HTTPS, according to the proto parameter of the last element of the Forwarded header and the last value of the
+// X-Forwarded-Proto header. If both are set, they must both be https, since a proxy that only sets one of them may
+// pass the other one on from the client unchanged
+func IsForwardedHTTPS(request *http.Request) bool {
+	_, _, _, trusted := requestPeer(request)
+	if !trusted {
+		return false
+	}
+	var protos []string
+	if proto := forwardedProto(request.Header); proto != "" {
+		protos = append(protos, proto)
+	}
+	if forwardedProtos := splitHeaderList(request.Header, "X-Forwarded-Proto"); len(forwardedProtos) > 0 {
+		protos = append(protos, forwardedProtos[len(forwardedProtos)-1])
+	}
+	if len(protos) == 0 {
+		return false
+	}
+	for _, proto := range protos {
+		if !strings.EqualFold(proto, "https") {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveRealIP returns the IP address of the client that made the request. The request's remote address is used
+// unless it belongs to a trusted proxy (or the request came over a Unix socket), in which case the client's address is
+// taken from the CF-Connecting-IP (if the proxy is a Cloudflare server), Forwarded, X-Forwarded-For, or X-Real-IP
+// header, in that order
+func resolveRealIP(request *http.Request) string {
+	remoteHost, peer, tp, trusted := requestPeer(request)
+	if !trusted {
+		if peer.IsValid() {
+			return peer.String()
+		}
+		return remoteHost
+	}
+	if prefixesContain(tp.cloudflare, peer) {
+		if addr, err := parseForwardedAddr(request.Header.Get("CF-Connecting-IP")); err == nil {
+			return addr.String()
+		}
+	}
+	client := peer
+	if chain := forwardedForChain(request.Header); len(chain) > 0 {
+		client = tp.clientFromChain(chain, peer)
+	} else if chain := splitHeaderList(request.Header, "X-Forwarded-For"); len(chain) > 0 {
+		client = tp.clientFromChain(chain, peer)
+	} else if addr, err := parseForwardedAddr(request.Header.Get("X-Real-IP")); err == nil {
+		client = addr
+	}
+	if client.IsValid() {
+		return client.String()
+	}
+	return remoteHost
+}
+
+// isLocalAddr returns true if the address is a loopback address or in a private range (RFC 1918 or RFC 4193)
+func isLocalAddr(addr netip.Addr) bool {
+	return addr.IsLoopback() || addr.IsPrivate()
+}
+
+// IsLocalRequest returns true if the request came from a loopback or private (RFC 1918 or RFC 4193) address, or over
+// a Unix socket. Both the connection's peer and the client's address (see GetRealIP) must be local, so a request
+// forwarded by a reverse proxy on the same machine is only local if the proxy received it from a local client
+func IsLocalRequest(request *http.Request) bool {
+	_, peer, _, _ := requestPeer(request)
+	if request.RemoteAddr != unixSocketPeer && !isLocalAddr(peer) {
+		return false
+	}
+	clientIP := GetRealIP(request)
+	if clientIP == unixSocketPeer {
+		// the request came over a Unix socket without any forwarding headers
+		return true
+	}
+	client, err := netip.ParseAddr(clientIP)
+	return err == nil && isLocalAddr(client.Unmap())
+}
+
+// WithRealIP resolves the IP address of the client that made the request and returns a shallow copy of the
+// request with the address stored in its context, so that it only needs to be resolved once per request
+func WithRealIP(request *http.Request) *http.Request {
+	if _, ok := request.Context().Value(realIPContextKey{}).(string); ok {
+		return request
+	}
+	return request.WithContext(context.WithValue(request.Context(), realIPContextKey{}, resolveRealIP(request)))
+}
+
+// GetRealIP returns the IP address of the client that made the request, using the GOCHAN_TESTIP environment
+// variable if it is set. Forwarding headers are only used if the request came from a trusted proxy
+// (see SetTrustedProxies), and the right-most untrusted address in a forwarded chain is used, since addresses
+// to the left of it can be spoofed by the client
+func GetRealIP(request *http.Request) string {
+	ip, ok := os.LookupEnv(TestingIPEnvVar)
+	if ok {
+		return ip
+	}
+	if ip, ok = request.Context().Value(realIPContextKey{}).(string); ok {
+		return ip
+	}
+	return resolveRealIP(request)
+}
@@ -0,0 +1,306 @@
+package gcutil
+
+import (
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type realIPTestCase struct {
+	desc       string
+	remoteAddr string
+	headers    map[string]string
+	expectedIP string
+}
+
+func TestGetRealIP(t *testing.T) {
+	trusted, err := ParseIPPrefixes([]string{"10.0.0.0/8", "::1"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cloudflare, err := ParseIPPrefixes([]string{"172.64.0.0/13"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	SetTrustedProxies(trusted, cloudflare)
+	t.Cleanup(func() {
+		SetTrustedProxies(nil, nil)
+	})
+
+	testCases := []realIPTestCase{
+		{
+			desc:       "no proxy",
+			remoteAddr: "192.168.56.1:1234",
+			expectedIP: "192.168.56.1",
+		},
+		{
+			desc:       "remote address without port",
+			remoteAddr: "192.168.56.1",
+			expectedIP: "192.168.56.1",
+		},
+		{
+			desc:       "headers from untrusted client are ignored",
+			remoteAddr: "192.168.56.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For":  "192.168.56.2",
+				"Forwarded":        "for=192.168.56.2",
+				"X-Real-IP":        "192.168.56.2",
+				"CF-Connecting-IP": "192.168.56.2",
+			},
+			expectedIP: "192.168.56.1",
+		},
+		{
+			desc:       "X-Forwarded-For from trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.56.2"},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "spoofed X-Forwarded-For chain uses right-most untrusted address",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 192.168.56.2, 10.0.0.2"},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "X-Forwarded-For chain of trusted proxies uses left-most address",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			expectedIP: "10.0.0.3",
+		},
+		{
+			desc:       "invalid X-Forwarded-For entry stops the chain",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.56.2, garbage, 10.0.0.2"},
+			expectedIP: "10.0.0.2",
+		},
+		{
+			desc:       "Forwarded header takes precedence",
+			remoteAddr: "[::1]:1234",
+			headers: map[string]string{
+				"Forwarded":       `for=1.2.3.4, for="[2001:db8::17]:4711";proto=https;by=10.0.0.2`,
+				"X-Forwarded-For": "192.168.56.2",
+			},
+			expectedIP: "2001:db8::17",
+		},
+		{
+			desc:       "obfuscated Forwarded address stops the chain",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"Forwarded": "for=192.168.56.2, for=_hidden"},
+			expectedIP: "10.0.0.1",
+		},
+		{
+			desc:       "X-Real-IP from trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-IP": "192.168.56.2"},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "CF-Connecting-IP from Cloudflare",
+			remoteAddr: "172.64.1.1:1234",
+			headers: map[string]string{
+				"CF-Connecting-IP": "192.168.56.3",
+				"X-Forwarded-For":  "192.168.56.2",
+			},
+			expectedIP: "192.168.56.3",
+		},
+		{
+			desc:       "CF-Connecting-IP from non-Cloudflare trusted proxy is ignored",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"CF-Connecting-IP": "192.168.56.3",
+				"X-Forwarded-For":  "192.168.56.2",
+			},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "IPv4-mapped IPv6 address",
+			remoteAddr: "[::ffff:192.168.56.1]:1234",
+			expectedIP: "192.168.56.1",
+		},
+		{
+			desc:       "X-Forwarded-For from proxy connected over Unix socket",
+			remoteAddr: "@",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.56.2"},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "X-Real-IP from proxy connected over Unix socket",
+			remoteAddr: "@",
+			headers:    map[string]string{"X-Real-IP": "192.168.56.2"},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "X-Forwarded-For chain from Unix socket uses right-most untrusted address",
+			remoteAddr: "@",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 192.168.56.2, 10.0.0.2"},
+			expectedIP: "192.168.56.2",
+		},
+		{
+			desc:       "Unix socket without forwarding headers",
+			remoteAddr: "@",
+			expectedIP: "@",
+		},
+		{
+			desc:       "invalid remote address isn't trusted",
+			remoteAddr: "garbage",
+			headers:    map[string]string{"X-Forwarded-For": "192.168.56.2"},
+			expectedIP: "garbage",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := &http.Request{
+				RemoteAddr: tC.remoteAddr,
+				Header:     make(http.Header),
+			}
+			for k, v := range tC.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tC.expectedIP, GetRealIP(req))
+		})
+	}
+
+	req := &http.Request{RemoteAddr: "10.0.0.1:1234", Header: make(http.Header)}
+	req.Header.Set("X-Forwarded-For", "192.168.56.2")
+	req = WithRealIP(req)
+	// the IP address stored in the request context is used after it has been resolved
+	req.Header.Set("X-Forwarded-For", "192.168.56.4")
+	assert.Equal(t, "192.168.56.2", GetRealIP(req))
+
+	t.Setenv(TestingIPEnvVar, "192.168.56.5")
+	assert.Equal(t, "192.168.56.5", GetRealIP(req))
+}
+
+func TestParseIPPrefixes(t *testing.T) {
+	prefixes, err := ParseIPPrefixes([]string{"192.168.56.1", " 10.1.2.3/8 ", "", "2001:db8::/32"})
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("192.168.56.1/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, prefixes)
+
+	_, err = ParseIPPrefixes([]string{"not an IP"})
+	assert.ErrorIs(t, err, ErrInvalidIP)
+	_, err = ParseIPPrefixes([]string{"192.168.56.0/33"})
+	assert.ErrorIs(t, err, ErrInvalidSubnet)
+}
+
+func TestLoadIPPrefixesFile(t *testing.T) {
+	ipsFile := filepath.Join(t.TempDir(), "ips.txt")
+	assert.NoError(t, os.WriteFile(ipsFile, []byte("# comment\n173.245.48.0/20\n\n2400:cb00::/32\n"), 0644))
+	prefixes, err := LoadIPPrefixesFile(ipsFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("173.245.48.0/20"),
+		netip.MustParsePrefix("2400:cb00::/32"),
+	}, prefixes)
+
+	// the Cloudflare IP ranges file included with gochan must be valid
+	prefixes, err = LoadIPPrefixesFile("../../examples/configs/cloudflare-ips.txt")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, prefixes)
+}
+
+func TestIsForwardedHTTPS(t *testing.T) {
+	trusted, err := ParseIPPrefixes([]string{"10.0.0.0/8"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	SetTrustedProxies(trusted, nil)
+	t.Cleanup(func() {
+		SetTrustedProxies(nil, nil)
+	})
+
+	testCases := []struct {
+		desc       string
+		remoteAddr string
+		headers    map[string]string
+		expected   bool
+	}{
+		{desc: "no headers", remoteAddr: "10.0.0.1:1234"},
+		{desc: "X-Forwarded-Proto from trusted proxy", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https"}, expected: true},
+		{desc: "X-Forwarded-Proto from untrusted address", remoteAddr: "192.168.56.1:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https"}},
+		{desc: "Forwarded proto from trusted proxy", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": `for=192.168.56.1;proto="HTTPS"`}, expected: true},
+		{desc: "Forwarded proto from untrusted address", remoteAddr: "192.168.56.1:1234",
+			headers: map[string]string{"Forwarded": "for=192.168.56.1;proto=https"}},
+		{desc: "Forwarded proto set by client", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=192.168.56.1;proto=https, for=192.168.56.2;proto=http"}},
+		{desc: "Forwarded proto disagrees with X-Forwarded-Proto", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=192.168.56.1;proto=http", "X-Forwarded-Proto": "https"}},
+		{desc: "Forwarded proto passed on from client by proxy that sets X-Forwarded-Proto", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=192.168.56.1;proto=https", "X-Forwarded-Proto": "http"}},
+		{desc: "Forwarded proto agrees with X-Forwarded-Proto", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"Forwarded": "for=192.168.56.1;proto=https", "X-Forwarded-Proto": "https"}, expected: true},
+		{desc: "X-Forwarded-Proto from proxy connected over Unix socket", remoteAddr: "@",
+			headers: map[string]string{"X-Forwarded-Proto": "https"}, expected: true},
+		{desc: "last X-Forwarded-Proto value", remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "http, https"}, expected: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tc.expected, IsForwardedHTTPS(req))
+		})
+	}
+}
+
+func TestIsLocalRequest(t *testing.T) {
+	trusted, err := ParseIPPrefixes([]string{"127.0.0.1", "203.0.113.1"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	SetTrustedProxies(trusted, nil)
+	t.Cleanup(func() {
+		SetTrustedProxies(nil, nil)
+	})
+
+	testCases := []struct {
+		desc       string
+		remoteAddr string
+		headers    map[string]string
+		expected   bool
+	}{
+		{desc: "loopback", remoteAddr: "127.0.0.1:1234", expected: true},
+		{desc: "IPv6 loopback", remoteAddr: "[::1]:1234", expected: true},
+		{desc: "private address", remoteAddr: "192.168.56.1:1234", expected: true},
+		{desc: "public address", remoteAddr: "198.51.100.1:1234"},
+		{desc: "Unix socket", remoteAddr: "@", expected: true},
+		{desc: "public client forwarded by local proxy", remoteAddr: "127.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1"}},
+		{desc: "public client forwarded over Unix socket", remoteAddr: "@",
+			headers: map[string]string{"X-Real-IP": "198.51.100.1"}},
+		{desc: "local client forwarded over Unix socket", remoteAddr: "@",
+			headers: map[string]string{"X-Real-IP": "127.0.0.1"}, expected: true},
+		{desc: "spoofed local address from public proxy", remoteAddr: "203.0.113.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "127.0.0.1"}},
+		{desc: "spoofed local address from untrusted client", remoteAddr: "198.51.100.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "127.0.0.1"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req := &http.Request{
+				RemoteAddr: tc.remoteAddr,
+				Header:     make(http.Header),
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tc.expected, IsLocalRequest(req))
+		})
+	}
+}
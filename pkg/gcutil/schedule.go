@@ -0,0 +1,183 @@
+package gcutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ScheduleDisabled is the schedule of a job that never runs
+	ScheduleDisabled = "off"
+
+	// maxScheduleYears is how far ahead Next looks for a matching time, so that a schedule that never matches (e.g. the
+	// 30th of February) doesn't loop forever
+	maxScheduleYears = 5
+)
+
+var (
+	ErrInvalidSchedule = errors.New("invalid schedule")
+
+	scheduleDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// Schedule determines when a recurring job runs
+type Schedule interface {
+	// Next returns the first time after t that the job should run, or the zero time if it never runs
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a cron expression with five fields (minute, hour, day of month, month, and day of week), a
+// descriptor (@yearly, @monthly, @weekly, @daily, or @hourly), @every followed by a duration of whole minutes (e.g.
+// "@every 15m"), or "off". Fields can be *, a number, a range (1-5), a list (1,3,5), or a step (*/15 or 0-30/10), and
+// Sunday is 0 or 7. Times are in the server's time zone
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == ScheduleDisabled {
+		return disabledSchedule{}, nil
+	}
+	if interval, ok := strings.CutPrefix(spec, "@every "); ok {
+		duration, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || duration < time.Minute || duration%time.Minute != 0 {
+			return nil, fmt.Errorf("%w %q: @every requires a duration of whole minutes, e.g. 5m or 1h30m", ErrInvalidSchedule, spec)
+		}
+		return intervalSchedule(duration), nil
+	}
+	if expr, ok := scheduleDescriptors[spec]; ok {
+		spec = expr
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w %q: expected 5 fields (minute, hour, day of month, month, day of week) or a descriptor like @daily",
+			ErrInvalidSchedule, spec)
+	}
+	var cs cronSchedule
+	var err error
+	ranges := []struct {
+		field    *uint64
+		name     string
+		min, max int
+	}{
+		{&cs.minutes, "minute", 0, 59},
+		{&cs.hours, "hour", 0, 23},
+		{&cs.days, "day of month", 1, 31},
+		{&cs.months, "month", 1, 12},
+		{&cs.weekdays, "day of week", 0, 7},
+	}
+	for f, r := range ranges {
+		if *r.field, err = parseScheduleField(fields[f], r.min, r.max); err != nil {
+			return nil, fmt.Errorf("%w %q: %s field: %w", ErrInvalidSchedule, spec, r.name, err)
+		}
+	}
+	if cs.weekdays&(1<<7) != 0 {
+		// 7 and 0 are both Sunday
+		cs.weekdays = cs.weekdays&^(1<<7) | 1
+	}
+	// if both day fields are restricted, a day matches if either of them match, as in cron
+	cs.anyDay = fields[2] == "*" || fields[4] == "*"
+	return &cs, nil
+}
+
+// parseScheduleField returns a bit set of the values matched by a field of a cron expression
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		rangeStr, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		var err error
+		if hasStep {
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		start, end := min, max
+		if rangeStr != "*" {
+			startStr, endStr, isRange := strings.Cut(rangeStr, "-")
+			if start, err = strconv.Atoi(startStr); err != nil {
+				return 0, fmt.Errorf("invalid value %q", startStr)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endStr); err != nil {
+					return 0, fmt.Errorf("invalid value %q", endStr)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q is out of range (%d-%d)", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+type disabledSchedule struct{}
+
+func (disabledSchedule) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+// intervalSchedule runs at a fixed interval of whole minutes, starting from the minute after it was loaded
+type intervalSchedule time.Duration
+
+func (is intervalSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Minute).Add(time.Duration(is))
+}
+
+// cronSchedule is a parsed cron expression, with each field stored as a bit set of the values that it matches
+type cronSchedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	anyDay   bool
+}
+
+func (cs *cronSchedule) dayMatches(t time.Time) bool {
+	dayMatches := cs.days&(1<<t.Day()) != 0
+	weekdayMatches := cs.weekdays&(1<<int(t.Weekday())) != 0
+	if cs.anyDay {
+		return dayMatches && weekdayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+func (cs *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxScheduleYears, 0, 0)
+	for t.Before(limit) {
+		if cs.months&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.hours&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.minutes&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
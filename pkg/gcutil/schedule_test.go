@@ -0,0 +1,54 @@
+package gcutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	start := time.Date(2024, time.January, 10, 10, 30, 45, 0, time.UTC)
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 10, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC)},
+		{"0 4 * * *", time.Date(2024, time.January, 11, 4, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, time.January, 11, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 12 1-5 * *", time.Date(2024, time.February, 1, 12, 30, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2024, time.February, 29, 12, 0, 0, 0, time.UTC)},
+		{"0,45 9-17/4 * * 1-5", time.Date(2024, time.January, 10, 13, 0, 0, 0, time.UTC)},
+		// both day fields are restricted, so either one matching is enough
+		{"0 0 1 * 5", time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		{"@every 5m", time.Date(2024, time.January, 10, 10, 35, 0, 0, time.UTC)},
+		{"@every 1h30m", time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+		{ScheduleDisabled, time.Time{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tC.spec)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tC.expected, schedule.Next(start))
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8",
+		"*/0 * * * *", "5-1 * * * *", "a * * * *", "@fortnightly", "@every 30s", "@every 90s", "@every soon",
+	} {
+		_, err := ParseSchedule(spec)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, "spec: %q", spec)
+	}
+}
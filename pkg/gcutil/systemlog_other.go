@@ -0,0 +1,15 @@
+//go:build !unix
+
+package gcutil
+
+import (
+	"errors"
+	"io"
+
+	"github.com/rs/zerolog"
+)
+
+// newSystemLogWriter returns an error, since syslog and journald are only supported on Unix-like systems
+func newSystemLogWriter(_ string) (zerolog.LevelWriter, io.Closer, error) {
+	return nil, nil, errors.New("system logging is only supported on Unix-like systems")
+}
@@ -0,0 +1,128 @@
+//go:build unix
+
+package gcutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	journaldSocket      = "/run/systemd/journal/socket"
+	systemLogIdentifier = "gochan"
+)
+
+// newSystemLogWriter returns a writer that sends log events to the system logger, either "syslog" or "journald"
+func newSystemLogWriter(systemLogger string) (zerolog.LevelWriter, io.Closer, error) {
+	switch systemLogger {
+	case SystemLoggerSyslog:
+		writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, systemLogIdentifier)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zerolog.SyslogLevelWriter(writer), writer, nil
+	case SystemLoggerJournald:
+		conn, err := net.Dial("unixgram", journaldSocket)
+		if err != nil {
+			return nil, nil, err
+		}
+		jw := &journaldWriter{conn: conn}
+		return jw, conn, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrInvalidSystemLogger, systemLogger)
+	}
+}
+
+// journaldWriter sends zerolog events to journald using its native protocol, with the event's fields as journal fields
+type journaldWriter struct {
+	conn net.Conn
+}
+
+func (jw *journaldWriter) Write(p []byte) (int, error) {
+	return jw.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (jw *journaldWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if _, err := jw.conn.Write(journaldMessage(level, p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// journaldPriority returns the syslog priority of a zerolog level
+func journaldPriority(level zerolog.Level) syslog.Priority {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return syslog.LOG_DEBUG
+	case zerolog.WarnLevel:
+		return syslog.LOG_WARNING
+	case zerolog.ErrorLevel:
+		return syslog.LOG_ERR
+	case zerolog.FatalLevel:
+		return syslog.LOG_CRIT
+	case zerolog.PanicLevel:
+		return syslog.LOG_EMERG
+	default:
+		return syslog.LOG_INFO
+	}
+}
+
+// journaldFieldName converts a zerolog field name to a valid journal field name, which can only contain uppercase
+// letters, digits, and underscores, and can't start with an underscore
+func journaldFieldName(key string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteByte('_')
+		}
+	}
+	return "GOCHAN_" + strings.TrimLeft(builder.String(), "_")
+}
+
+func writeJournaldField(buf *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(key + "=" + value + "\n")
+		return
+	}
+	// values containing newlines are written with their length instead of a separator
+	buf.WriteString(key + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// journaldMessage builds a journald native protocol datagram from a zerolog JSON event
+func journaldMessage(level zerolog.Level, p []byte) []byte {
+	var buf bytes.Buffer
+	fields := map[string]any{}
+	message := strings.TrimSpace(string(p))
+	if err := json.Unmarshal(p, &fields); err == nil {
+		if msg, ok := fields[zerolog.MessageFieldName].(string); ok {
+			message = msg
+		}
+		delete(fields, zerolog.MessageFieldName)
+		delete(fields, zerolog.LevelFieldName)
+		delete(fields, zerolog.TimestampFieldName)
+	}
+	writeJournaldField(&buf, "MESSAGE", message)
+	writeJournaldField(&buf, "PRIORITY", fmt.Sprint(int(journaldPriority(level))))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", systemLogIdentifier)
+	for key, value := range fields {
+		str, ok := value.(string)
+		if !ok {
+			ba, _ := json.Marshal(value)
+			str = string(ba)
+		}
+		writeJournaldField(&buf, journaldFieldName(key), str)
+	}
+	return buf.Bytes()
+}
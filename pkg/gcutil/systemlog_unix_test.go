@@ -0,0 +1,33 @@
+//go:build unix
+
+package gcutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournaldMessage(t *testing.T) {
+	msg := journaldMessage(zerolog.ErrorLevel,
+		[]byte(`{"level":"error","time":"2024-01-01T00:00:00Z","IP":"192.168.56.1","postID":5,"message":"Something went wrong"}`+"\n"))
+	lines := bytes.Split(msg, []byte("\n"))
+	assert.Contains(t, lines, []byte("MESSAGE=Something went wrong"))
+	assert.Contains(t, lines, []byte("PRIORITY=3"))
+	assert.Contains(t, lines, []byte("SYSLOG_IDENTIFIER=gochan"))
+	assert.Contains(t, lines, []byte("GOCHAN_IP=192.168.56.1"))
+	assert.Contains(t, lines, []byte("GOCHAN_POSTID=5"))
+	assert.NotContains(t, string(msg), "2024-01-01")
+
+	// multi-line values are written with their length
+	msg = journaldMessage(zerolog.InfoLevel, []byte(`{"message":"line 1\nline 2"}`))
+	var expected bytes.Buffer
+	expected.WriteString("MESSAGE\n")
+	binary.Write(&expected, binary.LittleEndian, uint64(len("line 1\nline 2")))
+	expected.WriteString("line 1\nline 2\n")
+	assert.True(t, bytes.HasPrefix(msg, expected.Bytes()))
+	assert.Equal(t, "GOCHAN_BOARD_DIR", journaldFieldName("_board.dir"))
+}
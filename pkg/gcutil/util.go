@@ -8,8 +8,6 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
-	"net"
-	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -105,27 +103,6 @@ func GetFormattedFilesize(size float64) string {
 	return fmt.Sprintf("%0.2fGB", size/1024.0/1024.0/1024.0)
 }
 
-// GetRealIP checks the GOCHAN_TESTIP environment variable as well as HTTP_CF_CONNCTING_IP
-// and X-Forwarded-For HTTP headers to get a potentially obfuscated IP address, before
-// getting the request's reported remote address
-func GetRealIP(request *http.Request) string {
-	ip, ok := os.LookupEnv(TestingIPEnvVar)
-	if ok {
-		return ip
-	}
-	if ip = request.Header.Get("HTTP_CF_CONNECTING_IP"); ip != "" {
-		return ip
-	}
-	if ip = request.Header.Get("X-Forwarded-For"); ip != "" {
-		return ip
-	}
-	remoteHost, _, err := net.SplitHostPort(request.RemoteAddr)
-	if err != nil {
-		return request.RemoteAddr
-	}
-	return remoteHost
-}
-
 // HackyStringToInt parses a string to an int, or 0 if error
 func HackyStringToInt(text string) int {
 	value, _ := strconv.Atoi(text)
@@ -0,0 +1,281 @@
+// Package health checks whether gochan's dependencies (the database, DocumentRoot, templates, and external tools)
+// are usable. The checks are used by the /healthz and /readyz endpoints and the doctor command
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gctemplates"
+)
+
+const (
+	// DefaultTimeout is the maximum amount of time that the checks in a report can take
+	DefaultTimeout = 5 * time.Second
+)
+
+// Status is the result of a check or of a report
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// StatusWarning means that gochan can serve requests, but some features may not work
+	StatusWarning Status = "warning"
+	// StatusError means that gochan can't serve requests correctly
+	StatusError Status = "error"
+)
+
+func (s Status) severity() int {
+	switch s {
+	case StatusWarning:
+		return 1
+	case StatusError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Result is the result of a single check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	// Suggestion is a suggested fix if the check didn't pass
+	Suggestion string  `json:"suggestion,omitempty"`
+	DurationMS float64 `json:"durationMs"`
+}
+
+// Report is the combined result of a set of checks. Its status is the most severe status of its checks
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// OK returns true if none of the checks failed with StatusError
+func (r *Report) OK() bool {
+	return r.Status != StatusError
+}
+
+// Check is a named check that returns its result
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Result
+}
+
+var (
+	// LivenessChecks are the checks that don't depend on external services, used by /healthz. They are run often by
+	// container orchestrators, so they shouldn't write files or load templates
+	LivenessChecks = []Check{
+		{Name: "documentRoot", Run: checkDocumentRoot},
+	}
+	// ReadinessChecks are the checks used by /readyz to determine if gochan and its dependencies are ready to serve
+	// requests
+	ReadinessChecks = []Check{
+		{Name: "database", Run: checkDatabase},
+		{Name: "documentRoot", Run: checkDocumentRoot},
+		{Name: "templates", Run: checkTemplates},
+		{Name: "tools", Run: checkTools},
+	}
+	// DoctorChecks are all of the checks, including ones that are too slow or intrusive to run on every request to
+	// /readyz, used by the doctor command
+	DoctorChecks = []Check{
+		{Name: "database", Run: checkDatabase},
+		{Name: "documentRoot", Run: checkDocumentRoot},
+		{Name: "documentRootWritable", Run: checkDocumentRootWritable},
+		{Name: "templates", Run: checkTemplates},
+		{Name: "tools", Run: checkTools},
+	}
+)
+
+// RunChecks runs the given checks in order and returns a report with their results
+func RunChecks(ctx context.Context, checks []Check) *Report {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	report := &Report{Status: StatusOK, Checks: make([]Result, 0, len(checks))}
+	for _, check := range checks {
+		start := time.Now()
+		result := check.Run(ctx)
+		result.Name = check.Name
+		result.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+		if result.Status.severity() > report.Status.severity() {
+			report.Status = result.Status
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+func checkDatabase(ctx context.Context) Result {
+	sqlConfig := config.GetSQLConfig()
+	if err := gcsql.Ping(ctx); err != nil {
+		return Result{
+			Status:  StatusError,
+			Message: "Unable to connect to the database: " + err.Error(),
+			Suggestion: fmt.Sprintf("Make sure that the %s server at %q is running and that DBname, DBusername, and DBpassword are correct",
+				sqlConfig.DBtype, sqlConfig.DBhost),
+		}
+	}
+	version, flag, err := gcsql.GetCompleteDatabaseVersion()
+	if err != nil {
+		return Result{
+			Status:     StatusError,
+			Message:    "Unable to get the database version: " + err.Error(),
+			Suggestion: "Make sure that the database user has permission to read the gochan tables",
+		}
+	}
+	switch flag {
+	case gcsql.DBUpToDate:
+		return Result{Status: StatusOK, Message: fmt.Sprintf("Connected to %s database (version %d)", sqlConfig.DBtype, version)}
+	case gcsql.DBClean:
+		return Result{
+			Status:     StatusError,
+			Message:    "The database hasn't been initialized",
+			Suggestion: "Start gochan to create the tables, or check that DBprefix is correct",
+		}
+	case gcsql.DBModernButAhead:
+		return Result{
+			Status:     StatusError,
+			Message:    fmt.Sprintf("The database version (%d) is newer than the version this build of gochan supports (%d)", version, gcsql.DatabaseVersion),
+			Suggestion: "Upgrade gochan to the version that last updated the database",
+		}
+	case gcsql.DBCorrupted:
+		return Result{
+			Status:     StatusError,
+			Message:    "The database version table is corrupted",
+			Suggestion: "Restore the database from a backup",
+		}
+	default:
+		return Result{
+			Status:     StatusError,
+			Message:    fmt.Sprintf("The database version (%d) is older than the current version (%d)", version, gcsql.DatabaseVersion),
+			Suggestion: "Start gochan to update the database",
+		}
+	}
+}
+
+func checkDocumentRoot(_ context.Context) Result {
+	documentRoot := config.GetSystemCriticalConfig().DocumentRoot
+	fi, err := os.Stat(documentRoot)
+	if err == nil && !fi.IsDir() {
+		err = errors.New("not a directory")
+	}
+	if err != nil {
+		return Result{
+			Status:     StatusError,
+			Message:    fmt.Sprintf("Unable to use DocumentRoot %q: %s", documentRoot, err),
+			Suggestion: "Set DocumentRoot in gochan.json to the directory that gochan's HTML files are served from",
+		}
+	}
+	return Result{Status: StatusOK, Message: fmt.Sprintf("DocumentRoot %q exists", documentRoot)}
+}
+
+// checkDocumentRootWritable makes sure that pages and uploads can be written by creating and deleting a test file
+func checkDocumentRootWritable(_ context.Context) Result {
+	documentRoot := config.GetSystemCriticalConfig().DocumentRoot
+	testFile, err := os.CreateTemp(documentRoot, ".gochan-health-*")
+	if err != nil {
+		return Result{
+			Status:     StatusError,
+			Message:    fmt.Sprintf("DocumentRoot %q is not writable: %s", documentRoot, err),
+			Suggestion: "Give the user that gochan runs as write permission for DocumentRoot and its subdirectories",
+		}
+	}
+	testFile.Close()
+	if err = os.Remove(testFile.Name()); err != nil {
+		return Result{
+			Status:     StatusWarning,
+			Message:    fmt.Sprintf("Unable to delete test file %q: %s", testFile.Name(), err),
+			Suggestion: "Give the user that gochan runs as permission to delete files in DocumentRoot",
+		}
+	}
+	return Result{Status: StatusOK, Message: fmt.Sprintf("DocumentRoot %q is writable", documentRoot)}
+}
+
+func checkTemplates(_ context.Context) Result {
+	if err := gctemplates.CheckTemplates(); err != nil {
+		return Result{
+			Status:     StatusError,
+			Message:    "Unable to load templates: " + strings.ReplaceAll(err.Error(), "\n", "; "),
+			Suggestion: "Make sure that TemplateDir is set to gochan's templates directory, and fix or remove any broken template overrides",
+		}
+	}
+	return Result{Status: StatusOK, Message: "All templates loaded"}
+}
+
+// anyBoardConfig returns true if matches returns true for the global configuration or any board's configuration
+func anyBoardConfig(matches func(boardConfig *config.BoardConfig) bool) bool {
+	if matches(config.GetBoardConfig("")) {
+		return true
+	}
+	for _, board := range gcsql.AllBoards {
+		if matches(config.GetBoardConfig(board.Dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolPath returns the configured path of a tool, or its name if the path isn't set so that the system path is used
+func toolPath(configuredPath string, name string) string {
+	if configuredPath == "" {
+		return name
+	}
+	return configuredPath
+}
+
+func checkTools(_ context.Context) Result {
+	var missing []string
+	var suggestions []string
+	status := StatusOK
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			missing = append(missing, tool)
+			status = StatusWarning
+		}
+	}
+	if len(missing) > 0 {
+		suggestions = append(suggestions, "Install ffmpeg (which includes ffprobe) to support video and audio uploads")
+	}
+	systemCritical := config.GetSystemCriticalConfig()
+	if anyBoardConfig(func(boardConfig *config.BoardConfig) bool { return boardConfig.AllowPDFUploads }) {
+		pdftoppmPath := toolPath(systemCritical.PdftoppmPath, "pdftoppm")
+		if _, err := exec.LookPath(pdftoppmPath); err != nil {
+			missing = append(missing, pdftoppmPath)
+			if status == StatusOK {
+				status = StatusWarning
+			}
+			suggestions = append(suggestions,
+				"Install pdftoppm (included with poppler-utils) or set PdftoppmPath to create thumbnails of uploaded PDFs")
+		}
+	}
+	if anyBoardConfig(func(boardConfig *config.BoardConfig) bool {
+		return boardConfig.StripImageMetadata == "exif" || boardConfig.StripImageMetadata == "all"
+	}) {
+		exiftoolPath := toolPath(systemCritical.ExiftoolPath, "exiftool")
+		if _, err := exec.LookPath(exiftoolPath); err != nil {
+			missing = append(missing, exiftoolPath)
+			status = StatusError
+			suggestions = append(suggestions,
+				"Install exiftool or set ExiftoolPath, since StripImageMetadata is set to remove metadata from uploaded images")
+		}
+	}
+	if len(missing) > 0 {
+		return Result{
+			Status:     status,
+			Message:    "Missing tools: " + strings.Join(missing, ", "),
+			Suggestion: strings.Join(suggestions, ". "),
+		}
+	}
+	return Result{Status: StatusOK, Message: "All required tools were found"}
+}
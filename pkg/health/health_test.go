@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func setDocumentRoot(t *testing.T, documentRoot string) {
+	t.Helper()
+	systemCritical := *config.GetSystemCriticalConfig()
+	systemCritical.DocumentRoot = documentRoot
+	config.SetSystemCriticalConfig(&systemCritical)
+}
+
+func TestRunChecks(t *testing.T) {
+	checkWithStatus := func(status Status) func(context.Context) Result {
+		return func(ctx context.Context) Result {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline, "checks should have a timeout")
+			return Result{Status: status, Message: string(status)}
+		}
+	}
+	report := RunChecks(context.Background(), []Check{
+		{Name: "first", Run: checkWithStatus(StatusOK)},
+		{Name: "second", Run: checkWithStatus(StatusWarning)},
+	})
+	assert.Equal(t, StatusWarning, report.Status)
+	assert.True(t, report.OK())
+	if assert.Len(t, report.Checks, 2) {
+		assert.Equal(t, "first", report.Checks[0].Name)
+		assert.Equal(t, StatusOK, report.Checks[0].Status)
+		assert.Equal(t, "second", report.Checks[1].Name)
+	}
+
+	report = RunChecks(context.Background(), []Check{
+		{Name: "first", Run: checkWithStatus(StatusError)},
+		{Name: "second", Run: checkWithStatus(StatusWarning)},
+	})
+	assert.Equal(t, StatusError, report.Status)
+	assert.False(t, report.OK())
+
+	report = RunChecks(context.Background(), nil)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.NotNil(t, report.Checks, "checks should be an empty array instead of null in JSON")
+}
+
+func TestCheckDocumentRoot(t *testing.T) {
+	config.InitTestConfig()
+	documentRoot := t.TempDir()
+	setDocumentRoot(t, documentRoot)
+	result := checkDocumentRoot(context.Background())
+	assert.Equal(t, StatusOK, result.Status, result.Message)
+	result = checkDocumentRootWritable(context.Background())
+	assert.Equal(t, StatusOK, result.Status, result.Message)
+	entries, err := os.ReadDir(documentRoot)
+	assert.NoError(t, err)
+	assert.Empty(t, entries, "the test file should be deleted")
+
+	setDocumentRoot(t, filepath.Join(documentRoot, "missing"))
+	result = checkDocumentRoot(context.Background())
+	assert.Equal(t, StatusError, result.Status)
+	assert.NotEmpty(t, result.Suggestion)
+	result = checkDocumentRootWritable(context.Background())
+	assert.Equal(t, StatusError, result.Status)
+
+	notDir := filepath.Join(documentRoot, "file.txt")
+	assert.NoError(t, os.WriteFile(notDir, nil, 0644))
+	setDocumentRoot(t, notDir)
+	result = checkDocumentRoot(context.Background())
+	assert.Equal(t, StatusError, result.Status)
+	assert.Contains(t, result.Message, "not a directory")
+}
+
+func TestCheckDatabaseNotConnected(t *testing.T) {
+	config.InitTestConfig()
+	result := checkDatabase(context.Background())
+	assert.Equal(t, StatusError, result.Status)
+	assert.Contains(t, result.Message, gcsql.ErrNotConnected.Error())
+	assert.NotEmpty(t, result.Suggestion)
+}
+
+func TestCheckToolsExiftool(t *testing.T) {
+	config.InitTestConfig()
+	systemCritical := *config.GetSystemCriticalConfig()
+	systemCritical.ExiftoolPath = filepath.Join(t.TempDir(), "exiftool")
+	config.SetSystemCriticalConfig(&systemCritical)
+
+	boardConfig := *config.GetBoardConfig("")
+	boardConfig.StripImageMetadata = "exif"
+	assert.NoError(t, config.SetBoardConfig("", &boardConfig))
+	result := checkTools(context.Background())
+	assert.Equal(t, StatusError, result.Status)
+	assert.Contains(t, result.Message, systemCritical.ExiftoolPath)
+	assert.Contains(t, result.Suggestion, "exiftool")
+
+	boardConfig.StripImageMetadata = ""
+	assert.NoError(t, config.SetBoardConfig("", &boardConfig))
+	result = checkTools(context.Background())
+	assert.NotEqual(t, StatusError, result.Status, "exiftool isn't required if image metadata isn't stripped")
+	assert.NotContains(t, result.Message, "exiftool")
+}
+
+func TestCheckToolsPdftoppm(t *testing.T) {
+	config.InitTestConfig()
+	systemCritical := *config.GetSystemCriticalConfig()
+	systemCritical.PdftoppmPath = filepath.Join(t.TempDir(), "pdftoppm")
+	config.SetSystemCriticalConfig(&systemCritical)
+
+	boardConfig := *config.GetBoardConfig("")
+	boardConfig.AllowPDFUploads = true
+	assert.NoError(t, config.SetBoardConfig("", &boardConfig))
+	result := checkTools(context.Background())
+	assert.Equal(t, StatusWarning, result.Status, "PDFs can still be uploaded with a generic thumbnail")
+	assert.Contains(t, result.Message, systemCritical.PdftoppmPath)
+	assert.Contains(t, result.Suggestion, "PdftoppmPath")
+
+	boardConfig.AllowPDFUploads = false
+	assert.NoError(t, config.SetBoardConfig("", &boardConfig))
+	result = checkTools(context.Background())
+	assert.NotContains(t, result.Message, "pdftoppm", "pdftoppm isn't required if PDF uploads aren't allowed")
+}
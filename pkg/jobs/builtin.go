@@ -0,0 +1,154 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/backup"
+	"github.com/gochan-org/gochan/pkg/building"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+const (
+	backupFilePrefix = "gochan-backup-"
+	backupFileSuffix = ".tar.gz"
+)
+
+var (
+	ErrBackupDirNotSet = errors.New("BackupDir must be set in the configuration to rotate backups")
+)
+
+func expireBans(ctx context.Context) error {
+	deactivated, err := gcsql.DeactivateExpiredBans(&gcsql.RequestOptions{Context: ctx})
+	if err != nil {
+		return err
+	}
+	if deactivated > 0 {
+		gcutil.LogInfo().Str("job", "expireBans").Int64("bans", deactivated).Msg("Deactivated expired bans")
+	}
+	return nil
+}
+
+func expireSessions(ctx context.Context) error {
+	deleted, err := gcsql.DeleteExpiredSessions(&gcsql.RequestOptions{Context: ctx})
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		gcutil.LogInfo().Str("job", "expireSessions").Int64("sessions", deleted).Msg("Deleted expired staff sessions")
+	}
+	return nil
+}
+
+func purgeDeletedPosts(ctx context.Context) error {
+	return gcsql.PermanentlyRemoveDeletedPosts(&gcsql.RequestOptions{Context: ctx})
+}
+
+func optimizeDatabase(_ context.Context) error {
+	return gcsql.OptimizeDatabase()
+}
+
+func rebuildFront(_ context.Context) error {
+	return building.BuildFrontPage()
+}
+
+// rotateBackups creates a backup archive in BackupDir and deletes the oldest archives if there are more than
+// BackupsToKeep
+func rotateBackups(ctx context.Context) error {
+	siteConfig := config.GetSiteConfig()
+	if siteConfig.BackupDir == "" {
+		return ErrBackupDirNotSet
+	}
+	if err := os.MkdirAll(siteConfig.BackupDir, 0700); err != nil {
+		return err
+	}
+	archivePath := filepath.Join(siteConfig.BackupDir,
+		backupFilePrefix+time.Now().Format("20060102-150405")+backupFileSuffix)
+	file, err := os.OpenFile(archivePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, problems, err := backup.Create(ctx, file)
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(archivePath)
+		return fmt.Errorf("unable to create backup: %w", err)
+	}
+	for _, problem := range problems {
+		gcutil.LogWarning().Str("job", "rotateBackups").
+			Str("archivePath", archivePath).
+			Str("path", problem.Path).
+			Msg(problem.Problem)
+	}
+	gcutil.LogInfo().Str("job", "rotateBackups").Str("archivePath", archivePath).Msg("Created backup")
+	return pruneBackups(siteConfig.BackupDir, siteConfig.BackupsToKeep)
+}
+
+// pruneBackups deletes the oldest backup archives created by rotateBackups in dir if there are more than keep, or
+// does nothing if keep is 0. Other files in dir are left alone
+func pruneBackups(dir string, keep int) error {
+	if keep < 1 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var archives []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, backupFilePrefix) && strings.HasSuffix(name, backupFileSuffix) {
+			archives = append(archives, name)
+		}
+	}
+	if len(archives) <= keep {
+		return nil
+	}
+	// the archive names include the time they were created, so they sort from oldest to newest
+	slices.Sort(archives)
+	var errs []error
+	for _, name := range archives[:len(archives)-keep] {
+		if err = os.Remove(filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		gcutil.LogInfo().Str("job", "rotateBackups").Str("archive", name).Msg("Deleted old backup")
+	}
+	return errors.Join(errs...)
+}
+
+func init() {
+	for _, job := range []Job{
+		{Name: "expireBans", Description: "Deactivate bans that have expired", DefaultSchedule: "@hourly", Run: expireBans},
+		{Name: "expireSessions", Description: "Delete expired staff sessions", DefaultSchedule: "@hourly", Run: expireSessions},
+		{Name: "optimizeDatabase", Description: "Optimize the database tables", DefaultSchedule: "@weekly", Run: optimizeDatabase},
+		{
+			Name:            "purgeDeletedPosts",
+			Description:     "Permanently remove deleted posts from the database",
+			DefaultSchedule: gcutil.ScheduleDisabled,
+			Run:             purgeDeletedPosts,
+		},
+		{Name: "rebuildFront", Description: "Rebuild the front page", DefaultSchedule: gcutil.ScheduleDisabled, Run: rebuildFront},
+		{
+			Name:            "rotateBackups",
+			Description:     "Create a backup archive in BackupDir and delete the oldest ones beyond BackupsToKeep",
+			DefaultSchedule: gcutil.ScheduleDisabled,
+			Run:             rotateBackups,
+		},
+	} {
+		if err := Register(job); err != nil {
+			panic(err)
+		}
+	}
+}
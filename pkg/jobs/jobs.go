@@ -0,0 +1,266 @@
+// Package jobs runs recurring background jobs, like expiring bans and sessions and optimizing the database, on
+// schedules that can be changed with the JobSchedules field of the site configuration
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
+)
+
+const (
+	StatusNeverRun = "never run"
+	StatusRunning  = "running"
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+)
+
+var (
+	ErrJobExists   = errors.New("a job with that name is already registered")
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrInvalidJob  = errors.New("invalid job")
+
+	jobRuns = metrics.NewCounterVec("gochan_job_runs_total", "Number of background job runs, by job and result",
+		"job", "result")
+
+	jobsMutex sync.Mutex
+	jobs      = map[string]*jobState{}
+	// jobsCtx is the context that jobs are run with, cancelled when the scheduler is stopped
+	jobsCtx = context.Background()
+	// runningJobs is used by Wait to wait for running jobs to finish
+	runningJobs sync.WaitGroup
+)
+
+// Job is a function that is run in the background on a schedule
+type Job struct {
+	// Name identifies the job in the JobSchedules configuration field, the manage page, and the logs
+	Name string
+	// Description is shown on the manage page
+	Description string
+	// DefaultSchedule is used if the job isn't in JobSchedules. See gcutil.ParseSchedule for the format
+	DefaultSchedule string
+	// Run does the job's work. It should stop early and return ctx.Err() if ctx is cancelled
+	Run func(ctx context.Context) error
+}
+
+// Status is the current state of a registered job
+type Status struct {
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	Schedule     string        `json:"schedule"`
+	Status       string        `json:"status"`
+	LastRun      *time.Time    `json:"lastRun,omitempty"`
+	LastDuration time.Duration `json:"lastDuration"`
+	LastError    string        `json:"lastError,omitempty"`
+	// NextRun is nil if the job is disabled or its schedule never matches
+	NextRun *time.Time `json:"nextRun,omitempty"`
+}
+
+type jobState struct {
+	job          Job
+	spec         string
+	schedule     gcutil.Schedule
+	nextRun      time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+	running      bool
+}
+
+// updateSchedule parses the job's schedule if it hasn't been parsed yet or was changed in the configuration since it
+// was last parsed, and sets the next run time based on now. The jobsMutex should be locked before it is called
+func (js *jobState) updateSchedule(now time.Time) {
+	spec := js.job.DefaultSchedule
+	if configured, ok := config.GetSiteConfig().JobSchedules[js.job.Name]; ok {
+		spec = configured
+	}
+	if js.schedule != nil && spec == js.spec {
+		return
+	}
+	schedule, err := gcutil.ParseSchedule(spec)
+	if err != nil {
+		// JobSchedules is validated when the configuration is loaded and the default when the job is registered, so
+		// this shouldn't happen
+		gcutil.LogError(err).Caller().Str("job", js.job.Name).Msg("Invalid job schedule")
+		if js.schedule != nil {
+			return
+		}
+		schedule, _ = gcutil.ParseSchedule(gcutil.ScheduleDisabled)
+	}
+	js.spec = spec
+	js.schedule = schedule
+	js.nextRun = schedule.Next(now)
+}
+
+func (js *jobState) status() Status {
+	status := Status{
+		Name:         js.job.Name,
+		Description:  js.job.Description,
+		Schedule:     js.spec,
+		LastDuration: js.lastDuration,
+	}
+	switch {
+	case js.running:
+		status.Status = StatusRunning
+	case js.lastRun.IsZero():
+		status.Status = StatusNeverRun
+	case js.lastErr != nil:
+		status.Status = StatusFailed
+	default:
+		status.Status = StatusOK
+	}
+	if !js.lastRun.IsZero() {
+		lastRun := js.lastRun
+		status.LastRun = &lastRun
+	}
+	if js.lastErr != nil {
+		status.LastError = js.lastErr.Error()
+	}
+	if !js.nextRun.IsZero() {
+		nextRun := js.nextRun
+		status.NextRun = &nextRun
+	}
+	return status
+}
+
+// Register adds a job to be run by the scheduler. It returns ErrJobExists if a job with the same name is already
+// registered, or an error if the default schedule is invalid
+func Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("%w: jobs must have a name and a function to run", ErrInvalidJob)
+	}
+	if _, err := gcutil.ParseSchedule(job.DefaultSchedule); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrInvalidJob, job.Name, err)
+	}
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	if _, ok := jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+	// the schedule is parsed the first time the scheduler checks the job, since the configuration may not be loaded yet
+	jobs[job.Name] = &jobState{job: job}
+	return nil
+}
+
+// runJob runs the job and records the result. The job's running field should be set (with the jobsMutex locked) and
+// runningJobs incremented before it is called
+func runJob(ctx context.Context, js *jobState) {
+	defer runningJobs.Done()
+	start := time.Now()
+	logger := gcutil.Logger().With().Str("job", js.job.Name).Logger()
+	logger.Debug().Msg("Running job")
+	err := func() (err error) {
+		defer func() {
+			if a := recover(); a != nil {
+				logger.Error().Stack().Err(fmt.Errorf("%v", a)).Msg("Recovered from panic while running job")
+				err = fmt.Errorf("job panicked: %v", a)
+			}
+		}()
+		return js.job.Run(ctx)
+	}()
+	duration := time.Since(start).Round(time.Millisecond)
+
+	jobsMutex.Lock()
+	js.running = false
+	js.lastRun = start
+	js.lastDuration = duration
+	js.lastErr = err
+	jobsMutex.Unlock()
+
+	if err != nil {
+		jobRuns.Inc(js.job.Name, "failure")
+		logger.Err(err).Caller().Dur("duration", duration).Msg("Job failed")
+		return
+	}
+	jobRuns.Inc(js.job.Name, "success")
+	logger.Info().Dur("duration", duration).Msg("Finished job")
+}
+
+// runDue starts the jobs that are due to run at now in the background. A job that is still running from its last
+// scheduled time is skipped until the next one
+func runDue(ctx context.Context, now time.Time) {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	for _, js := range jobs {
+		js.updateSchedule(now)
+		if js.nextRun.IsZero() || now.Before(js.nextRun) {
+			continue
+		}
+		js.nextRun = js.schedule.Next(now)
+		if js.running {
+			gcutil.LogWarning().Str("job", js.job.Name).Msg("Skipping job because its previous run hasn't finished")
+			continue
+		}
+		js.running = true
+		runningJobs.Add(1)
+		go runJob(ctx, js)
+	}
+}
+
+// Start runs the registered jobs on their schedules, checking for jobs to run at the start of every minute, until ctx
+// is cancelled. Jobs are passed a context that is cancelled with ctx
+func Start(ctx context.Context) {
+	jobsMutex.Lock()
+	jobsCtx = ctx
+	jobsMutex.Unlock()
+	gcutil.LogInfo().Int("jobs", len(Statuses())).Msg("Started job scheduler")
+	for {
+		now := time.Now()
+		runDue(ctx, now)
+		timer := time.NewTimer(time.Until(now.Truncate(time.Minute).Add(time.Minute)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Wait waits for any jobs that are running to finish
+func Wait() {
+	runningJobs.Wait()
+}
+
+// RunNow starts the job with the given name in the background, regardless of its schedule. It returns ErrJobNotFound
+// if the job isn't registered or ErrJobRunning if it is already running
+func RunNow(name string) error {
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	js, ok := jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if js.running {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	js.running = true
+	runningJobs.Add(1)
+	go runJob(jobsCtx, js)
+	return nil
+}
+
+// Statuses returns the status of each registered job, sorted by name
+func Statuses() []Status {
+	now := time.Now()
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+	statuses := make([]Status, 0, len(jobs))
+	for _, js := range jobs {
+		js.updateSchedule(now)
+		statuses = append(statuses, js.status())
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return statuses
+}
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTestJobs replaces the registered jobs (including the built-in ones) with an empty list for the test
+func setupTestJobs(t *testing.T) {
+	t.Helper()
+	config.InitTestConfig()
+	jobsMutex.Lock()
+	oldJobs := jobs
+	jobs = map[string]*jobState{}
+	jobsMutex.Unlock()
+	t.Cleanup(func() {
+		Wait()
+		jobsMutex.Lock()
+		jobs = oldJobs
+		jobsMutex.Unlock()
+		config.GetSiteConfig().JobSchedules = nil
+	})
+}
+
+func getTestStatus(t *testing.T, name string) Status {
+	t.Helper()
+	for _, status := range Statuses() {
+		if status.Name == name {
+			return status
+		}
+	}
+	t.Fatalf("job %q not found", name)
+	return Status{}
+}
+
+func TestBuiltinJobs(t *testing.T) {
+	config.InitTestConfig()
+	names := map[string]bool{}
+	for _, status := range Statuses() {
+		names[status.Name] = true
+		assert.NotEmpty(t, status.Description)
+		assert.Equal(t, StatusNeverRun, status.Status)
+	}
+	for _, name := range []string{"expireBans", "expireSessions", "optimizeDatabase", "purgeDeletedPosts", "rebuildFront", "rotateBackups"} {
+		assert.True(t, names[name], "built-in job %q should be registered", name)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	setupTestJobs(t)
+	noop := func(context.Context) error { return nil }
+	assert.NoError(t, Register(Job{Name: "test", DefaultSchedule: "@daily", Run: noop}))
+	assert.ErrorIs(t, Register(Job{Name: "test", DefaultSchedule: "@daily", Run: noop}), ErrJobExists)
+	assert.ErrorIs(t, Register(Job{Name: "invalid", DefaultSchedule: "daily", Run: noop}), ErrInvalidJob)
+	assert.ErrorIs(t, Register(Job{Name: "", DefaultSchedule: "@daily", Run: noop}), ErrInvalidJob)
+	assert.ErrorIs(t, Register(Job{Name: "nofunc", DefaultSchedule: "@daily"}), ErrInvalidJob)
+	assert.Len(t, Statuses(), 1)
+}
+
+func TestRunDue(t *testing.T) {
+	setupTestJobs(t)
+	var runs int
+	assert.NoError(t, Register(Job{
+		Name:            "counter",
+		DefaultSchedule: "@every 5m",
+		Run: func(context.Context) error {
+			runs++
+			return nil
+		},
+	}))
+	assert.NoError(t, Register(Job{
+		Name:            "failing",
+		DefaultSchedule: "0 * * * *",
+		Run: func(context.Context) error {
+			return errors.New("uh oh")
+		},
+	}))
+	assert.NoError(t, Register(Job{
+		Name:            "panicking",
+		DefaultSchedule: "0 * * * *",
+		Run: func(context.Context) error {
+			panic("oh no")
+		},
+	}))
+	assert.NoError(t, Register(Job{
+		Name:            "disabled",
+		DefaultSchedule: "off",
+		Run: func(context.Context) error {
+			t.Error("disabled job shouldn't run")
+			return nil
+		},
+	}))
+	ctx := context.Background()
+	start := time.Date(2024, time.January, 10, 10, 30, 45, 0, time.Local)
+
+	runDue(ctx, start)
+	Wait()
+	assert.Zero(t, runs, "jobs shouldn't run when their schedule is first checked")
+	runDue(ctx, start.Add(4*time.Minute))
+	Wait()
+	assert.Zero(t, runs)
+	runDue(ctx, start.Add(5*time.Minute))
+	Wait()
+	assert.Equal(t, 1, runs)
+	status := getTestStatus(t, "counter")
+	assert.Equal(t, StatusOK, status.Status)
+	assert.NotNil(t, status.LastRun)
+
+	runDue(ctx, time.Date(2024, time.January, 10, 11, 0, 0, 0, time.Local))
+	Wait()
+	assert.Equal(t, 2, runs)
+	status = getTestStatus(t, "failing")
+	assert.Equal(t, StatusFailed, status.Status)
+	assert.Equal(t, "uh oh", status.LastError)
+	status = getTestStatus(t, "panicking")
+	assert.Equal(t, StatusFailed, status.Status)
+	assert.Contains(t, status.LastError, "oh no")
+	status = getTestStatus(t, "disabled")
+	assert.Equal(t, StatusNeverRun, status.Status)
+	assert.Nil(t, status.NextRun)
+	assert.Equal(t, 1.0, jobRuns.Value("failing", "failure"))
+
+	// schedules are updated when the configuration changes
+	config.GetSiteConfig().JobSchedules = map[string]string{"counter": "off"}
+	runDue(ctx, time.Date(2024, time.January, 10, 11, 5, 0, 0, time.Local))
+	Wait()
+	assert.Equal(t, 2, runs)
+	status = getTestStatus(t, "counter")
+	assert.Equal(t, "off", status.Schedule)
+	assert.Nil(t, status.NextRun)
+}
+
+func TestRunNow(t *testing.T) {
+	setupTestJobs(t)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	assert.NoError(t, Register(Job{
+		Name:            "blocking",
+		DefaultSchedule: "off",
+		Run: func(context.Context) error {
+			started <- struct{}{}
+			<-finish
+			return nil
+		},
+	}))
+	assert.ErrorIs(t, RunNow("missing"), ErrJobNotFound)
+	assert.NoError(t, RunNow("blocking"))
+	<-started
+	assert.Equal(t, StatusRunning, getTestStatus(t, "blocking").Status)
+	assert.ErrorIs(t, RunNow("blocking"), ErrJobRunning)
+	close(finish)
+	Wait()
+	assert.Equal(t, StatusOK, getTestStatus(t, "blocking").Status)
+}
+
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"gochan-backup-20240101-030000.tar.gz",
+		"gochan-backup-20240103-030000.tar.gz",
+		"gochan-backup-20240102-030000.tar.gz",
+		"gochan-backup-20231231-030000.tar.gz",
+		"other.tar.gz",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+	assert.NoError(t, pruneBackups(dir, 0))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5, "backups shouldn't be deleted if BackupsToKeep is 0")
+
+	assert.NoError(t, pruneBackups(dir, 2))
+	entries, err = os.ReadDir(dir)
+	assert.NoError(t, err)
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.Name())
+	}
+	assert.Equal(t, []string{
+		"gochan-backup-20240102-030000.tar.gz",
+		"gochan-backup-20240103-030000.tar.gz",
+		"other.tar.gz",
+	}, remaining)
+}
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+)
+
+// luaJobAdapter returns a job function that calls a Lua function. If the Lua function returns a string (or raises an
+// error), the job fails with it as the error
+func luaJobAdapter(l *lua.LState, fn *lua.LFunction) func(context.Context) error {
+	return func(_ context.Context) error {
+		// the plugin's sandbox keeps the job from using the Lua state at the same time as the plugin's other functions
+		rets, err := luautil.CallFunction(l, fn, 1, true)
+		if err != nil {
+			return err
+		}
+		if errStr := lua.LVAsString(rets[0]); errStr != "" {
+			return errors.New(errStr)
+		}
+		return nil
+	}
+}
+
+func PreloadModule(l *lua.LState) int {
+	t := l.NewTable()
+	l.SetFuncs(t, map[string]lua.LGFunction{
+		"register_job": func(l *lua.LState) int {
+			err := Register(Job{
+				Name:            l.CheckString(1),
+				Description:     l.CheckString(2),
+				DefaultSchedule: l.CheckString(3),
+				Run:             luaJobAdapter(l, l.CheckFunction(4)),
+			})
+			if err != nil {
+				l.Push(luar.New(l, err))
+			} else {
+				l.Push(lua.LNil)
+			}
+			return 1
+		},
+	})
+	l.Push(t)
+	return 1
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	lua "github.com/yuin/gopher-lua"
+	luar "layeh.com/gopher-luar"
+)
+
+func TestRegisterJobFromLua(t *testing.T) {
+	setupTestJobs(t)
+	buf := new(bytes.Buffer)
+	l := lua.NewState()
+	defer l.Close()
+	l.SetGlobal("buffer", luar.New(l, buf))
+	l.PreloadModule("jobs", PreloadModule)
+
+	assert.NoError(t, l.DoString(`local jobs = require("jobs")
+local err = jobs.register_job("luaJob", "Job registered from Lua", "@daily", function()
+	buffer:WriteString("ran lua job\n")
+end)
+assert(err == nil)
+err = jobs.register_job("luaJob", "Duplicate job", "@daily", function() end)
+assert(err ~= nil)
+err = jobs.register_job("invalidJob", "Job with an invalid schedule", "daily", function() end)
+assert(err ~= nil)
+err = jobs.register_job("failingJob", "Job that returns an error", "off", function()
+	return "uh oh"
+end)
+assert(err == nil)
+err = jobs.register_job("raisingJob", "Job that raises an error", "off", function()
+	error("oh no")
+end)
+assert(err == nil)`))
+
+	status := getTestStatus(t, "luaJob")
+	assert.Equal(t, "Job registered from Lua", status.Description)
+	assert.Equal(t, "@daily", status.Schedule)
+	assert.NotNil(t, status.NextRun)
+
+	assert.NoError(t, RunNow("luaJob"))
+	Wait()
+	assert.Equal(t, "ran lua job\n", buf.String())
+	assert.Equal(t, StatusOK, getTestStatus(t, "luaJob").Status)
+
+	assert.NoError(t, RunNow("failingJob"))
+	Wait()
+	status = getTestStatus(t, "failingJob")
+	assert.Equal(t, StatusFailed, status.Status)
+	assert.Equal(t, "uh oh", status.LastError)
+
+	assert.NoError(t, RunNow("raisingJob"))
+	Wait()
+	status = getTestStatus(t, "raisingJob")
+	assert.Equal(t, StatusFailed, status.Status)
+	assert.Contains(t, status.LastError, "oh no")
+	assert.Equal(t, 0, l.GetTop(), "the Lua stack should be left empty")
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Eggbertx/go-forms"
@@ -16,9 +17,11 @@ import (
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gctemplates"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/jobs"
 	"github.com/gochan-org/gochan/pkg/posting"
 	"github.com/gochan-org/gochan/pkg/server"
 	"github.com/gochan-org/gochan/pkg/server/serverutil"
+	"github.com/gochan-org/gochan/pkg/webhooks"
 	"github.com/rs/zerolog"
 	"github.com/uptrace/bunrouter"
 )
@@ -737,36 +740,280 @@ func reparseHTMLCallback(_ http.ResponseWriter, _ *http.Request, _ *gcsql.Staff,
 	return outputStr, nil
 }
 
-// viewLogCallback handles requests to /manage/viewlog for viewing the gochan log file
-func viewLogCallback(_ http.ResponseWriter, _ *http.Request, _ *gcsql.Staff, _ bool, logger zerolog.Logger) (output any, err error) {
-	logPath := path.Join(config.GetSystemCriticalConfig().LogDir, "gochan.log")
-	logBytes, err := os.ReadFile(logPath)
+// viewLogPageURL returns the URL of the given page of the log viewer, keeping the current filters
+func viewLogPageURL(request *http.Request, page int) string {
+	query := request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	return config.WebPath("manage/viewlog") + "?" + query.Encode()
+}
+
+// viewLogCallback handles requests to /manage/viewlog for viewing and searching the gochan log files. The log is
+// read from the end, so the first page has the newest entries
+func viewLogCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	var form viewLogForm
+	if err = forms.FillStructFromForm(request, &form); err != nil {
+		logger.Err(err).Caller().Msg("Unable to fill struct from form")
+		return "", server.NewServerError(err, http.StatusBadRequest)
+	}
+	filter, err := form.filter()
 	if err != nil {
-		logger.Err(err).Caller().Send()
-		return "", errors.New("unable to open log file")
+		logger.Warn().Err(err).Caller().Msg("Invalid log filter")
+		return "", err
 	}
-	buf := bytes.NewBufferString("")
-	err = serverutil.MinifyTemplate(gctemplates.ManageViewLog, map[string]any{
-		"logText": string(logBytes),
-	}, buf, "text/html")
+	logPath := path.Join(config.GetSystemCriticalConfig().LogDir, form.logFilename())
+	entries, more, err := gcutil.ReadLogEntries(logPath, filter, (form.Page-1)*form.Limit, form.Limit)
 	if err != nil {
+		logger.Err(err).Caller().Str("logPath", logPath).Send()
+		return "", errors.New("unable to read log file")
+	}
+
+	if wantsJSON {
+		return map[string]any{
+			"log":     form.Log,
+			"page":    form.Page,
+			"more":    more,
+			"entries": entries,
+		}, nil
+	}
+
+	selectedLevels := make(map[string]bool, len(form.Levels))
+	for _, level := range form.Levels {
+		selectedLevels[level] = true
+	}
+	data := map[string]any{
+		"form":           form,
+		"entries":        entries,
+		"levels":         []string{"fatal", "error", "warn", "info", "debug", "trace"},
+		"selectedLevels": selectedLevels,
+	}
+	if form.Page > 1 {
+		data["prevURL"] = viewLogPageURL(request, form.Page-1)
+	}
+	if more {
+		data["nextURL"] = viewLogPageURL(request, form.Page+1)
+	}
+	buf := bytes.NewBufferString("")
+	if err = serverutil.MinifyTemplate(gctemplates.ManageViewLog, data, buf, "text/html"); err != nil {
 		logger.Err(err).Str("template", gctemplates.ManageViewLog).Caller().Send()
 		return "", err
 	}
 	return buf.String(), nil
 }
 
+// jobsCallback handles requests to /manage/jobs for viewing the status of the background jobs and running them
+// immediately
+func jobsCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	var message string
+	if jobName := request.PostFormValue("job"); jobName != "" {
+		logger = logger.With().Str("job", jobName).Logger()
+		err = jobs.RunNow(jobName)
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			logger.Warn().Err(err).Caller().Send()
+			return "", server.NewServerError(err, http.StatusNotFound)
+		} else if errors.Is(err, jobs.ErrJobRunning) {
+			return "", server.NewServerError(err, http.StatusConflict)
+		} else if err != nil {
+			logger.Err(err).Caller().Msg("Unable to run job")
+			return "", errors.New("unable to run job")
+		}
+		logger.Info().Msg("Started job from manage page")
+		message = "Started job " + jobName
+	}
+
+	statuses := jobs.Statuses()
+	if wantsJSON {
+		return statuses, nil
+	}
+	buf := bytes.NewBufferString("")
+	if err = serverutil.MinifyTemplate(gctemplates.ManageJobs, map[string]any{
+		"jobs":    statuses,
+		"message": message,
+	}, buf, "text/html"); err != nil {
+		logger.Err(err).Str("template", gctemplates.ManageJobs).Caller().Send()
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// webhookJSON is a webhook and the result of the last delivery to it, for JSON requests to /manage/webhooks
+type webhookJSON struct {
+	gcsql.Webhook
+	LastDelivery *webhooks.Delivery `json:"last_delivery,omitempty"`
+}
+
+// getWebhookFromForm returns the webhook with the ID in the given form value
+func getWebhookFromForm(request *http.Request, key string, logger zerolog.Logger) (*gcsql.Webhook, error) {
+	idStr := request.FormValue(key)
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		logger.Warn().Err(err).Caller().Str(key, idStr).Send()
+		return nil, server.NewServerError("invalid webhook ID", http.StatusBadRequest)
+	}
+	webhook, err := gcsql.GetWebhookByID(id)
+	if errors.Is(err, gcsql.ErrWebhookNotFound) {
+		return nil, server.NewServerError(err, http.StatusNotFound)
+	} else if err != nil {
+		logger.Err(err).Caller().Int(key, id).Msg("Unable to get webhook")
+		return nil, errors.New("unable to get webhook")
+	}
+	return webhook, nil
+}
+
+// webhooksCallback handles requests to /manage/webhooks for creating, editing, enabling/disabling, deleting, and
+// testing the webhooks that are sent when moderation events happen
+func webhooksCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	var message string
+	webhook := &gcsql.Webhook{MaxRetries: 3, IsActive: true}
+	editing := request.FormValue("edit") != ""
+	if editing {
+		if webhook, err = getWebhookFromForm(request, "edit", logger); err != nil {
+			return nil, err
+		}
+		logger = logger.With().Int("editID", webhook.ID).Logger()
+	}
+
+	switch {
+	case request.FormValue("disable") != "" || request.FormValue("enable") != "":
+		key := "enable"
+		if request.FormValue("disable") != "" {
+			key = "disable"
+		}
+		toggled, err := getWebhookFromForm(request, key, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err = gcsql.SetWebhookActive(toggled.ID, key == "enable"); err != nil {
+			logger.Err(err).Caller().Int(key+"ID", toggled.ID).Msg("Unable to " + key + " webhook")
+			return nil, errors.New("unable to " + key + " webhook")
+		}
+		logger.Info().Int(key+"ID", toggled.ID).Msg("Webhook " + key + "d")
+	case request.PostFormValue("delete") != "":
+		deleted, err := getWebhookFromForm(request, "delete", logger)
+		if err != nil {
+			return nil, err
+		}
+		if err = gcsql.DeleteWebhook(deleted.ID); err != nil {
+			logger.Err(err).Caller().Int("deleteID", deleted.ID).Msg("Unable to delete webhook")
+			return nil, errors.New("unable to delete webhook")
+		}
+		logger.Info().Int("deleteID", deleted.ID).Msg("Webhook deleted")
+		message = "Deleted webhook " + deleted.Name
+	case request.PostFormValue("test") != "":
+		tested, err := getWebhookFromForm(request, "test", logger)
+		if err != nil {
+			return nil, err
+		}
+		delivery := webhooks.SendTest(tested)
+		if wantsJSON {
+			return delivery, nil
+		}
+		if delivery.OK() {
+			message = fmt.Sprintf("Test delivery to %s succeeded (HTTP %d)", tested.Name, delivery.StatusCode)
+		} else {
+			message = fmt.Sprintf("Test delivery to %s failed: %s", tested.Name, delivery.Error)
+		}
+	case request.PostFormValue("dowebhook") != "":
+		webhook.Name = request.PostFormValue("name")
+		webhook.URL = request.PostFormValue("url")
+		webhook.PayloadTemplate = strings.TrimSpace(request.PostFormValue("payloadtemplate"))
+		webhook.IsActive = request.PostFormValue("isactive") == "on"
+		if secret := request.PostFormValue("secret"); secret != "" || request.PostFormValue("clearsecret") == "on" {
+			// the secret isn't shown on the page, so it is only changed if a new one is entered or it is cleared
+			webhook.Secret = secret
+		}
+		if webhook.MaxRetries, err = strconv.Atoi(request.PostFormValue("maxretries")); err != nil {
+			return nil, server.NewServerError(webhooks.ErrInvalidMaxRetries, http.StatusBadRequest)
+		}
+		var events, boards []string
+		for _, event := range webhooks.Events {
+			if request.PostFormValue("event-"+event) == "on" {
+				events = append(events, event)
+			}
+		}
+		for k, v := range request.PostForm {
+			if strings.HasPrefix(k, "board-") && v[0] == "on" {
+				boards = append(boards, k[6:])
+			}
+		}
+		webhook.Events = strings.Join(events, ",")
+		webhook.Boards = strings.Join(boards, ",")
+		if err = webhooks.Validate(webhook); err != nil {
+			logger.Warn().Err(err).Caller().Str("name", webhook.Name).Msg("Invalid webhook")
+			return nil, server.NewServerError(err, http.StatusBadRequest)
+		}
+		logger = logger.With().
+			Str("name", webhook.Name).
+			Str("url", webhook.URL).
+			Str("events", webhook.Events).
+			Str("boards", webhook.Boards).
+			Logger()
+		if editing {
+			err = webhook.Update()
+		} else {
+			err = gcsql.CreateWebhook(webhook)
+		}
+		if err != nil {
+			logger.Err(err).Caller().Msg("Unable to save webhook")
+			return nil, errors.New("unable to save webhook")
+		}
+		if editing {
+			logger.Info().Msg("Webhook updated")
+			message = "Updated webhook " + webhook.Name
+		} else {
+			logger.Info().Int("webhookID", webhook.ID).Msg("Webhook created")
+			message = "Created webhook " + webhook.Name
+			webhook = &gcsql.Webhook{MaxRetries: 3, IsActive: true}
+		}
+	}
+
+	allWebhooks, err := gcsql.GetWebhooks(gcsql.TrueOrFalse)
+	if err != nil {
+		logger.Err(err).Caller().Msg("Unable to get webhooks")
+		return nil, errors.New("unable to get webhooks")
+	}
+	webhooksList := make([]webhookJSON, len(allWebhooks))
+	for w, wh := range allWebhooks {
+		webhooksList[w] = webhookJSON{Webhook: wh, LastDelivery: webhooks.LastDelivery(wh.ID)}
+	}
+	if wantsJSON {
+		return webhooksList, nil
+	}
+
+	selectedBoards := map[string]bool{}
+	for _, dir := range webhook.BoardList() {
+		selectedBoards[dir] = true
+	}
+	buf := bytes.NewBufferString("")
+	if err = serverutil.MinifyTemplate(gctemplates.ManageWebhooks, map[string]any{
+		"webhooks":        webhooksList,
+		"webhook":         webhook,
+		"editing":         editing,
+		"events":          webhooks.Events,
+		"allBoards":       gcsql.AllBoards,
+		"selectedBoards":  selectedBoards,
+		"maxRetriesLimit": webhooks.MaxRetriesLimit,
+		"message":         message,
+	}, buf, "text/html"); err != nil {
+		logger.Err(err).Str("template", gctemplates.ManageWebhooks).Caller().Send()
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func registerAdminPages() {
 	RegisterManagePage("updateannouncements", "Update staff announcements", AdminPerms, NoJSON, updateAnnouncementsCallback)
 	RegisterManagePage("boards", "Boards", AdminPerms, NoJSON, boardsCallback)
 	RegisterManagePageWithMethods("boards/:board", "Modify Board", AdminPerms, NoJSON, true, modifyBoardCallback, http.MethodGet, http.MethodPost)
 	RegisterManagePage("boardsections", "Board sections", AdminPerms, OptionalJSON, boardSectionsCallback)
 	RegisterManagePage("cleanup", "Cleanup", AdminPerms, NoJSON, cleanupCallback)
+	RegisterManagePage("jobs", "Background jobs", AdminPerms, OptionalJSON, jobsCallback)
 	RegisterManagePage("fixthumbnails", "Regenerate thumbnails", AdminPerms, NoJSON, fixThumbnailsCallback)
 	RegisterManagePage("templates", "Override templates", AdminPerms, NoJSON, templatesCallback)
 	RegisterManagePage("rebuildfront", "Rebuild front page", AdminPerms, OptionalJSON, rebuildFrontCallback)
 	RegisterManagePage("rebuildboards", "Rebuild boards", AdminPerms, OptionalJSON, rebuildBoardsCallback)
 	RegisterManagePage("rebuildall", "Rebuild everything", AdminPerms, OptionalJSON, rebuildAllCallback)
 	RegisterManagePage("reparsehtml", "Reparse HTML", AdminPerms, NoJSON, reparseHTMLCallback)
-	RegisterManagePage("viewlog", "View log", AdminPerms, NoJSON, viewLogCallback)
+	RegisterManagePage("reloadconfig", "Reload configuration", AdminPerms, OptionalJSON, reloadConfigCallback)
+	RegisterManagePage("viewlog", "View log", AdminPerms, OptionalJSON, viewLogCallback)
+	RegisterManagePage("webhooks", "Webhooks", AdminPerms, OptionalJSON, webhooksCallback)
 }
@@ -714,9 +714,11 @@ func registerModeratorPages() {
 	RegisterManagePage("filters", "Post Filters", ModPerms, NoJSON, filtersCallback)
 	RegisterManagePageWithMethods("filters/hits/:filterID", "Filter Hits", ModPerms, NoJSON, true, filterHitsCallback, http.MethodGet, http.MethodPost)
 	RegisterManagePage("ipsearch", "IP Search", ModPerms, NoJSON, ipSearchCallback)
+	RegisterManagePage("posterid", "Poster ID Search", ModPerms, OptionalJSON, posterIDCallback)
 	RegisterManagePage("reports", "Reports", ModPerms, OptionalJSON, reportsCallback)
 	RegisterManagePage("threadattrs", "View/Update Thread Attributes", ModPerms, OptionalJSON, threadAttrsCallback)
 	RegisterManagePage("postinfo", "Post Info", ModPerms, AlwaysJSON, postInfoCallback)
 	RegisterManagePage("fingerprint", "Get Image/Thumbnail Fingerprint", ModPerms, AlwaysJSON, fingerprintCallback)
+	RegisterManagePage("similarimages", "Similar Images", ModPerms, OptionalJSON, similarImagesCallback)
 	RegisterManagePage("wordfilters", "Wordfilters", ModPerms, NoJSON, wordfiltersCallback)
 }
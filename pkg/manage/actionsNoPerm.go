@@ -74,6 +74,8 @@ type staffInfoJSON struct {
 	Actions  []Action         `json:"actions,omitempty"`
 	Reports  []reportWithLink `json:"reports,omitempty"`
 	Appeals  []gcsql.Appeal   `json:"appeals,omitempty"`
+	// UnreadAppeals is the number of open appeals with messages from banned users that staff haven't read yet
+	UnreadAppeals int `json:"unread_appeals"`
 }
 
 func staffInfoCallback(_ http.ResponseWriter, request *http.Request, staff *gcsql.Staff, _ bool, logger zerolog.Logger) (output any, err error) {
@@ -94,11 +96,16 @@ func staffInfoCallback(_ http.ResponseWriter, request *http.Request, staff *gcsq
 		if info.Appeals, err = gcsql.GetAppeals(gcsql.AppealsQueryOptions{
 			Active:    gcsql.OnlyTrue,
 			Unexpired: gcsql.OnlyTrue,
+			Resolved:  gcsql.OnlyFalse,
 			Limit:     4,
 		}); err != nil {
 			logger.Err(err).Caller().Send()
 			return nil, fmt.Errorf("unable to get the number of open appeals: %w", err)
 		}
+		if info.UnreadAppeals, err = gcsql.GetUnreadAppealCount(); err != nil {
+			logger.Err(err).Caller().Send()
+			return nil, fmt.Errorf("unable to get the number of unread appeals: %w", err)
+		}
 	}
 	return info, nil
 }
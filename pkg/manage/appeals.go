@@ -2,8 +2,10 @@ package manage
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Eggbertx/go-forms"
 	"github.com/gochan-org/gochan/pkg/gcsql"
@@ -54,13 +56,27 @@ func appealsCallback(_ http.ResponseWriter, request *http.Request, staff *gcsql.
 		}
 		logger.Info().Msg("Approved appeal(s)")
 	} else if form.isDeny() {
-		return "", server.NewServerError("deny appeal not yet implemented", http.StatusNotImplemented)
+		appealAt, err := form.appealAt()
+		if err != nil {
+			logger.Err(err).Caller().
+				Str("appealWait", form.AppealWait).Send()
+			return "", err
+		}
+		for _, denyID := range form.AppealIDs {
+			if err = gcsql.DenyAppeal(denyID, staff.ID, appealAt); err != nil {
+				logger.Err(err).Caller().
+					Int("denyAppeal", denyID).Send()
+				return "", err
+			}
+		}
+		logger.Info().Str("appealWait", form.AppealWait).Msg("Denied appeal(s)")
 	}
 
 	appeals, err := gcsql.GetAppeals(gcsql.AppealsQueryOptions{
 		Limit:           form.Limit,
 		Active:          gcsql.OnlyTrue,
 		Unexpired:       gcsql.OnlyTrue,
+		Resolved:        gcsql.OnlyFalse,
 		OrderDescending: true,
 	})
 	if err != nil {
@@ -83,16 +99,99 @@ func appealsCallback(_ http.ResponseWriter, request *http.Request, staff *gcsql.
 	return buf.String(), err
 }
 
-func appealConversationCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, _ bool, logger zerolog.Logger) (output any, err error) {
+// doAppealConversationAction handles a staff member replying to, approving, or denying an appeal from its conversation page
+func doAppealConversationAction(appealID int, form *appealConversationForm, staff *gcsql.Staff, logger zerolog.Logger) error {
+	if form.DoReply == "" && form.DoApprove == "" && form.DoDeny == "" {
+		return nil
+	}
+	message := strings.TrimSpace(form.Message)
+	if form.DoReply != "" && message == "" {
+		return server.NewServerError("reply message can not be empty", http.StatusBadRequest)
+	}
+	if message != "" {
+		if err := gcsql.AddAppealMessage(appealID, staff.ID, message); err != nil {
+			logger.Err(err).Caller().Msg("Unable to reply to appeal")
+			return err
+		}
+		logger.Info().Msg("Replied to appeal")
+	}
+
+	if form.DoApprove != "" {
+		if err := gcsql.ApproveAppeal(appealID, staff.ID); err != nil {
+			logger.Err(err).Caller().Msg("Unable to approve appeal")
+			return err
+		}
+		logger.Info().Msg("Approved appeal")
+	} else if form.DoDeny != "" {
+		appealAt, err := getAppealAt(form.AppealWait)
+		if err != nil {
+			logger.Err(err).Caller().
+				Str("appealWait", form.AppealWait).Send()
+			return err
+		}
+		if err = gcsql.DenyAppeal(appealID, staff.ID, appealAt); err != nil {
+			logger.Err(err).Caller().Msg("Unable to deny appeal")
+			return err
+		}
+		logger.Info().Str("appealWait", form.AppealWait).Msg("Denied appeal")
+	}
+	return nil
+}
+
+func appealConversationCallback(_ http.ResponseWriter, request *http.Request, staff *gcsql.Staff, _ bool, logger zerolog.Logger) (output any, err error) {
 	params, _ := request.Context().Value(requestContextKey{}).(bunrouter.Params)
 	appealID, err := params.Int("appealID")
 	if err != nil {
 		logger.Err(err).Caller().Msg("Appeal ID is not a valid integer")
 		return nil, server.NewServerError("missing appeal ID", http.StatusBadRequest)
 	}
+	logger = logger.With().Int("appealID", appealID).Logger()
+
+	if request.Method == http.MethodPost {
+		var form appealConversationForm
+		if err = forms.FillStructFromForm(request, &form); err != nil {
+			logger.Err(err).Caller().
+				Msg("Unable to fill struct from form")
+			return "", server.NewServerError(err, http.StatusBadRequest)
+		}
+		if err = doAppealConversationAction(appealID, &form, staff, logger); err != nil {
+			return "", err
+		}
+	}
+
+	appeal, err := gcsql.GetAppealByID(appealID)
+	if errors.Is(err, gcsql.ErrAppealDoesNotExist) {
+		return "", server.NewServerError(err, http.StatusNotFound)
+	} else if err != nil {
+		logger.Err(err).Caller().Msg("Unable to get appeal")
+		return "", fmt.Errorf("failed to get appeal: %w", err)
+	}
+	messages, err := gcsql.GetAppealMessages(appealID)
+	if err != nil {
+		logger.Err(err).Caller().Msg("Unable to get appeal messages")
+		return "", fmt.Errorf("failed to get appeal messages: %w", err)
+	}
+	ban, err := gcsql.GetIPBanByID(nil, appeal.IPBanID)
+	if err != nil {
+		logger.Err(err).Caller().Int("banID", appeal.IPBanID).Msg("Unable to get appealed ban")
+		return "", fmt.Errorf("failed to get appealed ban: %w", err)
+	}
+	if ban == nil {
+		logger.Error().Caller().Int("banID", appeal.IPBanID).Msg("Appealed ban does not exist")
+		return "", server.NewServerError("appealed ban does not exist", http.StatusNotFound)
+	}
+	if appeal.StaffUnread {
+		if err = gcsql.MarkAppealRead(appealID); err != nil {
+			logger.Err(err).Caller().Msg("Unable to mark appeal as read")
+			return "", fmt.Errorf("failed to mark appeal as read: %w", err)
+		}
+	}
 
 	data := map[string]any{
 		"appealID": appealID,
+		"appeal":   appeal,
+		"messages": messages,
+		"ban":      ban,
 	}
 
 	SetCustomPageTitle(request, fmt.Sprintf("Appeal %d Conversation", appealID))
@@ -103,5 +202,4 @@ func appealConversationCallback(_ http.ResponseWriter, request *http.Request, _
 		return "", fmt.Errorf("failed executing appeal conversation page template: %w", err)
 	}
 	return buf.String(), err
-
 }
@@ -164,6 +164,12 @@ func submitFilterFormData(request *http.Request, staff *gcsql.Staff, infoEv, err
 					Str("field", fc.Field).Send()
 				return gcsql.ErrInvalidConditionField
 			}
+			if fc.Field == "ahash" {
+				if _, _, err = gcsql.ParseFingerprintSearch(fc.Search); err != nil {
+					errEv.Err(err).Caller().Str("search", fc.Search).Send()
+					return err
+				}
+			}
 			conditionsLogArr.Interface(fc)
 			conditions = append(conditions, fc)
 		}
@@ -3,9 +3,12 @@ package manage
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Eggbertx/durationutil"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
 	"github.com/gochan-org/gochan/pkg/server"
 	"github.com/rs/zerolog"
 )
@@ -160,8 +163,11 @@ func (brf *createOrModifyBoardForm) writeConfig() error {
 type appealsForm struct {
 	DoApprove string `form:"doapprove" method:"POST"`
 	DoDeny    string `form:"dodeny" method:"POST"`
-	AppealIDs []int  `form:"appeal,required,notempty" method:"POST"`
-	Limit     int    `form:"limit,default=20"`
+	AppealIDs []int  `form:"appeal" method:"POST"`
+	// AppealWait is an optional duration that the banned user must wait before submitting a new appeal after their
+	// appeal is denied
+	AppealWait string `form:"appealwait" method:"POST"`
+	Limit      int    `form:"limit,default=20"`
 }
 
 func (af *appealsForm) validate() error {
@@ -183,3 +189,93 @@ func (af *appealsForm) isApprove() bool {
 func (af *appealsForm) isDeny() bool {
 	return af.DoDeny != ""
 }
+
+// appealAt returns the time that the banned user can submit a new appeal if the appeal is denied, or a zero time if
+// AppealWait is not set
+func (af *appealsForm) appealAt() (time.Time, error) {
+	return getAppealAt(af.AppealWait)
+}
+
+type appealConversationForm struct {
+	Message    string `form:"message" method:"POST"`
+	DoReply    string `form:"doreply" method:"POST"`
+	DoApprove  string `form:"doapprove" method:"POST"`
+	DoDeny     string `form:"dodeny" method:"POST"`
+	AppealWait string `form:"appealwait" method:"POST"`
+}
+
+func getAppealAt(appealWait string) (time.Time, error) {
+	appealWait = strings.TrimSpace(appealWait)
+	if appealWait == "" {
+		return time.Time{}, nil
+	}
+	duration, err := durationutil.ParseLongerDuration(appealWait)
+	if err != nil {
+		return time.Time{}, server.NewServerError("invalid appeal wait duration: "+err.Error(), http.StatusBadRequest)
+	}
+	return time.Now().Add(duration), nil
+}
+
+const (
+	// viewLogTimeLayout is the layout of the datetime-local inputs in the log viewer
+	viewLogTimeLayout = "2006-01-02T15:04"
+	viewLogMaxLimit   = 1000
+)
+
+type viewLogForm struct {
+	Log    string   `form:"log"`
+	Levels []string `form:"level"`
+	Since  string   `form:"since"`
+	Until  string   `form:"until"`
+	IP     string   `form:"ip"`
+	Action string   `form:"action"`
+	Board  string   `form:"board"`
+	Search string   `form:"search"`
+	Page   int      `form:"page,default=1"`
+	Limit  int      `form:"limit,default=100"`
+}
+
+// logFilename returns the name of the log file selected in the form, the error log by default
+func (vlf *viewLogForm) logFilename() string {
+	if vlf.Log == "access" {
+		return gcutil.AccessLogFilename
+	}
+	vlf.Log = "error"
+	return gcutil.ErrorLogFilename
+}
+
+// filter validates the form and returns the log filter to use for reading the log
+func (vlf *viewLogForm) filter() (*gcutil.LogFilter, error) {
+	if vlf.Page < 1 {
+		vlf.Page = 1
+	}
+	if vlf.Limit < 1 || vlf.Limit > viewLogMaxLimit {
+		vlf.Limit = 100
+	}
+	filter := &gcutil.LogFilter{
+		Levels: vlf.Levels,
+		IP:     strings.TrimSpace(vlf.IP),
+		Action: strings.TrimSpace(vlf.Action),
+		Board:  strings.TrimSpace(vlf.Board),
+		Text:   strings.TrimSpace(vlf.Search),
+	}
+	var err error
+	if vlf.Since != "" {
+		if filter.Since, err = time.ParseInLocation(viewLogTimeLayout, vlf.Since, time.Local); err != nil {
+			return nil, server.NewServerError("invalid start time", http.StatusBadRequest)
+		}
+	}
+	if vlf.Until != "" {
+		if filter.Until, err = time.ParseInLocation(viewLogTimeLayout, vlf.Until, time.Local); err != nil {
+			return nil, server.NewServerError("invalid end time", http.StatusBadRequest)
+		}
+		// include the entries logged during the last minute
+		filter.Until = filter.Until.Add(time.Minute - time.Nanosecond)
+	}
+	if filter.IP != "" {
+		if _, err = gcutil.ParseIPPrefixes([]string{filter.IP}); err != nil {
+			return nil, server.NewServerError("invalid IP address or range", http.StatusBadRequest)
+		}
+	}
+	return filter, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	_ "github.com/gochan-org/gochan/pkg/gcsql/initsql"
+	"github.com/gochan-org/gochan/pkg/server"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/uptrace/bunrouter"
@@ -55,3 +56,36 @@ func TestSetCustomPageTitle(t *testing.T) {
 	output := responseWriter.Body.String()
 	assert.Contains(t, output, "<title>Custom Title Set - Gochan</title>", "page title should be set to custom title")
 }
+
+func TestCheckStateChangingRequest(t *testing.T) {
+	config.InitTestConfig()
+	siteHost := config.GetSystemCriticalConfig().SiteHost
+	testCases := []struct {
+		desc         string
+		method       string
+		referer      string
+		expectStatus int
+	}{
+		{desc: "GET request", method: http.MethodGet, referer: "http://" + siteHost + "/manage/reloadconfig", expectStatus: http.StatusMethodNotAllowed},
+		{desc: "POST request from another site", method: http.MethodPost, referer: "http://example.net/", expectStatus: http.StatusForbidden},
+		{desc: "POST request without referer", method: http.MethodPost, expectStatus: http.StatusForbidden},
+		{desc: "POST request from the site", method: http.MethodPost, referer: "http://" + siteHost + "/manage/reloadconfig"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://"+siteHost+"/manage/reloadconfig", http.NoBody)
+			if tc.referer != "" {
+				req.Header.Set("Referer", tc.referer)
+			}
+			err := CheckStateChangingRequest(req)
+			if tc.expectStatus == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var serverError *server.ServerError
+			if assert.ErrorAs(t, err, &serverError) {
+				assert.Equal(t, tc.expectStatus, serverError.StatusCode)
+			}
+		})
+	}
+}
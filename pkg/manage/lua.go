@@ -134,9 +134,7 @@ func luaBanIP(l *lua.LState) int {
 	return 1
 }
 
-func luaHandlerOutputToGo(l *lua.LState) (any, error) {
-	outV := l.Get(-2)
-	errV := l.Get(-1)
+func luaHandlerOutputToGo(outV, errV lua.LValue) (any, error) {
 	err := luautil.LValueToError(errV)
 	if err != nil {
 		return nil, err
@@ -171,11 +169,8 @@ func PreloadModule(l *lua.LState) int {
 			fn := l.CheckFunction(5)
 			actionHandler := func(writer http.ResponseWriter, request *http.Request, staff *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
 				logger = logger.With().Str("lua", "register_manage_page").Logger()
-				if err = l.CallByParam(lua.P{
-					Fn:      fn,
-					NRet:    2,
-					Protect: true,
-				}, luar.New(l, writer), luar.New(l, request), luar.New(l, staff), lua.LBool(wantsJSON), luar.New(l, &logger)); err != nil {
+				rets, err := luautil.CallFunction(l, fn, 2, true, writer, request, staff, wantsJSON, &logger)
+				if err != nil {
 					var apiError *lua.ApiError
 					logger.Err(err).Caller().Send()
 					if errors.As(err, &apiError) {
@@ -184,7 +179,7 @@ func PreloadModule(l *lua.LState) int {
 					}
 					return "", err
 				}
-				return luaHandlerOutputToGo(l)
+				return luaHandlerOutputToGo(rets[0], rets[1])
 			}
 			RegisterManagePage(actionID, actionTitle, actionPerms, actionJSON, actionHandler)
 			return 0
@@ -270,11 +265,8 @@ func PreloadModule(l *lua.LState) int {
 
 			action.Callback = func(writer http.ResponseWriter, request *http.Request, staff *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
 				logger = logger.With().Str("lua", "register_staff_action").Logger()
-				if err = l.CallByParam(lua.P{
-					Fn:      fn,
-					NRet:    2,
-					Protect: true,
-				}, luar.New(l, writer), luar.New(l, request), luar.New(l, staff), lua.LBool(wantsJSON), luar.New(l, &logger)); err != nil {
+				rets, err := luautil.CallFunction(l, fn, 2, true, writer, request, staff, wantsJSON, &logger)
+				if err != nil {
 					var apiError *lua.ApiError
 					logger.Err(err).Caller().Send()
 					if errors.As(err, &apiError) {
@@ -283,7 +275,7 @@ func PreloadModule(l *lua.LState) int {
 					}
 					return "", err
 				}
-				return luaHandlerOutputToGo(l)
+				return luaHandlerOutputToGo(rets[0], rets[1])
 			}
 
 			methodsVal := l.Get(2)
@@ -0,0 +1,85 @@
+package manage
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/building"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gctemplates"
+	"github.com/gochan-org/gochan/pkg/server"
+	"github.com/gochan-org/gochan/pkg/server/serverutil"
+	"github.com/rs/zerolog"
+)
+
+type posterIDPostsJSON struct {
+	PosterID string           `json:"id"`
+	Thread   int              `json:"thread"`
+	Posts    []*building.Post `json:"posts"`
+}
+
+// posterIDCallback shows the posts in a thread that share a poster ID, given either a post with the ID or the
+// thread's top post ID and the poster ID
+func posterIDCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	data := map[string]any{
+		"posterID": strings.ToLower(strings.TrimSpace(request.FormValue("id"))),
+	}
+	var posterID string
+	var opID int
+	var posts []*building.Post
+	if postIDStr := request.FormValue("postid"); postIDStr != "" {
+		postID, err := strconv.Atoi(postIDStr)
+		if err != nil {
+			logger.Err(err).Caller().Str("postID", postIDStr).Send()
+			return nil, server.NewServerError("invalid post ID", http.StatusBadRequest)
+		}
+		logger = logger.With().Int("postID", postID).Logger()
+		if posterID, posts, err = building.GetPostsSharingPosterID(postID); err != nil {
+			logger.Err(err).Caller().Msg("Unable to get posts with the same poster ID")
+			return nil, errors.New("unable to get posts with the same poster ID")
+		}
+		if posterID == "" {
+			return nil, server.NewServerError("post does not exist", http.StatusNotFound)
+		}
+		data["posterID"] = posterID
+	} else if threadStr := request.FormValue("thread"); threadStr != "" {
+		posterID = data["posterID"].(string)
+		if opID, err = strconv.Atoi(threadStr); err != nil {
+			logger.Err(err).Caller().Str("thread", threadStr).Send()
+			return nil, server.NewServerError("invalid thread", http.StatusBadRequest)
+		}
+		if posterID == "" {
+			return nil, server.NewServerError("missing poster ID", http.StatusBadRequest)
+		}
+		logger = logger.With().Int("thread", opID).Str("posterID", posterID).Logger()
+		if posts, err = building.GetThreadPostsByPosterID(opID, posterID); err != nil {
+			logger.Err(err).Caller().Msg("Unable to get posts with poster ID")
+			return nil, errors.New("unable to get posts with poster ID")
+		}
+	}
+	if len(posts) > 0 {
+		opID = posts[0].ParentID
+	}
+	if opID > 0 {
+		data["thread"] = opID
+	}
+	data["posts"] = posts
+
+	if wantsJSON {
+		return posterIDPostsJSON{
+			PosterID: posterID,
+			Thread:   opID,
+			Posts:    posts,
+		}, nil
+	}
+	buf := bytes.NewBufferString("")
+	if err = serverutil.MinifyTemplate(gctemplates.ManagePosterID, data, buf, "text/html"); err != nil {
+		logger.Err(err).Caller().
+			Str("template", gctemplates.ManagePosterID).Send()
+		return "", errors.New("unable to render poster ID page template")
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,109 @@
+package manage
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/building"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/events"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gctemplates"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/posting"
+	"github.com/gochan-org/gochan/pkg/posting/geoip"
+	"github.com/gochan-org/gochan/pkg/server/serverutil"
+	"github.com/rs/zerolog"
+)
+
+type reloadConfigJSON struct {
+	Message         string   `json:"message"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// ReloadConfig re-reads and validates gochan.json and the board configuration files and applies the new
+// configuration to the running subsystems (logging, minifier, GeoIP, CAPTCHA, templates, and consts.js). It is
+// used when gochan receives SIGHUP and by the reloadconfig manage page. It returns the names of settings that
+// were changed but require a restart to take effect. If the configuration is invalid, the running configuration
+// is left unchanged
+func ReloadConfig(logger zerolog.Logger) (restartRequired []string, err error) {
+	oldSiteCfg := *config.GetSiteConfig()
+	if restartRequired, err = config.ReloadConfig(); err != nil {
+		logger.Err(err).Caller().Msg("Unable to reload configuration")
+		return nil, err
+	}
+	systemCritical := config.GetSystemCriticalConfig()
+	siteCfg := config.GetSiteConfig()
+	gcutil.SetLogLevel(systemCritical.LogLevel())
+	serverutil.InitMinifier()
+
+	if oldSiteCfg.GeoIPType != siteCfg.GeoIPType || !reflect.DeepEqual(oldSiteCfg.GeoIPOptions, siteCfg.GeoIPOptions) {
+		if err = geoip.Close(); err != nil {
+			logger.Warn().Err(err).Caller().Msg("Unable to close previous GeoIP handler")
+		}
+		if err = geoip.SetupGeoIP(siteCfg.GeoIPType, siteCfg.GeoIPOptions); err != nil {
+			logger.Err(err).Caller().Str("GeoIPType", siteCfg.GeoIPType).Msg("Unable to initialize GeoIP")
+			return restartRequired, err
+		}
+	}
+	if err = posting.InitCaptcha(); err != nil {
+		ev := logger.Err(err).Caller()
+		if siteCfg.Captcha != nil {
+			ev.Str("CaptchaType", siteCfg.Captcha.Type)
+		}
+		ev.Msg("Unable to initialize CAPTCHA")
+		return restartRequired, err
+	}
+	if err = gctemplates.InitTemplates(); err != nil {
+		logger.Err(err).Caller().Msg("Unable to initialize templates")
+		return restartRequired, err
+	}
+	if err = building.BuildJS(); err != nil {
+		logger.Err(err).Caller().Msg("Failed building consts.js")
+		return restartRequired, err
+	}
+	if _, err, _ = events.TriggerEvent("config-reloaded"); err != nil {
+		logger.Err(err).Caller().Msg("Error running config-reloaded event handlers")
+		return restartRequired, err
+	}
+
+	ev := logger.Info()
+	if len(restartRequired) > 0 {
+		gcutil.LogArray("restartRequired", restartRequired, ev)
+	}
+	ev.Msg("Reloaded configuration")
+	return restartRequired, nil
+}
+
+// reloadConfigCallback handles requests to /manage/reloadconfig for reloading gochan.json and the board
+// configuration files without restarting gochan. GET requests show a form that submits a POST request to reload it
+func reloadConfigCallback(_ http.ResponseWriter, request *http.Request, _ *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	if request.Method != http.MethodPost && !wantsJSON {
+		return `<form action="` + config.WebPath("manage/reloadconfig") + `" method="post">` +
+			`<input name="reload" id="reload" type="submit" value="Reload configuration" />` +
+			`</form>`, nil
+	}
+	if err = CheckStateChangingRequest(request); err != nil {
+		logger.Warn().Err(err).Str("method", request.Method).Str("referer", request.Referer()).
+			Msg("Rejected configuration reload request")
+		return "", err
+	}
+	restartRequired, err := ReloadConfig(logger)
+	if err != nil {
+		return "", errors.New("unable to reload configuration: " + err.Error())
+	}
+	message := "Reloaded configuration successfully"
+	if wantsJSON {
+		return reloadConfigJSON{
+			Message:         message,
+			RestartRequired: restartRequired,
+		}, nil
+	}
+	if len(restartRequired) > 0 {
+		message += ". The following settings were changed but require a restart to take effect: " +
+			strings.Join(restartRequired, ", ")
+	}
+	return message, nil
+}
@@ -2,13 +2,18 @@ package manage
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"fmt"
+	"html"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Eggbertx/durationutil"
+	"github.com/gochan-org/gochan/pkg/building"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gctemplates"
@@ -21,24 +26,125 @@ import (
 type reportWithLink struct {
 	gcsql.PostReport
 	PostLink string `json:"post_link"`
+	// CategoryName is the display name of the report's category in the board's configuration
+	CategoryName string `json:"category_name,omitempty"`
+	Severity     int    `json:"severity"`
+	// ReportCount is the number of open reports of the reported post
+	ReportCount int `json:"report_count"`
+	// Notify is true if staff should be notified about the report
+	Notify bool `json:"notify"`
+}
+
+// postOverHideThreshold returns true if the post still has enough open reports in one of its board's report
+// categories to stay hidden
+func postOverHideThreshold(postID int, board string) (bool, error) {
+	for _, category := range config.GetBoardConfig(board).ReportCategories {
+		if category.HideAfter <= 0 {
+			continue
+		}
+		numReports, err := gcsql.CountPostReports(postID, category.ID)
+		if err != nil {
+			return false, err
+		}
+		if numReports >= category.HideAfter {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// unhidePostsOfReports unhides the hidden posts of the handled reports, since they have been reviewed by a moderator,
+// and rebuilds their boards. Posts that still have enough open reports in a category to be hidden stay hidden
+func unhidePostsOfReports(reports []gcsql.PostReport, errEv *zerolog.Event) error {
+	unhidden := make(map[int]bool)
+	var boardIDs []int
+	for _, report := range reports {
+		if !report.PostHidden || unhidden[report.PostID] {
+			continue
+		}
+		keepHidden, err := postOverHideThreshold(report.PostID, report.Board)
+		if err != nil {
+			errEv.Err(err).Caller().
+				Int("postID", report.PostID).
+				Msg("Unable to count open reports of reported post")
+			return server.NewServerError("failed to unhide reported post", http.StatusInternalServerError)
+		}
+		unhidden[report.PostID] = true
+		if keepHidden {
+			continue
+		}
+		if err = gcsql.SetPostHidden(report.PostID, false); err != nil {
+			errEv.Err(err).Caller().
+				Int("postID", report.PostID).
+				Msg("Unable to unhide reported post")
+			return server.NewServerError("failed to unhide reported post", http.StatusInternalServerError)
+		}
+		boardID, err := gcsql.GetBoardIDFromDir(report.Board)
+		if err != nil {
+			errEv.Err(err).Caller().
+				Str("board", report.Board).Send()
+			return server.NewServerError("failed to get board of reported post", http.StatusInternalServerError)
+		}
+		if !slices.Contains(boardIDs, boardID) {
+			boardIDs = append(boardIDs, boardID)
+		}
+	}
+	if len(boardIDs) == 0 {
+		return nil
+	}
+	if err := building.BuildBoards(false, boardIDs...); err != nil {
+		errEv.Err(err).Caller().Msg("Unable to rebuild boards after unhiding reported posts")
+		return server.NewServerError("failed to rebuild boards", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+// getReporterBan returns a report ban using the duration and reason submitted with the reports form, to be applied
+// to the IP address of each selected report's reporter
+func getReporterBan(request *http.Request, staff *gcsql.Staff, errEv *zerolog.Event) (*gcsql.ReportBan, error) {
+	now := time.Now()
+	ban := &gcsql.ReportBan{
+		StaffID:   &staff.ID,
+		IssuedAt:  now,
+		ExpiresAt: now,
+		Reason:    html.EscapeString(strings.TrimSpace(request.PostFormValue("reporter-ban-reason"))),
+	}
+	durationStr := strings.TrimSpace(request.PostFormValue("reporter-ban-duration"))
+	ban.Permanent = durationStr == ""
+	if !ban.Permanent {
+		duration, err := durationutil.ParseLongerDuration(durationStr)
+		if err != nil {
+			errEv.Err(err).Caller().
+				Str("duration", durationStr).
+				Msg("Invalid reporter ban duration")
+			return nil, server.NewServerError("invalid reporter ban duration", http.StatusBadRequest)
+		}
+		ban.ExpiresAt = now.Add(duration)
+	}
+	return ban, nil
 }
 
 func doReportHandling(request *http.Request, staff *gcsql.Staff, infoEv, errEv *zerolog.Event) error {
 	doDismissAll := request.PostFormValue("dismiss-all")
 	doDismissSel := request.PostFormValue("dismiss-sel")
 	doBlockSel := request.PostFormValue("block-sel")
+	doBanReporterSel := request.PostFormValue("ban-reporter-sel")
 
 	if doDismissAll != "" {
-		_, err := gcsql.Exec(nil, `UPDATE DBPREFIXreports SET is_cleared = 1`)
+		reports, err := gcsql.GetReports(false)
 		if err != nil {
 			errEv.Err(err).Caller().Send()
 			return err
 		}
+		if _, err = gcsql.Exec(nil, `UPDATE DBPREFIXreports SET is_cleared = 1`); err != nil {
+			errEv.Err(err).Caller().Send()
+			return err
+		}
 		infoEv.Msg("All reports dismissed")
-		return nil
+		return unhidePostsOfReports(reports, errEv)
 	}
 
-	if doDismissSel == "" && doBlockSel == "" {
+	if doDismissSel == "" && doBlockSel == "" && doBanReporterSel == "" {
 		return nil
 	}
 
@@ -73,6 +179,22 @@ func doReportHandling(request *http.Request, staff *gcsql.Staff, infoEv, errEv *
 	}
 	gcutil.LogArray("reportIDs", checkedReports, infoEv)
 
+	var reporterBan *gcsql.ReportBan
+	var err error
+	if doBanReporterSel != "" {
+		if reporterBan, err = getReporterBan(request, staff, errEv); err != nil {
+			return err
+		}
+	}
+
+	openReports, err := gcsql.GetReports(false)
+	if err != nil {
+		errEv.Err(err).Caller().Send()
+		return server.NewServerError("failed to get reports", http.StatusInternalServerError)
+	}
+
+	var handledReports []gcsql.PostReport
+	bannedReporters := make(map[string]bool)
 	for _, reportID := range checkedReports {
 		matched, err := gcsql.ClearReport(reportID, staff.ID, doBlockSel != "")
 		if !matched {
@@ -87,9 +209,36 @@ func doReportHandling(request *http.Request, staff *gcsql.Staff, infoEv, errEv *
 				Msg("failed to clear report")
 			return server.NewServerError(fmt.Sprintf("failed to clear report with id %d", reportID), http.StatusInternalServerError)
 		}
+		i := slices.IndexFunc(openReports, func(report gcsql.PostReport) bool {
+			return report.ID == reportID
+		})
+		if i < 0 {
+			continue
+		}
+		report := openReports[i]
+		handledReports = append(handledReports, report)
+
+		if reporterBan == nil || bannedReporters[report.ReporterIP] {
+			continue
+		}
+		ban := *reporterBan
+		ban.IP = report.ReporterIP
+		if err = gcsql.NewReportBan(&ban); err != nil {
+			errEv.Err(err).Caller().
+				Str("reporterIP", report.ReporterIP).
+				Msg("Unable to ban reporter")
+			return server.NewServerError("failed to ban reporter", http.StatusInternalServerError)
+		}
+		bannedReporters[report.ReporterIP] = true
+		gcutil.LogInfo().
+			Str("staff", staff.Username).
+			Str("reporterIP", report.ReporterIP).
+			Bool("permanent", ban.Permanent).
+			Time("expiresAt", ban.ExpiresAt).
+			Msg("Reporter banned from reporting posts")
 	}
 	infoEv.Msg("Reports dismissed")
-	return nil
+	return unhidePostsOfReports(handledReports, errEv)
 }
 
 func getReportsWithLinks() ([]reportWithLink, error) {
@@ -97,14 +246,36 @@ func getReportsWithLinks() ([]reportWithLink, error) {
 	if err != nil {
 		return nil, err
 	}
+	reportCounts := make(map[int]int)
+	for _, report := range reports {
+		reportCounts[report.PostID]++
+	}
+
 	var reportsWithLinks []reportWithLink
 	for _, report := range reports {
 		var reportData reportWithLink
 		reportData.PostReport = report
 		reportData.PostLink = config.WebPath(
 			report.Board, "res", strconv.Itoa(report.ThreadOP)+".html#"+strconv.Itoa(report.PostID))
+		reportData.ReportCount = reportCounts[report.PostID]
+		// uncategorized reports (and reports in categories that have been removed) always notify staff
+		reportData.Notify = true
+		if category := config.GetBoardConfig(report.Board).GetReportCategory(report.Category); category != nil {
+			reportData.CategoryName = category.Name
+			reportData.Severity = category.Severity
+			reportData.Notify = category.NotifyStaff
+		}
 		reportsWithLinks = append(reportsWithLinks, reportData)
 	}
+
+	// most severe and most reported posts first
+	slices.SortStableFunc(reportsWithLinks, func(a, b reportWithLink) int {
+		return cmp.Or(
+			cmp.Compare(b.Severity, a.Severity),
+			cmp.Compare(b.ReportCount, a.ReportCount),
+			cmp.Compare(a.PostID, b.PostID),
+		)
+	})
 	return reportsWithLinks, nil
 }
 
@@ -0,0 +1,41 @@
+package manage
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostOverHideThreshold(t *testing.T) {
+	config.InitTestConfig()
+	boardConfig := config.GetBoardConfig("test")
+	boardConfig.ReportCategories = []config.ReportCategory{
+		{ID: "spam", HideAfter: 3},
+		{ID: "rules"},
+		{ID: "illegal", HideAfter: 1},
+	}
+	if !assert.NoError(t, config.SetBoardConfig("test", boardConfig)) {
+		t.FailNow()
+	}
+	mock := gcsql.SetupMockDB(t, "sqlite3")
+	const countQuery = `SELECT COUNT\(\*\) FROM reports WHERE post_id = \? AND category = \? AND is_cleared = FALSE`
+
+	mock.ExpectPrepare(countQuery).ExpectQuery().WithArgs(1, "spam").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectPrepare(countQuery).ExpectQuery().WithArgs(1, "illegal").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	keepHidden, err := postOverHideThreshold(1, "test")
+	assert.NoError(t, err)
+	assert.False(t, keepHidden, "post below every category's threshold should be unhidden")
+
+	mock.ExpectPrepare(countQuery).ExpectQuery().WithArgs(2, "spam").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	keepHidden, err = postOverHideThreshold(2, "test")
+	assert.NoError(t, err)
+	assert.True(t, keepHidden, "post with enough open reports in a category should stay hidden")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
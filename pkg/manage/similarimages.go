@@ -0,0 +1,199 @@
+package manage
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gctemplates"
+	"github.com/gochan-org/gochan/pkg/posting/uploads"
+	"github.com/gochan-org/gochan/pkg/server"
+	"github.com/gochan-org/gochan/pkg/server/serverutil"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultSimilarImagesLimit = 50
+	maxSimilarImagesLimit     = 200
+)
+
+type similarImagesJSON struct {
+	Fingerprint string             `json:"fingerprint"`
+	Tolerance   int                `json:"tolerance"`
+	Similar     []similarImageJSON `json:"similar"`
+	FilterID    int                `json:"filterID,omitempty"`
+}
+
+type similarImageJSON struct {
+	PostID           int    `json:"postID"`
+	Board            string `json:"board"`
+	OriginalFilename string `json:"originalFilename"`
+	Filename         string `json:"filename"`
+	Checksum         string `json:"checksum"`
+	Fingerprint      string `json:"fingerprint"`
+	Distance         int    `json:"distance"`
+	URL              string `json:"url"`
+}
+
+// getFormInt returns the integer value of the form field, or defaultValue if the field is empty
+func getFormInt(request *http.Request, field string, defaultValue int) (int, error) {
+	valueStr := request.FormValue(field)
+	if valueStr == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(valueStr)
+}
+
+// banImageFingerprint creates a filter that rejects or bans uploads with a fingerprint within the given tolerance
+func banImageFingerprint(request *http.Request, staff *gcsql.Staff, fingerprint string, tolerance int, logger zerolog.Logger) (*gcsql.Filter, error) {
+	filter := &gcsql.Filter{
+		StaffID:     &staff.ID,
+		StaffNote:   request.PostFormValue("note"),
+		MatchAction: request.PostFormValue("action"),
+		MatchDetail: request.PostFormValue("detail"),
+		IsActive:    true,
+	}
+	if _, ok := filterActionsMap[filter.MatchAction]; !ok {
+		return nil, gcsql.ErrInvalidMatchAction
+	}
+	var boards []int
+	if boardIDStr := request.PostFormValue("boardid"); boardIDStr != "" {
+		boardID, err := strconv.Atoi(boardIDStr)
+		if err != nil {
+			logger.Err(err).Caller().Str("boardID", boardIDStr).Send()
+			return nil, err
+		}
+		boards = append(boards, boardID)
+	}
+	conditions := []gcsql.FilterCondition{{
+		Field:     "ahash",
+		MatchMode: gcsql.ExactMatch,
+		Search:    gcsql.FingerprintSearch(fingerprint, tolerance),
+	}}
+	if err := gcsql.ApplyFilter(filter, conditions, boards); err != nil {
+		logger.Err(err).Caller().
+			Str("fingerprint", fingerprint).
+			Int("tolerance", tolerance).
+			Msg("Unable to create image fingerprint filter")
+		return nil, err
+	}
+	logger.Info().
+		Int("filterID", filter.ID).
+		Str("fingerprint", fingerprint).
+		Int("tolerance", tolerance).
+		Str("action", filter.MatchAction).
+		Msg("Image fingerprint banned")
+	return filter, nil
+}
+
+func similarImagesCallback(_ http.ResponseWriter, request *http.Request, staff *gcsql.Staff, wantsJSON bool, logger zerolog.Logger) (output any, err error) {
+	tolerance, err := getFormInt(request, "tolerance", config.GetSiteConfig().SimilarImageTolerance)
+	if err != nil || tolerance < 0 {
+		return nil, server.NewServerError("invalid tolerance value", http.StatusBadRequest)
+	}
+	limit, err := getFormInt(request, "limit", defaultSimilarImagesLimit)
+	if err != nil || limit < 1 || limit > maxSimilarImagesLimit {
+		limit = defaultSimilarImagesLimit
+	}
+	data := map[string]any{
+		"tolerance": tolerance,
+		"limit":     limit,
+		"actions":   filterActionsMap,
+		"allBoards": gcsql.AllBoards,
+	}
+
+	var postID int
+	fingerprint := request.FormValue("fingerprint")
+	if postIDStr := request.FormValue("postid"); postIDStr != "" {
+		if postID, err = strconv.Atoi(postIDStr); err != nil {
+			logger.Err(err).Caller().Str("postID", postIDStr).Send()
+			return nil, server.NewServerError("invalid post ID", http.StatusBadRequest)
+		}
+		logger = logger.With().Int("postID", postID).Logger()
+		post, err := gcsql.GetPostFromID(postID, true)
+		if err != nil {
+			logger.Err(err).Caller().Msg("Unable to get post")
+			return nil, errors.New("unable to get post data from ID")
+		}
+		upload, err := post.GetUpload()
+		if err != nil {
+			logger.Err(err).Caller().Msg("Unable to get upload")
+			return nil, err
+		}
+		if upload == nil || upload.IsEmbed() || upload.Filename == "deleted" {
+			return nil, errors.New("post does not have an uploaded file")
+		}
+		if fingerprint, err = uploads.GetPostImageFingerprint(postID); err != nil {
+			logger.Err(err).Caller().Msg("Unable to get image fingerprint")
+			return nil, err
+		}
+		opID, boardDir, err := gcsql.GetTopPostAndBoardDirFromPostID(postID)
+		if err != nil {
+			logger.Err(err).Caller().Msg("Unable to get top post and board")
+			return nil, errors.New("unable to get top post and board")
+		}
+		data["sourcePost"] = &gcsql.SimilarUpload{Upload: *upload, BoardDir: boardDir, OpID: opID}
+	}
+	if fingerprint != "" {
+		if fingerprint, _, err = gcsql.ParseFingerprintSearch(fingerprint); err != nil {
+			return nil, server.NewServerError(err.Error(), http.StatusBadRequest)
+		}
+		data["fingerprint"] = fingerprint
+		logger = logger.With().Str("fingerprint", fingerprint).Logger()
+	}
+
+	if request.Method == http.MethodPost && request.PostFormValue("banimage") != "" {
+		if fingerprint == "" {
+			return nil, server.NewServerError("missing image fingerprint", http.StatusBadRequest)
+		}
+		filter, err := banImageFingerprint(request, staff, fingerprint, tolerance, logger)
+		if err != nil {
+			return nil, err
+		}
+		data["filterID"] = filter.ID
+	}
+
+	var similar []gcsql.SimilarUpload
+	if fingerprint != "" {
+		if similar, err = gcsql.GetSimilarUploads(fingerprint, tolerance, limit); err != nil {
+			logger.Err(err).Caller().Int("tolerance", tolerance).Msg("Unable to search for similar images")
+			return nil, errors.New("unable to search for similar images")
+		}
+	}
+	data["similar"] = similar
+
+	if wantsJSON {
+		similarJSON := similarImagesJSON{
+			Fingerprint: fingerprint,
+			Tolerance:   tolerance,
+			Similar:     make([]similarImageJSON, len(similar)),
+		}
+		if filterID, ok := data["filterID"].(int); ok {
+			similarJSON.FilterID = filterID
+		}
+		for s, upload := range similar {
+			similarJSON.Similar[s] = similarImageJSON{
+				PostID:           upload.PostID,
+				Board:            upload.BoardDir,
+				OriginalFilename: upload.OriginalFilename,
+				Filename:         upload.Filename,
+				Checksum:         upload.Checksum,
+				Fingerprint:      upload.Fingerprint,
+				Distance:         upload.Distance,
+				URL:              upload.WebPath(),
+			}
+		}
+		return similarJSON, nil
+	}
+
+	buf := bytes.NewBufferString("")
+	if err = serverutil.MinifyTemplate(gctemplates.ManageSimilarImages, data, buf, "text/html"); err != nil {
+		logger.Err(err).Caller().
+			Str("template", gctemplates.ManageSimilarImages).Send()
+		return "", errors.New("unable to render similar images page template")
+	}
+	return buf.String(), nil
+}
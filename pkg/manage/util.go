@@ -33,6 +33,20 @@ var (
 	}
 )
 
+// CheckStateChangingRequest returns an error if the request isn't a POST request from a page on the site. Manage
+// pages that change gochan's state call it before doing so, so that other sites can't make a logged in staff
+// member's browser do it with a link or a form
+func CheckStateChangingRequest(request *http.Request) error {
+	if request.Method != http.MethodPost {
+		return server.NewServerError("this action requires a POST request", http.StatusMethodNotAllowed)
+	}
+	refererResult, err := serverutil.CheckReferer(request)
+	if err != nil || refererResult != serverutil.InternalReferer {
+		return server.NewServerError("this action must be submitted from a page on this site", http.StatusForbidden)
+	}
+	return nil
+}
+
 func createSession(key, username, password string, request *http.Request, writer http.ResponseWriter) error {
 	domain := request.Host
 	infoEv, warnEv, errEv := gcutil.LogRequest(request)
@@ -100,6 +114,7 @@ func createSession(key, username, password string, request *http.Request, writer
 		Path:     systemCritical.WebRoot,
 		Domain:   domain,
 		Expires:  time.Now().Add(expirationDur),
+		Secure:   serverutil.IsSecureRequest(request),
 		SameSite: http.SameSiteStrictMode,
 	})
 
@@ -110,6 +125,7 @@ func createSession(key, username, password string, request *http.Request, writer
 			Msg("Error creating new staff session")
 		return ErrUnableToCreateSession
 	}
+	gcsql.StaffLoginEvent.Notify(&gcsql.StaffLoginPayload{Staff: staff, IP: gcutil.GetRealIP(request)})
 
 	return nil
 }
@@ -157,6 +173,14 @@ func dashboardCallback(_ http.ResponseWriter, _ *http.Request, staff *gcsql.Staf
 		rankString = "janitor"
 	}
 
+	var unreadAppeals int
+	if staff.Rank >= ModPerms {
+		if unreadAppeals, err = gcsql.GetUnreadAppealCount(); err != nil {
+			logger.Err(err).Caller().Msg("Unable to get the number of unread appeals")
+			return nil, err
+		}
+	}
+
 	availableActions := getAvailableActions(staff.Rank, true)
 	if err = serverutil.MinifyTemplate(gctemplates.ManageDashboard, map[string]any{
 		"actions":       availableActions,
@@ -164,6 +188,7 @@ func dashboardCallback(_ http.ResponseWriter, _ *http.Request, staff *gcsql.Staf
 		"rankString":    rankString,
 		"announcements": announcements,
 		"boards":        gcsql.AllBoards,
+		"unreadAppeals": unreadAppeals,
 	}, dashBuffer, "text/html"); err != nil {
 		logger.Err(err).Str("template", "manage_dashboard.html").Caller().Send()
 		return "", err
@@ -0,0 +1,307 @@
+// Package metrics provides counters, histograms, and gauges that can be written in the Prometheus text exposition
+// format, so that gochan's internals can be monitored without depending on the Prometheus client library
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContentType is the Content-Type of the output of WriteText
+	ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	labelValueSeparator = "\xff"
+)
+
+var (
+	// DefaultBuckets are the default histogram buckets, in seconds
+	DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	ErrWrongLabelCount = errors.New("wrong number of label values")
+
+	registryMutex sync.Mutex
+	registry      []collector
+	registered    = map[string]bool{}
+)
+
+// collector is a metric that can be written in the text exposition format
+type collector interface {
+	metricName() string
+	writeTo(w *bufio.Writer) error
+}
+
+// register adds c to the metrics written by WriteText. It panics if a metric with the same name was already
+// registered, since that is a programming error
+func register(c collector) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	name := c.metricName()
+	if registered[name] {
+		panic(fmt.Sprintf("metric %q is already registered", name))
+	}
+	registered[name] = true
+	registry = append(registry, c)
+}
+
+// WriteText writes all registered metrics to w in the Prometheus text exposition format, sorted by name. If a gauge
+// function returns an error, the gauge is left out and the error is returned after the other metrics are written
+func WriteText(w io.Writer) error {
+	registryMutex.Lock()
+	collectors := slices.Clone(registry)
+	registryMutex.Unlock()
+	slices.SortFunc(collectors, func(a, b collector) int {
+		return strings.Compare(a.metricName(), b.metricName())
+	})
+
+	bw := bufio.NewWriter(w)
+	var errs []error
+	for _, c := range collectors {
+		if err := c.writeTo(bw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.metricName(), err))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+type metricInfo struct {
+	name       string
+	help       string
+	labelNames []string
+}
+
+func (mi *metricInfo) metricName() string {
+	return mi.name
+}
+
+func (mi *metricInfo) writeHeader(w *bufio.Writer, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", mi.name, escapeHelp(mi.help), mi.name, metricType)
+}
+
+func (mi *metricInfo) seriesKey(labelValues []string) string {
+	if len(labelValues) != len(mi.labelNames) {
+		panic(fmt.Errorf("%w for %s (expected %d, got %d)", ErrWrongLabelCount, mi.name, len(mi.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, labelValueSeparator)
+}
+
+// labels returns the label set of a series in the text format, including the extra label if it isn't empty
+func (mi *metricInfo) labels(key string, extraName, extraValue string) string {
+	var pairs []string
+	if len(mi.labelNames) > 0 {
+		for i, value := range strings.Split(key, labelValueSeparator) {
+			pairs = append(pairs, mi.labelNames[i]+`="`+escapeLabelValue(value)+`"`)
+		}
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+escapeLabelValue(extraValue)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a set of counters with the same name, partitioned by the values of its labels
+type CounterVec struct {
+	metricInfo
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec registers and returns a new counter. Its name should end with _total
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	cv := &CounterVec{
+		metricInfo: metricInfo{name: name, help: help, labelNames: labelNames},
+		values:     map[string]float64{},
+	}
+	register(cv)
+	return cv
+}
+
+// Inc increments the counter with the given label values by 1
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+// Add adds v (which must not be negative) to the counter with the given label values
+func (cv *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("counter %s can't be decreased", cv.name))
+	}
+	key := cv.seriesKey(labelValues)
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.values[key] += v
+}
+
+// Value returns the current value of the counter with the given label values
+func (cv *CounterVec) Value(labelValues ...string) float64 {
+	key := cv.seriesKey(labelValues)
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return cv.values[key]
+}
+
+func (cv *CounterVec) writeTo(w *bufio.Writer) error {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.writeHeader(w, "counter")
+	for _, key := range sortedKeys(cv.values) {
+		fmt.Fprintf(w, "%s%s %s\n", cv.name, cv.labels(key, "", ""), formatFloat(cv.values[key]))
+	}
+	return nil
+}
+
+type histogramSeries struct {
+	// counts holds the number of observations in each bucket (not cumulative), with the last one being +Inf
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec is a set of histograms with the same name and buckets, partitioned by the values of its labels
+type HistogramVec struct {
+	metricInfo
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// NewHistogramVec registers and returns a new histogram. If buckets is nil, DefaultBuckets is used
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	hv := &HistogramVec{
+		metricInfo: metricInfo{name: name, help: help, labelNames: labelNames},
+		buckets:    buckets,
+		series:     map[string]*histogramSeries{},
+	}
+	register(hv)
+	return hv
+}
+
+// Observe adds v to the histogram with the given label values
+func (hv *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := hv.seriesKey(labelValues)
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	series, ok := hv.series[key]
+	if !ok {
+		series = &histogramSeries{counts: make([]uint64, len(hv.buckets)+1)}
+		hv.series[key] = series
+	}
+	b, _ := slices.BinarySearch(hv.buckets, v)
+	series.counts[b]++
+	series.count++
+	series.sum += v
+}
+
+// ObserveSince adds the number of seconds since start to the histogram with the given label values. It is meant
+// to be deferred, e.g. defer hv.ObserveSince(time.Now(), "value")
+func (hv *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	hv.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns the number of observations in the histogram with the given label values
+func (hv *HistogramVec) Count(labelValues ...string) uint64 {
+	key := hv.seriesKey(labelValues)
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	if series, ok := hv.series[key]; ok {
+		return series.count
+	}
+	return 0
+}
+
+func (hv *HistogramVec) writeTo(w *bufio.Writer) error {
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	hv.writeHeader(w, "histogram")
+	for _, key := range sortedKeys(hv.series) {
+		series := hv.series[key]
+		var cumulative uint64
+		for b, upperBound := range hv.buckets {
+			cumulative += series.counts[b]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, hv.labels(key, "le", formatFloat(upperBound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", hv.name, hv.labels(key, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", hv.name, hv.labels(key, "", ""), formatFloat(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", hv.name, hv.labels(key, "", ""), series.count)
+	}
+	return nil
+}
+
+// GaugeFunc is a gauge whose value is read by calling a function when the metrics are written
+type GaugeFunc struct {
+	metricInfo
+	fn func() (float64, error)
+}
+
+// NewGaugeFunc registers and returns a new gauge that gets its value from fn
+func NewGaugeFunc(name, help string, fn func() (float64, error)) *GaugeFunc {
+	gf := &GaugeFunc{
+		metricInfo: metricInfo{name: name, help: help},
+		fn:         fn,
+	}
+	register(gf)
+	return gf
+}
+
+func (gf *GaugeFunc) writeTo(w *bufio.Writer) error {
+	value, err := gf.fn()
+	if err != nil {
+		return err
+	}
+	gf.writeHeader(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", gf.name, formatFloat(value))
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCollector(t *testing.T, c collector) string {
+	t.Helper()
+	var builder strings.Builder
+	bw := bufio.NewWriter(&builder)
+	assert.NoError(t, c.writeTo(bw))
+	assert.NoError(t, bw.Flush())
+	return builder.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	cv := NewCounterVec("test_counter_total", "A test counter", "board")
+	cv.Inc("test")
+	cv.Add(2.5, "test")
+	cv.Inc(`quote"d`)
+	assert.Equal(t, 3.5, cv.Value("test"))
+	assert.Zero(t, cv.Value("missing"))
+	assert.Equal(t, `# HELP test_counter_total A test counter
+# TYPE test_counter_total counter
+test_counter_total{board="quote\"d"} 1
+test_counter_total{board="test"} 3.5
+`, writeCollector(t, cv))
+
+	assert.Panics(t, func() {
+		cv.Inc()
+	}, "the number of label values must match the number of labels")
+	assert.Panics(t, func() {
+		cv.Add(-1, "test")
+	}, "counters can't be decreased")
+	assert.Panics(t, func() {
+		NewCounterVec("test_counter_total", "Duplicate counter")
+	}, "metric names must be unique")
+}
+
+func TestHistogramVec(t *testing.T) {
+	hv := NewHistogramVec("test_duration_seconds", "A test histogram", []float64{1, 0.1}, "page")
+	hv.Observe(0.0625, "front")
+	hv.Observe(0.1, "front")
+	hv.Observe(0.5, "front")
+	hv.Observe(3, "front")
+	hv.ObserveSince(time.Now(), "catalog")
+	assert.EqualValues(t, 4, hv.Count("front"))
+	assert.EqualValues(t, 1, hv.Count("catalog"))
+	output := writeCollector(t, hv)
+	assert.Contains(t, output, `# HELP test_duration_seconds A test histogram
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{page="catalog",le="0.1"} 1
+test_duration_seconds_bucket{page="catalog",le="1"} 1
+test_duration_seconds_bucket{page="catalog",le="+Inf"} 1
+`)
+	assert.Contains(t, output, `test_duration_seconds_bucket{page="front",le="0.1"} 2
+test_duration_seconds_bucket{page="front",le="1"} 3
+test_duration_seconds_bucket{page="front",le="+Inf"} 4
+test_duration_seconds_sum{page="front"} 3.6625
+test_duration_seconds_count{page="front"} 4
+`)
+}
+
+func TestGaugeFunc(t *testing.T) {
+	var err error
+	gf := NewGaugeFunc("test_gauge", "A test gauge\nwith a newline", func() (float64, error) {
+		return 5, err
+	})
+	assert.Equal(t, `# HELP test_gauge A test gauge\nwith a newline
+# TYPE test_gauge gauge
+test_gauge 5
+`, writeCollector(t, gf))
+
+	err = errors.New("not connected")
+	var builder strings.Builder
+	bw := bufio.NewWriter(&builder)
+	assert.ErrorIs(t, gf.writeTo(bw), err)
+	bw.Flush()
+	assert.Empty(t, builder.String())
+}
+
+func TestWriteText(t *testing.T) {
+	gaugeErr := errors.New("gauge error")
+	NewGaugeFunc("test_write_text_failing", "A failing gauge", func() (float64, error) {
+		return 0, gaugeErr
+	})
+	cv := NewCounterVec("test_write_text_total", "A counter without labels")
+	cv.Inc()
+	NewCounterVec("test_write_text_another_total", "Another counter")
+
+	var builder strings.Builder
+	err := WriteText(&builder)
+	assert.ErrorIs(t, err, gaugeErr)
+	output := builder.String()
+	assert.Contains(t, output, "test_write_text_total 1\n")
+	assert.NotContains(t, output, "test_write_text_failing")
+	// metrics are sorted by name
+	assert.Less(t, strings.Index(output, "test_write_text_another_total"), strings.Index(output, "test_write_text_total"))
+}
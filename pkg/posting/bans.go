@@ -2,12 +2,12 @@ package posting
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/Eggbertx/go-forms"
-	"github.com/gochan-org/gochan/pkg/building"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gctemplates"
@@ -17,31 +17,64 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func showBanpage(ban *gcsql.IPBan, post *gcsql.Post, postBoard *gcsql.Board, writer http.ResponseWriter, _ *http.Request) {
-	banPageBuffer := bytes.NewBufferString("")
-	err := serverutil.MinifyTemplate(gctemplates.BanPage, map[string]any{
+// getBanPageAppealData adds the ban's latest appeal and its conversation to the ban page data, along with whether the
+// banned user can submit a new appeal
+func getBanPageAppealData(ban *gcsql.IPBan, data map[string]any) error {
+	appeals, err := gcsql.GetAppeals(gcsql.AppealsQueryOptions{
+		BanID:           ban.ID,
+		OrderDescending: true,
+		Limit:           1,
+	})
+	if err != nil {
+		return err
+	}
+	var appeal *gcsql.Appeal
+	if len(appeals) > 0 {
+		appeal = &appeals[0]
+		if data["appealMessages"], err = gcsql.GetAppealMessages(appeal.ID); err != nil {
+			return err
+		}
+	}
+	data["appeal"] = appeal
+	// the ban's appeal_at value is used as the cooldown for new appeals, and is pushed back when staff deny an appeal
+	appealLater := ban.AppealAt.After(time.Now())
+	noOpenAppeal := appeal == nil || appeal.Status.IsResolved()
+	data["appealLater"] = ban.CanAppeal && appealLater && noOpenAppeal
+	data["canAppeal"] = ban.CanAppeal && !appealLater && noOpenAppeal
+	return nil
+}
+
+func showBanpage(ban *gcsql.IPBan, ip string, postBoard *gcsql.Board, writer http.ResponseWriter, notice string) {
+	data := map[string]any{
 		"systemCritical": config.GetSystemCriticalConfig(),
 		"siteConfig":     config.GetSiteConfig(),
 		"boardConfig":    config.GetBoardConfig(postBoard.Dir),
-		"ip":             post.IP,
+		"ip":             ip,
 		"ban":            ban,
 		"board":          postBoard,
 		"permanent":      ban.Permanent,
 		"expires":        ban.ExpiresAt,
-	}, banPageBuffer, "text/html")
+		"appealNotice":   notice,
+	}
+	if err := getBanPageAppealData(ban, data); err != nil {
+		gcutil.LogError(err).Caller().
+			Str("IP", ip).
+			Int("banID", ban.ID).
+			Msg("Unable to get ban appeal info")
+		server.ServeErrorPage(writer, "Error getting ban appeal info: "+err.Error())
+		return
+	}
+	banPageBuffer := bytes.NewBufferString("")
+	err := serverutil.MinifyTemplate(gctemplates.BanPage, data, banPageBuffer, "text/html")
 	if err != nil {
 		gcutil.LogError(err).
-			Str("IP", post.IP).
+			Str("IP", ip).
 			Str("building", "minifier").
 			Str("template", "banpage.html").Send()
 		server.ServeErrorPage(writer, "Error minifying page: "+err.Error())
 		return
 	}
 	writer.Write(banPageBuffer.Bytes())
-	gcutil.LogWarning().
-		Str("IP", post.IP).
-		Str("boardDir", postBoard.Dir).
-		Msg("Rejected post from banned IP")
 }
 
 // checks the post IP against the IP range ban list. It returns true if a ban page or an error page was served (causing MakePost() to return)
@@ -59,13 +92,20 @@ func checkIpBan(post *gcsql.Post, postBoard *gcsql.Board, writer http.ResponseWr
 		return false // ip is not banned and there were no errors, keep going
 	}
 	// IP is banned
-	showBanpage(ipBan, post, postBoard, writer, request)
+	postsRejected.Inc("ban")
+	showBanpage(ipBan, post.IP, postBoard, writer, "")
+	gcutil.LogWarning().
+		Str("IP", post.IP).
+		Str("boardDir", postBoard.Dir).
+		Msg("Rejected post from banned IP")
 	return true
 }
 
 type appealForm struct {
-	BanID         int    `form:"banid,required" method:"POST"`
-	BoardID       int    `form:"boardid,required" method:"POST"`
+	BanID   int `form:"banid,required" method:"POST"`
+	BoardID int `form:"boardid,required" method:"POST"`
+	// AppealID is set if the banned user is replying to an appeal that is still open, instead of submitting a new one
+	AppealID      int    `form:"appealid" method:"POST"`
 	AppealMessage string `form:"appealmsg,required,notempty" method:"POST"`
 	DoAppeal      string `form:"doappeal,required,notempty" method:"POST"`
 }
@@ -108,10 +148,12 @@ func handleAppeal(writer http.ResponseWriter, request *http.Request, infoEv *zer
 	gcutil.LogStr("rangeStart", ban.RangeStart, infoEv, errEv)
 	gcutil.LogStr("rangeEnd", ban.RangeEnd, infoEv, errEv)
 
-	isCorrectIP, err := ban.IsBanned(gcutil.GetRealIP(request))
+	ip := gcutil.GetRealIP(request)
+	isCorrectIP, err := ban.IsBanned(ip)
 	if err != nil {
 		errEv.Err(err).Caller().Send()
 		server.ServeErrorPage(writer, err.Error())
+		return
 	}
 	if !isCorrectIP {
 		errEv.Caller().
@@ -124,9 +166,41 @@ func handleAppeal(writer http.ResponseWriter, request *http.Request, infoEv *zer
 		server.ServeErrorPage(writer, "Requested ban is not active")
 		return
 	}
+
+	if form.AppealID > 0 {
+		// replying to an appeal
+		gcutil.LogInt("appealID", form.AppealID, infoEv, errEv)
+		appeal, err := gcsql.GetAppealByID(form.AppealID)
+		if errors.Is(err, gcsql.ErrAppealDoesNotExist) || (err == nil && appeal.IPBanID != ban.ID) {
+			errEv.Caller().Msg("Rejected reply to an appeal that doesn't belong to the ban")
+			server.ServeErrorPage(writer, fmt.Sprintf("Invalid appeal id: %d", form.AppealID))
+			return
+		} else if err != nil {
+			errEv.Err(err).Caller().Msg("Unable to get appeal")
+			server.ServeErrorPage(writer, "Error getting appeal info")
+			return
+		}
+		if appeal.Status.IsResolved() {
+			errEv.Caller().
+				Str("status", string(appeal.Status)).
+				Msg("Rejected reply to a closed appeal")
+			server.ServeErrorPage(writer, "This appeal has already been "+string(appeal.Status))
+			return
+		}
+		if err = gcsql.AddAppealMessage(appeal.ID, 0, form.AppealMessage); err != nil {
+			errEv.Err(err).Caller().Msg("Unable to submit appeal reply")
+			server.ServeErrorPage(writer, "Unable to submit reply")
+			return
+		}
+		infoEv.Msg("Appeal reply submitted")
+		showBanpage(ban, ip, board, writer, "Your reply has been sent.")
+		return
+	}
+
 	if !ban.CanAppeal {
 		errEv.Caller().Msg("Rejected appeal submission, appeals denied for this ban")
 		server.ServeErrorPage(writer, "You can not appeal this ban")
+		return
 	}
 	boardCfg := config.GetBoardConfig(board.Dir)
 	if ban.AppealAt.After(time.Now()) {
@@ -134,6 +208,24 @@ func handleAppeal(writer http.ResponseWriter, request *http.Request, infoEv *zer
 			Time("appealAt", ban.AppealAt).
 			Msg("Rejected appeal submission, can't appeal yet")
 		server.ServeErrorPage(writer, "You are not able to appeal this ban until "+ban.AppealAt.Format(boardCfg.DateTimeFormat))
+		return
+	}
+	openAppeals, err := gcsql.GetAppeals(gcsql.AppealsQueryOptions{
+		BanID:    ban.ID,
+		Resolved: gcsql.OnlyFalse,
+		Limit:    1,
+	})
+	if err != nil {
+		errEv.Err(err).Caller().Msg("Unable to check for open appeals")
+		server.ServeErrorPage(writer, "Error getting appeal info")
+		return
+	}
+	if len(openAppeals) > 0 {
+		errEv.Caller().
+			Int("openAppealID", openAppeals[0].ID).
+			Msg("Rejected appeal submission, ban already has an open appeal")
+		server.ServeErrorPage(writer, "You already have an open appeal for this ban")
+		return
 	}
 	if err = ban.Appeal(form.AppealMessage); err != nil {
 		errEv.Err(err).Caller().
@@ -144,29 +236,5 @@ func handleAppeal(writer http.ResponseWriter, request *http.Request, infoEv *zer
 	}
 
 	infoEv.Msg("Appeal submitted")
-
-	writer.WriteHeader(http.StatusOK)
-
-	var buf bytes.Buffer
-	if err = building.BuildPageHeader(&buf, "Appeal Submitted", board.Dir, map[string]any{
-		"siteConfig":  config.GetSiteConfig(),
-		"boardConfig": boardCfg,
-	}); err != nil {
-		err = server.NewServerError(err, http.StatusInternalServerError)
-		errEv.Err(err).Caller().Msg("Error building page header")
-		server.ServeErrorPage(writer, "Error building page header: "+err.Error())
-		return
-	}
-	buf.WriteString(`<br><div class="text-center">Your appeal has been submitted and will be reviewed.</div><br>`)
-
-	if err = building.BuildPageFooter(&buf); err != nil {
-		err = server.NewServerError(err, http.StatusInternalServerError)
-		errEv.Err(err).Caller().Msg("Error building page footer")
-		server.ServeErrorPage(writer, "Error building page footer: "+err.Error())
-		return
-	}
-
-	writer.Write(buf.Bytes())
-
-	// http.Redirect(writer, request, config.WebPath(request.FormValue("board")), http.StatusFound)
+	showBanpage(ban, ip, board, writer, "Your appeal has been submitted and will be reviewed.")
 }
@@ -142,8 +142,8 @@ func embedTestRunner(t *testing.T, tc *embedTestCase, boardCfg *config.BoardConf
 			ExpectQuery().WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"COALESCE(MAX(file_order) + 1, 0)"}).AddRow(1))
 		mock.ExpectPrepare(`INSERT INTO files\s+` +
-			`\(\s*post_id, file_order, original_filename, filename, checksum, file_size, is_spoilered, thumbnail_width, thumbnail_height, width, height\)\s*` +
-			`VALUES\(\?,\?,\?,\?,\?,\?,\?,\?,\?,\?,\?\)`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
+			`\(\s*post_id, file_order, original_filename, filename, checksum, stored_checksum, file_size, is_spoilered, thumbnail_width, thumbnail_height, width, height\)\s*` +
+			`VALUES\(\?,\?,\?,\?,\?,\?,\?,\?,\?,\?,\?,\?\)`).ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectPrepare(`SELECT MAX\(id\) FROM files`).
 			ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"MAX(id)"}).AddRow(99))
 		mock.ExpectCommit()
@@ -25,10 +25,9 @@ var (
 	ErrWorksafeBoard = errors.New("this board does not allow NSFW content")
 )
 
-// InitPosting prepares the formatter and the temp post pruner
+// InitPosting prepares the formatter
 func InitPosting() {
 	msgfmtr.Init()
-	go tempCleaner()
 }
 
 type MessageFormatter struct {
@@ -62,6 +62,7 @@ func RegisterGeoIPHandler(id string, cb GeoIPHandler) error {
 func SetupGeoIP(id string, options map[string]any) (err error) {
 	if id == "" {
 		// not using GeoIP
+		activeHandler = nil
 		return nil
 	}
 	var ok bool
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
 	"github.com/rs/zerolog"
 	lua "github.com/yuin/gopher-lua"
 	luar "layeh.com/gopher-luar"
@@ -24,15 +25,11 @@ func (lh *luaHandler) Init(options map[string]any) error {
 	for k, v := range options {
 		optionsT.RawSetString(k, luar.New(lh.lState, v))
 	}
-	p := lua.P{
-		Fn:   lh.initFunc,
-		NRet: 1,
-	}
-	err := lh.lState.CallByParam(p, optionsT)
+	rets, err := luautil.CallFunction(lh.lState, lh.initFunc, 1, true, optionsT)
 	if err != nil {
 		return err
 	}
-	errStr := lua.LVAsString(lh.lState.Get(-1))
+	errStr := lua.LVAsString(rets[0])
 	if errStr != "" {
 		return errors.New(errStr)
 	}
@@ -40,23 +37,15 @@ func (lh *luaHandler) Init(options map[string]any) error {
 }
 
 func (lh *luaHandler) GetCountry(request *http.Request, board string, errEv *zerolog.Event) (*Country, error) {
-	p := lua.P{
-		Fn:   lh.getCountryFunc,
-		NRet: 2,
-	}
-	err := lh.lState.CallByParam(p,
-		luar.New(lh.lState, request),
-		lua.LString(board),
-		luar.New(lh.lState, errEv))
-
+	rets, err := luautil.CallFunction(lh.lState, lh.getCountryFunc, 2, true, request, board, errEv)
 	if err != nil {
 		return nil, err
 	}
-	countryTable, ok := lh.lState.Get(-2).(*lua.LTable)
+	countryTable, ok := rets[0].(*lua.LTable)
 	if !ok {
 		return nil, errors.New("invalid value returned by get_country (expected table)")
 	}
-	errStr := lua.LVAsString(lh.lState.Get(-1))
+	errStr := lua.LVAsString(rets[1])
 	if errStr != "" {
 		return nil, errors.New(errStr)
 	}
@@ -78,15 +67,11 @@ func (lh *luaHandler) Close() error {
 	if lh.closeFunc == lua.LNil {
 		return nil
 	}
-	p := lua.P{
-		Fn:   lh.closeFunc,
-		NRet: 1,
-	}
-	err := lh.lState.CallByParam(p)
+	rets, err := luautil.CallFunction(lh.lState, lh.closeFunc, 1, true)
 	if err != nil {
 		return err
 	}
-	errStr := lua.LVAsString(lh.lState.Get(-1))
+	errStr := lua.LVAsString(rets[0])
 	if errStr != "" {
 		return errors.New(errStr)
 	}
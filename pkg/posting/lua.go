@@ -4,9 +4,9 @@ import (
 	"fmt"
 
 	"github.com/frustra/bbcode"
+	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
 	"github.com/gochan-org/gochan/pkg/gcutil"
 	lua "github.com/yuin/gopher-lua"
-	luar "layeh.com/gopher-luar"
 )
 
 func luaTableToHTMLTag(l *lua.LState, table *lua.LTable) (*bbcode.HTMLTag, error) {
@@ -37,14 +37,17 @@ func luaTableToHTMLTag(l *lua.LState, table *lua.LTable) (*bbcode.HTMLTag, error
 		childrenT := childrenLV.(*lua.LTable)
 		if childrenT.Len() > 0 {
 			tag.Children = make([]*bbcode.HTMLTag, childrenT.Len())
-			childrenT.ForEach(func(iLV, childLV lua.LValue) {
-				childT, err := luaTableToHTMLTag(l, childLV.(*lua.LTable))
+			for i := 1; i <= childrenT.Len(); i++ {
+				childLT, ok := childrenT.RawGetInt(i).(*lua.LTable)
+				if !ok {
+					return nil, fmt.Errorf("expected table for child %d, got %s", i, childrenT.RawGetInt(i).Type().String())
+				}
+				childT, err := luaTableToHTMLTag(l, childLT)
 				if err != nil {
-					l.RaiseError("Error converting child table to HTMLTag: %v", err)
-					return
+					return nil, fmt.Errorf("error converting child table to HTMLTag: %w", err)
 				}
-				tag.Children[int(iLV.(lua.LNumber)-1)] = childT
-			})
+				tag.Children[i-1] = childT
+			}
 		}
 	case lua.LTNil:
 	default:
@@ -65,26 +68,23 @@ func PreloadBBCodeModule(l *lua.LState) int {
 			}
 			bbcodeFunc := l.CheckFunction(2)
 			msgfmtr.bbCompiler.SetTag(bbcodeTag, func(node *bbcode.BBCodeNode) (*bbcode.HTMLTag, bool) {
-				err := l.CallByParam(lua.P{
-					Fn:   bbcodeFunc,
-					NRet: 2,
-				}, luar.New(l, node))
+				// the tag is compiled while a post is being formatted, outside of the plugin's Lua code, so errors are
+				// logged and the tag is left as it would be without the plugin instead of being raised in the Lua state
+				rets, err := luautil.CallFunction(l, bbcodeFunc, 2, true, node)
 				if err != nil {
-					gcutil.LogError(err).Caller().Msg("Error calling bbcode function")
-					l.RaiseError("Error calling bbcode function: %v", err)
-					return nil, false
+					gcutil.LogError(err).Caller().Str("tag", bbcodeTag).Msg("Error calling bbcode function")
+					return bbcode.DefaultTagCompiler(node)
 				}
-				tagRet := l.CheckAny(-2)
-				if tagRet.Type() != lua.LTTable {
-					l.RaiseError("Invalid return value from bbcode function (expected table)")
-					return nil, false
+				tagTable, ok := rets[0].(*lua.LTable)
+				if !ok {
+					gcutil.LogError(nil).Caller().Str("tag", bbcodeTag).
+						Msg("Invalid return value from bbcode function (expected table)")
+					return bbcode.DefaultTagCompiler(node)
 				}
-				tagTable := tagRet.(*lua.LTable)
 				tag, err := luaTableToHTMLTag(l, tagTable)
 				if err != nil {
-					gcutil.LogError(err).Caller().Msg("Error converting table to HTMLTag")
-					l.RaiseError("Error converting table to HTMLTag: %v", err)
-					return nil, false
+					gcutil.LogError(err).Caller().Str("tag", bbcodeTag).Msg("Error converting table to HTMLTag")
+					return bbcode.DefaultTagCompiler(node)
 				}
 				return tag, true
 			})
@@ -1,7 +1,6 @@
 package posting
 
 import (
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
@@ -19,6 +18,7 @@ import (
 	"github.com/gochan-org/gochan/pkg/events"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
 	"github.com/gochan-org/gochan/pkg/posting/geoip"
 	"github.com/gochan-org/gochan/pkg/posting/uploads"
 	"github.com/gochan-org/gochan/pkg/server"
@@ -34,6 +34,11 @@ const (
 var (
 	ErrorPostTooLong = errors.New("post is too long")
 	ErrInvalidFlag   = errors.New("invalid selected flag")
+
+	postsCreated = metrics.NewCounterVec("gochan_posts_created_total", "Number of posts created, by board", "board")
+	// postsRejected counts posts rejected because of the post cooldown, a filter, a missing or invalid captcha,
+	// a ban, or a bad referer
+	postsRejected = metrics.NewCounterVec("gochan_posts_rejected_total", "Number of posts rejected, by reason", "reason")
 )
 
 func attachFlag(request *http.Request, post *gcsql.Post, board string, errEv *zerolog.Event) error {
@@ -115,6 +120,7 @@ func HandleFilterAction(filter *gcsql.Filter, post *gcsql.Post, upload *gcsql.Up
 	}
 	switch filter.MatchAction {
 	case "reject":
+		postsRejected.Inc("filter")
 		gcutil.LogWarning().
 			Str("ip", post.IP).
 			Str("userAgent", request.UserAgent()).
@@ -142,21 +148,25 @@ func setCookies(writer http.ResponseWriter, request *http.Request) {
 			Msg("Unable to parse configured cookie max age duration")
 		maxAge = yearInSeconds
 	}
+	secure := serverutil.IsSecureRequest(request)
 
 	http.SetCookie(writer, &http.Cookie{
 		Name:   "email",
 		Value:  url.QueryEscape(request.PostFormValue("postemail")),
 		MaxAge: int(maxAge),
+		Secure: secure,
 	})
 	http.SetCookie(writer, &http.Cookie{
 		Name:   "name",
 		Value:  url.QueryEscape(request.PostFormValue("postname")),
 		MaxAge: int(maxAge),
+		Secure: secure,
 	})
 	http.SetCookie(writer, &http.Cookie{
 		Name:   "password",
 		Value:  url.QueryEscape(request.PostFormValue("postpassword")),
 		MaxAge: int(maxAge),
+		Secure: secure,
 	})
 }
 
@@ -196,11 +206,7 @@ func ParseName(name string, boardConfig *config.BoardConfig) (string, string) {
 		if secure && reserved {
 			tripcodePart = reservedTrip
 		} else if secure {
-			hash := gcutil.Md5Sum(tripcodePart + config.GetSystemCriticalConfig().RandomSeed)
-			for range 64 {
-				hash = gcutil.Md5Sum(hash + name)
-			}
-			tripcodePart = base64.StdEncoding.EncodeToString([]byte(hash))[:10]
+			tripcodePart = secureTripcode(tripcodePart, name)
 		} else {
 			tripcodePart = tripcode.Tripcode(tripcodePart)
 		}
@@ -235,15 +241,34 @@ func getPostFromRequest(request *http.Request, boardConfig *config.BoardConfig,
 			}
 		}
 	}
-	post.Name, post.Tripcode = ParseName(request.PostFormValue("postname"), boardConfig)
-	post.IsSecureTripcode = strings.Contains(request.PostFormValue("postname"), "##")
+	formName := request.PostFormValue("postname")
+	if strings.Contains(formName, "##") {
+		staff, err := gcsql.GetStaffFromRequest(request)
+		if err != nil {
+			errEv.Err(err).Caller().Msg("Unable to get staff info")
+			return nil, errors.New("unable to get staff info")
+		}
+		var capcode string
+		if post.Name, capcode = ParseCapcode(formName, staff); capcode != "" {
+			gcutil.LogStr("capcode", capcode, infoEv, errEv)
+			post.Tripcode = capcode
+			post.IsRoleSignature = true
+		}
+	}
+	if !post.IsRoleSignature {
+		post.Name, post.Tripcode = ParseName(formName, boardConfig)
+		post.IsSecureTripcode = strings.Contains(formName, "##")
+	}
 	post.Email, _ = getEmailAndCommand(request)
 
 	password := request.PostFormValue("postpassword")
 	if password == "" {
 		password = gcutil.RandomString(12)
 	}
-	post.Password = gcutil.Md5Sum(password)
+	if post.Password, err = gcsql.HashPostPassword(password); err != nil {
+		errEv.Err(err).Caller().Msg("Unable to hash post password")
+		return nil, errors.New("unable to hash post password")
+	}
 	return
 }
 
@@ -337,6 +362,7 @@ func MakePost(writer http.ResponseWriter, request *http.Request) {
 	}
 	if refererResult != serverutil.InternalReferer {
 		// post has no referrer, or has a referrer from a different domain, probably a spambot
+		postsRejected.Inc("referer")
 		gcutil.LogWarning().
 			Str("spam", "badReferer").
 			Str("IP", gcutil.GetRealIP(request)).
@@ -453,6 +479,7 @@ func MakePost(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 	if tooSoon {
+		postsRejected.Inc("cooldown")
 		errEv.Int("delay", delay).Msg("Rejecting post (user must wait before making another post)")
 		server.ServeError(writer, "Please wait before making a new post", wantsJSON, nil)
 		return
@@ -469,6 +496,7 @@ func MakePost(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 	if !captchaSuccess {
+		postsRejected.Inc("captcha")
 		server.ServeError(writer, "Missing or invalid captcha response", wantsJSON, nil)
 		warnEv.Msg("Missing or invalid captcha response")
 		return
@@ -575,6 +603,7 @@ func MakePost(writer http.ResponseWriter, request *http.Request) {
 		})
 		return
 	}
+	postsCreated.Inc(board.Dir)
 	if upload != nil && !upload.IsEmbed() {
 		if err = config.TakeOwnership(filePath); err != nil {
 			errEv.Err(err).Caller().
@@ -625,6 +654,11 @@ func MakePost(writer http.ResponseWriter, request *http.Request) {
 				server.ServeError(writer, "Unable to prune post from cyclic thread", wantsJSON, nil)
 				return
 			}
+			gcsql.PostDeletedEvent.Notify(&gcsql.PostDeletedPayload{
+				PostID:   prunePost.PostID,
+				ThreadID: prunePost.ThreadID,
+				BoardDir: prunePost.Dir,
+			})
 			if prunePost.Filename != "" && prunePost.Filename != "deleted" && !strings.HasPrefix(prunePost.Filename, "embed:") {
 				prunePostFile := path.Join(documentRoot, prunePost.Dir, "src", prunePost.Filename)
 				prunePostThumbName, _ := uploads.GetThumbnailFilenames(prunePost.Filename)
@@ -653,6 +687,12 @@ func MakePost(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	created := &gcsql.PostCreatedPayload{Post: post, Board: board}
+	gcsql.PostCreatedEvent.Notify(created)
+	if post.IsTopPost {
+		gcsql.ThreadCreatedEvent.Notify(created)
+	}
+
 	if wantsJSON {
 		topPost, _ := post.TopPostID()
 		server.ServeJSON(writer, map[string]any{
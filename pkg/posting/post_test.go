@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,3 +66,67 @@ func TestParseName(t *testing.T) {
 		})
 	}
 }
+
+func TestSecureTripcodeModes(t *testing.T) {
+	config.InitTestConfig()
+	boardConfig := config.GetBoardConfig("test")
+	config.SetRandomSeed("lol")
+	systemCritical := config.GetSystemCriticalConfig()
+	defer config.SetSystemCriticalConfig(systemCritical)
+
+	kdfConfig := *systemCritical
+	kdfConfig.SecureTripcodeMode = config.SecureTripcodeKDF
+	kdfConfig.TripcodeSecret = "secret"
+	config.SetSystemCriticalConfig(&kdfConfig)
+	_, trip := ParseName("Name##notReserved", boardConfig)
+	assert.Len(t, trip, 10)
+	assert.NotEqual(t, "MGU5NDdiYm", trip)
+	_, otherNameTrip := ParseName("Other##notReserved", boardConfig)
+	assert.Equal(t, trip, otherNameTrip, "secure tripcodes shouldn't depend on the name")
+
+	config.SetRandomSeed("changed")
+	_, sameTrip := ParseName("Name##notReserved", boardConfig)
+	assert.Equal(t, trip, sameTrip, "changing RandomSeed shouldn't change secure tripcodes")
+
+	kdfConfig.TripcodeSecret = "other secret"
+	config.SetSystemCriticalConfig(&kdfConfig)
+	_, otherSecretTrip := ParseName("Name##notReserved", boardConfig)
+	assert.NotEqual(t, trip, otherSecretTrip)
+
+	legacyConfig := kdfConfig
+	legacyConfig.SecureTripcodeMode = config.SecureTripcodeLegacy
+	legacyConfig.RandomSeed = "lol"
+	config.SetSystemCriticalConfig(&legacyConfig)
+	_, trip = ParseName("Name##notReserved", boardConfig)
+	assert.Equal(t, "MGU5NDdiYm", trip, "legacy mode should keep existing secure tripcodes")
+}
+
+func TestParseCapcode(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		name            string
+		rank            int
+		expectedName    string
+		expectedCapcode string
+	}{
+		{desc: "not staff", name: "Name ## Mod", rank: 0, expectedName: "Name ## Mod"},
+		{desc: "moderator", name: "Name ## Mod", rank: 2, expectedName: "Name", expectedCapcode: "mod"},
+		{desc: "case and alias", name: "##ADMINISTRATOR", rank: 3, expectedCapcode: "admin"},
+		{desc: "rank too low", name: "Name ## Admin", rank: 2, expectedName: "Name ## Admin"},
+		{desc: "lower capcode", name: "Name ## Janitor", rank: 3, expectedName: "Name", expectedCapcode: "janitor"},
+		{desc: "secure tripcode", name: "Name##password", rank: 3, expectedName: "Name##password"},
+		{desc: "no capcode", name: "Name", rank: 3, expectedName: "Name"},
+		{desc: "tripcode and capcode", name: "Name#trip##Mod", rank: 2, expectedName: "Name", expectedCapcode: "mod"},
+		{desc: "secure tripcode and capcode", name: "Name ##password ## Mod", rank: 2, expectedName: "Name", expectedCapcode: "mod"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			name, capcode := ParseCapcode(tc.name, &gcsql.Staff{Rank: tc.rank})
+			assert.Equal(t, tc.expectedName, name)
+			assert.Equal(t, tc.expectedCapcode, capcode)
+		})
+	}
+	name, capcode := ParseCapcode("Name ## Mod", nil)
+	assert.Equal(t, "Name ## Mod", name)
+	assert.Empty(t, capcode)
+}
@@ -1,23 +1,90 @@
 package posting
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/gochan-org/gochan/pkg/building"
+	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gcutil"
 )
 
 var (
-	ErrInvalidReport   = errors.New("invalid report submitted")
-	ErrInvalidPost     = errors.New("post does not exist")
-	ErrNoReportedPosts = errors.New("no posts selected")
-	ErrNoReportReason  = errors.New("no report reason given")
-	ErrDuplicateReport = errors.New("post already reported")
+	ErrInvalidReport         = errors.New("invalid report submitted")
+	ErrInvalidPost           = errors.New("post does not exist")
+	ErrNoReportedPosts       = errors.New("no posts selected")
+	ErrNoReportReason        = errors.New("no report reason given")
+	ErrDuplicateReport       = errors.New("post already reported")
+	ErrInvalidReportCategory = errors.New("invalid report category")
+	ErrReportCooldown        = errors.New("please wait before reporting another post")
+	ErrTooManyReports        = errors.New("too many reports submitted, try again later")
+	ErrReporterBanned        = errors.New("you are not allowed to report posts")
 )
 
+// checkReportLimits returns an error if the IP address is banned from reporting posts, or if reporting the given number
+// of posts would go over the board's report cooldown or hourly limit
+func checkReportLimits(ip string, numPosts int, boardConfig *config.BoardConfig) error {
+	ban, err := gcsql.CheckReportBan(ip)
+	if err != nil {
+		return err
+	}
+	if ban != nil {
+		return ErrReporterBanned
+	}
+
+	now := time.Now()
+	recentReports, err := gcsql.CountReportsFromIP(ip, now.Add(-time.Duration(boardConfig.Cooldowns.Report)*time.Second))
+	if err != nil {
+		return err
+	}
+	if recentReports > 0 {
+		return ErrReportCooldown
+	}
+	if boardConfig.MaxReportsPerHour > 0 {
+		if recentReports, err = gcsql.CountReportsFromIP(ip, now.Add(-time.Hour)); err != nil {
+			return err
+		}
+		if recentReports+numPosts > boardConfig.MaxReportsPerHour {
+			return ErrTooManyReports
+		}
+	}
+	return nil
+}
+
+// applyReportCategoryActions hides the post if it has enough reports in the category, and logs a warning if the category
+// notifies staff. It returns true if the post was hidden
+func applyReportCategoryActions(postID int, category *config.ReportCategory, board string) (bool, error) {
+	if category.NotifyStaff {
+		gcutil.LogWarning().
+			Int("postID", postID).
+			Str("board", board).
+			Str("category", category.ID).
+			Msg("Post reported in a category that requires staff attention")
+	}
+	if category.HideAfter <= 0 {
+		return false, nil
+	}
+	numReports, err := gcsql.CountPostReports(postID, category.ID)
+	if err != nil || numReports < category.HideAfter {
+		return false, err
+	}
+	if err = gcsql.SetPostHidden(postID, true); err != nil {
+		return false, err
+	}
+	gcutil.LogInfo().
+		Int("postID", postID).
+		Str("board", board).
+		Str("category", category.ID).
+		Int("reports", numReports).
+		Msg("Post hidden after being reported")
+	return true, nil
+}
+
 func HandleReport(request *http.Request) error {
 	board := request.FormValue("board")
 	if request.Method != "POST" {
@@ -26,10 +93,10 @@ func HandleReport(request *http.Request) error {
 	var reportedPosts []int
 
 	var id int
-	if !gcsql.DoesBoardExistByDir(board) {
-		return gcsql.ErrBoardDoesNotExist
+	boardID, err := gcsql.GetBoardIDFromDir(board)
+	if err != nil {
+		return err
 	}
-	var err error
 	for key, val := range request.Form {
 		if _, err = fmt.Sscanf(key, "check%d", &id); err != nil || val[0] != "on" {
 			err = nil
@@ -40,26 +107,58 @@ func HandleReport(request *http.Request) error {
 	if len(reportedPosts) == 0 {
 		return ErrNoReportedPosts
 	}
+	for _, postID := range reportedPosts {
+		// the board's report categories, limits, and rebuild only apply to its own posts
+		post := &gcsql.Post{ID: postID}
+		postBoard, err := post.GetBoardDir()
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && postBoard != board) {
+			return fmt.Errorf("%w: /%s/%d", ErrInvalidPost, board, postID)
+		} else if err != nil {
+			return err
+		}
+	}
 	ip := gcutil.GetRealIP(request)
+	boardConfig := config.GetBoardConfig(board)
+	categoryID := request.PostFormValue("category")
+	var category *config.ReportCategory
+	if categoryID != "" {
+		if category = boardConfig.GetReportCategory(categoryID); category == nil {
+			return ErrInvalidReportCategory
+		}
+	}
 	reason := strings.TrimSpace(request.PostFormValue("reason"))
-	if reason == "" {
+	if reason == "" && category == nil {
 		return ErrNoReportReason
 	}
+	if err = checkReportLimits(ip, len(reportedPosts), boardConfig); err != nil {
+		return err
+	}
 
+	var hidPosts bool
 	for _, postID := range reportedPosts {
-		// check to see if the post has already been reported with this report string or if it can't be reported
-		isDuplicate, isBlocked, err := gcsql.CheckPostReports(postID, reason)
+		// check to see if the post has already been reported by this IP or with this report string, or if it can't be reported
+		isDuplicate, isBlocked, err := gcsql.CheckPostReports(postID, ip, categoryID, reason)
 		if err != nil {
 			return err
 		}
 		if isDuplicate || isBlocked {
-			// post has already been reported, and for the same reason, moving on
+			// post has already been reported, moving on
 			continue
 		}
 
-		if _, err = gcsql.CreateReport(postID, ip, reason); err != nil {
+		if _, err = gcsql.CreateReport(postID, ip, categoryID, reason); err != nil {
 			return err
 		}
+		if category != nil {
+			hidden, err := applyReportCategoryActions(postID, category, board)
+			if err != nil {
+				return err
+			}
+			hidPosts = hidPosts || hidden
+		}
+	}
+	if hidPosts {
+		return building.BuildBoards(false, boardID)
 	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package posting
 
 import (
+	"context"
 	"os"
 	"path"
 	"time"
@@ -8,55 +9,68 @@ import (
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/jobs"
 	"github.com/gochan-org/gochan/pkg/posting/uploads"
 )
 
-func tempCleaner() {
-	ticker := time.Tick(time.Minute * 5)
-	for range ticker {
-		for p := range gcsql.TempPosts {
-			post := &gcsql.TempPosts[p]
-			if !time.Now().After(post.CreatedOn.Add(time.Minute * 5)) {
-				continue
-			}
-			// temporary post is >= 5 minutes, time to prune it
-			gcsql.TempPosts[p] = gcsql.TempPosts[len(gcsql.TempPosts)-1]
-			gcsql.TempPosts = gcsql.TempPosts[:len(gcsql.TempPosts)-1]
-			upload, err := post.GetUpload()
-			if err != nil {
-				continue
-			}
-			if upload.OriginalFilename == "" || upload.Filename == "deleted" || upload.IsEmbed() {
-				continue
-			}
-			board, err := post.GetBoard()
-			if err != nil {
-				continue
-			}
+// pruneTempPosts removes the temporary posts (used for CAPTCHA verification) that are at least 5 minutes old, along
+// with their uploads
+func pruneTempPosts(_ context.Context) error {
+	// iterate backwards so that moving the last post into a pruned post's place doesn't skip it
+	for p := len(gcsql.TempPosts) - 1; p >= 0; p-- {
+		post := gcsql.TempPosts[p]
+		if !time.Now().After(post.CreatedOn.Add(time.Minute * 5)) {
+			continue
+		}
+		// temporary post is >= 5 minutes, time to prune it
+		gcsql.TempPosts[p] = gcsql.TempPosts[len(gcsql.TempPosts)-1]
+		gcsql.TempPosts = gcsql.TempPosts[:len(gcsql.TempPosts)-1]
+		upload, err := post.GetUpload()
+		if err != nil {
+			continue
+		}
+		if upload.OriginalFilename == "" || upload.Filename == "deleted" || upload.IsEmbed() {
+			continue
+		}
+		board, err := post.GetBoard()
+		if err != nil {
+			continue
+		}
 
-			systemCritical := config.GetSystemCriticalConfig()
-			fileSrc := path.Join(systemCritical.DocumentRoot, board.Dir, "src", upload.OriginalFilename)
-			if err = os.Remove(fileSrc); err != nil {
-				gcutil.LogError(err).
-					Str("subject", "tempUpload").
-					Str("filePath", fileSrc).Send()
-			}
+		systemCritical := config.GetSystemCriticalConfig()
+		fileSrc := path.Join(systemCritical.DocumentRoot, board.Dir, "src", upload.OriginalFilename)
+		if err = os.Remove(fileSrc); err != nil {
+			gcutil.LogError(err).
+				Str("subject", "tempUpload").
+				Str("filePath", fileSrc).Send()
+		}
 
-			thumbnail, catalogThumbnail := uploads.GetThumbnailFilenames(
-				path.Join(systemCritical.DocumentRoot, board.Dir, "thumb", upload.Filename))
-			if err = os.Remove(thumbnail); err != nil {
+		thumbnail, catalogThumbnail := uploads.GetThumbnailFilenames(
+			path.Join(systemCritical.DocumentRoot, board.Dir, "thumb", upload.Filename))
+		if err = os.Remove(thumbnail); err != nil {
+			gcutil.LogError(err).
+				Str("subject", "tempUpload").
+				Str("filePath", thumbnail).Send()
+		}
+
+		if post.IsTopPost {
+			if err = os.Remove(catalogThumbnail); err != nil {
 				gcutil.LogError(err).
 					Str("subject", "tempUpload").
-					Str("filePath", thumbnail).Send()
-			}
-
-			if post.IsTopPost {
-				if err = os.Remove(catalogThumbnail); err != nil {
-					gcutil.LogError(err).
-						Str("subject", "tempUpload").
-						Str("filePath", catalogThumbnail).Send()
-				}
+					Str("filePath", catalogThumbnail).Send()
 			}
 		}
 	}
+	return nil
+}
+
+func init() {
+	if err := jobs.Register(jobs.Job{
+		Name:            "pruneTempPosts",
+		Description:     "Remove the uploads of posts that were held for a CAPTCHA and never completed",
+		DefaultSchedule: "@every 5m",
+		Run:             pruneTempPosts,
+	}); err != nil {
+		panic(err)
+	}
 }
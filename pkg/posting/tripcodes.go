@@ -0,0 +1,69 @@
+package posting
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// secure tripcodes are stored in the 10 character tripcode column
+	secureTripcodeLength = 10
+
+	// Argon2id parameters for secure tripcodes (19 MiB memory, 2 iterations, 1 thread)
+	secureTripcodeTime    = 2
+	secureTripcodeMemory  = 19 * 1024
+	secureTripcodeThreads = 1
+)
+
+// legacySecureTripcode returns the secure tripcode generated by older versions of gochan, using 64 rounds of MD5 over
+// the password, RandomSeed, and the full name field
+func legacySecureTripcode(password string, name string) string {
+	hash := gcutil.Md5Sum(password + config.GetSystemCriticalConfig().RandomSeed)
+	for range 64 {
+		hash = gcutil.Md5Sum(hash + name)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(hash))[:secureTripcodeLength]
+}
+
+// kdfSecureTripcode returns the secure tripcode derived from the password and TripcodeSecret using Argon2id
+func kdfSecureTripcode(password string, secret string) string {
+	key := argon2.IDKey([]byte(password), []byte(secret),
+		secureTripcodeTime, secureTripcodeMemory, secureTripcodeThreads, 8)
+	return base64.StdEncoding.EncodeToString(key)[:secureTripcodeLength]
+}
+
+// secureTripcode returns the secure tripcode for the password using the configured SecureTripcodeMode. The legacy
+// algorithm is used if the mode is "legacy" or TripcodeSecret isn't set
+func secureTripcode(password string, name string) string {
+	systemCritical := config.GetSystemCriticalConfig()
+	if systemCritical.SecureTripcodeMode == config.SecureTripcodeLegacy || systemCritical.TripcodeSecret == "" {
+		return legacySecureTripcode(password, name)
+	}
+	return kdfSecureTripcode(password, systemCritical.TripcodeSecret)
+}
+
+// ParseCapcode checks if the name ends with a staff capcode (e.g. "Name ## Mod", case insensitive), and returns the
+// name without it (or any tripcode before it) and the capcode if the staff member's rank is high enough to use it. Otherwise it returns the name
+// unchanged and an empty string, and the part after ## is treated as a secure tripcode password
+func ParseCapcode(name string, staff *gcsql.Staff) (string, string) {
+	if staff == nil || staff.Rank < 1 {
+		return name, ""
+	}
+	sepIndex := strings.LastIndex(name, "##")
+	if sepIndex == -1 {
+		return name, ""
+	}
+	capcode := gcsql.CapcodeFromString(name[sepIndex+2:])
+	if capcode == "" || staff.Rank < gcsql.Capcodes[capcode] {
+		return name, ""
+	}
+	// the capcode replaces the tripcode, so any tripcode password before it (e.g. "Name#trip ## Mod") is removed
+	// instead of being shown as part of the name
+	name, _, _ = strings.Cut(name[:sepIndex], "#")
+	return strings.TrimSpace(name), capcode
+}
@@ -19,17 +19,26 @@ import (
 	"github.com/gochan-org/gochan/pkg/events"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
 	"github.com/rs/zerolog"
 )
 
 var (
-	uploadHandlers  map[string]UploadHandler
+	uploadHandlers map[string]UploadHandler
+	// uploadHandlerNames maps extensions to the names of their handlers, used as the handler label in metrics
+	uploadHandlerNames       = map[string]string{}
+	uploadProcessingDuration = metrics.NewHistogramVec("gochan_upload_processing_seconds",
+		"Time taken to process uploads (e.g. generating thumbnails), by upload handler", nil, "handler")
+
 	ImageExtensions = []string{
 		".gif", ".jpg", ".jpeg", ".png", ".webp",
 	}
 	VideoExtensions = []string{
 		".mp4", ".webm",
 	}
+	AudioExtensions = []string{
+		".mp3", ".ogg", ".flac", ".opus",
+	}
 	ErrSpoileredImagesNotAllowed = errors.New("spoilered images are not allowed on this board")
 )
 
@@ -38,6 +47,7 @@ type UploadHandler func(upload *gcsql.Upload, post *gcsql.Post, board string, fi
 func RegisterUploadHandler(ext string, handler UploadHandler) {
 	gcutil.LogInfo().Str("ext", ext).Msg("Registering upload extension handler")
 	uploadHandlers[ext] = handler
+	uploadHandlerNames[ext] = "custom"
 }
 
 func IsImage(file string) bool {
@@ -60,14 +70,38 @@ func IsVideo(file string) bool {
 	return false
 }
 
+func IsAudio(file string) bool {
+	ext := path.Ext(file)
+	for _, aExt := range AudioExtensions {
+		if ext == aExt {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMetadata returns true if uploads with the given filename's extension may have metadata (duration, tags,
+// page count, etc) stored by their upload handler
+func HasMetadata(file string) bool {
+	return IsAudio(file) || IsVideo(file) || path.Ext(file) == ".pdf"
+}
+
 func init() {
 	uploadHandlers = make(map[string]UploadHandler)
 	for _, ext := range ImageExtensions {
 		uploadHandlers[ext] = processImage
+		uploadHandlerNames[ext] = "image"
 	}
 	for _, ext := range VideoExtensions {
 		uploadHandlers[ext] = processVideo
+		uploadHandlerNames[ext] = "video"
 	}
+	for _, ext := range AudioExtensions {
+		uploadHandlers[ext] = processAudio
+		uploadHandlerNames[ext] = "audio"
+	}
+	uploadHandlers[".pdf"] = processPDF
+	uploadHandlerNames[".pdf"] = "pdf"
 }
 
 // AttachUploadFromRequest reads an incoming HTTP request and processes any incoming files.
@@ -160,21 +194,46 @@ func AttachUploadFromRequest(request *http.Request, writer http.ResponseWriter,
 	}
 
 	uploadHandler, ok := uploadHandlers[ext]
+	handlerName := uploadHandlerNames[ext]
 	if !ok {
-		// ext isn't registered by default (jpg, jpeg, png, gif, webp, mp4, webm) or by a plugin,
+		// ext isn't registered by default (jpg, jpeg, png, gif, webp, mp4, webm, mp3, ogg, flac, opus, pdf) or by a plugin,
 		// it's either unsupported or a static thumb as set in configuration
 		uploadHandler = processOther
+		handlerName = "other"
 	}
 
-	if err = uploadHandler(upload, post, postBoard.Dir, filePath, thumbPath, catalogThumbPath, infoEv, accessEv, errEv); err != nil {
+	processStart := time.Now()
+	err = uploadHandler(upload, post, postBoard.Dir, filePath, thumbPath, catalogThumbPath, infoEv, accessEv, errEv)
+	uploadProcessingDuration.ObserveSince(processStart, handlerName)
+	if err != nil {
 		// uploadHandler is assumed to handle logging
 		return nil, fmt.Errorf("error processing upload: %w", err)
 	}
+	setUploadFingerprint(upload, filePath)
+	if upload.StoredChecksum, err = fileChecksum(filePath); err != nil {
+		errEv.Err(err).Caller().Msg("Unable to calculate the checksum of the processed upload")
+		return nil, err
+	}
 
 	accessEv.Send()
 	return upload, nil
 }
 
+// fileChecksum returns the MD5 checksum of the file, used for checking that the stored upload hasn't changed since
+// it was processed
+func fileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath) // skipcq: GSC-G304
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New() // skipcq: GSC-G401, GO-S1023
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
 func getNewFilename() string {
 	now := time.Now().Unix()
 	return strconv.Itoa(int(now)) + strconv.Itoa(rand.Intn(98)+1) // skipcq: GSC-G404
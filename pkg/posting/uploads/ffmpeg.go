@@ -0,0 +1,82 @@
+package uploads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+)
+
+type ffprobeStream struct {
+	Index       int               `json:"index"`
+	CodecType   string            `json:"codec_type"`
+	CodecName   string            `json:"codec_name"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Tags        map[string]string `json:"tags"`
+	Disposition struct {
+		AttachedPic int `json:"attached_pic"`
+	} `json:"disposition"`
+}
+
+type ffprobeOutput struct {
+	Streams []ffprobeStream `json:"streams"`
+	Format  struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// ffmpegContext returns a context that is canceled after FFmpegTimeoutSeconds, so that a malicious or unusually large
+// file can't keep ffmpeg or ffprobe running indefinitely
+func ffmpegContext() (context.Context, context.CancelFunc) {
+	timeout := config.GetSystemCriticalConfig().FFmpegTimeoutSeconds
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+}
+
+func probeMedia(filePath string) (*ffprobeOutput, error) {
+	ctx, cancel := ffmpegContext()
+	defer cancel()
+	outputBytes, err := exec.CommandContext(ctx, "ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", "-show_streams", filePath).Output()
+	if err != nil {
+		return nil, err
+	}
+	var probe ffprobeOutput
+	if err = json.Unmarshal(outputBytes, &probe); err != nil {
+		return nil, err
+	}
+	return &probe, nil
+}
+
+func runFFmpeg(args ...string) error {
+	ctx, cancel := ffmpegContext()
+	defer cancel()
+	outputBytes, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		outputStringArr := strings.Split(string(outputBytes), "\n")
+		if len(outputStringArr) > 1 {
+			outputString := outputStringArr[len(outputStringArr)-2]
+			err = errors.New(outputString)
+		}
+	}
+	return err
+}
+
+// duration returns the duration of the media in seconds, or 0 if it isn't set or is invalid
+func (probe *ffprobeOutput) duration() float64 {
+	duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+	return duration
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
 )
 
 var (
@@ -35,13 +36,47 @@ func getHashLength() int {
 	return hashLength
 }
 
+// GetPostImageFingerprint returns the fingerprint of the upload attached to the given post ID. If it wasn't stored
+// when the upload was attached (e.g. the post was made before fingerprints were stored), it is calculated from the
+// file and stored so that it can be found by similar image searches
 func GetPostImageFingerprint(postID int) (string, error) {
+	fingerprint, err := gcsql.GetPostUploadFingerprint(postID)
+	if err != nil || fingerprint != "" {
+		return fingerprint, err
+	}
 	filename, board, err := gcsql.GetUploadFilenameAndBoard(postID)
 	if err != nil {
 		return "", err
 	}
 	filePath := path.Join(config.GetSystemCriticalConfig().DocumentRoot, board, "src", filename)
-	return GetFileFingerprint(filePath)
+	if fingerprint, err = GetFileFingerprint(filePath); err != nil {
+		return "", err
+	}
+	if err = gcsql.SetPostUploadFingerprint(postID, fingerprint); err != nil {
+		gcutil.LogWarning().Err(err).Caller().
+			Int("postID", postID).
+			Msg("Unable to store upload fingerprint")
+	}
+	return fingerprint, nil
+}
+
+// setUploadFingerprint sets the fingerprint of a newly processed upload so that it can be checked by ahash filter
+// conditions and stored when the upload is attached. Uploads that can't be fingerprinted are still accepted
+func setUploadFingerprint(upload *gcsql.Upload, filePath string) {
+	if !IsImage(filePath) && !IsVideo(filePath) {
+		return
+	}
+	if IsVideo(filePath) && upload.IsSpoilered {
+		// the thumbnail is the spoiler image, not a frame from the video
+		return
+	}
+	var err error
+	upload.Fingerprint, err = GetFileFingerprint(filePath)
+	if err != nil && !errors.Is(err, ErrVideoThumbFingerprint) {
+		gcutil.LogWarning().Err(err).Caller().
+			Str("filePath", filePath).
+			Msg("Unable to get upload fingerprint")
+	}
 }
 
 func GetFileFingerprint(filePath string) (string, error) {
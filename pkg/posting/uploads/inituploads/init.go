@@ -35,11 +35,22 @@ func getThumbnailWebPathTmplFunc(postID int) string {
 	return config.WebPath(board, "thumb", filename)
 }
 
+func getUploadMetadataTmplFunc(postID int) map[string]string {
+	metadata, err := gcsql.GetUploadMetadata(postID)
+	if err != nil {
+		gcutil.LogError(err).Caller().Int("postID", postID).Send()
+	}
+	return metadata
+}
+
 func init() {
 	gctemplates.AddTemplateFuncs(template.FuncMap{
 		"getCatalogThumbnail": getCatalogThumbnailTmplFunc,
 		"getThreadThumbnail":  getThreadThumbnailTmplFunc,
 		"getUploadType":       getUploadTypeTmplFunc,
 		"getThumbnailWebPath": getThumbnailWebPathTmplFunc,
+		"getUploadMetadata":   getUploadMetadataTmplFunc,
+		"isAudio":             uploads.IsAudio,
+		"uploadHasMetadata":   uploads.HasMetadata,
 	})
 }
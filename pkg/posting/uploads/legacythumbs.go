@@ -0,0 +1,100 @@
+package uploads
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+// legacyThumbnailsComponent is added to the database_version table once the legacy thumbnails have been renamed, so
+// that the uploads only need to be checked the first time gochan starts after being upgraded
+const legacyThumbnailsComponent = "legacythumbnails"
+
+// legacyThumbnailExtensions are upload extensions whose thumbnails used to keep the upload's extension (for example
+// <name>t.pdf, a symbolic link created by AllowOtherExtensions) before they were given .png thumbnails
+var legacyThumbnailExtensions = []string{".pdf", ".mp3", ".ogg", ".flac", ".opus"}
+
+// RenameLegacyThumbnails renames thumbnails of existing uploads that still use their legacy name to the name returned
+// by GetThumbnailFilenames, so that they are shown and found by deletes, moves, and thumbnail regeneration. It is safe
+// to run more than once, and thumbnails are left alone if a file with the new name already exists. It returns the
+// number of thumbnails that were renamed
+func RenameLegacyThumbnails() (int, error) {
+	conditions := make([]string, len(legacyThumbnailExtensions))
+	params := make([]any, len(legacyThumbnailExtensions))
+	for e, ext := range legacyThumbnailExtensions {
+		conditions[e] = "filename LIKE ?"
+		params[e] = "%" + ext
+	}
+	query := `SELECT filename, dir FROM DBPREFIXfiles
+		JOIN DBPREFIXposts ON post_id = DBPREFIXposts.id
+		JOIN DBPREFIXthreads ON thread_id = DBPREFIXthreads.id
+		JOIN DBPREFIXboards ON DBPREFIXboards.id = board_id
+		WHERE ` + strings.Join(conditions, " OR ")
+	rows, err := gcsql.Query(nil, query, params...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	documentRoot := config.GetSystemCriticalConfig().DocumentRoot
+	var renamed int
+	for rows.Next() {
+		var filename, dir string
+		if err = rows.Scan(&filename, &dir); err != nil {
+			return renamed, err
+		}
+		ext := path.Ext(filename)
+		base := strings.TrimSuffix(filename, ext)
+		thumbDir := path.Join(documentRoot, dir, "thumb")
+		thumbPath, catalogThumbPath := GetThumbnailFilenames(filename)
+		for suffix, newName := range map[string]string{"t": thumbPath, "c": catalogThumbPath} {
+			oldPath := path.Join(thumbDir, base+suffix+ext)
+			newPath := path.Join(thumbDir, newName)
+			if oldPath == newPath {
+				continue
+			}
+			if _, err = os.Lstat(oldPath); errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return renamed, err
+			}
+			if _, err = os.Lstat(newPath); err == nil {
+				continue
+			}
+			if err = os.Rename(oldPath, newPath); err != nil {
+				return renamed, err
+			}
+			renamed++
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return renamed, err
+	}
+	if renamed > 0 {
+		gcutil.LogInfo().Int("renamed", renamed).Msg("Renamed legacy thumbnails")
+	}
+	return renamed, nil
+}
+
+// RenameLegacyThumbnailsOnce calls RenameLegacyThumbnails if it hasn't finished successfully on this database before,
+// and records that it has if it does
+func RenameLegacyThumbnailsOnce() error {
+	_, err := gcsql.GetComponentVersion(legacyThumbnailsComponent)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, err = RenameLegacyThumbnails(); err != nil {
+		return err
+	}
+	return gcsql.RegisterComponent(nil, legacyThumbnailsComponent, 1)
+}
@@ -0,0 +1,84 @@
+package uploads
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameLegacyThumbnails(t *testing.T) {
+	mock := gcsql.SetupMockDB(t, "sqlite3")
+	require.NotNil(t, mock)
+	t.Cleanup(func() { gcsql.Close() })
+	systemCritical := config.GetSystemCriticalConfig()
+	oldDocumentRoot := systemCritical.DocumentRoot
+	systemCritical.DocumentRoot = t.TempDir()
+	t.Cleanup(func() { systemCritical.DocumentRoot = oldDocumentRoot })
+
+	thumbDir := path.Join(systemCritical.DocumentRoot, "test", "thumb")
+	require.NoError(t, os.MkdirAll(thumbDir, 0755))
+	icon := path.Join(systemCritical.DocumentRoot, "pdfthumb.png")
+	require.NoError(t, os.WriteFile(icon, []byte("icon"), 0644))
+	for _, name := range []string{"123t.pdf", "123c.pdf", "456t.mp3"} {
+		require.NoError(t, os.Symlink(icon, path.Join(thumbDir, name)))
+	}
+	// thumbnails that already have the new name are left alone
+	require.NoError(t, os.WriteFile(path.Join(thumbDir, "456t.png"), []byte("new"), 0644))
+
+	mock.ExpectPrepare(`SELECT filename, dir FROM files\s+JOIN posts .+ WHERE filename LIKE \? OR .+`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "dir"}).
+			AddRow("123.pdf", "test").
+			AddRow("456.mp3", "test").
+			AddRow("789.ogg", "test"))
+
+	renamed, err := RenameLegacyThumbnails()
+	require.NoError(t, err)
+	assert.Equal(t, 2, renamed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	for _, name := range []string{"123t.png", "123c.png", "456t.mp3", "456t.png"} {
+		_, err = os.Lstat(path.Join(thumbDir, name))
+		assert.NoError(t, err, name)
+	}
+	for _, name := range []string{"123t.pdf", "123c.pdf"} {
+		_, err = os.Lstat(path.Join(thumbDir, name))
+		assert.ErrorIs(t, err, os.ErrNotExist, name)
+	}
+	content, err := os.ReadFile(path.Join(thumbDir, "456t.png"))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
+
+func TestRenameLegacyThumbnailsOnce(t *testing.T) {
+	mock := gcsql.SetupMockDB(t, "sqlite3")
+	require.NotNil(t, mock)
+	t.Cleanup(func() { gcsql.Close() })
+	systemCritical := config.GetSystemCriticalConfig()
+	oldDocumentRoot := systemCritical.DocumentRoot
+	systemCritical.DocumentRoot = t.TempDir()
+	t.Cleanup(func() { systemCritical.DocumentRoot = oldDocumentRoot })
+	const versionQuery = `SELECT version FROM database_version WHERE component = \?`
+
+	// the thumbnails are renamed and the component is recorded the first time
+	mock.ExpectPrepare(versionQuery).ExpectQuery().WithArgs(legacyThumbnailsComponent).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectPrepare(`SELECT filename, dir FROM files`).ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"filename", "dir"}))
+	mock.ExpectPrepare(`INSERT INTO database_version \(component, version\) VALUES \(\?,\?\)`).ExpectExec().
+		WithArgs(legacyThumbnailsComponent, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, RenameLegacyThumbnailsOnce())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// and the uploads aren't checked again after that
+	mock.ExpectPrepare(versionQuery).ExpectQuery().WithArgs(legacyThumbnailsComponent).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	assert.NoError(t, RenameLegacyThumbnailsOnce())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
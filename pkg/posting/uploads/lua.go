@@ -3,6 +3,7 @@ package uploads
 import (
 	"errors"
 
+	"github.com/gochan-org/gochan/pkg/gcplugin/luautil"
 	"github.com/gochan-org/gochan/pkg/gcsql"
 	"github.com/rs/zerolog"
 	lua "github.com/yuin/gopher-lua"
@@ -17,14 +18,13 @@ func PreloadModule(l *lua.LState) int {
 			ext := l.CheckString(1)
 			handler := l.CheckFunction(2)
 			RegisterUploadHandler(ext, func(upload *gcsql.Upload, post *gcsql.Post, board, filePath, thumbPath, catalogThumbPath string, infoEv, accessEv, errEv *zerolog.Event) error {
-				l.CallByParam(lua.P{
-					Fn:   handler,
-					NRet: 1,
-					// Protect: true,
-				}, luar.New(l, upload), luar.New(l, post), lua.LString(board), lua.LString(filePath), lua.LString(thumbPath), lua.LString(catalogThumbPath), luar.New(l, infoEv), luar.New(l, accessEv), luar.New(l, errEv))
-
-				errRet := l.CheckAny(-1)
-				if errRet != nil && errRet.Type() != lua.LTNil {
+				rets, err := luautil.CallFunction(l, handler, 1, false,
+					upload, post, board, filePath, thumbPath, catalogThumbPath, infoEv, accessEv, errEv)
+				if err != nil {
+					return err
+				}
+				errRet := rets[0]
+				if errRet.Type() != lua.LTNil {
 					return errors.New(errRet.String())
 				}
 				return nil
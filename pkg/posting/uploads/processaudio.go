@@ -0,0 +1,154 @@
+package uploads
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/rs/zerolog"
+)
+
+const (
+	waveformColor = "#34345c"
+)
+
+var (
+	ErrInvalidAudio = errors.New("upload does not contain a valid audio stream")
+
+	// audioMetadataTags is the list of (lowercase) tags that are stored with the upload if they are set
+	audioMetadataTags = []string{"title", "artist", "album", "date", "genre", "track"}
+)
+
+type audioThumbnail struct {
+	path      string
+	thumbType ThumbnailCategory
+}
+
+// audioMetadata returns the duration, bitrate, and tags of the audio file. Tags may be stored in the
+// format (ID3, FLAC) or in the audio stream (Ogg Vorbis/Opus comments)
+func (probe *ffprobeOutput) audioMetadata() map[string]string {
+	metadata := make(map[string]string)
+	if probe.Format.Duration != "" {
+		metadata["duration"] = probe.Format.Duration
+	}
+	if probe.Format.BitRate != "" {
+		metadata["bitrate"] = probe.Format.BitRate
+	}
+	tagSources := []map[string]string{probe.Format.Tags}
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "audio" {
+			tagSources = append(tagSources, stream.Tags)
+		}
+	}
+	for _, tags := range tagSources {
+		for key, value := range tags {
+			key = strings.ToLower(key)
+			value = strings.TrimSpace(value)
+			if _, ok := metadata[key]; ok || value == "" {
+				continue
+			}
+			for _, tag := range audioMetadataTags {
+				if key == tag {
+					metadata[key] = value
+					break
+				}
+			}
+		}
+	}
+	return metadata
+}
+
+// coverArt returns the embedded cover art stream, or nil if the file doesn't have one
+func (probe *ffprobeOutput) coverArt() *ffprobeStream {
+	for s, stream := range probe.Streams {
+		if stream.CodecType == "video" && stream.Disposition.AttachedPic == 1 && stream.Width > 0 && stream.Height > 0 {
+			return &probe.Streams[s]
+		}
+	}
+	return nil
+}
+
+func (probe *ffprobeOutput) hasAudioStream() bool {
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "audio" {
+			return true
+		}
+	}
+	return false
+}
+
+func createCoverArtThumbnail(audio, thumb string, streamIndex, width, height int) error {
+	return runFFmpeg("-y", "-i", audio, "-map", "0:"+strconv.Itoa(streamIndex), "-frames:v", "1",
+		"-filter:v", fmt.Sprintf("scale=%d:%d", width, height), thumb)
+}
+
+func createWaveformThumbnail(audio, thumb string, width, height int) error {
+	return runFFmpeg("-y", "-i", audio, "-filter_complex",
+		fmt.Sprintf("showwavespic=s=%dx%d:colors=%s", width, height, waveformColor), "-frames:v", "1", thumb)
+}
+
+func processAudio(upload *gcsql.Upload, post *gcsql.Post, board string, filePath string, thumbPath string, catalogThumbPath string, infoEv *zerolog.Event, accessEv *zerolog.Event, errEv *zerolog.Event) error {
+	infoEv.Str("post", "withAudio")
+	accessEv.Str("handler", "audio")
+	probe, err := probeMedia(filePath)
+	if err != nil {
+		errEv.Err(err).Caller().Msg("Error getting audio info")
+		return ErrInvalidAudio
+	}
+	if !probe.hasAudioStream() {
+		errEv.Err(ErrInvalidAudio).Caller().Send()
+		return ErrInvalidAudio
+	}
+	upload.Metadata = probe.audioMetadata()
+
+	if upload.IsSpoilered {
+		if err = createSpoilerThumbnail(upload, board, post.IsTopPost, thumbPath); err != nil {
+			errEv.Err(err).Caller().Msg("Unable to create spoiler thumbnail")
+			return ErrUnableToCreateSpoiler
+		}
+		return nil
+	}
+
+	thumbType := ThumbnailReply
+	if post.ThreadID == 0 {
+		thumbType = ThumbnailOP
+	}
+	thumbnails := []audioThumbnail{{thumbPath, thumbType}}
+	if post.ThreadID == 0 {
+		thumbnails = append(thumbnails, audioThumbnail{catalogThumbPath, ThumbnailCatalog})
+	}
+
+	cover := probe.coverArt()
+	for t, thumbnail := range thumbnails {
+		var width, height int
+		if cover != nil {
+			width, height = getThumbnailSize(cover.Width, cover.Height, board, thumbnail.thumbType)
+			if err = createCoverArtThumbnail(filePath, thumbnail.path, cover.Index, width, height); err != nil {
+				// broken or unsupported cover art, fall back to the waveform
+				gcutil.LogWarning().Err(err).
+					Str("filePath", filePath).
+					Int("streamIndex", cover.Index).
+					Msg("Unable to create thumbnail from cover art, using waveform instead")
+				cover = nil
+			}
+		}
+		if cover == nil {
+			width, height = getBoardThumbnailSize(board, thumbnail.thumbType)
+			height /= 2
+			if err = createWaveformThumbnail(filePath, thumbnail.path, width, height); err != nil {
+				errEv.Err(err).Caller().
+					Str("thumbPath", thumbnail.path).
+					Msg("Error creating audio waveform thumbnail")
+				return err
+			}
+		}
+		if t == 0 {
+			upload.ThumbnailWidth = width
+			upload.ThumbnailHeight = height
+		}
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package uploads
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/rs/zerolog"
+)
+
+const (
+	pdfThumbnail        = "pdfthumb.png"
+	pdfRenderTimeout    = 30 * time.Second
+	maxObjectStreamSize = 10 * 1024 * 1024
+)
+
+var (
+	ErrInvalidPDF          = errors.New("upload is not a valid PDF document")
+	ErrPDFActiveContent    = errors.New("PDF contains active content that can't be removed")
+	ErrUnableToDisarmPDF   = errors.New("unable to remove active content from PDF")
+	pdfNameRE              = regexp.MustCompile(`/[^\x00\t\n\f\r /<>\[\]()%{}]*`)
+	pdfStreamRE            = regexp.MustCompile(`stream\r?\n`)
+	pdfObjectRE            = regexp.MustCompile(`\d+\s+\d+\s+obj`)
+	pdfWhitespaceOrEmptyRE = regexp.MustCompile(`^[\x00\t\n\f\r ]*$`)
+
+	// pdfActiveNames are names that cause a PDF reader to run scripts, open files or URLs, or submit data,
+	// either automatically or when the user interacts with the document
+	pdfActiveNames = map[string]bool{
+		"JavaScript":    true,
+		"JS":            true,
+		"OpenAction":    true,
+		"AA":            true,
+		"Launch":        true,
+		"EmbeddedFile":  true,
+		"EmbeddedFiles": true,
+		"RichMedia":     true,
+		"SubmitForm":    true,
+		"ImportData":    true,
+		"GoToE":         true,
+		"GoToR":         true,
+		"XFA":           true,
+	}
+)
+
+// pdfName is a name object found in PDF data, with its decoded value (without the leading slash)
+type pdfName struct {
+	start int
+	end   int
+	value string
+}
+
+// decodePDFName decodes #xx hex escape sequences in a raw name object
+func decodePDFName(raw []byte) string {
+	if bytes.IndexByte(raw, '#') < 0 {
+		return string(raw)
+	}
+	decoded := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if b, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				decoded = append(decoded, byte(b))
+				i += 2
+				continue
+			}
+		}
+		decoded = append(decoded, raw[i])
+	}
+	return string(decoded)
+}
+
+// pdfStreams returns the start and end offsets of the stream data in the document
+func pdfStreams(data []byte) [][2]int {
+	var streams [][2]int
+	for _, loc := range pdfStreamRE.FindAllIndex(data, -1) {
+		if bytes.HasSuffix(data[:loc[0]], []byte("end")) {
+			continue // endstream keyword
+		}
+		streamEnd := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if streamEnd < 0 {
+			streamEnd = len(data) - loc[1]
+		}
+		streams = append(streams, [2]int{loc[1], loc[1] + streamEnd})
+	}
+	return streams
+}
+
+// findPDFNames returns the name objects in the PDF data, skipping stream data (page contents, images, etc)
+// since it isn't parsed as objects
+func findPDFNames(data []byte) []pdfName {
+	streams := pdfStreams(data)
+	var names []pdfName
+	var s int
+	for _, match := range pdfNameRE.FindAllIndex(data, -1) {
+		for s < len(streams) && streams[s][1] <= match[0] {
+			s++
+		}
+		if s < len(streams) && streams[s][0] <= match[0] {
+			continue
+		}
+		names = append(names, pdfName{
+			start: match[0],
+			end:   match[1],
+			value: decodePDFName(data[match[0]+1 : match[1]]),
+		})
+	}
+	return names
+}
+
+// countPDFPages returns the number of page objects (/Type /Page) in the given names
+func countPDFPages(data []byte, names []pdfName) int {
+	var pages int
+	for n := 0; n < len(names)-1; n++ {
+		if names[n].value == "Type" && names[n+1].value == "Page" &&
+			pdfWhitespaceOrEmptyRE.Match(data[names[n].end:names[n+1].start]) {
+			pages++
+		}
+	}
+	return pages
+}
+
+// disarmPDFNames neutralizes active content by flipping the case of the letters in the raw names, in place. The
+// length of the data doesn't change so the cross-reference table offsets remain valid. It returns the number of
+// names that were changed
+func disarmPDFNames(data []byte, names []pdfName) int {
+	var disarmed int
+	for _, name := range names {
+		if !pdfActiveNames[name.value] {
+			continue
+		}
+		for i := name.start + 1; i < name.end; i++ {
+			switch c := data[i]; {
+			case c >= 'a' && c <= 'z':
+				data[i] = c - 'a' + 'A'
+			case c >= 'A' && c <= 'Z':
+				data[i] = c - 'A' + 'a'
+			}
+		}
+		disarmed++
+	}
+	return disarmed
+}
+
+// pdfObjectStreams returns the decompressed contents of the object streams (PDF 1.5+) in the document, which
+// may contain dictionaries that aren't visible in the uncompressed data. Object streams that can't be fully
+// decompressed (because they use a filter other than FlateDecode, a predictor, or are corrupted or too large) can't
+// be inspected for active content, so ErrInvalidPDF is returned for them instead of letting them through
+func pdfObjectStreams(data []byte) ([][]byte, error) {
+	var streams [][]byte
+	objects := pdfObjectRE.FindAllIndex(data, -1)
+	for _, stream := range pdfStreams(data) {
+		// find the dictionary of the object this stream belongs to
+		o := sort.Search(len(objects), func(i int) bool {
+			return objects[i][1] > stream[0]
+		})
+		if o == 0 {
+			continue
+		}
+		var isObjStm, hasFilter, hasDecodeParms bool
+		var filters []string
+		for _, name := range findPDFNames(data[objects[o-1][1]:stream[0]]) {
+			switch name.value {
+			case "ObjStm":
+				isObjStm = true
+			case "Filter":
+				hasFilter = true
+			case "DecodeParms":
+				hasDecodeParms = true
+			case "FlateDecode", "Fl", "ASCIIHexDecode", "AHx", "ASCII85Decode", "A85", "LZWDecode", "LZW",
+				"RunLengthDecode", "RL", "CCITTFaxDecode", "CCF", "JBIG2Decode", "DCTDecode", "DCT", "JPXDecode", "Crypt":
+				filters = append(filters, name.value)
+			}
+		}
+		if !isObjStm {
+			continue
+		}
+		if !hasFilter {
+			// the objects are stored uncompressed, so they were already checked with the rest of the document
+			continue
+		}
+		if len(filters) != 1 || filters[0] != "FlateDecode" || hasDecodeParms {
+			return nil, fmt.Errorf("%w: object stream with unsupported filters %v", ErrInvalidPDF, filters)
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data[stream[0]:stream[1]]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to decompress object stream: %w", ErrInvalidPDF, err)
+		}
+		decompressed, err := io.ReadAll(io.LimitReader(reader, maxObjectStreamSize+1))
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to decompress object stream: %w", ErrInvalidPDF, err)
+		}
+		if len(decompressed) > maxObjectStreamSize {
+			return nil, fmt.Errorf("%w: object stream is larger than %d bytes", ErrInvalidPDF, maxObjectStreamSize)
+		}
+		streams = append(streams, decompressed)
+	}
+	return streams, nil
+}
+
+// sanitizePDF disables active content in the PDF document and returns the number of pages. If active content is
+// found in a compressed object stream, ErrPDFActiveContent is returned since it can't be changed in place
+func sanitizePDF(data []byte) (pages int, disarmed int, err error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return 0, 0, ErrInvalidPDF
+	}
+	names := findPDFNames(data)
+	pages = countPDFPages(data, names)
+	disarmed = disarmPDFNames(data, names)
+	streams, err := pdfObjectStreams(data)
+	if err != nil {
+		return pages, disarmed, err
+	}
+	for _, stream := range streams {
+		streamNames := findPDFNames(stream)
+		pages += countPDFPages(stream, streamNames)
+		for _, name := range streamNames {
+			if pdfActiveNames[name.value] {
+				return pages, disarmed, ErrPDFActiveContent
+			}
+		}
+	}
+	return pages, disarmed, nil
+}
+
+func getPdftoppmPath() (string, error) {
+	pdftoppmPath := config.GetSystemCriticalConfig().PdftoppmPath
+	if pdftoppmPath == "" {
+		pdftoppmPath = "pdftoppm"
+	}
+	return exec.LookPath(pdftoppmPath)
+}
+
+// renderPDFPage renders the first page of the PDF to a PNG file with its longest side scaled to the given size. It
+// returns the path of the PNG file, which should be removed by the caller when it is no longer needed
+func renderPDFPage(pdftoppmPath, filePath, outPrefix string, size int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pdfRenderTimeout)
+	defer cancel()
+	outputBytes, err := exec.CommandContext(ctx, pdftoppmPath, "-f", "1", "-l", "1", "-singlefile", "-png",
+		"-scale-to", strconv.Itoa(size), filePath, outPrefix).CombinedOutput()
+	if err != nil && len(outputBytes) > 0 {
+		err = errors.New(string(bytes.TrimSpace(outputBytes)))
+	}
+	return outPrefix + ".png", err
+}
+
+func createPDFThumbnails(upload *gcsql.Upload, post *gcsql.Post, board, filePath, thumbPath, catalogThumbPath string) error {
+	pdftoppmPath, err := getPdftoppmPath()
+	if err != nil {
+		return err
+	}
+	thumbWidth, thumbHeight := getBoardThumbnailSize(board, ThumbnailOP)
+	pagePath, err := renderPDFPage(pdftoppmPath, filePath, thumbPath+".page", max(thumbWidth, thumbHeight))
+	defer os.Remove(pagePath)
+	if err != nil {
+		return err
+	}
+	page, err := imaging.Open(pagePath)
+	if err != nil {
+		return err
+	}
+
+	thumbType := ThumbnailReply
+	if post.ThreadID == 0 {
+		thumbType = ThumbnailOP
+		catalogThumbnail := createImageThumbnail(page, board, ThumbnailCatalog)
+		if err = imaging.Save(catalogThumbnail, catalogThumbPath); err != nil {
+			return err
+		}
+	}
+	thumbnail := createImageThumbnail(page, board, thumbType)
+	if err = imaging.Save(thumbnail, thumbPath); err != nil {
+		return err
+	}
+	upload.ThumbnailWidth = thumbnail.Bounds().Dx()
+	upload.ThumbnailHeight = thumbnail.Bounds().Dy()
+	return nil
+}
+
+func processPDF(upload *gcsql.Upload, post *gcsql.Post, board string, filePath string, thumbPath string, catalogThumbPath string, infoEv *zerolog.Event, accessEv *zerolog.Event, errEv *zerolog.Event) error {
+	boardConfig := config.GetBoardConfig(board)
+	if !boardConfig.AllowPDFUploads {
+		// PDFs may still be allowed with a static thumbnail via AllowOtherExtensions
+		return processOther(upload, post, board, filePath, thumbPath, catalogThumbPath, infoEv, accessEv, errEv)
+	}
+	infoEv.Str("post", "withPDF")
+	accessEv.Str("handler", "pdf")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		errEv.Err(err).Caller().Send()
+		return err
+	}
+	pages, disarmed, err := sanitizePDF(data)
+	if err != nil {
+		os.Remove(filePath)
+		errEv.Err(err).Caller().Int("disarmed", disarmed).Send()
+		return err
+	}
+	if disarmed > 0 {
+		accessEv.Int("disarmedNames", disarmed)
+		if err = os.WriteFile(filePath, data, config.NormalFileMode); err != nil {
+			errEv.Err(err).Caller().Msg("Unable to write sanitized PDF")
+			return ErrUnableToDisarmPDF
+		}
+	}
+	upload.Metadata = map[string]string{"pages": strconv.Itoa(pages)}
+
+	if upload.IsSpoilered {
+		if err = createSpoilerThumbnail(upload, board, post.IsTopPost, thumbPath); err != nil {
+			errEv.Err(err).Caller().Msg("Unable to create spoiler thumbnail")
+			return ErrUnableToCreateSpoiler
+		}
+		return nil
+	}
+
+	if err = createPDFThumbnails(upload, post, board, filePath, thumbPath, catalogThumbPath); err == nil {
+		return nil
+	}
+	gcutil.LogWarning().Err(err).
+		Str("filePath", filePath).
+		Msg("Unable to render PDF thumbnail, using generic thumbnail instead")
+	os.Remove(thumbPath)
+	os.Remove(catalogThumbPath)
+
+	if post.ThreadID == 0 {
+		upload.ThumbnailWidth = boardConfig.ThumbWidth
+		upload.ThumbnailHeight = boardConfig.ThumbHeight
+	} else {
+		upload.ThumbnailWidth = boardConfig.ThumbWidthReply
+		upload.ThumbnailHeight = boardConfig.ThumbHeightReply
+	}
+	genericThumbPath := path.Join(config.GetSystemCriticalConfig().DocumentRoot, "static", pdfThumbnail)
+	if err = os.Symlink(genericThumbPath, thumbPath); err != nil {
+		errEv.Err(err).Caller().Str("thumbPath", thumbPath).Send()
+		return err
+	}
+	if post.ThreadID == 0 {
+		if err = os.Symlink(genericThumbPath, catalogThumbPath); err != nil {
+			errEv.Err(err).Caller().Str("catalogThumbPath", catalogThumbPath).Send()
+			return err
+		}
+	}
+	return nil
+}
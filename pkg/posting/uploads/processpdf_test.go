@@ -0,0 +1,95 @@
+package uploads
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPDF = `%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R /OpenAction 4 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /AA << /O 4 0 R >> >>
+endobj
+4 0 obj
+<< /S /J#61vaScript /JS (app.alert\(1\)) >>
+endobj
+5 0 obj
+<</Type/Page/Parent 2 0 R>>
+endobj
+trailer
+<< /Root 1 0 R >>
+%%EOF
+`
+
+func objectStreamPDF(t *testing.T, objects string) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write([]byte(objects))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return fmt.Appendf(nil, "%%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 2 /First 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n%%%%EOF\n",
+		compressed.Len(), compressed.Bytes())
+}
+
+func TestSanitizePDF(t *testing.T) {
+	data := []byte(testPDF)
+	pages, disarmed, err := sanitizePDF(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pages)
+	assert.Equal(t, 4, disarmed)
+	assert.Len(t, data, len(testPDF), "sanitized PDF must not change in size")
+	for _, name := range findPDFNames(data) {
+		assert.False(t, pdfActiveNames[name.value], "active name %q was not disarmed", name.value)
+	}
+	assert.Contains(t, string(data), "/oPENaCTION")
+	assert.Contains(t, string(data), "/j#61VAsCRIPT")
+
+	// sanitizing an already sanitized PDF should be a no-op
+	sanitized := bytes.Clone(data)
+	_, disarmed, err = sanitizePDF(data)
+	assert.NoError(t, err)
+	assert.Zero(t, disarmed)
+	assert.Equal(t, sanitized, data)
+}
+
+func TestSanitizePDFObjectStreams(t *testing.T) {
+	pages, _, err := sanitizePDF(objectStreamPDF(t, "3 0 4 30 << /Type /Page /Parent 2 0 R >> << /Type /Page >>"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pages)
+
+	_, _, err = sanitizePDF(objectStreamPDF(t, "3 0 << /Type /Page /AA << /O 4 0 R >> >>"))
+	assert.ErrorIs(t, err, ErrPDFActiveContent)
+}
+
+func TestSanitizePDFUninspectableObjectStreams(t *testing.T) {
+	chained := bytes.Replace(objectStreamPDF(t, "3 0 << /Type /Page >>"),
+		[]byte("/Filter /FlateDecode"), []byte("/Filter [/ASCII85Decode /FlateDecode]"), 1)
+	_, _, err := sanitizePDF(chained)
+	assert.ErrorIs(t, err, ErrInvalidPDF)
+
+	predictor := bytes.Replace(objectStreamPDF(t, "3 0 << /Type /Page >>"),
+		[]byte("/Filter /FlateDecode"), []byte("/Filter /FlateDecode /DecodeParms << /Predictor 12 >>"), 1)
+	_, _, err = sanitizePDF(predictor)
+	assert.ErrorIs(t, err, ErrInvalidPDF)
+
+	corrupted := objectStreamPDF(t, "3 0 << /Type /Page /AA << /O 4 0 R >> >>")
+	start := bytes.Index(corrupted, []byte("stream\n")) + len("stream\n")
+	corrupted[start+4] ^= 0xff
+	_, _, err = sanitizePDF(corrupted)
+	assert.ErrorIs(t, err, ErrInvalidPDF)
+}
+
+func TestSanitizeInvalidPDF(t *testing.T) {
+	_, _, err := sanitizePDF([]byte("<html><script>alert(1)</script></html>"))
+	assert.ErrorIs(t, err, ErrInvalidPDF)
+}
@@ -1,21 +1,166 @@
 package uploads
 
 import (
-	"os/exec"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
 	"strconv"
-	"strings"
 
 	"github.com/gochan-org/gochan/pkg/config"
 	"github.com/gochan-org/gochan/pkg/gcsql"
-	"github.com/gochan-org/gochan/pkg/gcutil"
 	"github.com/rs/zerolog"
 )
 
+var (
+	ErrInvalidVideo           = errors.New("upload does not contain a valid video stream")
+	ErrVideoTooLong           = errors.New("video is too long")
+	ErrVideoResolutionTooHigh = errors.New("video resolution is too high")
+	ErrDisallowedVideoStream  = errors.New("video contains a disallowed stream")
+	ErrUnableToProcessVideo   = errors.New("unable to process video")
+
+	// safeVideoCodecs maps video file extensions to the video and audio codecs (as reported by ffprobe) that
+	// can be kept as-is if TranscodeVideos is set to "unsafe"
+	safeVideoCodecs = map[string][]string{
+		".mp4":  {"h264", "aac", "mp3"},
+		".webm": {"vp8", "vp9", "av1", "vorbis", "opus"},
+	}
+	// transcodeVideoArgs maps video file extensions to the ffmpeg arguments used for re-encoding video and audio streams
+	transcodeVideoArgs = map[string]map[string][]string{
+		".mp4": {
+			"video": {"libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p"},
+			"audio": {"aac", "-b:a", "160k"},
+		},
+		".webm": {
+			"video": {"libvpx-vp9", "-crf", "32", "-b:v", "0", "-row-mt", "1", "-deadline", "good", "-cpu-used", "4"},
+			"audio": {"libopus", "-b:a", "128k"},
+		},
+	}
+)
+
+// checkVideoPolicy returns an error if the video violates the board's video upload restrictions
+func checkVideoPolicy(probe *ffprobeOutput, boardConfig *config.BoardConfig) error {
+	var video *ffprobeStream
+	for s, stream := range probe.Streams {
+		if slices.Contains(boardConfig.RejectVideoStreamTypes, stream.CodecType) {
+			return fmt.Errorf("%w (%s)", ErrDisallowedVideoStream, stream.CodecType)
+		}
+		if video == nil && stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 {
+			video = &probe.Streams[s]
+		}
+	}
+	if video == nil {
+		return ErrInvalidVideo
+	}
+	if boardConfig.MaxVideoDuration > 0 && probe.duration() > float64(boardConfig.MaxVideoDuration) {
+		return fmt.Errorf("%w (maximum duration is %d seconds)", ErrVideoTooLong, boardConfig.MaxVideoDuration)
+	}
+	if (boardConfig.MaxVideoWidth > 0 && video.Width > boardConfig.MaxVideoWidth) ||
+		(boardConfig.MaxVideoHeight > 0 && video.Height > boardConfig.MaxVideoHeight) {
+		return fmt.Errorf("%w (%dx%d)", ErrVideoResolutionTooHigh, video.Width, video.Height)
+	}
+	return nil
+}
+
+// videoProcessingArgs returns the ffmpeg arguments for remuxing and/or re-encoding the video according to the
+// board's configuration, or nil if the video doesn't need to be changed
+func videoProcessingArgs(probe *ffprobeOutput, boardConfig *config.BoardConfig, filePath, outPath string) []string {
+	ext := path.Ext(filePath)
+	args := []string{"-y", "-i", filePath}
+	var transcode bool
+	var outStream int
+	for _, stream := range probe.Streams {
+		if stream.CodecType != "video" && stream.CodecType != "audio" {
+			// only video and audio streams are kept
+			continue
+		}
+		codecArgs := []string{"copy"}
+		if transcodeArgs, ok := transcodeVideoArgs[ext][stream.CodecType]; ok {
+			if boardConfig.TranscodeVideos == "all" ||
+				(boardConfig.TranscodeVideos == "unsafe" && !slices.Contains(safeVideoCodecs[ext], stream.CodecName)) {
+				codecArgs = transcodeArgs
+			}
+		}
+		if stream.CodecType == "video" && stream.Disposition.AttachedPic == 1 {
+			// cover art is dropped instead of being re-encoded as a video stream
+			if codecArgs[0] != "copy" {
+				continue
+			}
+		}
+		if codecArgs[0] != "copy" {
+			transcode = true
+		}
+		args = append(args, "-map", "0:"+strconv.Itoa(stream.Index))
+		args = append(args, "-c:"+strconv.Itoa(outStream), codecArgs[0])
+		args = append(args, codecArgs[1:]...)
+		outStream++
+	}
+	if !transcode && !boardConfig.StripVideoMetadata {
+		return nil
+	}
+	if boardConfig.StripVideoMetadata {
+		args = append(args, "-map_metadata", "-1", "-map_metadata:s:v", "-1", "-map_metadata:s:a", "-1",
+			"-map_chapters", "-1", "-fflags", "+bitexact")
+	}
+	if ext == ".mp4" {
+		args = append(args, "-movflags", "+faststart")
+	}
+	return append(args, outPath)
+}
+
 func processVideo(upload *gcsql.Upload, post *gcsql.Post, board string, filePath string, thumbPath string, catalogThumbPath string, infoEv *zerolog.Event, accessEv *zerolog.Event, errEv *zerolog.Event) error {
 	boardConfig := config.GetBoardConfig(board)
 	infoEv.Str("post", "withVideo")
 	accessEv.Str("handler", "video")
-	var err error
+
+	probe, err := probeMedia(filePath)
+	if err != nil {
+		os.Remove(filePath)
+		errEv.Err(err).Caller().Msg("Error getting video info")
+		return ErrInvalidVideo
+	}
+	if err = checkVideoPolicy(probe, boardConfig); err != nil {
+		os.Remove(filePath)
+		errEv.Err(err).Caller().Msg("Video rejected")
+		return err
+	}
+
+	if args := videoProcessingArgs(probe, boardConfig, filePath, filePath+".tmp"+path.Ext(filePath)); args != nil {
+		tmpPath := args[len(args)-1]
+		if err = runFFmpeg(args...); err != nil {
+			os.Remove(tmpPath)
+			os.Remove(filePath)
+			errEv.Err(err).Caller().
+				Strs("ffmpegArgs", args).
+				Msg("Unable to remux or transcode video")
+			return ErrUnableToProcessVideo
+		}
+		if err = os.Rename(tmpPath, filePath); err != nil {
+			os.Remove(tmpPath)
+			errEv.Err(err).Caller().Send()
+			return ErrUnableToProcessVideo
+		}
+		if fi, err := os.Stat(filePath); err == nil {
+			upload.FileSize = int(fi.Size())
+		}
+		if probe, err = probeMedia(filePath); err != nil {
+			errEv.Err(err).Caller().Msg("Error getting processed video info")
+			return ErrUnableToProcessVideo
+		}
+	}
+
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 {
+			upload.Width = stream.Width
+			upload.Height = stream.Height
+			break
+		}
+	}
+	if probe.Format.Duration != "" {
+		upload.Metadata = map[string]string{"duration": probe.Format.Duration}
+	}
+
 	if upload.IsSpoilered {
 		if err = createSpoilerThumbnail(upload, board, post.IsTopPost, thumbPath); err != nil {
 			errEv.Err(err).Caller().Msg("Unable to create spoiler thumbnail")
@@ -48,32 +193,11 @@ func processVideo(upload *gcsql.Upload, post *gcsql.Post, board string, filePath
 		return err
 	}
 
-	outputBytes, err := exec.Command("ffprobe", "-v", "quiet", "-show_format", "-show_streams", filePath).CombinedOutput()
-	if err != nil {
-		gcutil.LogError(err).Msg("Error getting video info")
-		return err
-	}
-	if outputBytes != nil {
-		outputStringArr := strings.Split(string(outputBytes), "\n")
-		for _, line := range outputStringArr {
-			lineArr := strings.Split(line, "=")
-			if len(lineArr) < 2 {
-				continue
-			}
-			value, _ := strconv.Atoi(lineArr[1])
-			switch lineArr[0] {
-			case "width":
-				upload.Width = value
-			case "height":
-				upload.Height = value
-			}
-		}
-		thumbType := ThumbnailReply
-		if post.ThreadID == 0 {
-			thumbType = ThumbnailOP
-		}
-		upload.ThumbnailWidth, upload.ThumbnailHeight = getThumbnailSize(
-			upload.Width, upload.Height, board, thumbType)
+	thumbType := ThumbnailReply
+	if post.ThreadID == 0 {
+		thumbType = ThumbnailOP
 	}
+	upload.ThumbnailWidth, upload.ThumbnailHeight = getThumbnailSize(
+		upload.Width, upload.Height, board, thumbType)
 	return nil
 }
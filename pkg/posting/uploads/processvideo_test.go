@@ -0,0 +1,98 @@
+package uploads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testVideoProbe(streams ...ffprobeStream) *ffprobeOutput {
+	probe := &ffprobeOutput{Streams: streams}
+	for s := range probe.Streams {
+		probe.Streams[s].Index = s
+	}
+	probe.Format.Duration = "95.500000"
+	return probe
+}
+
+func TestCheckVideoPolicy(t *testing.T) {
+	video := ffprobeStream{CodecType: "video", CodecName: "h264", Width: 1920, Height: 1080}
+	audio := ffprobeStream{CodecType: "audio", CodecName: "aac"}
+	subtitle := ffprobeStream{CodecType: "subtitle", CodecName: "mov_text"}
+	boardConfig := &config.BoardConfig{UploadConfig: config.UploadConfig{
+		RejectVideoStreamTypes: []string{"subtitle", "attachment"},
+	}}
+
+	assert.NoError(t, checkVideoPolicy(testVideoProbe(video, audio), boardConfig))
+	assert.ErrorIs(t, checkVideoPolicy(testVideoProbe(audio), boardConfig), ErrInvalidVideo)
+	err := checkVideoPolicy(testVideoProbe(video, audio, subtitle), boardConfig)
+	assert.ErrorIs(t, err, ErrDisallowedVideoStream)
+	assert.EqualError(t, err, "video contains a disallowed stream (subtitle)")
+
+	boardConfig.MaxVideoDuration = 60
+	assert.ErrorIs(t, checkVideoPolicy(testVideoProbe(video, audio), boardConfig), ErrVideoTooLong)
+	boardConfig.MaxVideoDuration = 120
+	boardConfig.MaxVideoHeight = 720
+	assert.ErrorIs(t, checkVideoPolicy(testVideoProbe(video, audio), boardConfig), ErrVideoResolutionTooHigh)
+	boardConfig.MaxVideoHeight = 1080
+	assert.NoError(t, checkVideoPolicy(testVideoProbe(video, audio), boardConfig))
+}
+
+func TestVideoProcessingArgs(t *testing.T) {
+	video := ffprobeStream{CodecType: "video", CodecName: "hevc", Width: 1280, Height: 720}
+	audio := ffprobeStream{CodecType: "audio", CodecName: "aac"}
+	data := ffprobeStream{CodecType: "data", CodecName: "bin_data"}
+	probe := testVideoProbe(video, audio, data)
+	boardConfig := &config.BoardConfig{}
+
+	assert.Nil(t, videoProcessingArgs(probe, boardConfig, "in.mp4", "out.mp4"), "video should be left as-is by default")
+
+	boardConfig.StripVideoMetadata = true
+	assert.Equal(t, []string{
+		"-y", "-i", "in.mp4",
+		"-map", "0:0", "-c:0", "copy",
+		"-map", "0:1", "-c:1", "copy",
+		"-map_metadata", "-1", "-map_metadata:s:v", "-1", "-map_metadata:s:a", "-1", "-map_chapters", "-1", "-fflags", "+bitexact",
+		"-movflags", "+faststart", "out.mp4",
+	}, videoProcessingArgs(probe, boardConfig, "in.mp4", "out.mp4"))
+
+	boardConfig.StripVideoMetadata = false
+	boardConfig.TranscodeVideos = "unsafe"
+	assert.Equal(t, []string{
+		"-y", "-i", "in.mp4",
+		"-map", "0:0", "-c:0", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+		"-map", "0:1", "-c:1", "copy",
+		"-movflags", "+faststart", "out.mp4",
+	}, videoProcessingArgs(probe, boardConfig, "in.mp4", "out.mp4"))
+
+	boardConfig.TranscodeVideos = "all"
+	args := videoProcessingArgs(testVideoProbe(
+		ffprobeStream{CodecType: "video", CodecName: "vp9"},
+		ffprobeStream{CodecType: "audio", CodecName: "opus"},
+	), boardConfig, "in.webm", "out.webm")
+	assert.Contains(t, args, "libvpx-vp9")
+	assert.Contains(t, args, "libopus")
+	assert.NotContains(t, args, "-movflags")
+}
+
+func TestFFmpegContext(t *testing.T) {
+	config.InitTestConfig()
+	systemCritical := config.GetSystemCriticalConfig()
+	t.Cleanup(func() {
+		systemCritical.FFmpegTimeoutSeconds = config.DefaultFFmpegTimeout
+	})
+
+	ctx, cancel := ffmpegContext()
+	deadline, ok := ctx.Deadline()
+	cancel()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(config.DefaultFFmpegTimeout*time.Second), deadline, time.Second)
+
+	systemCritical.FFmpegTimeoutSeconds = 0
+	ctx, cancel = ffmpegContext()
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
@@ -0,0 +1,159 @@
+package uploads
+
+import (
+	"errors"
+	"os"
+	"path"
+
+	"github.com/disintegration/imaging"
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+)
+
+var (
+	ErrThumbnailUnsupported = errors.New("thumbnails can only be regenerated for images and videos")
+)
+
+// RegeneratedThumbnail is the result of regenerating an upload's thumbnail
+type RegeneratedThumbnail struct {
+	PostID      int    `json:"post"`
+	Board       string `json:"board"`
+	Filename    string `json:"filename"`
+	ThumbWidth  int    `json:"thumbWidth"`
+	ThumbHeight int    `json:"thumbHeight"`
+	Error       error  `json:"-"`
+}
+
+// RegenerateThumbnails recreates the thumbnails (and catalog thumbnails for thread OPs) of the image and video
+// uploads on the board with the given directory, or only the upload attached to the given post if postID > 0, using
+// the board's current thumbnail settings. Errors for individual uploads are stored in the returned results instead
+// of stopping the regeneration
+func RegenerateThumbnails(boardDir string, postID int) ([]RegeneratedThumbnail, error) {
+	query := `SELECT f.id, f.post_id, f.filename, f.is_spoilered, p.is_top_post, b.dir
+	FROM DBPREFIXfiles f
+	JOIN DBPREFIXposts p ON p.id = f.post_id
+	JOIN DBPREFIXthreads t ON t.id = p.thread_id
+	JOIN DBPREFIXboards b ON b.id = t.board_id
+	WHERE p.is_deleted = FALSE AND f.filename != 'deleted' AND f.filename NOT LIKE 'embed:%'`
+	var params []any
+	if postID > 0 {
+		query += " AND p.id = ?"
+		params = append(params, postID)
+	} else {
+		query += " AND b.dir = ?"
+		params = append(params, boardDir)
+	}
+	rows, err := gcsql.Query(nil, query+" ORDER BY f.id", params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type regenUpload struct {
+		upload   gcsql.Upload
+		isOP     bool
+		boardDir string
+	}
+	var regenUploads []regenUpload
+	for rows.Next() {
+		var ru regenUpload
+		if err = rows.Scan(&ru.upload.ID, &ru.upload.PostID, &ru.upload.Filename, &ru.upload.IsSpoilered, &ru.isOP, &ru.boardDir); err != nil {
+			return nil, err
+		}
+		regenUploads = append(regenUploads, ru)
+	}
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+
+	results := make([]RegeneratedThumbnail, len(regenUploads))
+	for i, ru := range regenUploads {
+		results[i] = RegeneratedThumbnail{
+			PostID:   ru.upload.PostID,
+			Board:    ru.boardDir,
+			Filename: ru.upload.Filename,
+		}
+		if results[i].Error = regenerateThumbnail(&ru.upload, ru.boardDir, ru.isOP); results[i].Error != nil {
+			continue
+		}
+		results[i].ThumbWidth = ru.upload.ThumbnailWidth
+		results[i].ThumbHeight = ru.upload.ThumbnailHeight
+		_, results[i].Error = gcsql.Exec(nil,
+			"UPDATE DBPREFIXfiles SET width = ?, height = ?, thumbnail_width = ?, thumbnail_height = ? WHERE id = ?",
+			ru.upload.Width, ru.upload.Height, ru.upload.ThumbnailWidth, ru.upload.ThumbnailHeight, ru.upload.ID)
+	}
+	return results, nil
+}
+
+// regenerateThumbnail replaces the upload's thumbnail (and catalog thumbnail if isOP is true) and sets the upload's
+// dimensions and thumbnail dimensions
+func regenerateThumbnail(upload *gcsql.Upload, boardDir string, isOP bool) error {
+	if !IsImage(upload.Filename) && !IsVideo(upload.Filename) {
+		return ErrThumbnailUnsupported
+	}
+	documentRoot := config.GetSystemCriticalConfig().DocumentRoot
+	filePath := path.Join(documentRoot, boardDir, "src", upload.Filename)
+	thumbPath, catalogThumbPath := GetThumbnailFilenames(path.Join(documentRoot, boardDir, "thumb", upload.Filename))
+	if _, err := os.Stat(filePath); err != nil {
+		return err
+	}
+	for _, oldThumb := range []string{thumbPath, catalogThumbPath} {
+		if err := os.Remove(oldThumb); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	thumbType := ThumbnailReply
+	if isOP {
+		thumbType = ThumbnailOP
+	}
+
+	if IsVideo(upload.Filename) {
+		probe, err := probeMedia(filePath)
+		if err != nil {
+			return err
+		}
+		for _, stream := range probe.Streams {
+			if stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 {
+				upload.Width = stream.Width
+				upload.Height = stream.Height
+				break
+			}
+		}
+		if upload.IsSpoilered {
+			return createSpoilerThumbnail(upload, boardDir, isOP, thumbPath)
+		}
+		boardConfig := config.GetBoardConfig(boardDir)
+		thumbSize := boardConfig.ThumbWidthReply
+		if isOP {
+			thumbSize = boardConfig.ThumbWidth
+			if err = createVideoThumbnail(filePath, catalogThumbPath, boardConfig.ThumbWidthCatalog); err != nil {
+				return err
+			}
+		}
+		upload.ThumbnailWidth, upload.ThumbnailHeight = getThumbnailSize(upload.Width, upload.Height, boardDir, thumbType)
+		return createVideoThumbnail(filePath, thumbPath, thumbSize)
+	}
+
+	img, err := imaging.Open(filePath)
+	if err != nil {
+		return err
+	}
+	upload.Width = img.Bounds().Max.X
+	upload.Height = img.Bounds().Max.Y
+	upload.ThumbnailWidth, upload.ThumbnailHeight = getThumbnailSize(upload.Width, upload.Height, boardDir, thumbType)
+	if upload.IsSpoilered {
+		return createSpoilerThumbnail(upload, boardDir, isOP, thumbPath)
+	}
+	if isOP {
+		if err = imaging.Save(createImageThumbnail(img, boardDir, ThumbnailCatalog), catalogThumbPath); err != nil {
+			return err
+		}
+	}
+	if ShouldCreateThumbnail(filePath, upload.Width, upload.Height, upload.ThumbnailWidth, upload.ThumbnailHeight) {
+		return imaging.Save(createImageThumbnail(img, boardDir, thumbType), thumbPath)
+	}
+	// the image fits in the thumbnail size, so the thumbnail is a symlink to the original
+	upload.ThumbnailWidth = upload.Width
+	upload.ThumbnailHeight = upload.Height
+	return os.Symlink(filePath, thumbPath)
+}
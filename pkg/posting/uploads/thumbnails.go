@@ -4,7 +4,6 @@ import (
 	"errors"
 	"image"
 	"os"
-	"os/exec"
 	"path"
 	"strconv"
 	"strings"
@@ -33,6 +32,11 @@ var (
 		".webp": ".png",
 		".jfif": ".jpg",
 		".jpeg": ".jpg",
+		".mp3":  ".png",
+		".ogg":  ".png",
+		".flac": ".png",
+		".opus": ".png",
+		".pdf":  ".png",
 	}
 	ErrUnableToCreateSpoiler = errors.New("unable to create spoiler thumbnail")
 )
@@ -79,15 +83,7 @@ func createImageThumbnail(imageObj image.Image, boardDir string, thumbType Thumb
 
 func createVideoThumbnail(video, thumb string, size int) error {
 	sizeStr := strconv.Itoa(size)
-	outputBytes, err := exec.Command("ffmpeg", "-y" /* "-itsoffset", "-1", */, "-i", video, "-vframes", "1", "-filter:v", "scale='min("+sizeStr+"\\, "+sizeStr+"):-1'", thumb).CombinedOutput()
-	if err != nil {
-		outputStringArr := strings.Split(string(outputBytes), "\n")
-		if len(outputStringArr) > 1 {
-			outputString := outputStringArr[len(outputStringArr)-2]
-			err = errors.New(outputString)
-		}
-	}
-	return err
+	return runFFmpeg("-y" /* "-itsoffset", "-1", */, "-i", video, "-vframes", "1", "-filter:v", "scale='min("+sizeStr+"\\, "+sizeStr+"):-1'", thumb)
 }
 
 func createSpoilerThumbnail(upload *gcsql.Upload, board string, isOP bool, thumbnailPath string) error {
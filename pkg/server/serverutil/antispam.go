@@ -25,7 +25,8 @@ const (
 
 type RefererResult int
 
-// CheckReferer checks to make sure that the incoming request is from the same domain
+// CheckReferer checks to make sure that the incoming request is from the same domain. If the request was made over
+// HTTPS, the referer must also use HTTPS to be considered internal
 func CheckReferer(request *http.Request) (RefererResult, error) {
 	referer := request.Referer()
 	if referer == "" {
@@ -41,7 +42,7 @@ func CheckReferer(request *http.Request) (RefererResult, error) {
 		Host: systemCriticalConfig.SiteHost,
 	}
 	var result RefererResult = ExternalReferer
-	if rURL.Host == siteURLBase.Host {
+	if rURL.Host == siteURLBase.Host && (!IsSecureRequest(request) || rURL.Scheme == "https") {
 		result = InternalReferer
 	}
 	return result, nil
@@ -1,6 +1,7 @@
 package serverutil
 
 import (
+	"crypto/tls"
 	"net/http"
 	"testing"
 
@@ -39,6 +40,20 @@ var (
 			siteHost:       "[::1]:8080",
 			expectedResult: InternalReferer,
 		},
+		{
+			desc:           "Internal referer, HTTPS request",
+			referer:        "https://gochan.org",
+			siteHost:       "gochan.org",
+			secure:         true,
+			expectedResult: InternalReferer,
+		},
+		{
+			desc:           "HTTP referer, HTTPS request",
+			referer:        "http://gochan.org",
+			siteHost:       "gochan.org",
+			secure:         true,
+			expectedResult: ExternalReferer,
+		},
 	}
 )
 
@@ -46,6 +61,7 @@ type checkRefererTestCase struct {
 	desc           string
 	referer        string
 	siteHost       string
+	secure         bool
 	expectedResult RefererResult
 }
 
@@ -61,6 +77,10 @@ func TestCheckReferer(t *testing.T) {
 			systemCriticalConfig.SiteHost = tC.siteHost
 			config.SetSystemCriticalConfig(systemCriticalConfig)
 			req.Header.Set("Referer", tC.referer)
+			req.TLS = nil
+			if tC.secure {
+				req.TLS = &tls.ConnectionState{}
+			}
 			result, err := CheckReferer(req)
 			assert.NoError(t, err)
 			assert.Equal(t, tC.expectedResult, result)
@@ -16,7 +16,9 @@ import (
 var (
 	buf  bytes.Buffer
 	data = map[string]any{
-		"logText": "text goes here",
+		"errorTitle":  "Error :c",
+		"errorHeader": "Error",
+		"errorText":   "text goes here",
 	}
 	luaStringTemplateTestCases = []luaTemplateTestCase[string]{
 		{
@@ -24,15 +26,15 @@ var (
 			template: gctemplates.ErrorPage,
 			data:     data,
 			luaScript: `local serverutil = require("serverutil")
-				return serverutil.minify_template("manage_viewlog.html", data, buf, "text/html")`,
-			expectString: `<textarea class=viewlog rows=24 spellcheck=false readonly>text goes here</textarea>`,
+				return serverutil.minify_template("error.html", data, buf, "text/html")`,
+			expectString: `<!doctype html><meta charset=utf-8><title>Error :c</title><h1>Error</h1><p>text goes here<hr><address>Site powered by Gochan ` + config.GochanVersion + `</address>`,
 		},
 		{
 			desc:     "minify HTML with nil data",
-			template: gctemplates.ManageViewLog,
+			template: gctemplates.FrontIntro,
 			luaScript: `local serverutil = require("serverutil")
-			return serverutil.minify_template("manage_viewlog.html", nil, buf, "text/html")`,
-			expectString: `<textarea class=viewlog rows=24 spellcheck=false readonly></textarea>`,
+			return serverutil.minify_template("front_intro.html", nil, buf, "text/html")`,
+			expectString: `Welcome to Gochan!`,
 		},
 		{
 			desc:     "error, unrecognized template name",
@@ -3,6 +3,8 @@ package serverutil
 import (
 	"net/http"
 	"time"
+
+	"github.com/gochan-org/gochan/pkg/gcutil"
 )
 
 // DeleteCookie deletes the given cookie if it exists. It returns true if it exists and false
@@ -18,6 +20,13 @@ func DeleteCookie(writer http.ResponseWriter, request *http.Request, cookieName
 	return true
 }
 
+// IsSecureRequest returns true if the request was made over HTTPS, either directly to gochan when TLS is configured,
+// to a web server that sets the HTTPS FastCGI parameter, or to a trusted reverse proxy that sets the Forwarded or
+// X-Forwarded-Proto header
+func IsSecureRequest(request *http.Request) bool {
+	return request.TLS != nil || gcutil.IsForwardedHTTPS(request)
+}
+
 func IsRequestingJSON(request *http.Request) bool {
 	jsonField := request.FormValue("json")
 	if jsonField == "" {
@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/events"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+)
+
+var initOnce sync.Once
+
+// Init registers the event handlers that send the payloads to the webhooks. It should be called after the database
+// is initialized
+func Init() {
+	initOnce.Do(func() {
+		// the handler looks up the webhooks in the database, so it runs asynchronously to avoid slowing down the
+		// request that triggered the event
+		events.RegisterEvent(Events, handleEvent, events.Async())
+	})
+}
+
+// handleEvent sends the event to the active webhooks that are triggered by it. Errors are logged instead of returned,
+// so that a broken webhook doesn't stop the action that triggered the event
+func handleEvent(trigger string, data ...any) error {
+	var eventData any
+	if len(data) > 0 {
+		eventData = data[0]
+	}
+	payload, board, err := buildPayload(trigger, eventData)
+	if err != nil {
+		gcutil.LogError(err).Caller().Str("event", trigger).Msg("Unable to build webhook payload")
+		return nil
+	}
+	webhooks, err := gcsql.GetWebhooks(gcsql.OnlyTrue)
+	if err != nil {
+		gcutil.LogError(err).Caller().Str("event", trigger).Msg("Unable to get webhooks")
+		return nil
+	}
+	for _, webhook := range webhooks {
+		if !webhook.HasEvent(trigger) || (board != "" && !webhook.WatchesBoard(board)) {
+			continue
+		}
+		deliverAsync(webhook, payload)
+	}
+	return nil
+}
+
+// buildPayload returns the payload for the event and the directory of the board it happened on, if the webhooks
+// should be filtered by board. Poster IP addresses are only included for bans and staff logins
+func buildPayload(trigger string, eventData any) (*Payload, string, error) {
+	if !slices.Contains(Events, trigger) {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownEvent, trigger)
+	}
+	switch event := eventData.(type) {
+	case *gcsql.ReportCreatedPayload:
+		return newPayload(trigger, map[string]any{
+			"report_id": event.Report.ID,
+			"post_id":   event.Report.PostID,
+			"reason":    event.Report.Reason,
+			"category":  event.Report.Category,
+		}), "", nil
+	case *gcsql.AppealCreatedPayload:
+		return newPayload(trigger, map[string]any{
+			"appeal_id": event.AppealID,
+			"ban_id":    event.Ban.ID,
+			"message":   event.Message,
+		}), "", nil
+	case *gcsql.FilterHitPayload:
+		return newPayload(trigger, map[string]any{
+			"filter_id":    event.Filter.ID,
+			"match_action": event.Filter.MatchAction,
+			"staff_note":   event.Filter.StaffNote,
+			"name":         event.Post.Name,
+			"subject":      event.Post.Subject,
+			"message":      event.Post.MessageRaw,
+		}), "", nil
+	case *gcsql.PostCreatedPayload:
+		post, board := event.Post, event.Board
+		return newPayload(trigger, map[string]any{
+			"board":       board.Dir,
+			"board_title": board.Title,
+			"thread_id":   post.ID,
+			"name":        post.Name,
+			"tripcode":    post.Tripcode,
+			"subject":     post.Subject,
+			"message":     post.MessageRaw,
+			"path":        config.WebPath(board.Dir, "res", strconv.Itoa(post.ID)+".html"),
+		}), board.Dir, nil
+	case *gcsql.StaffLoginPayload:
+		return newPayload(trigger, map[string]any{
+			"username": event.Staff.Username,
+			"rank":     event.Staff.Rank,
+			"ip":       event.IP,
+		}), "", nil
+	case *gcsql.BanIssuedPayload:
+		ban := event.Ban
+		ip, err := ban.IP()
+		if err != nil {
+			return nil, "", err
+		}
+		payloadData := map[string]any{
+			"ban_id":        ban.ID,
+			"ip":            ip,
+			"staff_id":      ban.StaffID,
+			"global":        ban.IsGlobalBan(),
+			"is_thread_ban": ban.IsThreadBan,
+			"permanent":     ban.Permanent,
+			"can_appeal":    ban.CanAppeal,
+			"reason":        ban.Message,
+			"staff_note":    ban.StaffNote,
+		}
+		if !ban.Permanent {
+			payloadData["expires_at"] = ban.ExpiresAt
+		}
+		return newPayload(trigger, payloadData), "", nil
+	}
+	return nil, "", fmt.Errorf(events.InvalidArgumentErrorStr, trigger)
+}
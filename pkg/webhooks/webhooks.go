@@ -0,0 +1,302 @@
+// Package webhooks sends HTTP POST requests to the webhooks configured on the /manage/webhooks page when moderation
+// events like new reports, appeals, and bans are triggered, so that staff can be notified by a chat app or other
+// external services
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	"github.com/gochan-org/gochan/pkg/gcutil"
+	"github.com/gochan-org/gochan/pkg/metrics"
+)
+
+const (
+	// MaxRetriesLimit is the highest number of times a failed delivery can be retried
+	MaxRetriesLimit = 10
+	// TestEvent is the event sent by SendTest
+	TestEvent = "test"
+)
+
+var (
+	ErrInvalidName       = errors.New("webhook name must be between 1 and 45 characters")
+	ErrInvalidURL        = errors.New("webhook URL must be an absolute http or https URL")
+	ErrNoEvents          = errors.New("webhook must have at least one event")
+	ErrUnknownEvent      = errors.New("unrecognized webhook event")
+	ErrInvalidMaxRetries = fmt.Errorf("webhook retries must be between 0 and %d", MaxRetriesLimit)
+	ErrInvalidTemplate   = errors.New("invalid webhook payload template")
+
+	// Events is the list of events that webhooks can be triggered by
+	Events = []string{"report-created", "appeal-created", "filter-hit", "thread-created", "staff-login", "ban-issued"}
+
+	deliveries = metrics.NewCounterVec("gochan_webhook_deliveries_total",
+		"Number of webhook deliveries, by result after any retries", "result")
+
+	client = &http.Client{Timeout: 10 * time.Second}
+	// retryBaseDelay is the delay before the first retry of a failed delivery, doubling after each retry up to
+	// maxRetryDelay
+	retryBaseDelay = time.Second
+	maxRetryDelay  = time.Minute
+
+	// deliveriesCtx is cancelled by Stop to stop retrying failed deliveries
+	deliveriesCtx, cancelDeliveries = context.WithCancel(context.Background())
+	runningDeliveries               sync.WaitGroup
+
+	lastDeliveriesMutex sync.Mutex
+	lastDeliveries      = map[int]Delivery{}
+)
+
+// Payload is the data sent to a webhook when one of its events is triggered. It is sent as JSON, or used as the
+// template data if the webhook has a payload template
+type Payload struct {
+	Event     string         `json:"event"`
+	Timestamp time.Time      `json:"timestamp"`
+	SiteName  string         `json:"site_name"`
+	Data      map[string]any `json:"data"`
+}
+
+// Delivery is the result of sending a payload to a webhook
+type Delivery struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// OK returns true if the webhook responded with a 2xx status code
+func (d Delivery) OK() bool {
+	return d.Error == ""
+}
+
+func newPayload(event string, data map[string]any) *Payload {
+	return &Payload{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		SiteName:  config.GetSiteConfig().SiteName,
+		Data:      data,
+	}
+}
+
+func parsePayloadTemplate(tmplStr string) (*template.Template, error) {
+	return template.New("payload").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			ba, err := json.Marshal(v)
+			return string(ba), err
+		},
+	}).Option("missingkey=zero").Parse(tmplStr)
+}
+
+// renderPayload returns the JSON body to send to the webhook, using its payload template if it has one
+func renderPayload(webhook *gcsql.Webhook, payload *Payload) ([]byte, error) {
+	if webhook.PayloadTemplate == "" {
+		return json.Marshal(payload)
+	}
+	tmpl, err := parsePayloadTemplate(webhook.PayloadTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, payload); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("%w: the template output is not valid JSON", ErrInvalidTemplate)
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign returns the value of the X-Gochan-Signature header for a request with the given timestamp and body, which
+// receivers can use to verify that the request came from gochan
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Validate checks the webhook's fields, trimming spaces from its name, URL, events, and boards
+func Validate(webhook *gcsql.Webhook) error {
+	webhook.Name = strings.TrimSpace(webhook.Name)
+	if webhook.Name == "" || len(webhook.Name) > 45 {
+		return ErrInvalidName
+	}
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	events := webhook.EventList()
+	if len(events) == 0 {
+		return ErrNoEvents
+	}
+	for _, event := range events {
+		if !slices.Contains(Events, event) {
+			return fmt.Errorf("%w: %q", ErrUnknownEvent, event)
+		}
+	}
+	webhook.Events = strings.Join(events, ",")
+	webhook.Boards = strings.Join(webhook.BoardList(), ",")
+	if webhook.MaxRetries < 0 || webhook.MaxRetries > MaxRetriesLimit {
+		return ErrInvalidMaxRetries
+	}
+	// make sure that the template produces valid JSON
+	_, err = renderPayload(webhook, newPayload(TestEvent, testData()))
+	return err
+}
+
+// post sends the body to the webhook once, returning the response status code and an error if the request failed or
+// the status code wasn't 2xx
+func post(ctx context.Context, webhook *gcsql.Webhook, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gochan/"+config.GochanVersion)
+	req.Header.Set("X-Gochan-Event", event)
+	req.Header.Set("X-Gochan-Timestamp", timestamp)
+	if webhook.Secret != "" {
+		req.Header.Set("X-Gochan-Signature", Sign(webhook.Secret, timestamp, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// shouldRetry returns true if a request that failed with the given status code (or 0 if no response was received)
+// may succeed if it is sent again
+func shouldRetry(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func retryDelay(retry int) time.Duration {
+	delay := retryBaseDelay << (retry - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
+
+// deliver sends the payload to the webhook, retrying with exponential backoff if it fails with a network error, a 429
+// status, or a 5xx status, up to the webhook's MaxRetries
+func deliver(ctx context.Context, webhook *gcsql.Webhook, payload *Payload, maxRetries int) Delivery {
+	delivery := Delivery{Event: payload.Event, Time: time.Now()}
+	body, err := renderPayload(webhook, payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	for {
+		delivery.Attempts++
+		delivery.StatusCode, err = post(ctx, webhook, payload.Event, body)
+		if err == nil {
+			delivery.Error = ""
+			return delivery
+		}
+		delivery.Error = err.Error()
+		if delivery.Attempts > maxRetries || !shouldRetry(delivery.StatusCode) {
+			return delivery
+		}
+		timer := time.NewTimer(retryDelay(delivery.Attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return delivery
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverAsync sends the payload to the webhook in the background and records the result
+func deliverAsync(webhook gcsql.Webhook, payload *Payload) {
+	runningDeliveries.Add(1)
+	go func() {
+		defer runningDeliveries.Done()
+		delivery := deliver(deliveriesCtx, &webhook, payload, webhook.MaxRetries)
+		recordDelivery(&webhook, &delivery)
+	}()
+}
+
+func recordDelivery(webhook *gcsql.Webhook, delivery *Delivery) {
+	lastDeliveriesMutex.Lock()
+	lastDeliveries[webhook.ID] = *delivery
+	lastDeliveriesMutex.Unlock()
+
+	if delivery.OK() {
+		deliveries.Inc("success")
+		gcutil.LogInfo().
+			Int("webhookID", webhook.ID).
+			Str("event", delivery.Event).
+			Int("attempts", delivery.Attempts).
+			Msg("Delivered webhook")
+		return
+	}
+	deliveries.Inc("failure")
+	gcutil.LogWarning().
+		Int("webhookID", webhook.ID).
+		Str("event", delivery.Event).
+		Int("attempts", delivery.Attempts).
+		Int("status", delivery.StatusCode).
+		Str("error", delivery.Error).
+		Msg("Unable to deliver webhook")
+}
+
+func testData() map[string]any {
+	return map[string]any{"message": "This is a test of the webhook"}
+}
+
+// SendTest sends a test payload to the webhook once (without retrying if it fails) and waits for the result
+func SendTest(webhook *gcsql.Webhook) Delivery {
+	delivery := deliver(deliveriesCtx, webhook, newPayload(TestEvent, testData()), 0)
+	recordDelivery(webhook, &delivery)
+	return delivery
+}
+
+// LastDelivery returns the result of the most recent delivery to the webhook with the given ID since gochan started,
+// or nil if nothing has been sent to it
+func LastDelivery(id int) *Delivery {
+	lastDeliveriesMutex.Lock()
+	defer lastDeliveriesMutex.Unlock()
+	delivery, ok := lastDeliveries[id]
+	if !ok {
+		return nil
+	}
+	return &delivery
+}
+
+// Wait waits for any deliveries that are in progress (including their retries) to finish
+func Wait() {
+	runningDeliveries.Wait()
+}
+
+// Stop cancels the retries of any failed deliveries and waits for the deliveries in progress to finish
+func Stop() {
+	cancelDeliveries()
+	Wait()
+}
@@ -0,0 +1,291 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gochan-org/gochan/pkg/config"
+	"github.com/gochan-org/gochan/pkg/gcsql"
+	_ "github.com/gochan-org/gochan/pkg/gcsql/initsql"
+	"github.com/stretchr/testify/assert"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver is a webhook receiver that responds to each request with the next status code in statuses (or 200 if
+// there are none left) and records the requests
+type testReceiver struct {
+	mutex    sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func (tr *testReceiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	tr.requests = append(tr.requests, receivedRequest{header: request.Header.Clone(), body: body})
+	status := http.StatusOK
+	if len(tr.statuses) > 0 {
+		status = tr.statuses[0]
+		tr.statuses = tr.statuses[1:]
+	}
+	writer.WriteHeader(status)
+}
+
+func (tr *testReceiver) received() []receivedRequest {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+	return tr.requests
+}
+
+func setupTestReceiver(t *testing.T, statuses ...int) (*testReceiver, *httptest.Server) {
+	t.Helper()
+	config.InitTestConfig()
+	oldDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	receiver := &testReceiver{statuses: statuses}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(func() {
+		Wait()
+		server.Close()
+		retryBaseDelay = oldDelay
+	})
+	return receiver, server
+}
+
+func TestDeliverSignsPayload(t *testing.T) {
+	receiver, server := setupTestReceiver(t)
+	webhook := &gcsql.Webhook{ID: 1, URL: server.URL, Secret: "hunter2"}
+	delivery := deliver(t.Context(), webhook, newPayload("report-created", map[string]any{"report_id": 1}), 3)
+	assert.True(t, delivery.OK())
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.StatusCode)
+
+	requests := receiver.received()
+	if !assert.Len(t, requests, 1) {
+		t.FailNow()
+	}
+	header := requests[0].header
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "report-created", header.Get("X-Gochan-Event"))
+
+	mac := hmac.New(sha256.New, []byte("hunter2"))
+	mac.Write([]byte(header.Get("X-Gochan-Timestamp") + "."))
+	mac.Write(requests[0].body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), header.Get("X-Gochan-Signature"))
+
+	var payload Payload
+	assert.NoError(t, json.Unmarshal(requests[0].body, &payload))
+	assert.Equal(t, "report-created", payload.Event)
+	assert.Equal(t, config.GetSiteConfig().SiteName, payload.SiteName)
+	assert.EqualValues(t, 1, payload.Data["report_id"])
+
+	// the signature header is omitted if the webhook doesn't have a secret
+	webhook.Secret = ""
+	assert.True(t, SendTest(webhook).OK())
+	requests = receiver.received()
+	if assert.Len(t, requests, 2) {
+		assert.Empty(t, requests[1].header.Get("X-Gochan-Signature"))
+		assert.Equal(t, TestEvent, requests[1].header.Get("X-Gochan-Event"))
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	receiver, server := setupTestReceiver(t,
+		// retried until it succeeds
+		http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK,
+		// client errors aren't retried
+		http.StatusBadRequest,
+		// gives up after MaxRetries
+		http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway,
+	)
+	webhook := &gcsql.Webhook{ID: 1, URL: server.URL}
+	payload := newPayload("ban-issued", nil)
+
+	delivery := deliver(t.Context(), webhook, payload, 3)
+	assert.True(t, delivery.OK())
+	assert.Equal(t, 3, delivery.Attempts)
+
+	delivery = deliver(t.Context(), webhook, payload, 3)
+	assert.False(t, delivery.OK())
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusBadRequest, delivery.StatusCode)
+
+	delivery = deliver(t.Context(), webhook, payload, 2)
+	assert.False(t, delivery.OK())
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusBadGateway, delivery.StatusCode)
+	assert.Len(t, receiver.received(), 7)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Second, retryDelay(1))
+	assert.Equal(t, 4*time.Second, retryDelay(3))
+	assert.Equal(t, maxRetryDelay, retryDelay(MaxRetriesLimit))
+}
+
+func TestRenderPayloadTemplate(t *testing.T) {
+	config.InitTestConfig()
+	payload := newPayload("thread-created", map[string]any{"board": "test", "subject": `"quoted" subject`})
+	webhook := &gcsql.Webhook{
+		PayloadTemplate: `{"content": {{json (printf "New thread on /%s/: %s" .Data.board .Data.subject)}}}`,
+	}
+	body, err := renderPayload(webhook, payload)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"content": "New thread on /test/: \"quoted\" subject"}`, string(body))
+
+	webhook.PayloadTemplate = `{"content": "{{.Data.subject}}"}`
+	_, err = renderPayload(webhook, payload)
+	assert.ErrorIs(t, err, ErrInvalidTemplate, "templates that don't produce valid JSON should be rejected")
+
+	webhook.PayloadTemplate = `{{.Data.subject`
+	_, err = renderPayload(webhook, payload)
+	assert.ErrorIs(t, err, ErrInvalidTemplate)
+}
+
+func TestValidate(t *testing.T) {
+	config.InitTestConfig()
+	webhook := &gcsql.Webhook{
+		Name:       " Staff chat ",
+		URL:        "https://chat.example.com/hooks/abc",
+		Events:     "report-created, ban-issued,",
+		Boards:     " test ,,",
+		MaxRetries: 3,
+	}
+	assert.NoError(t, Validate(webhook))
+	assert.Equal(t, "Staff chat", webhook.Name)
+	assert.Equal(t, "report-created,ban-issued", webhook.Events)
+	assert.Equal(t, "test", webhook.Boards)
+
+	testCases := []struct {
+		desc   string
+		modify func(*gcsql.Webhook)
+		err    error
+	}{
+		{"empty name", func(wh *gcsql.Webhook) { wh.Name = "" }, ErrInvalidName},
+		{"relative URL", func(wh *gcsql.Webhook) { wh.URL = "/hooks/abc" }, ErrInvalidURL},
+		{"unsupported scheme", func(wh *gcsql.Webhook) { wh.URL = "ftp://example.com" }, ErrInvalidURL},
+		{"no events", func(wh *gcsql.Webhook) { wh.Events = " , " }, ErrNoEvents},
+		{"unknown event", func(wh *gcsql.Webhook) { wh.Events = "report-created,startup" }, ErrUnknownEvent},
+		{"too many retries", func(wh *gcsql.Webhook) { wh.MaxRetries = MaxRetriesLimit + 1 }, ErrInvalidMaxRetries},
+		{"invalid template", func(wh *gcsql.Webhook) { wh.PayloadTemplate = "not json" }, ErrInvalidTemplate},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			invalid := *webhook
+			tc.modify(&invalid)
+			assert.ErrorIs(t, Validate(&invalid), tc.err)
+		})
+	}
+}
+
+func TestBuildPayload(t *testing.T) {
+	config.InitTestConfig()
+	payload, board, err := buildPayload("report-created", &gcsql.ReportCreatedPayload{
+		Report: &gcsql.Report{ID: 2, PostID: 3, IP: "192.168.56.1", Reason: "spam"},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, board)
+	assert.Equal(t, "spam", payload.Data["reason"])
+	assert.NotContains(t, payload.Data, "ip", "reporter IPs shouldn't be sent")
+
+	post := &gcsql.Post{ID: 5, IsTopPost: true, IP: "192.168.56.1", Subject: "Hello", MessageRaw: "world"}
+	payload, board, err = buildPayload("thread-created", &gcsql.PostCreatedPayload{
+		Post: post, Board: &gcsql.Board{Dir: "test", Title: "Testing board"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", board)
+	assert.Equal(t, 5, payload.Data["thread_id"])
+	assert.Equal(t, config.WebPath("test/res/5.html"), payload.Data["path"])
+	assert.NotContains(t, payload.Data, "ip")
+
+	payload, _, err = buildPayload("ban-issued", &gcsql.BanIssuedPayload{
+		Ban: &gcsql.IPBan{ID: 1, RangeStart: "192.168.56.1", RangeEnd: "192.168.56.1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.56.1", payload.Data["ip"])
+	assert.Equal(t, true, payload.Data["global"])
+
+	_, _, err = buildPayload("thread-created", post)
+	assert.Error(t, err)
+	_, _, err = buildPayload("staff-login", "admin")
+	assert.Error(t, err)
+	_, _, err = buildPayload("startup", nil)
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
+
+func TestHandleEvent(t *testing.T) {
+	receiver, server := setupTestReceiver(t)
+	config.SetTestDBConfig("sqlite3", filepath.Join(t.TempDir(), "gochan.db"), "gochan", "gochan", "gochan", "")
+	sqlConfig := config.GetSQLConfig()
+	if !assert.NoError(t, gcsql.ConnectToDB(&sqlConfig)) {
+		t.FailNow()
+	}
+	db, err := gcsql.GetDatabase()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	_, err = gcsql.ExecSQL(`CREATE TABLE DBPREFIXwebhooks(
+		id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+		name VARCHAR(45) NOT NULL,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,
+		boards TEXT NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		payload_template TEXT NOT NULL,
+		max_retries SMALLINT NOT NULL DEFAULT 3,
+		is_active BOOL NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)`)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, webhook := range []gcsql.Webhook{
+		{Name: "all boards", URL: server.URL + "/all", Events: "thread-created", IsActive: true},
+		{Name: "watched board", URL: server.URL + "/watched", Events: "thread-created", Boards: "test", IsActive: true},
+		{Name: "other board", URL: server.URL + "/other", Events: "thread-created", Boards: "other", IsActive: true},
+		{Name: "other event", URL: server.URL + "/reports", Events: "report-created", IsActive: true},
+		{Name: "inactive", URL: server.URL + "/inactive", Events: "thread-created", IsActive: false},
+	} {
+		assert.NoError(t, gcsql.CreateWebhook(&webhook))
+	}
+
+	post := &gcsql.Post{ID: 1, IsTopPost: true, Subject: "Hello"}
+	assert.NoError(t, handleEvent("thread-created", &gcsql.PostCreatedPayload{Post: post, Board: &gcsql.Board{Dir: "test"}}))
+	Wait()
+	var events []string
+	for _, request := range receiver.received() {
+		events = append(events, request.header.Get("X-Gochan-Event"))
+	}
+	assert.Equal(t, []string{"thread-created", "thread-created"}, events,
+		"only active webhooks for the event that watch the board should be sent")
+
+	webhooks, err := gcsql.GetWebhooks(gcsql.OnlyTrue)
+	assert.NoError(t, err)
+	assert.Len(t, webhooks, 4)
+	delivered := map[string]bool{}
+	for _, webhook := range webhooks {
+		delivered[webhook.Name] = LastDelivery(webhook.ID) != nil
+	}
+	assert.Equal(t, map[string]bool{
+		"all boards": true, "watched board": true, "other board": false, "other event": false,
+	}, delivered)
+
+	// errors are logged instead of returned, so that they don't stop the action that triggered the event
+	assert.NoError(t, handleEvent("thread-created", post))
+}
@@ -68,6 +68,7 @@ CREATE TABLE DBPREFIXposts(
 	password TEXT NOT NULL,
 	deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	is_deleted BOOL NOT NULL DEFAULT FALSE,
+	is_hidden BOOL NOT NULL DEFAULT FALSE,
 	banned_message TEXT,
 	flag VARCHAR(45) NOT NULL DEFAULT '',
 	country VARCHAR(80) NOT NULL DEFAULT '',
@@ -84,6 +85,7 @@ CREATE TABLE DBPREFIXfiles(
 	original_filename VARCHAR(255) NOT NULL,
 	filename VARCHAR(45) NOT NULL,
 	checksum TEXT NOT NULL,
+	stored_checksum VARCHAR(45) NOT NULL DEFAULT '',
 	file_size INT NOT NULL,
 	is_spoilered BOOL NOT NULL,
 	thumbnail_width INT NOT NULL,
@@ -185,6 +187,8 @@ CREATE TABLE DBPREFIXip_ban_appeals(
 	ip_ban_id {fk to serial} NOT NULL,
 	appeal_text TEXT NOT NULL,
 	is_denied BOOL NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	staff_unread BOOL NOT NULL DEFAULT TRUE,
 	timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	CONSTRAINT DBPREFIXip_ban_appeals_staff_id_fk
 		FOREIGN KEY(staff_id) REFERENCES DBPREFIXstaff(id),
@@ -224,6 +228,7 @@ CREATE TABLE DBPREFIXreports(
 	post_id {fk to serial} NOT NULL,
 	ip {inet} NOT NULL,
 	reason TEXT NOT NULL,
+	category VARCHAR(45) NOT NULL DEFAULT '',
 	is_cleared BOOL NOT NULL,
 	timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	CONSTRAINT DBPREFIXreports_handled_by_staff_id_fk
@@ -292,6 +297,54 @@ CREATE TABLE DBPREFIXfilter_hits(
 		ON DELETE CASCADE
 );
 
+CREATE TABLE DBPREFIXfile_metadata(
+	id {serial pk},
+	file_id {fk to serial} NOT NULL,
+	name VARCHAR(45) NOT NULL,
+	value TEXT NOT NULL,
+	CONSTRAINT DBPREFIXfile_metadata_file_id_fk
+		FOREIGN KEY(file_id) REFERENCES DBPREFIXfiles(id)
+		ON DELETE CASCADE,
+	CONSTRAINT DBPREFIXfile_metadata_file_id_name_unique UNIQUE(file_id, name)
+);
+
+CREATE TABLE DBPREFIXfile_fingerprints(
+	id {serial pk},
+	file_id {fk to serial} NOT NULL,
+	algorithm VARCHAR(16) NOT NULL,
+	fingerprint VARCHAR(255) NOT NULL,
+	CONSTRAINT DBPREFIXfile_fingerprints_file_id_fk
+		FOREIGN KEY(file_id) REFERENCES DBPREFIXfiles(id)
+		ON DELETE CASCADE,
+	CONSTRAINT DBPREFIXfile_fingerprints_file_id_algorithm_unique UNIQUE(file_id, algorithm)
+);
+
+CREATE TABLE DBPREFIXreport_bans(
+	id {serial pk},
+	staff_id {fk to serial},
+	ip {inet} NOT NULL,
+	issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	permanent BOOL NOT NULL DEFAULT FALSE,
+	reason TEXT NOT NULL,
+	CONSTRAINT DBPREFIXreport_bans_staff_id_fk
+		FOREIGN KEY(staff_id) REFERENCES DBPREFIXstaff(id)
+		ON DELETE SET NULL
+);
+
+CREATE TABLE DBPREFIXwebhooks(
+	id {serial pk},
+	name VARCHAR(45) NOT NULL,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	boards TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	payload_template TEXT NOT NULL,
+	max_retries SMALLINT NOT NULL DEFAULT 3,
+	is_active BOOL NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 
 INSERT INTO DBPREFIXdatabase_version(component, version)
 	VALUES('gochan', DBVERSION);
@@ -61,6 +61,7 @@ CREATE TABLE DBPREFIXposts(
 	password TEXT NOT NULL,
 	deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	is_deleted BOOL NOT NULL DEFAULT FALSE,
+	is_hidden BOOL NOT NULL DEFAULT FALSE,
 	banned_message TEXT,
 	flag VARCHAR(45) NOT NULL DEFAULT '',
 	country VARCHAR(80) NOT NULL DEFAULT '',
@@ -77,6 +78,7 @@ CREATE TABLE DBPREFIXfiles(
 	original_filename VARCHAR(255) NOT NULL,
 	filename VARCHAR(45) NOT NULL,
 	checksum TEXT NOT NULL,
+	stored_checksum VARCHAR(45) NOT NULL DEFAULT '',
 	file_size INT NOT NULL,
 	is_spoilered BOOL NOT NULL,
 	thumbnail_width INT NOT NULL,
@@ -178,6 +180,8 @@ CREATE TABLE DBPREFIXip_ban_appeals(
 	ip_ban_id BIGINT NOT NULL,
 	appeal_text TEXT NOT NULL,
 	is_denied BOOL NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	staff_unread BOOL NOT NULL DEFAULT TRUE,
 	timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	CONSTRAINT DBPREFIXip_ban_appeals_staff_id_fk
 		FOREIGN KEY(staff_id) REFERENCES DBPREFIXstaff(id),
@@ -217,6 +221,7 @@ CREATE TABLE DBPREFIXreports(
 	post_id BIGINT NOT NULL,
 	ip VARBINARY(16) NOT NULL,
 	reason TEXT NOT NULL,
+	category VARCHAR(45) NOT NULL DEFAULT '',
 	is_cleared BOOL NOT NULL,
 	timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	CONSTRAINT DBPREFIXreports_handled_by_staff_id_fk
@@ -285,5 +290,53 @@ CREATE TABLE DBPREFIXfilter_hits(
 		ON DELETE CASCADE
 );
 
+CREATE TABLE DBPREFIXfile_metadata(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	file_id BIGINT NOT NULL,
+	name VARCHAR(45) NOT NULL,
+	value TEXT NOT NULL,
+	CONSTRAINT DBPREFIXfile_metadata_file_id_fk
+		FOREIGN KEY(file_id) REFERENCES DBPREFIXfiles(id)
+		ON DELETE CASCADE,
+	CONSTRAINT DBPREFIXfile_metadata_file_id_name_unique UNIQUE(file_id, name)
+);
+
+CREATE TABLE DBPREFIXfile_fingerprints(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	file_id BIGINT NOT NULL,
+	algorithm VARCHAR(16) NOT NULL,
+	fingerprint VARCHAR(255) NOT NULL,
+	CONSTRAINT DBPREFIXfile_fingerprints_file_id_fk
+		FOREIGN KEY(file_id) REFERENCES DBPREFIXfiles(id)
+		ON DELETE CASCADE,
+	CONSTRAINT DBPREFIXfile_fingerprints_file_id_algorithm_unique UNIQUE(file_id, algorithm)
+);
+
+CREATE TABLE DBPREFIXreport_bans(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	staff_id BIGINT,
+	ip VARBINARY(16) NOT NULL,
+	issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	permanent BOOL NOT NULL DEFAULT FALSE,
+	reason TEXT NOT NULL,
+	CONSTRAINT DBPREFIXreport_bans_staff_id_fk
+		FOREIGN KEY(staff_id) REFERENCES DBPREFIXstaff(id)
+		ON DELETE SET NULL
+);
+
+CREATE TABLE DBPREFIXwebhooks(
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name VARCHAR(45) NOT NULL,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	boards TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	payload_template TEXT NOT NULL,
+	max_retries SMALLINT NOT NULL DEFAULT 3,
+	is_active BOOL NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 INSERT INTO DBPREFIXdatabase_version(component, version)
 	VALUES('gochan', DBVERSION);
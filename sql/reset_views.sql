@@ -27,7 +27,7 @@ SELECT id, thread_id FROM DBPREFIXposts WHERE is_top_post;
 
 CREATE VIEW DBPREFIXv_building_posts AS
 SELECT p.id AS id, p.thread_id AS thread_id, INET6_NTOA(ip) as ip, name, tripcode, is_secure_tripcode,
-email, subject, created_on, created_on as last_modified, op.id AS parent_id, t.last_bump as last_bump,
+is_role_signature, email, subject, created_on, created_on as last_modified, op.id AS parent_id, t.last_bump as last_bump,
 message, message_raw, COALESCE(banned_message, '') AS banned_message, t.board_id,
 (SELECT dir FROM DBPREFIXboards WHERE id = t.board_id LIMIT 1) AS dir,
 COALESCE(f.original_filename, '') as original_filename,
@@ -39,7 +39,7 @@ COALESCE(f.thumbnail_height, 0) AS th,
 COALESCE(f.width, 0) AS width,
 COALESCE(f.height, 0) AS height,
 COALESCE(f.is_spoilered, FALSE) AS spoiler_file,
-t.locked, t.stickied, t.cyclic, t.is_spoilered as spoiler_thread, flag, country, p.is_deleted
+t.locked, t.stickied, t.cyclic, t.is_spoilered as spoiler_thread, flag, country, p.is_deleted, p.is_hidden
 FROM DBPREFIXposts p
 LEFT JOIN DBPREFIXfiles f ON f.post_id = p.id AND p.is_deleted = FALSE
 LEFT JOIN DBPREFIXthreads t ON t.id = p.thread_id
@@ -109,7 +109,8 @@ LEFT JOIN DBPREFIXboards b ON b.id = t.board_id;
 CREATE VIEW DBPREFIXv_post_reports AS
 SELECT r.id, handled_by_staff_id AS staff_id, username AS staff_user, post_id, 
 (SELECT id FROM DBPREFIXposts p2 WHERE p2.is_top_post AND p.thread_id = p2.thread_id LIMIT 1) AS thread_op,
-dir as board, INET6_NTOA(r.ip) as reporter_ip, INET6_NTOA(p.ip) as poster_ip, reason, is_cleared
+dir as board, INET6_NTOA(r.ip) as reporter_ip, INET6_NTOA(p.ip) as poster_ip, reason, category, is_cleared,
+r.timestamp, p.message_raw AS post_message, p.is_hidden AS post_hidden
 FROM DBPREFIXreports r
 LEFT JOIN DBPREFIXstaff s ON handled_by_staff_id = s.id
 INNER JOIN DBPREFIXposts p ON r.post_id = p.id
@@ -118,14 +119,14 @@ INNER JOIN DBPREFIXboards b ON t.board_id = b.id
 WHERE is_cleared = FALSE;
 
 CREATE VIEW DBPREFIXv_appeal_messages AS
-SELECT iba.id, ibaa.staff_id, username AS staff_username, iba.ip_ban_id, ibaa.appeal_text, ibaa.is_denied, ib.is_active as is_ban_active, ib.expires_at as ban_expires_at, ib.permanent as permanent, iba.timestamp
-FROM DBPREFIXip_ban_appeals iba
-INNER JOIN DBPREFIXip_ban_appeals_audit ibaa ON id = appeal_id
-INNER JOIN DBPREFIXip_ban ib ON iba.ip_ban_id = ib.id
-LEFT JOIN DBPREFIXstaff s ON ibaa.staff_id = s.id;
+SELECT m.id, m.appeal_id, iba.ip_ban_id, m.staff_id, username AS staff_username, m.message_text, m.timestamp
+FROM DBPREFIXip_ban_appeals_messages m
+INNER JOIN DBPREFIXip_ban_appeals iba ON m.appeal_id = iba.id
+LEFT JOIN DBPREFIXstaff s ON m.staff_id = s.id;
 
 CREATE VIEW DBPREFIXv_appeals AS
-SELECT iba.id, iba.staff_id, username AS staff_username, iba.ip_ban_id, iba.appeal_text, iba.is_denied, ib.is_active as is_ban_active, ib.expires_at as ban_expires_at, ib.permanent as permanent, iba.timestamp
+SELECT iba.id, iba.staff_id, username AS staff_username, iba.ip_ban_id, iba.appeal_text, iba.is_denied, iba.status, iba.staff_unread,
+ib.is_active as is_ban_active, ib.expires_at as ban_expires_at, ib.permanent as permanent, ib.appeal_at as ban_appeal_at, iba.timestamp
 FROM DBPREFIXip_ban_appeals iba
 INNER JOIN DBPREFIXip_ban ib ON iba.ip_ban_id = ib.id
 LEFT JOIN DBPREFIXstaff s ON iba.staff_id = s.id;
\ No newline at end of file